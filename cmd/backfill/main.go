@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+	"user-balance-service/config"
+	"user-balance-service/internal/app"
+)
+
+const dateLayout = "2006-01-02"
+
+func main() {
+	// Configuration
+	cfg, err := config.NewConfig()
+	if err != nil {
+		log.Fatalf("Config error: %s", err)
+	}
+
+	app.SetLogrus(cfg.Log.Level)
+
+	var (
+		currencies = flag.String("currencies", strings.Join(cfg.Converter.Currencies, ","), "comma-separated currencies")
+		from       = flag.String("from", "", "first day, "+dateLayout)
+		to         = flag.String("to", time.Now().Format(dateLayout), "last day, "+dateLayout)
+	)
+	flag.Parse()
+
+	fromDate, err := time.Parse(dateLayout, *from)
+	if err != nil {
+		log.Fatalf("Invalid -from: %s", err)
+	}
+	toDate, err := time.Parse(dateLayout, *to)
+	if err != nil {
+		log.Fatalf("Invalid -to: %s", err)
+	}
+	if toDate.Before(fromDate) {
+		log.Fatalf("-to %s is before -from %s", *to, *from)
+	}
+
+	// Backfill
+	err = app.Backfill(cfg, strings.Split(*currencies, ","), fromDate, toDate)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -3,16 +3,25 @@ package config
 import (
 	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
+	"time"
 )
 
 type (
 	Config struct {
 		App       `yaml:"app"`
 		HTTP      `yaml:"http"`
+		Metrics   `yaml:"metrics"`
+		Tracing   `yaml:"tracing"`
+		Health    `yaml:"health"`
 		Log       `yaml:"logger"`
 		PG        `yaml:"postgres"`
 		Converter `yaml:"converter"`
 		Redis     `yaml:"redis"`
+		Cache     `yaml:"cache"`
+		Password  `yaml:"password"`
+		Auth      `yaml:"auth"`
+		Login     `yaml:"login"`
+		TwoFactor `yaml:"two_factor"`
 	}
 
 	App struct {
@@ -20,8 +29,39 @@ type (
 		Version string `env-required:"true" yaml:"version" env:"APP_VERSION"`
 	}
 
+	// HTTP clients are told apart by the address of the connection. With
+	// TrustProxy the X-Forwarded-For header set by a reverse proxy is used.
 	HTTP struct {
-		Port string `env-required:"true" yaml:"port" env:"HTTP_PORT"`
+		Port       string `env-required:"true" yaml:"port"        env:"HTTP_PORT"`
+		TrustProxy bool   `                    yaml:"trust_proxy" env:"HTTP_TRUST_PROXY"`
+	}
+
+	// Metrics are served for Prometheus at /metrics on their own Port, so it
+	// needn't be reachable from where the API is
+	Metrics struct {
+		Port string `env-default:"9090" yaml:"port" env:"METRICS_PORT"`
+	}
+
+	// Tracing sends spans to Exporter: otlp (OTLP over HTTP to Endpoint,
+	// host:port, plain HTTP with Insecure), stdout (to File if set) or none.
+	// SampleRatio of the traces started here are recorded; requests that
+	// carry a trace context follow the caller's decision.
+	Tracing struct {
+		Exporter    string  `env-default:"none"           yaml:"exporter"     env:"TRACING_EXPORTER"`
+		Endpoint    string  `env-default:"localhost:4318" yaml:"endpoint"     env:"TRACING_ENDPOINT"`
+		Insecure    bool    `                             yaml:"insecure"     env:"TRACING_INSECURE"`
+		File        string  `                             yaml:"file"         env:"TRACING_FILE"`
+		SampleRatio float64 `env-default:"1"              yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+	}
+
+	// Health checks postgres, redis, migrations and converter for /readyz.
+	// The ones listed in Critical fail it when down, the others only degrade
+	// it. Every check gets Timeout. On shutdown /readyz fails for DrainDelay
+	// before the server stops, so load balancers stop sending requests first.
+	Health struct {
+		Critical   []string      `env-default:"postgres,redis,migrations" yaml:"critical"    env:"HEALTH_CRITICAL"`
+		Timeout    time.Duration `env-default:"2s"                        yaml:"timeout"     env:"HEALTH_TIMEOUT"`
+		DrainDelay time.Duration `env-default:"5s"                        yaml:"drain_delay" env:"HEALTH_DRAIN_DELAY"`
 	}
 
 	Log struct {
@@ -33,15 +73,107 @@ type (
 		URL     string `env-required:"true"                 env:"PG_URL"`
 	}
 
+	// Converter asks rate providers (apilayer, cbr, static) in the order of
+	// Providers. URL and ApiKey are used by apilayer, CBRURL by cbr and
+	// StaticFile (YAML or CSV) by static. Rates are refreshed every
+	// RefreshInterval and never served when older than MaxRateAge. Currencies
+	// are refreshed from startup, others after their first request. HTTP
+	// providers give every attempt Timeout, retry 5xx and network errors
+	// Retries times and stop calling a provider for BreakerTimeout after
+	// BreakerThreshold failed requests.
 	Converter struct {
-		URL    string `env-required:"true" yaml:"url" env:"CONVERTER_URL"`
-		ApiKey string `env-required:"true"            env:"CONVERTER_API_KEY"`
+		Providers        []string      `env-default:"apilayer,cbr" yaml:"providers"         env:"CONVERTER_PROVIDERS"`
+		URL              string        `                           yaml:"url"               env:"CONVERTER_URL"`
+		ApiKey           string        `                                                    env:"CONVERTER_API_KEY"`
+		CBRURL           string        `                           yaml:"cbr_url"           env:"CONVERTER_CBR_URL"`
+		StaticFile       string        `                           yaml:"static_file"       env:"CONVERTER_STATIC_FILE"`
+		RefreshInterval  time.Duration `env-default:"10m"          yaml:"refresh_interval"  env:"CONVERTER_REFRESH_INTERVAL"`
+		MaxRateAge       time.Duration `env-default:"1h"           yaml:"max_rate_age"      env:"CONVERTER_MAX_RATE_AGE"`
+		Currencies       []string      `env-default:"USD,EUR"      yaml:"currencies"        env:"CONVERTER_CURRENCIES"`
+		Timeout          time.Duration `env-default:"5s"           yaml:"timeout"           env:"CONVERTER_TIMEOUT"`
+		Retries          int           `env-default:"2"            yaml:"retries"           env:"CONVERTER_RETRIES"`
+		RetryBackoff     time.Duration `env-default:"200ms"        yaml:"retry_backoff"     env:"CONVERTER_RETRY_BACKOFF"`
+		BreakerThreshold int           `env-default:"5"            yaml:"breaker_threshold" env:"CONVERTER_BREAKER_THRESHOLD"`
+		BreakerTimeout   time.Duration `env-default:"30s"          yaml:"breaker_timeout"   env:"CONVERTER_BREAKER_TIMEOUT"`
 	}
 
+	// Redis holds the denylist of revoked access tokens, the failed sign-in
+	// counters and the 2FA challenges, and backs the "redis" and "tiered" cache
+	// backends. It is required whatever the cache backend.
 	Redis struct {
-		Addr     string `env-required:"true" yaml:"addr" env:"REDIS_ADDRESS"`
-		Password string `env-required:"true"             env:"REDIS_PASSWORD"`
-		DB       int    `env-required:"true" yaml:"db"   env:"REDIS_DB"`
+		Addr     string `yaml:"addr" env:"REDIS_ADDRESS"`
+		Password string `            env:"REDIS_PASSWORD"`
+		DB       int    `yaml:"db"   env:"REDIS_DB"`
+	}
+
+	// Cache backend is one of redis, memory (in-process LRU), none or tiered
+	// (memory in front of Redis, local copies live for LocalTTL). Codec is
+	// json or msgpack. The backend only picks where accounts and history are
+	// cached: auth state stays in Redis with memory and none too.
+	Cache struct {
+		Backend  string        `env-default:"redis" yaml:"backend"   env:"CACHE_BACKEND"`
+		Codec    string        `env-default:"json"  yaml:"codec"     env:"CACHE_CODEC"`
+		TTL      time.Duration `env-default:"5m"    yaml:"ttl"       env:"CACHE_TTL"`
+		Size     int           `env-default:"10000" yaml:"size"      env:"CACHE_SIZE"`
+		LocalTTL time.Duration `env-default:"1s"    yaml:"local_ttl" env:"CACHE_LOCAL_TTL"`
+	}
+
+	// Password hashes are made with Algorithm (argon2id or bcrypt). Argon2Memory
+	// is in KiB. Hashes made with other settings are replaced on the next sign-in.
+	Password struct {
+		Algorithm         string `env-default:"argon2id" yaml:"algorithm"          env:"PASSWORD_ALGORITHM"`
+		Argon2Memory      uint32 `env-default:"65536"    yaml:"argon2_memory"      env:"PASSWORD_ARGON2_MEMORY"`
+		Argon2Iterations  uint32 `env-default:"3"        yaml:"argon2_iterations"  env:"PASSWORD_ARGON2_ITERATIONS"`
+		Argon2Parallelism uint8  `env-default:"2"        yaml:"argon2_parallelism" env:"PASSWORD_ARGON2_PARALLELISM"`
+		BcryptCost        int    `env-default:"12"       yaml:"bcrypt_cost"        env:"PASSWORD_BCRYPT_COST"`
+	}
+
+	// Auth access tokens live for AccessTokenTTL. A refresh token can be
+	// exchanged for a new pair until RefreshTokenTTL after sign-in. Access
+	// tokens are signed with the key with id SigningKey and verified with any
+	// of Keys, so a new key can be added before it signs and an old one kept
+	// until its tokens expire. The signing key can also be read from
+	// SigningKeyFile with SigningKeyAlgorithm instead of being one of Keys.
+	Auth struct {
+		AccessTokenTTL      time.Duration `env-default:"15m"   yaml:"access_token_ttl"      env:"AUTH_ACCESS_TOKEN_TTL"`
+		RefreshTokenTTL     time.Duration `env-default:"720h"  yaml:"refresh_token_ttl"     env:"AUTH_REFRESH_TOKEN_TTL"`
+		SigningKey          string        `env-required:"true" yaml:"signing_key"           env:"AUTH_SIGNING_KEY"`
+		SigningKeyFile      string        `                    yaml:"signing_key_file"      env:"AUTH_SIGNING_KEY_FILE"`
+		SigningKeyAlgorithm string        `env-default:"EdDSA" yaml:"signing_key_algorithm" env:"AUTH_SIGNING_KEY_ALGORITHM"`
+		Keys                []JWTKey      `                    yaml:"keys"`
+	}
+
+	// Login locks a username out after MaxUserFailures failed sign-ins and an
+	// IP address after MaxIPFailures, counted until FailureWindow passes
+	// without one. The first lockout lasts LockoutBase, every further failure
+	// doubles it up to LockoutMax.
+	Login struct {
+		MaxUserFailures int           `env-default:"5"   yaml:"max_user_failures" env:"LOGIN_MAX_USER_FAILURES"`
+		MaxIPFailures   int           `env-default:"20"  yaml:"max_ip_failures"   env:"LOGIN_MAX_IP_FAILURES"`
+		FailureWindow   time.Duration `env-default:"24h" yaml:"failure_window"    env:"LOGIN_FAILURE_WINDOW"`
+		LockoutBase     time.Duration `env-default:"1m"  yaml:"lockout_base"      env:"LOGIN_LOCKOUT_BASE"`
+		LockoutMax      time.Duration `env-default:"1h"  yaml:"lockout_max"       env:"LOGIN_LOCKOUT_MAX"`
+	}
+
+	// TwoFactor names the service as Issuer in authenticator apps. The code
+	// after the password has to be given within ChallengeTTL. Transfers of
+	// more than TransferThreshold take a code too; 0 turns that off.
+	// Recovery codes are stored as an HMAC keyed with RecoveryKey.
+	TwoFactor struct {
+		Issuer            string        `env-default:"user-balance-service" yaml:"issuer"             env:"TWO_FACTOR_ISSUER"`
+		ChallengeTTL      time.Duration `env-default:"5m"                   yaml:"challenge_ttl"      env:"TWO_FACTOR_CHALLENGE_TTL"`
+		TransferThreshold int           `env-default:"0"                    yaml:"transfer_threshold" env:"TWO_FACTOR_TRANSFER_THRESHOLD"`
+		RecoveryKey       string        `env-required:"true"                                          env:"TWO_FACTOR_RECOVERY_KEY"`
+	}
+
+	// JWTKey of Algorithm (HS256, RS256 or EdDSA) is read from File or given
+	// in Secret: the secret for HS256, a PEM key otherwise. A public key is
+	// enough for keys that only verify.
+	JWTKey struct {
+		Id        string `yaml:"id"`
+		Algorithm string `yaml:"algorithm"`
+		Secret    string `yaml:"secret"`
+		File      string `yaml:"file"`
 	}
 )
 
@@ -1,32 +1,59 @@
 package app
 
 import (
+	"context"
 	"fmt"
-	"github.com/go-redis/redis/v8"
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 	"user-balance-service/config"
+	"user-balance-service/internal/controller/http/problem"
 	v1 "user-balance-service/internal/controller/http/v1"
+	v2 "user-balance-service/internal/controller/http/v2"
+	"user-balance-service/internal/controller/http/validation"
+	"user-balance-service/internal/metrics"
 	"user-balance-service/internal/service"
 	"user-balance-service/internal/service/repo"
-	"user-balance-service/internal/service/webapi"
 	"user-balance-service/pkg/httpserver"
+	"user-balance-service/pkg/passhash"
 	"user-balance-service/pkg/postgres"
-	"user-balance-service/pkg/rediscache"
+	"user-balance-service/pkg/tracing"
 )
 
+// tracingShutdownTimeout bounds flushing the spans left on exit
+const tracingShutdownTimeout = 5 * time.Second
+
 func Run(cfg *config.Config) {
+	appMetrics := metrics.New()
+
+	// Tracing
+	log.Infof("Initializing %s tracing...", cfg.Tracing.Exporter)
+	shutdownTracing, err := setupTracing(cfg)
+	if err != nil {
+		log.Fatal(fmt.Errorf("app - Run - setupTracing: %w", err))
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+
+		err := shutdownTracing(ctx)
+		if err != nil {
+			log.Error(fmt.Errorf("app - Run - shutdownTracing: %w", err))
+		}
+	}()
+
 	// Cache
-	log.Info("Initializing Redis...")
-	redisCache := rediscache.New(redis.NewClient(&redis.Options{
-		Addr:     cfg.Redis.Addr,
-		Password: cfg.Redis.Password,
-		DB:       cfg.Redis.DB,
-	}))
+	log.Infof("Initializing %s cache...", cfg.Cache.Backend)
+	redisClient := newRedisClient(cfg)
+	defer redisClient.Close()
+	cacheBackend, err := newCache(cfg, redisClient, appMetrics)
+	if err != nil {
+		log.Fatal(fmt.Errorf("app - Run - newCache: %w", err))
+	}
 
 	// Repository
 	log.Info("Initializing repository")
@@ -35,24 +62,103 @@ func Run(cfg *config.Config) {
 		log.Fatal(fmt.Errorf("app - Run - postgres.New: %w", err))
 	}
 	defer pg.Close()
+	if pool, ok := pg.Pool.(metrics.Pool); ok {
+		appMetrics.ObservePool(pool)
+	}
 
 	// Web API
 	log.Info("Initializing webapi...")
-	converterWebApi := webapi.NewConverterAPI(http.DefaultClient, cfg.Converter.URL, cfg.Converter.ApiKey)
+	rateProvider, err := newRateProvider(cfg, appMetrics)
+	if err != nil {
+		log.Fatal(fmt.Errorf("app - Run - newRateProvider: %w", err))
+	}
+
+	// Exchange rates
+	log.Infof("Starting exchange rate refresh from %s...", rateProvider.Name())
+	rates := service.NewRateStore(rateProvider,
+		service.MaxRateAge(cfg.Converter.MaxRateAge),
+		service.Preload(cfg.Converter.Currencies...),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rates.Run(ctx, cfg.Converter.RefreshInterval)
 
 	// Service
 	log.Info("Initializing service...")
-	services := service.New(
-		repo.New(pg, redisCache),
-		converterWebApi,
+	hasher, err := passhash.New(
+		passhash.Algorithm(cfg.Password.Algorithm),
+		passhash.Argon2(cfg.Password.Argon2Memory, cfg.Password.Argon2Iterations, cfg.Password.Argon2Parallelism),
+		passhash.BcryptCost(cfg.Password.BcryptCost),
 	)
+	if err != nil {
+		log.Fatal(fmt.Errorf("app - Run - passhash.New: %w", err))
+	}
+	tokenKeys, err := newTokenKeys(cfg)
+	if err != nil {
+		log.Fatal(fmt.Errorf("app - Run - newTokenKeys: %w", err))
+	}
+	if cfg.Login.MaxUserFailures <= 0 || cfg.Login.MaxIPFailures <= 0 {
+		log.Fatalf("app - Run: login max failures must be positive, got %d per user and %d per IP", cfg.Login.MaxUserFailures, cfg.Login.MaxIPFailures)
+	}
+	services := service.New(service.Deps{
+		Repo:          repo.New(pg, cacheBackend),
+		Rates:         rates,
+		RateProvider:  rateProvider,
+		Hasher:        hasher,
+		TokenKeys:     tokenKeys,
+		Denylist:      repo.NewDenylistRepo(redisClient),
+		LoginAttempts: repo.NewLoginAttemptsRepo(redisClient),
+		Challenges:    repo.NewChallengeRepo(redisClient),
+		AuthOptions: []service.AuthOption{
+			service.AccessTokenTTL(cfg.Auth.AccessTokenTTL),
+			service.RefreshTokenTTL(cfg.Auth.RefreshTokenTTL),
+		},
+		LoginGuardOptions: []service.LoginGuardOption{
+			service.MaxFailures(cfg.Login.MaxUserFailures, cfg.Login.MaxIPFailures),
+			service.FailureWindow(cfg.Login.FailureWindow),
+			service.Lockout(cfg.Login.LockoutBase, cfg.Login.LockoutMax),
+		},
+		TwoFactorOptions: []service.TwoFactorOption{
+			service.TwoFactorIssuer(cfg.TwoFactor.Issuer),
+			service.ChallengeTTL(cfg.TwoFactor.ChallengeTTL),
+			service.TransferThreshold(cfg.TwoFactor.TransferThreshold),
+			service.RecoveryCodeKey([]byte(cfg.TwoFactor.RecoveryKey)),
+		},
+		AccountOptions: []service.AccountOption{
+			service.ObserveOperations(appMetrics),
+		},
+	})
+
+	// Health
+	log.Info("Initializing health checks...")
+	readiness, err := newHealth(cfg, pg, redisClient, rateProvider)
+	if err != nil {
+		log.Fatal(fmt.Errorf("app - Run - newHealth: %w", err))
+	}
 
 	// HTTP Server
 	log.Info("Initializing http server...")
 	handler := echo.New()
+	handler.HTTPErrorHandler = problem.HTTPErrorHandler
+	handler.Validator = validation.New()
+	handler.IPExtractor = echo.ExtractIPDirect()
+	if cfg.HTTP.TrustProxy {
+		handler.IPExtractor = echo.ExtractIPFromXFFHeader()
+	}
+	handler.Use(tracing.Middleware(cfg.App.Name), appMetrics.Middleware())
+	v1.NewHealthRoutes(handler, readiness)
+	v1.NewJWKSRoutes(handler, tokenKeys)
 	v1.NewRouter(handler, services)
+	v2.NewRouter(handler, services, v1.NewAuthMiddleware(services.Auth, services.ApiKeys).UserIdentity)
 	httpServer := httpserver.New(handler, httpserver.Port(cfg.HTTP.Port))
 
+	// Metrics server
+	log.Infof("Serving metrics on port %s...", cfg.Metrics.Port)
+	metricsHandler := http.NewServeMux()
+	metricsHandler.Handle("/metrics", appMetrics.Handler())
+	metricsHandler.Handle("/readyz", readiness)
+	metricsServer := httpserver.New(metricsHandler, httpserver.Port(cfg.Metrics.Port))
+
 	// Waiting signal
 	log.Info("Configuring graceful shutdown...")
 	interrupt := make(chan os.Signal, 1)
@@ -63,12 +169,22 @@ func Run(cfg *config.Config) {
 		log.Info("app - Run - signal: " + s.String())
 	case err = <-httpServer.Notify():
 		log.Error(fmt.Errorf("app - Run - httpServer.Notify: %w", err))
+	case err = <-metricsServer.Notify():
+		log.Error(fmt.Errorf("app - Run - metricsServer.Notify: %w", err))
 	}
 
 	// Graceful shutdown
+	log.Infof("Draining for %s...", cfg.Health.DrainDelay)
+	readiness.Drain()
+	time.Sleep(cfg.Health.DrainDelay)
+
 	log.Info("Shutting down...")
 	err = httpServer.Shutdown()
 	if err != nil {
 		log.Error(fmt.Errorf("app - Run - httpServer.Shutdown: %w", err))
 	}
+	err = metricsServer.Shutdown()
+	if err != nil {
+		log.Error(fmt.Errorf("app - Run - metricsServer.Shutdown: %w", err))
+	}
 }
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"time"
+	"user-balance-service/config"
+	"user-balance-service/internal/metrics"
+	"user-balance-service/internal/service"
+	"user-balance-service/internal/service/repo"
+	"user-balance-service/pkg/postgres"
+)
+
+// Backfill stores the daily rates of currencies between from and to that are
+// missing in Postgres, so history conversion doesn't have to fetch them.
+func Backfill(cfg *config.Config, currencies []string, from, to time.Time) error {
+	pg, err := postgres.New(cfg.PG.URL, postgres.MaxPoolSize(cfg.PG.PoolMax))
+	if err != nil {
+		return fmt.Errorf("app - Backfill - postgres.New: %w", err)
+	}
+	defer pg.Close()
+
+	// nothing scrapes a backfill, its metrics are dropped
+	rateProvider, err := newRateProvider(cfg, metrics.New())
+	if err != nil {
+		return fmt.Errorf("app - Backfill - newRateProvider: %w", err)
+	}
+
+	dailyRates := service.NewDailyRates(repo.NewRateRepo(pg), rateProvider)
+
+	for _, currency := range currencies {
+		fetched, err := dailyRates.Backfill(context.Background(), currency, from, to)
+		if err != nil {
+			return fmt.Errorf("app - Backfill - %s after %d days: %w", currency, fetched, err)
+		}
+
+		log.Infof("Backfilled %d days of %s rates", fetched, currency)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"user-balance-service/config"
+	"user-balance-service/internal/service"
+	"user-balance-service/pkg/cache"
+	"user-balance-service/pkg/memcache"
+	"user-balance-service/pkg/rediscache"
+)
+
+const (
+	cacheBackendRedis  = "redis"
+	cacheBackendMemory = "memory"
+	cacheBackendNone   = "none"
+	cacheBackendTiered = "tiered"
+)
+
+func newRedisClient(cfg *config.Config) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+}
+
+// newCache builds the cache backend chosen in config. Redis is wrapped so its
+// failures are reported to metrics and never fail requests.
+func newCache(cfg *config.Config, client *redis.Client, metrics cache.Metrics) (service.RedisCache, error) {
+	codec, err := cache.CodecByName(cfg.Cache.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	newRedis := func() *cache.Resilient {
+		redisCache := rediscache.New(client, rediscache.Expire(cfg.Cache.TTL), rediscache.Codec(codec))
+
+		return cache.NewResilient(redisCache, cache.Observe(metrics))
+	}
+
+	if (cfg.Cache.Backend == cacheBackendMemory || cfg.Cache.Backend == cacheBackendTiered) && cfg.Cache.Size <= 0 {
+		return nil, fmt.Errorf("cache size must be positive, got %d", cfg.Cache.Size)
+	}
+
+	switch cfg.Cache.Backend {
+	case cacheBackendRedis:
+		return newRedis(), nil
+	case cacheBackendMemory:
+		return memcache.New(memcache.Size(cfg.Cache.Size), memcache.Expire(cfg.Cache.TTL), memcache.Codec(codec)), nil
+	case cacheBackendNone:
+		return memcache.Nop{}, nil
+	case cacheBackendTiered:
+		front := memcache.New(memcache.Size(cfg.Cache.Size), memcache.Expire(cfg.Cache.LocalTTL), memcache.Codec(codec))
+		return memcache.NewTiered(front, newRedis()), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", cfg.Cache.Backend)
+	}
+}
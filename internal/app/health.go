@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"os"
+	"strconv"
+	"strings"
+	"user-balance-service/config"
+	"user-balance-service/internal/service/repo"
+	"user-balance-service/internal/service/webapi"
+	"user-balance-service/pkg/health"
+	"user-balance-service/pkg/postgres"
+)
+
+const (
+	checkPostgres   = "postgres"
+	checkRedis      = "redis"
+	checkMigrations = "migrations"
+	checkConverter  = "converter"
+
+	// migrationsDir holds the migrations, applied with the migrate build tag
+	migrationsDir = "migrations"
+)
+
+// newHealth registers the readiness checks, critical if listed in config
+func newHealth(cfg *config.Config, pg *postgres.Postgres, redisClient *redis.Client, rateProvider *webapi.Fallback) (*health.Health, error) {
+	if cfg.Health.Timeout <= 0 {
+		return nil, fmt.Errorf("health timeout must be positive, got %s", cfg.Health.Timeout)
+	}
+
+	critical := make(map[string]bool, len(cfg.Health.Critical))
+	for _, name := range cfg.Health.Critical {
+		switch name {
+		case checkPostgres, checkRedis, checkMigrations, checkConverter:
+			critical[name] = true
+		default:
+			return nil, fmt.Errorf("unknown health check %q", name)
+		}
+	}
+
+	schema := repo.NewSchemaRepo(pg)
+
+	h := health.New(health.Timeout(cfg.Health.Timeout))
+	h.Add(checkPostgres, critical[checkPostgres], pg.Pool.Ping)
+	h.Add(checkRedis, critical[checkRedis], func(ctx context.Context) error {
+		return redisClient.Ping(ctx).Err()
+	})
+	h.Add(checkMigrations, critical[checkMigrations], func(ctx context.Context) error {
+		return checkMigrationVersion(ctx, schema)
+	})
+	h.Add(checkConverter, critical[checkConverter], rateProvider.Ping)
+
+	return h, nil
+}
+
+// checkMigrationVersion fails while the schema is behind the migrations the
+// service was shipped with or a migration failed halfway. A newer schema is
+// fine: it's being rolled out and migrations are backward compatible.
+func checkMigrationVersion(ctx context.Context, schema *repo.SchemaRepo) error {
+	want, err := latestMigration(migrationsDir)
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := schema.MigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case dirty:
+		return fmt.Errorf("migration %d failed halfway", version)
+	case version < want:
+		return fmt.Errorf("schema version %d, want %d", version, want)
+	}
+
+	return nil
+}
+
+// latestMigration returns the highest version of the up migrations in dir,
+// named like 000011_audit_log.up.sql
+func latestMigration(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("migrations: %w", err)
+	}
+
+	latest := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".up.sql") {
+			continue
+		}
+
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			continue
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+
+	return latest, nil
+}
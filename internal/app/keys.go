@@ -0,0 +1,57 @@
+package app
+
+import (
+	"fmt"
+	"user-balance-service/config"
+	"user-balance-service/pkg/jwtkeys"
+)
+
+// newTokenKeys loads the keys access tokens are signed and verified with
+func newTokenKeys(cfg *config.Config) (*jwtkeys.Set, error) {
+	var (
+		signing      *jwtkeys.Key
+		verification []*jwtkeys.Key
+	)
+
+	keys := cfg.Auth.Keys
+	if cfg.Auth.SigningKeyFile != "" {
+		keys = append(keys, config.JWTKey{
+			Id:        cfg.Auth.SigningKey,
+			Algorithm: cfg.Auth.SigningKeyAlgorithm,
+			File:      cfg.Auth.SigningKeyFile,
+		})
+	}
+
+	for _, k := range keys {
+		key, err := loadTokenKey(k)
+		if err != nil {
+			return nil, err
+		}
+
+		if k.Id == cfg.Auth.SigningKey {
+			if signing != nil {
+				return nil, fmt.Errorf("signing key %q is configured twice", k.Id)
+			}
+			signing = key
+		} else {
+			verification = append(verification, key)
+		}
+	}
+
+	if signing == nil {
+		return nil, fmt.Errorf("signing key %q is not one of auth keys", cfg.Auth.SigningKey)
+	}
+
+	return jwtkeys.New(signing, verification...)
+}
+
+func loadTokenKey(k config.JWTKey) (*jwtkeys.Key, error) {
+	switch {
+	case k.File != "":
+		return jwtkeys.Load(k.Id, k.Algorithm, k.File)
+	case k.Algorithm == jwtkeys.HS256:
+		return jwtkeys.NewHMAC(k.Id, []byte(k.Secret))
+	default:
+		return jwtkeys.ParsePEM(k.Id, k.Algorithm, []byte(k.Secret))
+	}
+}
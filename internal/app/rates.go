@@ -0,0 +1,79 @@
+package app
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"user-balance-service/config"
+	"user-balance-service/internal/metrics"
+	"user-balance-service/internal/service/webapi"
+	"user-balance-service/pkg/breaker"
+	"user-balance-service/pkg/httpclient"
+	"user-balance-service/pkg/tracing"
+)
+
+const (
+	rateProviderApilayer = "apilayer"
+	rateProviderCBR      = "cbr"
+	rateProviderStatic   = "static"
+)
+
+// newRateProvider chains the providers listed in config, the first one that
+// answers wins. Every HTTP provider gets its own client, so one provider's
+// open circuit breaker doesn't stop the others, and its calls are measured
+// and traced under its name.
+func newRateProvider(cfg *config.Config, m *metrics.Metrics) (*webapi.Fallback, error) {
+	if len(cfg.Converter.Providers) == 0 {
+		return nil, fmt.Errorf("no rate providers configured")
+	}
+	if cfg.Converter.Retries < 0 {
+		return nil, fmt.Errorf("converter retries must not be negative, got %d", cfg.Converter.Retries)
+	}
+	if cfg.Converter.RetryBackoff < 0 {
+		return nil, fmt.Errorf("converter retry backoff must not be negative, got %s", cfg.Converter.RetryBackoff)
+	}
+
+	providers := make([]webapi.Provider, 0, len(cfg.Converter.Providers))
+	for _, name := range cfg.Converter.Providers {
+		switch name {
+		case rateProviderApilayer:
+			providers = append(providers, webapi.NewApilayer(m.Doer(name, tracing.NewDoer(name, newRateClient(cfg, name))), cfg.Converter.URL, cfg.Converter.ApiKey,
+				webapi.PingClient(newPingClient(cfg, name))))
+		case rateProviderCBR:
+			providers = append(providers, webapi.NewCBR(m.Doer(name, tracing.NewDoer(name, newRateClient(cfg, name))), cfg.Converter.CBRURL,
+				webapi.PingClient(newPingClient(cfg, name))))
+		case rateProviderStatic:
+			static, err := webapi.NewStaticFile(cfg.Converter.StaticFile)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, static)
+		default:
+			return nil, fmt.Errorf("unknown rate provider %q", name)
+		}
+	}
+
+	return webapi.NewFallback(providers...), nil
+}
+
+func newRateClient(cfg *config.Config, provider string) *httpclient.Client {
+	return httpclient.New(
+		httpclient.Timeout(cfg.Converter.Timeout),
+		httpclient.Retries(cfg.Converter.Retries),
+		httpclient.Backoff(cfg.Converter.RetryBackoff, 10*cfg.Converter.RetryBackoff),
+		httpclient.CircuitBreaker(breaker.New(
+			breaker.Threshold(cfg.Converter.BreakerThreshold),
+			breaker.Timeout(cfg.Converter.BreakerTimeout),
+			breaker.OnStateChange(func(from, to breaker.State) {
+				log.Warnf("webapi - %s - circuit breaker: %s -> %s", provider, from, to)
+			}),
+		)),
+	)
+}
+
+// newPingClient is the client of the readiness checks of provider: one try
+// and no breaker, so probes neither wait on retries nor trip the breaker of
+// rate requests, and they aren't counted as provider calls
+func newPingClient(cfg *config.Config, provider string) tracing.Doer {
+	return tracing.NewDoer(provider, &http.Client{Timeout: cfg.Converter.Timeout})
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"os"
+	"user-balance-service/config"
+)
+
+const (
+	tracingExporterOTLP   = "otlp"
+	tracingExporterStdout = "stdout"
+	tracingExporterNone   = "none"
+)
+
+// setupTracing sets the global propagator to W3C trace context and baggage
+// and, unless tracing is off, the global tracer provider. The returned func
+// flushes the spans left and closes the exporter.
+func setupTracing(cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Warnf("app - tracing: %s", err)
+	}))
+
+	exporter, closeExporter, err := newSpanExporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceNameKey.String(cfg.App.Name),
+			semconv.ServiceVersionKey.String(cfg.App.Version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if err != nil {
+			return fmt.Errorf("provider.Shutdown: %w", err)
+		}
+
+		return closeExporter()
+	}, nil
+}
+
+// newSpanExporter returns the exporter chosen in config, nil for none, and
+// what has to be closed after it
+func newSpanExporter(cfg *config.Config) (sdktrace.SpanExporter, func() error, error) {
+	noClose := func() error { return nil }
+
+	switch cfg.Tracing.Exporter {
+	case tracingExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Tracing.Endpoint)}
+		if cfg.Tracing.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err := otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("otlptracehttp.New: %w", err)
+		}
+
+		return exporter, noClose, nil
+	case tracingExporterStdout:
+		if cfg.Tracing.File == "" {
+			exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+			if err != nil {
+				return nil, nil, fmt.Errorf("stdouttrace.New: %w", err)
+			}
+
+			return exporter, noClose, nil
+		}
+
+		file, err := os.OpenFile(cfg.Tracing.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("os.OpenFile: %w", err)
+		}
+
+		// one span per line
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("stdouttrace.New: %w", err)
+		}
+
+		return exporter, file.Close, nil
+	case tracingExporterNone:
+		return nil, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown tracing exporter %q", cfg.Tracing.Exporter)
+	}
+}
@@ -0,0 +1,213 @@
+// Package audit records mutating API calls, who made them and how they
+// ended, in the audit log.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"user-balance-service/internal/controller/http/problem"
+	"user-balance-service/internal/controller/http/rbac"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+)
+
+const (
+	accountsCtx = "audit_accounts"
+
+	// maxBodySize of the request bodies recorded, larger ones are recorded
+	// without the body
+	maxBodySize = 64 << 10
+	// maxRequestIdLength and maxUserAgentLength keep client-supplied
+	// X-Request-ID and User-Agent values in check, in bytes
+	maxRequestIdLength = 64
+	maxUserAgentLength = 512
+	// recordTimeout bounds writing a record, which doesn't depend on the
+	// client still waiting for the response
+	recordTimeout = 5 * time.Second
+)
+
+// secretParams are recorded as "***"
+var secretParams = map[string]bool{
+	"password":         true,
+	"current_password": true,
+	"new_password":     true,
+	"code":             true,
+	"refresh_token":    true,
+	"challenge":        true,
+}
+
+// Account tags the request with accounts it operates on, so that the audit
+// log can be queried by account
+func Account(c echo.Context, ids ...int) {
+	accounts, _ := c.Get(accountsCtx).([]int)
+	for _, id := range ids {
+		if !contains(accounts, id) {
+			accounts = append(accounts, id)
+		}
+	}
+	c.Set(accountsCtx, accounts)
+}
+
+// Log records every request but GET, HEAD and OPTIONS once it's handled,
+// failed ones included. Put in front of the auth middleware it records
+// unauthenticated calls too, without an actor. A record that can't be
+// written is logged and doesn't affect the response.
+func Log(s service.Audit) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodOptions {
+				return next(c)
+			}
+
+			params := readParams(c)
+
+			err := next(c)
+
+			actor := rbac.Actor(c)
+			accounts, _ := c.Get(accountsCtx).([]int)
+			record := entity.AuditRecord{
+				UserId:     actor.UserId,
+				ApiKeyId:   actor.ApiKeyId,
+				Role:       actor.Role,
+				IP:         c.RealIP(),
+				UserAgent:  clean(req.UserAgent(), maxUserAgentLength),
+				RequestId:  requestId(c),
+				Operation:  req.Method + " " + c.Path(),
+				AccountIds: accounts,
+				Params:     params,
+				Status:     c.Response().Status,
+				Outcome:    entity.AuditSucceeded,
+			}
+			if err != nil {
+				p := problem.New(err)
+				record.Status = p.Status
+				record.Error = p.Code
+			}
+			if record.Status >= http.StatusBadRequest {
+				record.Outcome = entity.AuditFailed
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+			defer cancel()
+
+			rerr := s.Record(ctx, record)
+			if rerr != nil {
+				log.Errorf("audit - Log - s.Record: %s", rerr)
+			}
+
+			return err
+		}
+	}
+}
+
+// readParams collects the path and query parameters and the fields of a
+// JSON body, leaving the body to be read again by the handler
+func readParams(c echo.Context) json.RawMessage {
+	params := map[string]interface{}{}
+
+	if names := c.ParamNames(); len(names) > 0 {
+		path := map[string]string{}
+		for i, name := range names {
+			path[name] = c.ParamValues()[i]
+		}
+		params["path"] = path
+	}
+
+	if query := c.QueryParams(); len(query) > 0 {
+		values := map[string]interface{}{}
+		for name := range query {
+			values[name] = redact(name, query.Get(name))
+		}
+		params["query"] = values
+	}
+
+	if body := readBody(c.Request()); body != nil {
+		params["body"] = body
+	}
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		log.Errorf("audit - readParams - json.Marshal: %s", err)
+		return json.RawMessage("{}")
+	}
+
+	return raw
+}
+
+// readBody returns the fields of a JSON object body with secrets redacted
+func readBody(req *http.Request) map[string]interface{} {
+	if req.Body == nil || !strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(req.Body, maxBodySize+1))
+	req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), req.Body))
+	if err != nil || len(data) > maxBodySize {
+		return nil
+	}
+
+	var body map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&body)
+	if err != nil {
+		return nil
+	}
+
+	for name, value := range body {
+		body[name] = redact(name, value)
+	}
+
+	return body
+}
+
+func redact(name string, value interface{}) interface{} {
+	if secretParams[strings.ToLower(name)] {
+		return "***"
+	}
+
+	return value
+}
+
+func requestId(c echo.Context) string {
+	id := c.Response().Header().Get(echo.HeaderXRequestID)
+	if id == "" {
+		id = c.Request().Header.Get(echo.HeaderXRequestID)
+	}
+
+	return clean(id, maxRequestIdLength)
+}
+
+// clean drops invalid UTF-8 from a header value, which the log can't store,
+// and cuts it to max bytes without splitting a character
+func clean(value string, max int) string {
+	value = strings.ToValidUTF8(value, "")
+	if len(value) <= max {
+		return value
+	}
+
+	for max > 0 && !utf8.RuneStart(value[max]) {
+		max--
+	}
+
+	return value[:max]
+}
+
+func contains(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,206 @@
+package audit
+
+import (
+	"bytes"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user-balance-service/internal/controller/http/problem"
+	"user-balance-service/internal/controller/http/rbac"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+func TestLog(t *testing.T) {
+	actor := entity.Actor{UserId: 1, Role: entity.RoleUser, ApiKeyId: 3}
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		requestId  string
+		userAgent  string
+		handler    echo.HandlerFunc
+		authorized bool
+		wantRecord *entity.AuditRecord
+	}{
+		{
+			name:      "Succeeded",
+			method:    http.MethodPost,
+			path:      "/accounts/7/deposits?note=x",
+			body:      `{"amount":100}`,
+			requestId: "req-1",
+			handler: func(c echo.Context) error {
+				Account(c, 7)
+				return c.NoContent(http.StatusCreated)
+			},
+			authorized: true,
+			wantRecord: &entity.AuditRecord{
+				UserId:     1,
+				ApiKeyId:   3,
+				Role:       entity.RoleUser,
+				IP:         "192.0.2.1",
+				UserAgent:  "billing/1.0",
+				RequestId:  "req-1",
+				Operation:  "POST /accounts/:id/deposits",
+				AccountIds: []int{7},
+				Params:     []byte(`{"body":{"amount":100},"path":{"id":"7"},"query":{"note":"x"}}`),
+				Status:     http.StatusCreated,
+				Outcome:    entity.AuditSucceeded,
+			},
+		},
+		{
+			name:   "Failed",
+			method: http.MethodPost,
+			path:   "/accounts/7/withdrawals",
+			body:   `{"amount":100}`,
+			handler: func(c echo.Context) error {
+				Account(c, 7, 7)
+				return service.ErrInsufficientFunds
+			},
+			authorized: true,
+			wantRecord: &entity.AuditRecord{
+				UserId:     1,
+				ApiKeyId:   3,
+				Role:       entity.RoleUser,
+				IP:         "192.0.2.1",
+				UserAgent:  "billing/1.0",
+				Operation:  "POST /accounts/:id/withdrawals",
+				AccountIds: []int{7},
+				Params:     []byte(`{"body":{"amount":100},"path":{"id":"7"}}`),
+				Status:     http.StatusConflict,
+				Outcome:    entity.AuditFailed,
+				Error:      "insufficient_funds",
+			},
+		},
+		{
+			name:   "Unauthenticated",
+			method: http.MethodDelete,
+			path:   "/me",
+			handler: func(c echo.Context) error {
+				return c.NoContent(http.StatusNoContent)
+			},
+			wantRecord: &entity.AuditRecord{
+				IP:        "192.0.2.1",
+				UserAgent: "billing/1.0",
+				Operation: "DELETE /me",
+				Params:    []byte(`{}`),
+				Status:    http.StatusUnauthorized,
+				Outcome:   entity.AuditFailed,
+				Error:     "unauthorized",
+			},
+		},
+		{
+			name:      "Headers are cleaned",
+			method:    http.MethodDelete,
+			path:      "/me",
+			requestId: "req-\xffx" + strings.Repeat("é", 40),
+			userAgent: "billing/\xff1.0",
+			handler: func(c echo.Context) error {
+				return c.NoContent(http.StatusNoContent)
+			},
+			authorized: true,
+			wantRecord: &entity.AuditRecord{
+				UserId:    1,
+				ApiKeyId:  3,
+				Role:      entity.RoleUser,
+				IP:        "192.0.2.1",
+				UserAgent: "billing/1.0",
+				RequestId: "req-x" + strings.Repeat("é", 29),
+				Operation: "DELETE /me",
+				Params:    []byte(`{}`),
+				Status:    http.StatusNoContent,
+				Outcome:   entity.AuditSucceeded,
+			},
+		},
+		{
+			name:   "Secrets are redacted",
+			method: http.MethodPut,
+			path:   "/me/password",
+			body:   `{"current_password":"qwerty123","new_password":"qwerty456"}`,
+			handler: func(c echo.Context) error {
+				return c.NoContent(http.StatusNoContent)
+			},
+			authorized: true,
+			wantRecord: &entity.AuditRecord{
+				UserId:    1,
+				ApiKeyId:  3,
+				Role:      entity.RoleUser,
+				IP:        "192.0.2.1",
+				UserAgent: "billing/1.0",
+				Operation: "PUT /me/password",
+				Params:    []byte(`{"body":{"current_password":"***","new_password":"***"}}`),
+				Status:    http.StatusNoContent,
+				Outcome:   entity.AuditSucceeded,
+			},
+		},
+		{
+			name:   "Reads aren't recorded",
+			method: http.MethodGet,
+			path:   "/accounts/7",
+			handler: func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			},
+			authorized: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			audit := mock_service.NewMockAudit(ctrl)
+			if tc.wantRecord != nil {
+				audit.EXPECT().Record(gomock.Any(), *tc.wantRecord).Return(nil)
+			}
+
+			e := echo.New()
+			e.HTTPErrorHandler = problem.HTTPErrorHandler
+			e.IPExtractor = echo.ExtractIPDirect()
+			auth := func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					if !tc.authorized {
+						return echo.NewHTTPError(http.StatusUnauthorized, "invalid auth header")
+					}
+					rbac.SetActor(c, actor)
+					return next(c)
+				}
+			}
+			// the handler still gets the whole body
+			handler := func(c echo.Context) error {
+				body, err := io.ReadAll(c.Request().Body)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.body, string(body))
+				return tc.handler(c)
+			}
+			g := e.Group("", Log(audit), auth)
+			g.Add(tc.method, "/accounts/:id/deposits", handler)
+			g.Add(tc.method, "/accounts/:id/withdrawals", handler)
+			g.Add(tc.method, "/accounts/:id", handler)
+			g.Add(tc.method, "/me", handler)
+			g.Add(tc.method, "/me/password", handler)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+			req.RemoteAddr = "192.0.2.1:4321"
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("User-Agent", "billing/1.0")
+			if tc.userAgent != "" {
+				req.Header.Set("User-Agent", tc.userAgent)
+			}
+			if tc.requestId != "" {
+				req.Header.Set(echo.HeaderXRequestID, tc.requestId)
+			}
+
+			e.ServeHTTP(w, req)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+// Package etag exposes account versions as entity tags for optimistic
+// concurrency control.
+package etag
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+// Format returns a strong entity tag for version, e.g. "3"
+func Format(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// Set writes the ETag response header
+func Set(c echo.Context, version int) {
+	c.Response().Header().Set(HeaderETag, Format(version))
+}
+
+// IfMatch returns the version expected by the If-Match request header.
+// 0 means the header is absent or "*", i.e. any version is accepted.
+func IfMatch(c echo.Context) (int, error) {
+	header := strings.TrimSpace(c.Request().Header.Get(HeaderIfMatch))
+	if len(header) == 0 || header == "*" {
+		return 0, nil
+	}
+
+	// If-Match uses the strong comparison, so weak tags and lists are rejected
+	value, err := strconv.Unquote(header)
+	if err != nil || strings.HasPrefix(header, "W/") {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "If-Match must be a single strong ETag")
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil || version <= 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "If-Match must be a single strong ETag")
+	}
+
+	return version, nil
+}
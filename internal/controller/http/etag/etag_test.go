@@ -0,0 +1,54 @@
+package etag
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIfMatch(t *testing.T) {
+	testCases := []struct {
+		name        string
+		header      string
+		wantVersion int
+		wantErr     bool
+	}{
+		{name: "absent", header: "", wantVersion: 0},
+		{name: "any", header: "*", wantVersion: 0},
+		{name: "strong", header: `"3"`, wantVersion: 3},
+		{name: "weak", header: `W/"3"`, wantErr: true},
+		{name: "unquoted", header: "3", wantErr: true},
+		{name: "list", header: `"3", "4"`, wantErr: true},
+		{name: "not a number", header: `"abc"`, wantErr: true},
+		{name: "zero", header: `"0"`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", nil)
+			if len(tc.header) != 0 {
+				req.Header.Set(HeaderIfMatch, tc.header)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			got, err := IfMatch(c)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.wantVersion, got)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), w)
+
+	Set(c, 7)
+
+	assert.Equal(t, `"7"`, w.Header().Get(HeaderETag))
+}
@@ -0,0 +1,168 @@
+// Package problem renders errors as RFC 7807 problem details.
+package problem
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"user-balance-service/internal/controller/http/etag"
+	"user-balance-service/internal/controller/http/validation"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+)
+
+const (
+	ContentType = "application/problem+json"
+
+	typePrefix     = "/problems/"
+	internalCode   = "internal_error"
+	validationCode = "validation_failed"
+)
+
+// Details is the problem+json body. Code, Errors and Current are extension
+// members holding a stable machine-readable error code, per-field violations
+// and the current account state after a failed conditional update.
+type Details struct {
+	Type     string                  `json:"type"`
+	Title    string                  `json:"title"`
+	Status   int                     `json:"status"`
+	Detail   string                  `json:"detail,omitempty"`
+	Instance string                  `json:"instance,omitempty"`
+	Code     string                  `json:"code"`
+	Errors   []validation.FieldError `json:"errors,omitempty"`
+	Current  *entity.Account         `json:"current,omitempty"`
+	// RetryAfter is sent in the Retry-After header, in seconds
+	RetryAfter int `json:"-"`
+}
+
+// statuses maps domain error codes to HTTP statuses
+var statuses = map[string]int{
+	service.ErrAccountNotFound.Code:      http.StatusNotFound,
+	service.ErrInsufficientFunds.Code:    http.StatusConflict,
+	service.ErrInvalidAmount.Code:        http.StatusUnprocessableEntity,
+	service.ErrSameAccount.Code:          http.StatusUnprocessableEntity,
+	service.ErrUserNotFound.Code:         http.StatusNotFound,
+	service.ErrUserAlreadyExists.Code:    http.StatusConflict,
+	service.ErrInvalidCredentials.Code:   http.StatusUnauthorized,
+	service.ErrInvalidToken.Code:         http.StatusUnauthorized,
+	service.ErrRefreshTokenReused.Code:   http.StatusUnauthorized,
+	service.ErrConverterUnavailable.Code: http.StatusBadGateway,
+	service.ErrUnknownCurrency.Code:      http.StatusUnprocessableEntity,
+	service.ErrVersionMismatch.Code:      http.StatusPreconditionFailed,
+	service.ErrForbidden.Code:            http.StatusForbidden,
+	service.ErrAccountFrozen.Code:        http.StatusConflict,
+	service.ErrInvalidRole.Code:          http.StatusUnprocessableEntity,
+	service.ErrInvalidApiKey.Code:        http.StatusUnauthorized,
+	service.ErrApiKeyNotFound.Code:       http.StatusNotFound,
+	service.ErrInvalidScope.Code:         http.StatusUnprocessableEntity,
+	service.ErrTooManyAttempts.Code:      http.StatusTooManyRequests,
+	service.ErrUserDisabled.Code:         http.StatusForbidden,
+	service.ErrInvalidPassword.Code:      http.StatusUnprocessableEntity,
+	service.ErrTwoFactorRequired.Code:    http.StatusForbidden,
+	service.ErrInvalidTwoFactorCode.Code: http.StatusUnauthorized,
+	service.ErrInvalidChallenge.Code:     http.StatusUnauthorized,
+	service.ErrTwoFactorEnabled.Code:     http.StatusConflict,
+	service.ErrTwoFactorDisabled.Code:    http.StatusConflict,
+}
+
+// New builds problem details for err.
+func New(err error) Details {
+	var (
+		domainErr     *service.Error
+		validationErr *validation.Error
+		mismatchErr   *service.VersionMismatchError
+		converterErr  *service.ConverterError
+		lockedOutErr  *service.LockedOutError
+		httpErr       *echo.HTTPError
+	)
+
+	switch {
+	case errors.As(err, &mismatchErr):
+		p := newDetails(http.StatusPreconditionFailed, service.ErrVersionMismatch.Code, service.ErrVersionMismatch.Message)
+		p.Current = &mismatchErr.Current
+		return p
+	case errors.As(err, &lockedOutErr):
+		p := newDetails(http.StatusTooManyRequests, service.ErrTooManyAttempts.Code, service.ErrTooManyAttempts.Message)
+		p.RetryAfter = int(math.Ceil(lockedOutErr.RetryAfter.Seconds()))
+		return p
+	case errors.Is(err, service.ErrUnknownCurrency):
+		// no provider quotes the currency, which isn't an outage
+		return newDetails(http.StatusUnprocessableEntity, service.ErrUnknownCurrency.Code, service.ErrUnknownCurrency.Message)
+	case errors.As(err, &converterErr):
+		return newDetails(http.StatusBadGateway, service.ErrConverterUnavailable.Code, service.ErrConverterUnavailable.Message)
+	case errors.As(err, &validationErr):
+		p := newDetails(http.StatusUnprocessableEntity, validationCode, "request payload is invalid")
+		p.Errors = validationErr.Fields
+		return p
+	case errors.As(err, &domainErr):
+		status, ok := statuses[domainErr.Code]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		return newDetails(status, domainErr.Code, domainErr.Message)
+	case errors.As(err, &httpErr):
+		detail, ok := httpErr.Message.(string)
+		if !ok {
+			detail = http.StatusText(httpErr.Code)
+		}
+		return newDetails(httpErr.Code, codeFromStatus(httpErr.Code), detail)
+	default:
+		return newDetails(http.StatusInternalServerError, internalCode, http.StatusText(http.StatusInternalServerError))
+	}
+}
+
+// HTTPErrorHandler is an echo.HTTPErrorHandler writing problem+json responses.
+// Internal errors are logged but their text is never sent to the client.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	p := New(err)
+	p.Instance = c.Request().URL.Path
+
+	if p.Status >= http.StatusInternalServerError {
+		log.Errorf("problem - HTTPErrorHandler - %s %s: %s", c.Request().Method, p.Instance, err)
+	}
+
+	if p.Current != nil {
+		etag.Set(c, p.Current.Version)
+	}
+	if p.RetryAfter > 0 {
+		c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(p.RetryAfter))
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(p.Status)
+	} else {
+		c.Response().Header().Set(echo.HeaderContentType, ContentType)
+		err = c.JSON(p.Status, p)
+	}
+	if err != nil {
+		log.Errorf("problem - HTTPErrorHandler - c.JSON: %s", err)
+	}
+}
+
+func newDetails(status int, code, detail string) Details {
+	return Details{
+		Type:   typePrefix + strings.ReplaceAll(code, "_", "-"),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// codeFromStatus turns "Not Found" into "not_found"
+func codeFromStatus(status int) string {
+	text := http.StatusText(status)
+	if len(text) == 0 {
+		return internalCode
+	}
+
+	return strings.ToLower(strings.ReplaceAll(text, " ", "_"))
+}
@@ -0,0 +1,134 @@
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+)
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{
+			name:       "wrapped domain error",
+			err:        fmt.Errorf("repo - AccountRepo - WriteOff: %w", service.ErrInsufficientFunds),
+			wantStatus: http.StatusConflict,
+			wantCode:   "insufficient_funds",
+			wantDetail: "insufficient funds",
+		},
+		{
+			name:       "account not found",
+			err:        fmt.Errorf("repo - AccountRepo - GetAccount: %w", service.ErrAccountNotFound),
+			wantStatus: http.StatusNotFound,
+			wantCode:   "account_not_found",
+			wantDetail: "account not found",
+		},
+		{
+			name:       "invalid amount",
+			err:        service.ErrInvalidAmount,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   "invalid_amount",
+			wantDetail: "amount must be greater than 0",
+		},
+		{
+			name:       "echo error",
+			err:        echo.NewHTTPError(http.StatusBadRequest, "invalid account id"),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "bad_request",
+			wantDetail: "invalid account id",
+		},
+		{
+			name:       "version mismatch",
+			err:        fmt.Errorf("repo - AccountRepo - WriteOff: %w", &service.VersionMismatchError{Current: entity.Account{Id: 1, Version: 2}}),
+			wantStatus: http.StatusPreconditionFailed,
+			wantCode:   "version_mismatch",
+			wantDetail: "account was modified by another request",
+		},
+		{
+			name:       "converter error",
+			err:        &service.ConverterError{Err: errors.New("apilayer: unexpected status 503")},
+			wantStatus: http.StatusBadGateway,
+			wantCode:   "converter_unavailable",
+			wantDetail: "currency converter is unavailable",
+		},
+		{
+			name:       "unknown currency isn't an outage",
+			err:        fmt.Errorf("service - RateStore - fetch: %w", service.ErrUnknownCurrency),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   "unknown_currency",
+			wantDetail: "unknown currency",
+		},
+		{
+			name:       "unknown currency behind a converter error",
+			err:        &service.ConverterError{Err: fmt.Errorf("webapi - Fallback - Rate: %w", service.ErrUnknownCurrency)},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   "unknown_currency",
+			wantDetail: "unknown currency",
+		},
+		{
+			name:       "unknown error is hidden",
+			err:        errors.New("repo - AccountRepo - GetAccount - a.Pool.QueryRow: connection refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "internal_error",
+			wantDetail: "Internal Server Error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := New(tc.err)
+
+			assert.Equal(t, tc.wantStatus, got.Status)
+			assert.Equal(t, tc.wantCode, got.Code)
+			assert.Equal(t, tc.wantDetail, got.Detail)
+		})
+	}
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.GET("/account", func(c echo.Context) error {
+		return service.ErrAccountNotFound
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/account", nil)
+
+	e.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, `{"type":"/problems/account-not-found","title":"Not Found","status":404,`+
+		`"detail":"account not found","instance":"/account","code":"account_not_found"}`+"\n", w.Body.String())
+}
+
+func TestHTTPErrorHandler_VersionMismatch(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.PUT("/account", func(c echo.Context) error {
+		return &service.VersionMismatchError{Current: entity.Account{Id: 1, Balance: 300, Version: 5}}
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/account", nil)
+
+	e.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, `"5"`, w.Header().Get("ETag"))
+	assert.Equal(t, `{"type":"/problems/version-mismatch","title":"Precondition Failed","status":412,`+
+		`"detail":"account was modified by another request","instance":"/account","code":"version_mismatch",`+
+		`"current":{"id":1,"balance":300,"version":5}}`+"\n", w.Body.String())
+}
@@ -0,0 +1,66 @@
+// Package rbac carries the actor of a request, set by the auth middleware,
+// and gates routes by the permissions of its role.
+package rbac
+
+import (
+	"github.com/labstack/echo/v4"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+)
+
+const actorCtx = "actor"
+
+// HeaderTwoFactorCode carries a TOTP or recovery code for operations that
+// take a second factor
+const HeaderTwoFactorCode = "X-2FA-Code"
+
+func SetActor(c echo.Context, actor entity.Actor) {
+	c.Set(actorCtx, actor)
+}
+
+// Actor returns the actor set by the auth middleware, or a zero Actor without
+// any permissions
+func Actor(c echo.Context) entity.Actor {
+	actor, _ := c.Get(actorCtx).(entity.Actor)
+	return actor
+}
+
+// Require lets through actors granted perm
+func Require(perm entity.Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !Actor(c).Can(perm) {
+				return service.ErrForbidden
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// DenyApiKeys keeps requests made with an API key out, e.g. of managing keys
+func DenyApiKeys(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if Actor(c).ApiKeyId != 0 {
+			return service.ErrForbidden
+		}
+
+		return next(c)
+	}
+}
+
+// CheckAccount lets the actor at account id if it owns it or is granted perm
+func CheckAccount(c echo.Context, accounts service.Account, id int, perm entity.Permission) error {
+	return accounts.CheckAccess(c.Request().Context(), Actor(c), id, perm)
+}
+
+// TwoFactorCode returns the code of the X-2FA-Code header
+func TwoFactorCode(c echo.Context) string {
+	return c.Request().Header.Get(HeaderTwoFactorCode)
+}
+
+// AuthorizeTransfer asks twoFactor whether the actor may transfer amount
+// with the code given in the request
+func AuthorizeTransfer(c echo.Context, twoFactor service.TwoFactor, amount int) error {
+	return twoFactor.AuthorizeTransfer(c.Request().Context(), Actor(c), amount, TwoFactorCode(c))
+}
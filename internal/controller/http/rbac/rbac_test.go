@@ -0,0 +1,110 @@
+package rbac
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-balance-service/internal/controller/http/problem"
+	"user-balance-service/internal/entity"
+)
+
+func TestRequire(t *testing.T) {
+	testCases := []struct {
+		name           string
+		actor          *entity.Actor
+		wantStatusCode int
+	}{
+		{
+			name:           "Granted",
+			actor:          &entity.Actor{UserId: 1, Role: entity.RoleAdmin},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "Not granted",
+			actor:          &entity.Actor{UserId: 1, Role: entity.RoleOperator},
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "API key with scope",
+			actor:          &entity.Actor{UserId: 1, Role: entity.RoleAdmin, ApiKeyId: 3, Scopes: []entity.Permission{entity.PermReadAllHistory}},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "API key without scope",
+			actor:          &entity.Actor{UserId: 1, Role: entity.RoleAdmin, ApiKeyId: 3, Scopes: []entity.Permission{entity.PermRefund}},
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "API key with scope the owner lost",
+			actor:          &entity.Actor{UserId: 1, Role: entity.RoleUser, ApiKeyId: 3, Scopes: []entity.Permission{entity.PermReadAllHistory}},
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "No actor",
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = problem.HTTPErrorHandler
+			e.GET("/admin", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					if tc.actor != nil {
+						SetActor(c, *tc.actor)
+					}
+					return next(c)
+				}
+			}, Require(entity.PermReadAllHistory))
+
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+		})
+	}
+}
+
+func TestDenyApiKeys(t *testing.T) {
+	testCases := []struct {
+		name           string
+		actor          entity.Actor
+		wantStatusCode int
+	}{
+		{
+			name:           "Access token",
+			actor:          entity.Actor{UserId: 1, Role: entity.RoleUser},
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "API key",
+			actor:          entity.Actor{UserId: 1, Role: entity.RoleAdmin, ApiKeyId: 3, Scopes: entity.RoleAdmin.Permissions()},
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = problem.HTTPErrorHandler
+			e.GET("/api-keys", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					SetActor(c, tc.actor)
+					return next(c)
+				}
+			}, DenyApiKeys)
+
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api-keys", nil))
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+		})
+	}
+}
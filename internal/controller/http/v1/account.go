@@ -4,48 +4,58 @@ import (
 	"github.com/labstack/echo/v4"
 	"net/http"
 	"time"
+	"user-balance-service/internal/controller/http/audit"
+	"user-balance-service/internal/controller/http/etag"
+	"user-balance-service/internal/controller/http/rbac"
 	"user-balance-service/internal/entity"
 	"user-balance-service/internal/service"
 )
 
-const (
-	refillType        = "пополнение счёта"
-	writeoffType      = "снятие со счёта"
-	innertransferType = "иcходящий перевод"
-	outertransferType = "входящий перевод"
-)
-
 type accountRoutes struct {
-	s service.Account
-	h service.History
+	s  service.Account
+	h  service.History
+	tf service.TwoFactor
 }
 
-func newAccountRoutes(g *echo.Group, s service.Account, h service.History) {
-	r := &accountRoutes{s, h}
+func newAccountRoutes(g *echo.Group, s service.Account, h service.History, tf service.TwoFactor) {
+	r := &accountRoutes{s, h, tf}
 
 	g.POST("/create", r.createAccount)
-	g.GET("/state", r.getBalance) // ?currency=USD to get balance in chosen currency
-	g.PUT("/refill", r.refillBalance)
-	g.PUT("/write-off", r.writeOffBalance)
-	g.PUT("/transfer", r.transferMoney)
-	g.DELETE("/delete", r.deleteAccount)
+	g.GET("/state", r.getBalance)          // ?currency=USD to get balance in chosen currency
+	g.PUT("/refill", r.refillBalance)      // owner or accounts:refund
+	g.PUT("/write-off", r.writeOffBalance) // owner or accounts:manage
+	g.PUT("/transfer", r.transferMoney)    // owner of id_from or accounts:manage, X-2FA-Code above the threshold
+	g.DELETE("/delete", r.deleteAccount)   // owner or accounts:manage
 }
 
-// create account and set balance to 0
+type accountRequest struct {
+	Id int `json:"id" validate:"required,gt=0"`
+}
+
+type balanceRequest struct {
+	Id       int    `json:"id" validate:"required,gt=0"`
+	Currency string `json:"-" query:"currency" validate:"omitempty,iso4217"`
+}
+
+type operationRequest struct {
+	Id      int `json:"id" validate:"required,gt=0"`
+	Balance int `json:"balance" validate:"gt=0"`
+}
+
+// create account owned by the user and set balance to 0
 func (r *accountRoutes) createAccount(c echo.Context) error {
 	var input entity.Account
 
 	err := c.Bind(&input)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return err
 	}
 
-	id, err := r.s.CreateAccount(c.Request().Context())
+	id, err := r.s.CreateAccount(c.Request().Context(), rbac.Actor(c).UserId)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
+	audit.Account(c, id)
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"id": id,
@@ -54,29 +64,41 @@ func (r *accountRoutes) createAccount(c echo.Context) error {
 
 // refill balance
 func (r *accountRoutes) refillBalance(c echo.Context) error {
-	var input entity.Account
+	var input operationRequest
 
 	err := c.Bind(&input)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	version, err := etag.IfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	audit.Account(c, input.Id)
+	err = rbac.CheckAccount(c, r.s, input.Id, entity.PermRefund)
+	if err != nil {
 		return err
 	}
 
-	err = r.s.MakeDeposit(c.Request().Context(), input.Id, input.Balance)
+	err = r.s.MakeDeposit(c.Request().Context(), input.Id, input.Balance, version)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
 
 	_, err = r.h.SaveHistory(c.Request().Context(), entity.History{
-		Type:        refillType,
+		Type:        entity.RefillType,
 		Description: "",
 		Amount:      input.Balance,
 		AccountId:   input.Id,
 		Date:        entity.CustomTime(time.Now()),
 	})
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
 
@@ -85,31 +107,43 @@ func (r *accountRoutes) refillBalance(c echo.Context) error {
 	})
 }
 
-//  write off some money
+// write off some money
 func (r *accountRoutes) writeOffBalance(c echo.Context) error {
-	var input entity.Account
+	var input operationRequest
 
 	err := c.Bind(&input)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	version, err := etag.IfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	audit.Account(c, input.Id)
+	err = rbac.CheckAccount(c, r.s, input.Id, entity.PermManageAccounts)
+	if err != nil {
 		return err
 	}
 
-	err = r.s.WriteOff(c.Request().Context(), input.Id, input.Balance)
+	err = r.s.WriteOff(c.Request().Context(), input.Id, input.Balance, version)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
 
 	_, err = r.h.SaveHistory(c.Request().Context(), entity.History{
-		Type:        writeoffType,
+		Type:        entity.WriteOffType,
 		Description: "",
 		Amount:      input.Balance,
 		AccountId:   input.Id,
 		Date:        entity.CustomTime(time.Now()),
 	})
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
 
@@ -119,9 +153,9 @@ func (r *accountRoutes) writeOffBalance(c echo.Context) error {
 }
 
 type TransferRequest struct {
-	IdFrom int `json:"id_from"`
-	IdTo   int `json:"id_to"`
-	Amount int `json:"amount"`
+	IdFrom int `json:"id_from" validate:"required,gt=0"`
+	IdTo   int `json:"id_to" validate:"required,gt=0,nefield=IdFrom"`
+	Amount int `json:"amount" validate:"gt=0"`
 }
 
 // transfer money from one account to another
@@ -130,37 +164,54 @@ func (r *accountRoutes) transferMoney(c echo.Context) error {
 
 	err := c.Bind(&transaction)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	err = c.Validate(&transaction)
+	if err != nil {
+		return err
+	}
+
+	// If-Match refers to the source account
+	version, err := etag.IfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	audit.Account(c, transaction.IdFrom, transaction.IdTo)
+	err = rbac.CheckAccount(c, r.s, transaction.IdFrom, entity.PermManageAccounts)
+	if err != nil {
+		return err
+	}
+
+	err = rbac.AuthorizeTransfer(c, r.tf, transaction.Amount)
+	if err != nil {
 		return err
 	}
 
-	err = r.s.TransferMoney(c.Request().Context(), transaction.IdFrom, transaction.IdTo, transaction.Amount)
+	err = r.s.TransferMoney(c.Request().Context(), transaction.IdFrom, transaction.IdTo, transaction.Amount, version)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
 
 	_, err = r.h.SaveHistory(c.Request().Context(), entity.History{
-		Type:        innertransferType,
+		Type:        entity.InnerTransferType,
 		Description: "",
 		Amount:      transaction.Amount,
 		AccountId:   transaction.IdFrom,
 		Date:        entity.CustomTime(time.Now()),
 	})
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
 
 	_, err = r.h.SaveHistory(c.Request().Context(), entity.History{
-		Type:        outertransferType,
+		Type:        entity.OuterTransferType,
 		Description: "",
 		Amount:      transaction.Amount,
 		AccountId:   transaction.IdTo,
 		Date:        entity.CustomTime(time.Now()),
 	})
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
 
@@ -171,54 +222,74 @@ func (r *accountRoutes) transferMoney(c echo.Context) error {
 
 // check balance in rubles and convert to chosen currency
 func (r *accountRoutes) getBalance(c echo.Context) error {
-	currency := c.FormValue("currency")
-	var input entity.Account
-	var balance float64
+	var input balanceRequest
 
 	err := c.Bind(&input)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	err = rbac.CheckAccount(c, r.s, input.Id, entity.PermReadAccounts)
+	if err != nil {
 		return err
 	}
 
 	output, err := r.s.GetAccount(c.Request().Context(), input.Id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
 
-	if len(currency) != 0 {
-		item, err := r.s.ConvertToCurrency(c.Request().Context(), currency, float64(output.Balance))
-		if err != nil {
-			newErrorResponse(c, http.StatusInternalServerError, err.Error())
-			return err
-		}
-		balance = float64(output.Balance) / item
-	} else {
-		balance = float64(output.Balance)
-		if err != nil {
-			newErrorResponse(c, http.StatusInternalServerError, err.Error())
-			return err
-		}
+	etag.Set(c, output.Version)
+
+	if len(input.Currency) == 0 {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"balance": float64(output.Balance),
+		})
+	}
+
+	// the response tells which rate was used and how old it is
+	conversion, err := r.s.ConvertToCurrency(c.Request().Context(), input.Currency, float64(output.Balance))
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"balance": balance,
+		"balance":         conversion.Amount,
+		"currency":        conversion.Currency,
+		"rate":            conversion.Rate,
+		"rate_fetched_at": conversion.RateFetchedAt,
 	})
 }
 
 func (r *accountRoutes) deleteAccount(c echo.Context) error {
-	var input entity.Account
+	var input accountRequest
 
 	err := c.Bind(&input)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	version, err := etag.IfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	audit.Account(c, input.Id)
+	err = rbac.CheckAccount(c, r.s, input.Id, entity.PermManageAccounts)
+	if err != nil {
 		return err
 	}
 
-	err = r.s.DeleteAccount(c.Request().Context(), input.Id)
+	err = r.s.DeleteAccount(c.Request().Context(), input.Id, version)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
 
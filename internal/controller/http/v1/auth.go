@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"errors"
 	"github.com/labstack/echo/v4"
 	"net/http"
 	"user-balance-service/internal/entity"
@@ -16,6 +17,32 @@ func newAuthRoutes(g *echo.Group, s service.Auth) {
 
 	g.POST("/sign-up", r.signUp)
 	g.POST("/sign-in", r.signIn)
+	g.POST("/sign-in/2fa", r.signInTwoFactor)
+	g.POST("/refresh", r.refresh)
+	g.POST("/logout", r.logout)
+}
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type twoFactorSignInRequest struct {
+	Challenge string `json:"challenge" validate:"required"`
+	Code      string `json:"code"      validate:"required"`
+}
+
+// challengeResponse is returned by sign-in instead of tokens when the user
+// has two-factor authentication enabled
+type challengeResponse struct {
+	Challenge string `json:"challenge"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+// tokensResponse keeps the "token" field of the sign-in response from before
+// refresh tokens
+type tokensResponse struct {
+	Token string `json:"token"`
+	entity.Tokens
 }
 
 // registration of user
@@ -24,13 +51,15 @@ func (r *authRoutes) signUp(c echo.Context) error {
 
 	err := c.Bind(&input)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
 		return err
 	}
 
 	id, err := r.s.CreateUser(c.Request().Context(), input)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
 
@@ -43,17 +72,92 @@ func (r *authRoutes) signUp(c echo.Context) error {
 func (r *authRoutes) signIn(c echo.Context) error {
 	username, password, ok := c.Request().BasicAuth()
 	if !ok {
-		newErrorResponse(c, http.StatusUnauthorized, "invalid auth header")
-		return nil
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid auth header")
 	}
 
-	token, err := r.s.GenerateToken(c.Request().Context(), username, password)
+	tokens, err := r.s.GenerateToken(c.Request().Context(), username, password, client(c))
+	var challenge *service.TwoFactorChallengeError
+	if errors.As(err, &challenge) {
+		return c.JSON(http.StatusOK, challengeResponse{
+			Challenge: challenge.Challenge,
+			ExpiresIn: int64(challenge.ExpiresIn.Seconds()),
+		})
+	}
 	if err != nil {
-		newErrorResponse(c, http.StatusUnauthorized, err.Error())
-		return nil
+		return err
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"token": token,
-	})
+	return c.JSON(http.StatusOK, tokensResponse{Token: tokens.AccessToken, Tokens: tokens})
+}
+
+// second step of the authorization of a user with two-factor authentication
+func (r *authRoutes) signInTwoFactor(c echo.Context) error {
+	var input twoFactorSignInRequest
+
+	err := c.Bind(&input)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := r.s.CompleteSignIn(c.Request().Context(), input.Challenge, input.Code, client(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, tokensResponse{Token: tokens.AccessToken, Tokens: tokens})
+}
+
+// exchange of a refresh token for a new pair of tokens
+func (r *authRoutes) refresh(c echo.Context) error {
+	var input refreshTokenRequest
+
+	err := c.Bind(&input)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := r.s.RefreshToken(c.Request().Context(), input.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, tokensResponse{Token: tokens.AccessToken, Tokens: tokens})
+}
+
+// revocation of a refresh token and of the access token in the auth header
+func (r *authRoutes) logout(c echo.Context) error {
+	var input refreshTokenRequest
+
+	err := c.Bind(&input)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	accessToken, _ := bearerToken(c.Request())
+
+	err = r.s.Logout(c.Request().Context(), input.RefreshToken, accessToken)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func client(c echo.Context) entity.Client {
+	return entity.Client{
+		IP:        c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	}
 }
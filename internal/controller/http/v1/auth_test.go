@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+	"user-balance-service/internal/controller/http/problem"
+	"user-balance-service/internal/controller/http/validation"
 	"user-balance-service/internal/entity"
 	"user-balance-service/internal/service"
 	mock_service "user-balance-service/internal/service/mock"
@@ -48,14 +52,16 @@ func TestControllerAuth_signUp(t *testing.T) {
 			wantRequestBody: `{"id":1}` + "\n",
 		},
 		{
-			name: "Empty fields", // test-case doesn't work due to a bug in binding request body
+			name: "Empty fields",
 			args: args{
 				ctx: context.Background(),
 			},
-			inputBody:       `{"username":"test"}`,
-			mockBehaviour:   func(s *mock_service.MockAuth, args args) {},
-			wantStatusCode:  400,
-			wantRequestBody: `{"message":"invalid input body"}` + "\n",
+			inputBody:      `{"username":"test"}`,
+			mockBehaviour:  func(s *mock_service.MockAuth, args args) {},
+			wantStatusCode: 422,
+			wantRequestBody: `{"type":"/problems/validation-failed","title":"Unprocessable Entity","status":422,` +
+				`"detail":"request payload is invalid","instance":"/auth/sign-up","code":"validation_failed",` +
+				`"errors":[{"field":"password","rule":"required","message":"is required"}]}` + "\n",
 		},
 		{
 			name: "Service failure",
@@ -70,8 +76,9 @@ func TestControllerAuth_signUp(t *testing.T) {
 			mockBehaviour: func(s *mock_service.MockAuth, args args) {
 				s.EXPECT().CreateUser(args.ctx, args.user).Return(1, errors.New("service failure"))
 			},
-			wantStatusCode:  500,
-			wantRequestBody: `{"message":"service failure"}` + "\n",
+			wantStatusCode: 500,
+			wantRequestBody: `{"type":"/problems/internal-error","title":"Internal Server Error","status":500,` +
+				`"detail":"Internal Server Error","instance":"/auth/sign-up","code":"internal_error"}` + "\n",
 		},
 	}
 
@@ -88,6 +95,8 @@ func TestControllerAuth_signUp(t *testing.T) {
 
 			// create test server
 			e := echo.New()
+			e.HTTPErrorHandler = problem.HTTPErrorHandler
+			e.Validator = validation.New()
 			g := e.Group("/auth")
 			newAuthRoutes(g, services.Auth)
 
@@ -105,3 +114,236 @@ func TestControllerAuth_signUp(t *testing.T) {
 		})
 	}
 }
+
+func newAuthTestServer(auth service.Auth) *echo.Echo {
+	e := echo.New()
+	e.HTTPErrorHandler = problem.HTTPErrorHandler
+	e.Validator = validation.New()
+	newAuthRoutes(e.Group("/auth"), auth)
+	return e
+}
+
+func TestControllerAuth_signIn(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	auth := mock_service.NewMockAuth(ctrl)
+	auth.EXPECT().GenerateToken(gomock.Any(), "test", "qwerty", entity.Client{IP: "192.0.2.1", UserAgent: "billing/1.0"}).
+		Return(entity.Tokens{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900}, nil)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/sign-in", nil)
+	req.SetBasicAuth("test", "qwerty")
+	req.Header.Set("User-Agent", "billing/1.0")
+
+	newAuthTestServer(auth).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"token":"access","access_token":"access","refresh_token":"refresh","expires_in":900}`+"\n", w.Body.String())
+}
+
+func TestControllerAuth_signIn_LockedOut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	auth := mock_service.NewMockAuth(ctrl)
+	auth.EXPECT().GenerateToken(gomock.Any(), "test", "qwerty", gomock.Any()).
+		Return(entity.Tokens{}, &service.LockedOutError{RetryAfter: 90500 * time.Millisecond})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/sign-in", nil)
+	req.SetBasicAuth("test", "qwerty")
+
+	newAuthTestServer(auth).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "91", w.Header().Get("Retry-After"))
+	assert.Equal(t, `{"type":"/problems/too-many-attempts","title":"Too Many Requests","status":429,`+
+		`"detail":"too many failed sign-in attempts, try again later","instance":"/auth/sign-in","code":"too_many_attempts"}`+"\n", w.Body.String())
+}
+
+func TestControllerAuth_signIn_TwoFactor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	auth := mock_service.NewMockAuth(ctrl)
+	auth.EXPECT().GenerateToken(gomock.Any(), "test", "qwerty", gomock.Any()).
+		Return(entity.Tokens{}, fmt.Errorf("service: %w", &service.TwoFactorChallengeError{Challenge: "challenge", ExpiresIn: 5 * time.Minute}))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/sign-in", nil)
+	req.SetBasicAuth("test", "qwerty")
+
+	newAuthTestServer(auth).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"challenge":"challenge","expires_in":300}`+"\n", w.Body.String())
+}
+
+func TestControllerAuth_signInTwoFactor(t *testing.T) {
+	type MockBehaviour func(s *mock_service.MockAuth)
+
+	testCases := []struct {
+		name           string
+		inputBody      string
+		mockBehaviour  MockBehaviour
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"challenge":"challenge","code":"123456"}`,
+			mockBehaviour: func(s *mock_service.MockAuth) {
+				s.EXPECT().CompleteSignIn(gomock.Any(), "challenge", "123456", entity.Client{IP: "192.0.2.1"}).
+					Return(entity.Tokens{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900}, nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"token":"access","access_token":"access","refresh_token":"refresh","expires_in":900}` + "\n",
+		},
+		{
+			name:      "Wrong code",
+			inputBody: `{"challenge":"challenge","code":"000000"}`,
+			mockBehaviour: func(s *mock_service.MockAuth) {
+				s.EXPECT().CompleteSignIn(gomock.Any(), "challenge", "000000", gomock.Any()).
+					Return(entity.Tokens{}, service.ErrInvalidTwoFactorCode)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:      "Expired challenge",
+			inputBody: `{"challenge":"challenge","code":"123456"}`,
+			mockBehaviour: func(s *mock_service.MockAuth) {
+				s.EXPECT().CompleteSignIn(gomock.Any(), "challenge", "123456", gomock.Any()).
+					Return(entity.Tokens{}, fmt.Errorf("repo: %w", service.ErrInvalidChallenge))
+			},
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "No code",
+			inputBody:      `{"challenge":"challenge"}`,
+			mockBehaviour:  func(s *mock_service.MockAuth) {},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auth := mock_service.NewMockAuth(ctrl)
+			tc.mockBehaviour(auth)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/auth/sign-in/2fa", bytes.NewBufferString(tc.inputBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			newAuthTestServer(auth).ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if len(tc.wantBody) != 0 {
+				assert.Equal(t, tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestControllerAuth_refresh(t *testing.T) {
+	type MockBehaviour func(s *mock_service.MockAuth)
+
+	testCases := []struct {
+		name            string
+		inputBody       string
+		mockBehaviour   MockBehaviour
+		wantStatusCode  int
+		wantRequestBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"refresh_token":"refresh"}`,
+			mockBehaviour: func(s *mock_service.MockAuth) {
+				s.EXPECT().RefreshToken(gomock.Any(), "refresh").
+					Return(entity.Tokens{AccessToken: "access", RefreshToken: "next", ExpiresIn: 900}, nil)
+			},
+			wantStatusCode:  200,
+			wantRequestBody: `{"token":"access","access_token":"access","refresh_token":"next","expires_in":900}` + "\n",
+		},
+		{
+			name:           "No token",
+			inputBody:      `{}`,
+			mockBehaviour:  func(s *mock_service.MockAuth) {},
+			wantStatusCode: 422,
+			wantRequestBody: `{"type":"/problems/validation-failed","title":"Unprocessable Entity","status":422,` +
+				`"detail":"request payload is invalid","instance":"/auth/refresh","code":"validation_failed",` +
+				`"errors":[{"field":"refresh_token","rule":"required","message":"is required"}]}` + "\n",
+		},
+		{
+			name:      "Reused token",
+			inputBody: `{"refresh_token":"refresh"}`,
+			mockBehaviour: func(s *mock_service.MockAuth) {
+				s.EXPECT().RefreshToken(gomock.Any(), "refresh").Return(entity.Tokens{}, service.ErrRefreshTokenReused)
+			},
+			wantStatusCode: 401,
+			wantRequestBody: `{"type":"/problems/refresh-token-reused","title":"Unauthorized","status":401,` +
+				`"detail":"refresh token has already been used, sign in again","instance":"/auth/refresh",` +
+				`"code":"refresh_token_reused"}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auth := mock_service.NewMockAuth(ctrl)
+			tc.mockBehaviour(auth)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewBufferString(tc.inputBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			newAuthTestServer(auth).ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			assert.Equal(t, tc.wantRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestControllerAuth_logout(t *testing.T) {
+	testCases := []struct {
+		name        string
+		headerValue string
+		accessToken string
+	}{
+		{
+			name:        "With access token",
+			headerValue: "Bearer access",
+			accessToken: "access",
+		},
+		{
+			name: "Refresh token only",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auth := mock_service.NewMockAuth(ctrl)
+			auth.EXPECT().Logout(gomock.Any(), "refresh", tc.accessToken).Return(nil)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/auth/logout", bytes.NewBufferString(`{"refresh_token":"refresh"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if len(tc.headerValue) != 0 {
+				req.Header.Set("Authorization", tc.headerValue)
+			}
+
+			newAuthTestServer(auth).ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusNoContent, w.Code)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package v1
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"user-balance-service/pkg/health"
+)
+
+// Readiness is implemented by health.Health
+type Readiness interface {
+	Check(ctx context.Context) health.Report
+}
+
+// NewHealthRoutes adds the probes of the orchestrator. /healthz answers as
+// long as the process serves requests, /readyz answers 503 while a critical
+// dependency is down or the service is shutting down. The errors of the
+// checks aren't shown here, health.Health serves them on the metrics port.
+func NewHealthRoutes(handler *echo.Echo, readiness Readiness) {
+	handler.GET("/healthz", func(c echo.Context) error {
+		c.Response().Header().Set("Cache-Control", "no-store")
+		return c.JSON(http.StatusOK, health.Report{Status: health.StatusUp})
+	})
+
+	handler.GET("/readyz", func(c echo.Context) error {
+		report := readiness.Check(c.Request().Context())
+
+		status := http.StatusOK
+		if !report.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+
+		c.Response().Header().Set("Cache-Control", "no-store")
+		return c.JSON(status, report.Public())
+	})
+}
@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-balance-service/pkg/health"
+)
+
+func TestNewHealthRoutes(t *testing.T) {
+	testCases := []struct {
+		name       string
+		path       string
+		converter  error
+		postgres   error
+		drain      bool
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "alive",
+			path:       "/healthz",
+			postgres:   errors.New("connection refused"),
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"up"}`,
+		},
+		{
+			name:       "ready",
+			path:       "/readyz",
+			wantStatus: http.StatusOK,
+			wantBody: `{"status":"up","checks":{` +
+				`"converter":{"status":"up","critical":false,"duration_ms":0},` +
+				`"postgres":{"status":"up","critical":true,"duration_ms":0}}}`,
+		},
+		{
+			name:       "degraded",
+			path:       "/readyz",
+			converter:  errors.New("no provider reachable"),
+			wantStatus: http.StatusOK,
+			wantBody: `{"status":"degraded","checks":{` +
+				`"converter":{"status":"down","critical":false,"duration_ms":0},` +
+				`"postgres":{"status":"up","critical":true,"duration_ms":0}}}`,
+		},
+		{
+			name:       "critical dependency down",
+			path:       "/readyz",
+			postgres:   errors.New("connection refused"),
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody: `{"status":"down","checks":{` +
+				`"converter":{"status":"up","critical":false,"duration_ms":0},` +
+				`"postgres":{"status":"down","critical":true,"duration_ms":0}}}`,
+		},
+		{
+			name:       "draining",
+			path:       "/readyz",
+			drain:      true,
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   `{"status":"draining"}`,
+		},
+		{
+			name:       "alive while draining",
+			path:       "/healthz",
+			drain:      true,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"up"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := health.New()
+			h.Add("postgres", true, func(context.Context) error { return tc.postgres })
+			h.Add("converter", false, func(context.Context) error { return tc.converter })
+			if tc.drain {
+				h.Drain()
+			}
+
+			e := echo.New()
+			NewHealthRoutes(e, h)
+
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+			assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+			assert.JSONEq(t, tc.wantBody, w.Body.String())
+		})
+	}
+}
@@ -3,95 +3,111 @@ package v1
 import (
 	"github.com/labstack/echo/v4"
 	"net/http"
-	"strconv"
+	"user-balance-service/internal/controller/http/rbac"
 	"user-balance-service/internal/entity"
 	"user-balance-service/internal/service"
 )
 
 type historyRoutes struct {
 	s service.History
+	a service.Account
 }
 
-func newHistoryRoutes(g *echo.Group, s service.History) {
-	h := &historyRoutes{s: s}
+func newHistoryRoutes(g *echo.Group, s service.History, a service.Account) {
+	h := &historyRoutes{s: s, a: a}
 
-	g.GET("/all", h.getAll)  // + ?sort={category}; + ?limit=5&cursor=
-	g.GET("/:id", h.getById) // + ?sort={category}; + ?limit=5&cursor=
+	g.GET("/all", h.getAll, rbac.Require(entity.PermReadAllHistory)) // + ?sort={category}; + ?limit=5&cursor=; + ?currency=USD
+	g.GET("/:id", h.getById)                                         // + ?sort={category}; + ?limit=5&cursor=; + ?currency=USD
 
 }
 
+type historyRequest struct {
+	Sort     string `query:"sort" validate:"omitempty,oneof=date amount"`
+	Limit    int    `query:"limit" validate:"gte=0"`
+	Cursor   string `query:"cursor" validate:"omitempty,datetime=2006-01-02"`
+	Currency string `query:"currency" validate:"omitempty,iso4217"`
+}
+
+type accountHistoryRequest struct {
+	historyRequest
+	Id int `param:"id" validate:"required,gt=0"`
+}
+
 func (h *historyRoutes) getAll(c echo.Context) error {
-	var records []entity.History
-	var limit int
-	var err error
-
-	sort := c.FormValue("sort")
-	param := c.FormValue("cursor")
-	limitCheckNum := c.FormValue("limit")
-	if len(limitCheckNum) == 0 {
-		limit = 0
-	} else {
-		limit, err = strconv.Atoi(c.FormValue("limit"))
-		if err != nil {
-			newErrorResponse(c, http.StatusBadRequest, "v1 - history - getAll - strconv.Atoi(c.FormValue())")
-			return err
-		}
-	}
+	var input historyRequest
 
-	if limit > 0 {
-		records, err = h.s.Pagination(c.Request().Context(), limit, param, 0)
-	} else if len(sort) != 0 && (sort == "date" || sort == "amount") {
-		records, err = h.s.ShowSorted(c.Request().Context(), sort, 0)
-	} else {
-		records, err = h.s.ShowAll(c.Request().Context())
+	err := c.Bind(&input)
+	if err != nil {
+		return err
 	}
+	err = c.Validate(&input)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"records": records,
-	})
+	records, err := h.show(c, input, 0)
+	if err != nil {
+		return err
+	}
+
+	return h.respond(c, input, records)
 }
 
 func (h *historyRoutes) getById(c echo.Context) error {
-	var records []entity.History
-	var limit int
-	var err error
+	var input accountHistoryRequest
 
-	sort := c.FormValue("sort")
-	param := c.FormValue("cursor")
-	accountId, err := strconv.Atoi(c.Param("id"))
+	err := c.Bind(&input)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, "v1 - history - getById - strconv.Atoi(c.Param())")
 		return err
 	}
-	limitCheckNum := c.FormValue("limit")
-	if len(limitCheckNum) == 0 {
-		limit = 0
-	} else {
-		limit, err = strconv.Atoi(c.FormValue("limit"))
-		if err != nil {
-			newErrorResponse(c, http.StatusBadRequest, "v1 - history - getById - strconv.Atoi(c.FormValue())")
-			return err
-		}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
 	}
 
-	if limit > 0 {
-		records, err = h.s.Pagination(c.Request().Context(), limit, param, accountId)
-	} else if len(sort) != 0 && (sort == "date" || sort == "amount") {
-		records, err = h.s.ShowSorted(c.Request().Context(), sort, accountId)
-	} else {
-		records, err = h.s.ShowById(c.Request().Context(), accountId)
+	err = rbac.CheckAccount(c, h.a, input.Id, entity.PermReadAccounts)
+	if err != nil {
+		return err
+	}
+
+	records, err := h.show(c, input.historyRequest, input.Id)
+	if err != nil {
+		return err
+	}
+
+	return h.respond(c, input.historyRequest, records)
+}
+
+// show picks pagination, sorting or the full list; accountId 0 means all accounts
+func (h *historyRoutes) show(c echo.Context, input historyRequest, accountId int) ([]entity.History, error) {
+	ctx := c.Request().Context()
+
+	switch {
+	case input.Limit > 0:
+		return h.s.Pagination(ctx, input.Limit, input.Cursor, accountId)
+	case len(input.Sort) != 0:
+		return h.s.ShowSorted(ctx, input.Sort, accountId)
+	case accountId != 0:
+		return h.s.ShowById(ctx, accountId)
+	default:
+		return h.s.ShowAll(ctx)
+	}
+}
+
+// respond writes the records, converted with the rates of their dates if a currency is requested
+func (h *historyRoutes) respond(c echo.Context, input historyRequest, records []entity.History) error {
+	if len(input.Currency) == 0 {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"records": records,
+		})
 	}
 
+	converted, err := h.s.Convert(c.Request().Context(), records, input.Currency)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return err
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"records": records,
+		"records": converted,
 	})
 }
@@ -0,0 +1,20 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"user-balance-service/pkg/jwtkeys"
+)
+
+// KeySet is implemented by jwtkeys.Set
+type KeySet interface {
+	JWKS() jwtkeys.JWKS
+}
+
+// NewJWKSRoutes publishes the public keys access tokens can be verified with
+func NewJWKSRoutes(handler *echo.Echo, keys KeySet) {
+	handler.GET("/.well-known/jwks.json", func(c echo.Context) error {
+		c.Response().Header().Set("Cache-Control", "public, max-age=300")
+		return c.JSON(http.StatusOK, keys.JWKS())
+	})
+}
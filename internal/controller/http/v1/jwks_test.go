@@ -0,0 +1,38 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-balance-service/pkg/jwtkeys"
+)
+
+type staticKeySet jwtkeys.JWKS
+
+func (s staticKeySet) JWKS() jwtkeys.JWKS {
+	return jwtkeys.JWKS(s)
+}
+
+func TestNewJWKSRoutes(t *testing.T) {
+	e := echo.New()
+	NewJWKSRoutes(e, staticKeySet{Keys: []jwtkeys.JWK{{
+		KeyType:   "OKP",
+		Id:        "k1",
+		Use:       "sig",
+		Algorithm: jwtkeys.EdDSA,
+		Curve:     "Ed25519",
+		X:         "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+	}}})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+
+	e.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=300", w.Header().Get("Cache-Control"))
+	assert.Equal(t, `{"keys":[{"kty":"OKP","kid":"k1","use":"sig","alg":"EdDSA","crv":"Ed25519",`+
+		`"x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}`+"\n", w.Body.String())
+}
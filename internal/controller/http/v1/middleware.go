@@ -4,32 +4,47 @@ import (
 	"github.com/labstack/echo/v4"
 	"net/http"
 	"strings"
+	"user-balance-service/internal/controller/http/rbac"
+	"user-balance-service/internal/entity"
 	"user-balance-service/internal/service"
 )
 
-const (
-	userIdCtx = "userId"
-)
+// apiKeyHeader carries the API key of service-to-service calls
+const apiKeyHeader = "X-Api-Key"
 
 type AuthMiddleware struct {
-	s service.Auth
+	s    service.Auth
+	keys service.ApiKeys
+}
+
+func NewAuthMiddleware(s service.Auth, keys service.ApiKeys) *AuthMiddleware {
+	return &AuthMiddleware{s: s, keys: keys}
 }
 
+// UserIdentity authenticates the request with the X-Api-Key header if it's
+// set, otherwise with the bearer access token
 func (h *AuthMiddleware) UserIdentity(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		token, ok := bearerToken(c.Request())
-		if !ok {
-			newErrorResponse(c, http.StatusUnauthorized, "invalid auth header")
-			return nil
-		}
+		var (
+			actor entity.Actor
+			err   error
+		)
+
+		if key := c.Request().Header.Get(apiKeyHeader); key != "" {
+			actor, err = h.keys.ParseApiKey(c.Request().Context(), key)
+		} else {
+			token, ok := bearerToken(c.Request())
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid auth header")
+			}
 
-		userId, err := h.s.ParseToken(token)
+			actor, err = h.s.ParseToken(c.Request().Context(), token)
+		}
 		if err != nil {
-			newErrorResponse(c, http.StatusUnauthorized, err.Error())
 			return err
 		}
 
-		c.Set(userIdCtx, userId)
+		rbac.SetActor(c, actor)
 
 		return next(c)
 	}
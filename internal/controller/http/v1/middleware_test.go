@@ -2,12 +2,16 @@ package v1
 
 import (
 	"context"
+	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"user-balance-service/internal/controller/http/problem"
+	"user-balance-service/internal/controller/http/rbac"
+	"user-balance-service/internal/entity"
 	"user-balance-service/internal/service"
 	mock_service "user-balance-service/internal/service/mock"
 )
@@ -17,7 +21,7 @@ func TestAuthMiddleware_UserIdentity(t *testing.T) {
 		ctx context.Context
 	}
 
-	type MockBehaviour func(s *mock_service.MockAuth, token string)
+	type MockBehaviour func(s *mock_service.MockAuth, k *mock_service.MockApiKeys, token string)
 
 	testCases := []struct {
 		name            string
@@ -35,12 +39,58 @@ func TestAuthMiddleware_UserIdentity(t *testing.T) {
 			headerName:  "Authorization",
 			headerValue: "Bearer token",
 			token:       "token",
-			mockBehaviour: func(s *mock_service.MockAuth, token string) {
-				s.EXPECT().ParseToken(token).Return(1, nil)
+			mockBehaviour: func(s *mock_service.MockAuth, k *mock_service.MockApiKeys, token string) {
+				s.EXPECT().ParseToken(gomock.Any(), token).Return(entity.Actor{UserId: 1, Role: entity.RoleUser}, nil)
 			},
 			wantStatusCode:  200,
 			wantRequestBody: "1",
 		},
+		{
+			name:            "No header",
+			args:            args{ctx: context.Background()},
+			headerName:      "",
+			headerValue:     "",
+			mockBehaviour:   func(s *mock_service.MockAuth, k *mock_service.MockApiKeys, token string) {},
+			wantStatusCode:  401,
+			wantRequestBody: `{"type":"/problems/unauthorized","title":"Unauthorized","status":401,"detail":"invalid auth header","instance":"/api","code":"unauthorized"}` + "\n",
+		},
+		{
+			name:        "Invalid token",
+			args:        args{ctx: context.Background()},
+			headerName:  "Authorization",
+			headerValue: "Bearer token",
+			token:       "token",
+			mockBehaviour: func(s *mock_service.MockAuth, k *mock_service.MockApiKeys, token string) {
+				s.EXPECT().ParseToken(gomock.Any(), token).Return(entity.Actor{}, service.ErrInvalidToken)
+			},
+			wantStatusCode:  401,
+			wantRequestBody: `{"type":"/problems/invalid-token","title":"Unauthorized","status":401,"detail":"invalid or expired token","instance":"/api","code":"invalid_token"}` + "\n",
+		},
+		{
+			name:        "OK with API key",
+			args:        args{ctx: context.Background()},
+			headerName:  "X-Api-Key",
+			headerValue: "ubs_key",
+			token:       "ubs_key",
+			mockBehaviour: func(s *mock_service.MockAuth, k *mock_service.MockApiKeys, token string) {
+				k.EXPECT().ParseApiKey(gomock.Any(), token).
+					Return(entity.Actor{UserId: 2, Role: entity.RoleOperator, ApiKeyId: 5, Scopes: []entity.Permission{entity.PermRefund}}, nil)
+			},
+			wantStatusCode:  200,
+			wantRequestBody: "2",
+		},
+		{
+			name:        "Invalid API key",
+			args:        args{ctx: context.Background()},
+			headerName:  "X-Api-Key",
+			headerValue: "ubs_key",
+			token:       "ubs_key",
+			mockBehaviour: func(s *mock_service.MockAuth, k *mock_service.MockApiKeys, token string) {
+				k.EXPECT().ParseApiKey(gomock.Any(), token).Return(entity.Actor{}, service.ErrInvalidApiKey)
+			},
+			wantStatusCode:  401,
+			wantRequestBody: `{"type":"/problems/invalid-api-key","title":"Unauthorized","status":401,"detail":"invalid or revoked API key","instance":"/api","code":"invalid_api_key"}` + "\n",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -49,18 +99,22 @@ func TestAuthMiddleware_UserIdentity(t *testing.T) {
 			defer ctrl.Finish()
 
 			auth := mock_service.NewMockAuth(ctrl)
-			tc.mockBehaviour(auth, tc.token)
-			services := service.Service{Auth: auth}
-			authMiddleware := AuthMiddleware{services}
+			apiKeys := mock_service.NewMockApiKeys(ctrl)
+			tc.mockBehaviour(auth, apiKeys, tc.token)
+			authMiddleware := NewAuthMiddleware(auth, apiKeys)
 
 			e := echo.New()
-			var handlerFunc echo.HandlerFunc
-			e.GET("/api", authMiddleware.UserIdentity(handlerFunc))
+			e.HTTPErrorHandler = problem.HTTPErrorHandler
+			e.GET("/api", authMiddleware.UserIdentity(func(c echo.Context) error {
+				return c.String(http.StatusOK, fmt.Sprint(rbac.Actor(c).UserId))
+			}))
 
 			w := httptest.NewRecorder()
 			req := httptest.NewRequest(http.MethodGet, "/api", nil)
 
-			req.Header.Set(tc.headerName, tc.headerValue)
+			if len(tc.headerName) != 0 {
+				req.Header.Set(tc.headerName, tc.headerValue)
+			}
 
 			e.ServeHTTP(w, req)
 
@@ -5,31 +5,32 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	log "github.com/sirupsen/logrus"
 	"os"
+	"user-balance-service/internal/controller/http/audit"
 	"user-balance-service/internal/service"
 )
 
 func NewRouter(handler *echo.Echo, services *service.Service) {
+	handler.Use(middleware.RequestID())
 	handler.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
-		Format: `{"time":"${time_rfc3339_nano}", "method":"${method}","uri":"${uri}", "status":${status},"error":"${error}"}` + "\n",
+		Format: `{"time":"${time_rfc3339_nano}", "id":"${id}", "method":"${method}","uri":"${uri}", "status":${status},"error":"${error}"}` + "\n",
 		Output: setLogsFile(),
 	}))
 
-	auth := handler.Group("/auth")
+	auth := handler.Group("/auth", audit.Log(services.Audit))
 	{
 		newAuthRoutes(auth, services)
 	}
 
-	authMiddleware := &AuthMiddleware{services.Auth}
-	api := handler.Group("/api", authMiddleware.UserIdentity)
+	authMiddleware := NewAuthMiddleware(services.Auth, services.ApiKeys)
+	api := handler.Group("/api", audit.Log(services.Audit), authMiddleware.UserIdentity)
 	{
 		account := api.Group("/account")
 		{
-			newAccountRoutes(account, services.Account, services.History)
-
+			newAccountRoutes(account, services.Account, services.History, services.TwoFactor)
 		}
 		history := api.Group("/history")
 		{
-			newHistoryRoutes(history, services)
+			newHistoryRoutes(history, services.History, services.Account)
 		}
 	}
 }
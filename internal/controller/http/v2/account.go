@@ -0,0 +1,224 @@
+package v2
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"time"
+	"user-balance-service/internal/controller/http/audit"
+	"user-balance-service/internal/controller/http/etag"
+	"user-balance-service/internal/controller/http/rbac"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+)
+
+const accountRouteName = "v2-account"
+
+type accountRoutes struct {
+	s service.Account
+	h service.History
+}
+
+func newAccountRoutes(g *echo.Group, s service.Account, h service.History) {
+	r := &accountRoutes{s, h}
+
+	g.POST("", r.createAccount)
+	g.GET("/:id", r.getAccount, r.access(entity.PermReadAccounts)).Name = accountRouteName // ?currency=USD to get balance in chosen currency
+	g.DELETE("/:id", r.deleteAccount, r.access(entity.PermManageAccounts))
+	g.POST("/:id/deposits", r.makeDeposit, r.access(entity.PermRefund))
+	g.POST("/:id/withdrawals", r.makeWithdrawal, r.access(entity.PermManageAccounts))
+	g.GET("/:id/history", r.getHistory, r.access(entity.PermReadAccounts)) // ?sort={date|amount}; ?limit=5&cursor=
+	g.PUT("/:id/freeze", r.freezeAccount, rbac.Require(entity.PermFreeze))
+	g.DELETE("/:id/freeze", r.unfreezeAccount, rbac.Require(entity.PermFreeze))
+}
+
+// access lets through the owner of the account in the path and actors
+// granted perm
+func (r *accountRoutes) access(perm entity.Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id, err := accountIdParam(c)
+			if err != nil {
+				return err
+			}
+
+			audit.Account(c, id)
+			err = rbac.CheckAccount(c, r.s, id, perm)
+			if err != nil {
+				return err
+			}
+
+			return next(c)
+		}
+	}
+}
+
+type accountRequest struct {
+	Currency string `query:"currency" validate:"omitempty,iso4217"`
+}
+
+type operationRequest struct {
+	Amount int `json:"amount" validate:"gt=0"`
+}
+
+// create account owned by the user and set balance to 0
+func (r *accountRoutes) createAccount(c echo.Context) error {
+	id, err := r.s.CreateAccount(c.Request().Context(), rbac.Actor(c).UserId)
+	if err != nil {
+		return err
+	}
+	audit.Account(c, id)
+
+	c.Response().Header().Set(echo.HeaderLocation, c.Echo().Reverse(accountRouteName, id))
+
+	return c.JSON(http.StatusCreated, entity.Account{Id: id})
+}
+
+// check balance in rubles and convert to chosen currency
+func (r *accountRoutes) getAccount(c echo.Context) error {
+	var input accountRequest
+
+	id, err := accountIdParam(c)
+	if err != nil {
+		return err
+	}
+
+	err = c.Bind(&input)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	account, err := r.s.GetAccount(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+
+	etag.Set(c, account.Version)
+
+	if len(input.Currency) == 0 {
+		return c.JSON(http.StatusOK, account)
+	}
+
+	conversion, err := r.s.ConvertToCurrency(c.Request().Context(), input.Currency, float64(account.Balance))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"id":              account.Id,
+		"balance":         conversion.Amount,
+		"currency":        conversion.Currency,
+		"rate":            conversion.Rate,
+		"rate_fetched_at": conversion.RateFetchedAt,
+		"version":         account.Version,
+	})
+}
+
+func (r *accountRoutes) deleteAccount(c echo.Context) error {
+	id, err := accountIdParam(c)
+	if err != nil {
+		return err
+	}
+
+	version, err := etag.IfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	err = r.s.DeleteAccount(c.Request().Context(), id, version)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// frozen accounts can't be debited, deposits still go through
+func (r *accountRoutes) freezeAccount(c echo.Context) error {
+	return r.setFrozen(c, true)
+}
+
+func (r *accountRoutes) unfreezeAccount(c echo.Context) error {
+	return r.setFrozen(c, false)
+}
+
+func (r *accountRoutes) setFrozen(c echo.Context, frozen bool) error {
+	id, err := accountIdParam(c)
+	if err != nil {
+		return err
+	}
+	audit.Account(c, id)
+
+	err = r.s.SetFrozen(c.Request().Context(), id, frozen)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (r *accountRoutes) makeDeposit(c echo.Context) error {
+	return r.applyOperation(c, entity.RefillType, r.s.MakeDeposit)
+}
+
+func (r *accountRoutes) makeWithdrawal(c echo.Context) error {
+	return r.applyOperation(c, entity.WriteOffType, r.s.WriteOff)
+}
+
+// applyOperation changes the balance with op and records it in history.
+// The created history record is returned along with the account location.
+func (r *accountRoutes) applyOperation(c echo.Context, operationType string, op func(ctx context.Context, id, amount, version int) error) error {
+	id, err := accountIdParam(c)
+	if err != nil {
+		return err
+	}
+
+	var input operationRequest
+	err = c.Bind(&input)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	version, err := etag.IfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	err = op(c.Request().Context(), id, input.Amount, version)
+	if err != nil {
+		return err
+	}
+
+	record := entity.History{
+		Type:      operationType,
+		Amount:    input.Amount,
+		AccountId: id,
+		Date:      entity.CustomTime(time.Now()),
+	}
+	record.Id, err = r.h.SaveHistory(c.Request().Context(), record)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, c.Echo().Reverse(accountRouteName, id))
+
+	return c.JSON(http.StatusCreated, record)
+}
+
+func accountIdParam(c echo.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid account id")
+	}
+
+	return id, nil
+}
@@ -0,0 +1,366 @@
+package v2
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-balance-service/internal/controller/http/problem"
+	"user-balance-service/internal/controller/http/rbac"
+	"user-balance-service/internal/controller/http/validation"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+// newTestServer serves requests as user 1, who owns every account
+func newTestServer(account *mock_service.MockAccount, history service.History) *echo.Echo {
+	account.EXPECT().CheckAccess(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	return newTestServerAs(entity.Actor{UserId: 1, Role: entity.RoleUser}, &service.Service{Account: account, History: history})
+}
+
+// discardAudit stands in for the audit log in tests that don't check it
+type discardAudit struct{}
+
+func (discardAudit) Record(context.Context, entity.AuditRecord) error { return nil }
+
+func (discardAudit) ListAudit(context.Context, entity.AuditFilter) ([]entity.AuditRecord, error) {
+	return nil, nil
+}
+
+func newTestServerAs(actor entity.Actor, services *service.Service) *echo.Echo {
+	if services.Audit == nil {
+		services.Audit = discardAudit{}
+	}
+
+	e := echo.New()
+	e.HTTPErrorHandler = problem.HTTPErrorHandler
+	e.Validator = validation.New()
+	NewRouter(e, services, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			rbac.SetActor(c, actor)
+			return next(c)
+		}
+	})
+	return e
+}
+
+func TestAccountRoutes_createAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	account := mock_service.NewMockAccount(ctrl)
+	account.EXPECT().CreateAccount(gomock.Any(), 1).Return(7, nil)
+
+	e := newTestServer(account, mock_service.NewMockHistory(ctrl))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/accounts", nil)
+
+	e.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v2/accounts/7", w.Header().Get(echo.HeaderLocation))
+	assert.Equal(t, `{"id":7,"balance":0,"version":0}`+"\n", w.Body.String())
+}
+
+func TestAccountRoutes_getAccount(t *testing.T) {
+	type MockBehaviour func(s *mock_service.MockAccount)
+
+	testCases := []struct {
+		name           string
+		path           string
+		mockBehaviour  MockBehaviour
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name: "OK",
+			path: "/api/v2/accounts/1",
+			mockBehaviour: func(s *mock_service.MockAccount) {
+				s.EXPECT().GetAccount(gomock.Any(), 1).Return(entity.Account{Id: 1, Balance: 500, Version: 3}, nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"id":1,"balance":500,"version":3}` + "\n",
+		},
+		{
+			name: "OK with currency",
+			path: "/api/v2/accounts/1?currency=USD",
+			mockBehaviour: func(s *mock_service.MockAccount) {
+				s.EXPECT().GetAccount(gomock.Any(), 1).Return(entity.Account{Id: 1, Balance: 500}, nil)
+				s.EXPECT().ConvertToCurrency(gomock.Any(), "USD", float64(500)).Return(entity.Conversion{
+					Amount:        10,
+					Currency:      "USD",
+					Rate:          50,
+					RateFetchedAt: time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC),
+				}, nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody: `{"balance":10,"currency":"USD","id":1,"rate":50,` +
+				`"rate_fetched_at":"2022-10-20T12:00:00Z","version":0}` + "\n",
+		},
+		{
+			name:           "Invalid currency",
+			path:           "/api/v2/accounts/1?currency=usd1",
+			mockBehaviour:  func(s *mock_service.MockAccount) {},
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantBody: `{"type":"/problems/validation-failed","title":"Unprocessable Entity","status":422,` +
+				`"detail":"request payload is invalid","instance":"/api/v2/accounts/1","code":"validation_failed",` +
+				`"errors":[{"field":"currency","rule":"iso4217","message":"must be an ISO 4217 currency code"}]}` + "\n",
+		},
+		{
+			name:           "Invalid id",
+			path:           "/api/v2/accounts/abc",
+			mockBehaviour:  func(s *mock_service.MockAccount) {},
+			wantStatusCode: http.StatusBadRequest,
+			wantBody: `{"type":"/problems/bad-request","title":"Bad Request","status":400,` +
+				`"detail":"invalid account id","instance":"/api/v2/accounts/abc","code":"bad_request"}` + "\n",
+		},
+		{
+			name: "Not found",
+			path: "/api/v2/accounts/2",
+			mockBehaviour: func(s *mock_service.MockAccount) {
+				s.EXPECT().GetAccount(gomock.Any(), 2).Return(entity.Account{}, service.ErrAccountNotFound)
+			},
+			wantStatusCode: http.StatusNotFound,
+			wantBody: `{"type":"/problems/account-not-found","title":"Not Found","status":404,` +
+				`"detail":"account not found","instance":"/api/v2/accounts/2","code":"account_not_found"}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			account := mock_service.NewMockAccount(ctrl)
+			tc.mockBehaviour(account)
+
+			e := newTestServer(account, mock_service.NewMockHistory(ctrl))
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			assert.Equal(t, tc.wantBody, w.Body.String())
+		})
+	}
+}
+
+func TestAccountRoutes_makeWithdrawal(t *testing.T) {
+	type MockBehaviour func(s *mock_service.MockAccount, h *mock_service.MockHistory)
+
+	testCases := []struct {
+		name           string
+		inputBody      string
+		ifMatch        string
+		mockBehaviour  MockBehaviour
+		wantStatusCode int
+		wantLocation   string
+		wantETag       string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"amount":100}`,
+			mockBehaviour: func(s *mock_service.MockAccount, h *mock_service.MockHistory) {
+				s.EXPECT().WriteOff(gomock.Any(), 1, 100, 0).Return(nil)
+				h.EXPECT().SaveHistory(gomock.Any(), gomock.Any()).Return(3, nil)
+			},
+			wantStatusCode: http.StatusCreated,
+			wantLocation:   "/api/v2/accounts/1",
+		},
+		{
+			name:           "Invalid amount",
+			inputBody:      `{"amount":-100}`,
+			mockBehaviour:  func(s *mock_service.MockAccount, h *mock_service.MockHistory) {},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "Insufficient funds",
+			inputBody: `{"amount":100}`,
+			mockBehaviour: func(s *mock_service.MockAccount, h *mock_service.MockHistory) {
+				s.EXPECT().WriteOff(gomock.Any(), 1, 100, 0).
+					Return(fmt.Errorf("repo: %w", service.ErrInsufficientFunds))
+			},
+			wantStatusCode: http.StatusConflict,
+		},
+		{
+			name:      "Account not found",
+			inputBody: `{"amount":100}`,
+			mockBehaviour: func(s *mock_service.MockAccount, h *mock_service.MockHistory) {
+				s.EXPECT().WriteOff(gomock.Any(), 1, 100, 0).
+					Return(fmt.Errorf("repo: %w", service.ErrAccountNotFound))
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:      "OK with If-Match",
+			inputBody: `{"amount":100}`,
+			ifMatch:   `"2"`,
+			mockBehaviour: func(s *mock_service.MockAccount, h *mock_service.MockHistory) {
+				s.EXPECT().WriteOff(gomock.Any(), 1, 100, 2).Return(nil)
+				h.EXPECT().SaveHistory(gomock.Any(), gomock.Any()).Return(3, nil)
+			},
+			wantStatusCode: http.StatusCreated,
+			wantLocation:   "/api/v2/accounts/1",
+		},
+		{
+			name:      "Version mismatch",
+			inputBody: `{"amount":100}`,
+			ifMatch:   `"2"`,
+			mockBehaviour: func(s *mock_service.MockAccount, h *mock_service.MockHistory) {
+				s.EXPECT().WriteOff(gomock.Any(), 1, 100, 2).
+					Return(fmt.Errorf("repo: %w", &service.VersionMismatchError{
+						Current: entity.Account{Id: 1, Balance: 300, Version: 5},
+					}))
+			},
+			wantStatusCode: http.StatusPreconditionFailed,
+			wantETag:       `"5"`,
+		},
+		{
+			name:           "Weak If-Match",
+			inputBody:      `{"amount":100}`,
+			ifMatch:        `W/"2"`,
+			mockBehaviour:  func(s *mock_service.MockAccount, h *mock_service.MockHistory) {},
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			account := mock_service.NewMockAccount(ctrl)
+			history := mock_service.NewMockHistory(ctrl)
+			tc.mockBehaviour(account, history)
+
+			e := newTestServer(account, history)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/accounts/1/withdrawals",
+				bytes.NewBufferString(tc.inputBody))
+			req.Header.Set("Content-Type", "application/json")
+			if len(tc.ifMatch) != 0 {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			assert.Equal(t, tc.wantLocation, w.Header().Get(echo.HeaderLocation))
+			assert.Equal(t, tc.wantETag, w.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestAccountRoutes_deleteAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	account := mock_service.NewMockAccount(ctrl)
+	account.EXPECT().DeleteAccount(gomock.Any(), 4, 0).Return(nil)
+
+	e := newTestServer(account, mock_service.NewMockHistory(ctrl))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/api/v2/accounts/4", nil)
+
+	e.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestAccountRoutes_forbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	account := mock_service.NewMockAccount(ctrl)
+	account.EXPECT().CheckAccess(gomock.Any(), entity.Actor{UserId: 2, Role: entity.RoleUser}, 1, entity.PermReadAccounts).
+		Return(service.ErrForbidden)
+
+	e := newTestServerAs(entity.Actor{UserId: 2, Role: entity.RoleUser}, &service.Service{Account: account})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/accounts/1", nil)
+
+	e.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, `{"type":"/problems/forbidden","title":"Forbidden","status":403,`+
+		`"detail":"access denied","instance":"/api/v2/accounts/1","code":"forbidden"}`+"\n", w.Body.String())
+}
+
+func TestAccountRoutes_freezeAccount(t *testing.T) {
+	type MockBehaviour func(s *mock_service.MockAccount)
+
+	testCases := []struct {
+		name           string
+		role           entity.Role
+		method         string
+		mockBehaviour  MockBehaviour
+		wantStatusCode int
+	}{
+		{
+			name:   "Freeze by operator",
+			role:   entity.RoleOperator,
+			method: http.MethodPut,
+			mockBehaviour: func(s *mock_service.MockAccount) {
+				s.EXPECT().SetFrozen(gomock.Any(), 1, true).Return(nil)
+			},
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:   "Unfreeze by admin",
+			role:   entity.RoleAdmin,
+			method: http.MethodDelete,
+			mockBehaviour: func(s *mock_service.MockAccount) {
+				s.EXPECT().SetFrozen(gomock.Any(), 1, false).Return(nil)
+			},
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "Owner can't freeze",
+			role:           entity.RoleUser,
+			method:         http.MethodPut,
+			mockBehaviour:  func(s *mock_service.MockAccount) {},
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:   "Not found",
+			role:   entity.RoleOperator,
+			method: http.MethodPut,
+			mockBehaviour: func(s *mock_service.MockAccount) {
+				s.EXPECT().SetFrozen(gomock.Any(), 1, true).Return(service.ErrAccountNotFound)
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			account := mock_service.NewMockAccount(ctrl)
+			tc.mockBehaviour(account)
+
+			e := newTestServerAs(entity.Actor{UserId: 1, Role: tc.role}, &service.Service{Account: account})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, "/api/v2/accounts/1/freeze", nil)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package v2
+
+import (
+	"github.com/labstack/echo/v4"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+)
+
+type adminRoutes struct {
+	u  service.Users
+	tf service.TwoFactor
+	a  service.Audit
+}
+
+func newAdminRoutes(g *echo.Group, u service.Users, tf service.TwoFactor, a service.Audit) {
+	r := &adminRoutes{u, tf, a}
+
+	g.GET("/roles", r.getRoles)
+	g.GET("/users", r.getUsers)
+	g.PUT("/users/:id/disabled", r.disableUser)
+	g.DELETE("/users/:id/disabled", r.enableUser)
+	g.PUT("/users/:id/role", r.setRole)
+	g.DELETE("/users/:id/lock", r.unlockUser)
+	g.DELETE("/users/:id/2fa", r.resetTwoFactor)
+	g.DELETE("/ips/:ip/lock", r.unlockIP)
+	g.GET("/audit", r.getAudit) // ?user_id=&api_key_id=&account_id=&from=&to=&before=&limit=
+}
+
+type roleRequest struct {
+	Role entity.Role `json:"role" validate:"required"`
+}
+
+type usersRequest struct {
+	Query    string `query:"q" validate:"max=64"`
+	Disabled string `query:"disabled" validate:"omitempty,oneof=true false"`
+	After    int    `query:"after" validate:"gte=0"`
+	Limit    int    `query:"limit" validate:"gte=0"`
+}
+
+// auditRequest takes from and to in RFC 3339, e.g. 2022-10-20T12:00:00Z
+type auditRequest struct {
+	UserId    int    `query:"user_id" validate:"gte=0"`
+	ApiKeyId  int    `query:"api_key_id" validate:"gte=0"`
+	AccountId int    `query:"account_id" validate:"gte=0"`
+	From      string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To        string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Before    int64  `query:"before" validate:"gte=0"`
+	Limit     int    `query:"limit" validate:"gte=0"`
+}
+
+type roleResponse struct {
+	Role        entity.Role         `json:"role"`
+	Permissions []entity.Permission `json:"permissions"`
+}
+
+// roles and what they are granted besides access to own accounts
+func (r *adminRoutes) getRoles(c echo.Context) error {
+	roles := []entity.Role{entity.RoleAdmin, entity.RoleOperator, entity.RoleUser}
+
+	response := make([]roleResponse, 0, len(roles))
+	for _, role := range roles {
+		response = append(response, roleResponse{Role: role, Permissions: role.Permissions()})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// users matching q, a part of the username, page by page: after is the id
+// of the last user of the previous page
+func (r *adminRoutes) getUsers(c echo.Context) error {
+	var input usersRequest
+	err := c.Bind(&input)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	filter := entity.UserFilter{Query: input.Query, AfterId: input.After, Limit: input.Limit}
+	if input.Disabled != "" {
+		disabled := input.Disabled == "true"
+		filter.Disabled = &disabled
+	}
+
+	users, err := r.u.ListUsers(c.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	response := make([]userResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, newUserResponse(user))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// audit records newest first, page by page: before is the id of the last
+// record of the previous page
+func (r *adminRoutes) getAudit(c echo.Context) error {
+	var input auditRequest
+	err := c.Bind(&input)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	filter := entity.AuditFilter{
+		UserId:    input.UserId,
+		ApiKeyId:  input.ApiKeyId,
+		AccountId: input.AccountId,
+		BeforeId:  input.Before,
+		Limit:     input.Limit,
+	}
+	// validated above; the log is kept in UTC
+	if input.From != "" {
+		from, _ := time.Parse(time.RFC3339, input.From)
+		filter.From = from.UTC()
+	}
+	if input.To != "" {
+		to, _ := time.Parse(time.RFC3339, input.To)
+		filter.To = to.UTC()
+	}
+
+	records, err := r.a.ListAudit(c.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, records)
+}
+
+func (r *adminRoutes) disableUser(c echo.Context) error {
+	return r.setDisabled(c, true)
+}
+
+func (r *adminRoutes) enableUser(c echo.Context) error {
+	return r.setDisabled(c, false)
+}
+
+func (r *adminRoutes) setDisabled(c echo.Context, disabled bool) error {
+	id, err := userIdParam(c)
+	if err != nil {
+		return err
+	}
+
+	err = r.u.SetDisabled(c.Request().Context(), id, disabled)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (r *adminRoutes) setRole(c echo.Context) error {
+	id, err := userIdParam(c)
+	if err != nil {
+		return err
+	}
+
+	var input roleRequest
+	err = c.Bind(&input)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	err = r.u.SetRole(c.Request().Context(), id, input.Role)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"id":   id,
+		"role": input.Role,
+	})
+}
+
+// lifting of the sign-in lockout of a user
+func (r *adminRoutes) unlockUser(c echo.Context) error {
+	id, err := userIdParam(c)
+	if err != nil {
+		return err
+	}
+
+	err = r.u.UnlockUser(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// turning off the second factor of a user who lost both their app and
+// their recovery codes
+func (r *adminRoutes) resetTwoFactor(c echo.Context) error {
+	id, err := userIdParam(c)
+	if err != nil {
+		return err
+	}
+
+	err = r.tf.ResetTwoFactor(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// lifting of the sign-in lockout of an IP address
+func (r *adminRoutes) unlockIP(c echo.Context) error {
+	ip := net.ParseIP(c.Param("ip"))
+	if ip == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid IP address")
+	}
+
+	err := r.u.UnlockIP(c.Request().Context(), ip.String())
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func userIdParam(c echo.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid user id")
+	}
+
+	return id, nil
+}
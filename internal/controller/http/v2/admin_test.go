@@ -0,0 +1,337 @@
+package v2
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+func TestAdminRoutes_setRole(t *testing.T) {
+	type MockBehaviour func(s *mock_service.MockUsers)
+
+	testCases := []struct {
+		name           string
+		role           entity.Role
+		path           string
+		inputBody      string
+		mockBehaviour  MockBehaviour
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:      "OK",
+			role:      entity.RoleAdmin,
+			path:      "/api/v2/admin/users/5/role",
+			inputBody: `{"role":"operator"}`,
+			mockBehaviour: func(s *mock_service.MockUsers) {
+				s.EXPECT().SetRole(gomock.Any(), 5, entity.RoleOperator).Return(nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"id":5,"role":"operator"}` + "\n",
+		},
+		{
+			name:           "Operator is forbidden",
+			role:           entity.RoleOperator,
+			path:           "/api/v2/admin/users/5/role",
+			inputBody:      `{"role":"admin"}`,
+			mockBehaviour:  func(s *mock_service.MockUsers) {},
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Invalid user id",
+			role:           entity.RoleAdmin,
+			path:           "/api/v2/admin/users/abc/role",
+			inputBody:      `{"role":"admin"}`,
+			mockBehaviour:  func(s *mock_service.MockUsers) {},
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:      "Invalid role",
+			role:      entity.RoleAdmin,
+			path:      "/api/v2/admin/users/5/role",
+			inputBody: `{"role":"root"}`,
+			mockBehaviour: func(s *mock_service.MockUsers) {
+				s.EXPECT().SetRole(gomock.Any(), 5, entity.Role("root")).
+					Return(fmt.Errorf("service: %w", service.ErrInvalidRole))
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "User not found",
+			role:      entity.RoleAdmin,
+			path:      "/api/v2/admin/users/5/role",
+			inputBody: `{"role":"user"}`,
+			mockBehaviour: func(s *mock_service.MockUsers) {
+				s.EXPECT().SetRole(gomock.Any(), 5, entity.RoleUser).
+					Return(fmt.Errorf("repo: %w", service.ErrUserNotFound))
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			users := mock_service.NewMockUsers(ctrl)
+			tc.mockBehaviour(users)
+
+			e := newTestServerAs(entity.Actor{UserId: 1, Role: tc.role}, &service.Service{Users: users})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, tc.path, bytes.NewBufferString(tc.inputBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if len(tc.wantBody) != 0 {
+				assert.Equal(t, tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminRoutes_unlock(t *testing.T) {
+	type MockBehaviour func(s *mock_service.MockUsers)
+
+	testCases := []struct {
+		name           string
+		path           string
+		mockBehaviour  MockBehaviour
+		wantStatusCode int
+	}{
+		{
+			name: "User",
+			path: "/api/v2/admin/users/5/lock",
+			mockBehaviour: func(s *mock_service.MockUsers) {
+				s.EXPECT().UnlockUser(gomock.Any(), 5).Return(nil)
+			},
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name: "Unknown user",
+			path: "/api/v2/admin/users/5/lock",
+			mockBehaviour: func(s *mock_service.MockUsers) {
+				s.EXPECT().UnlockUser(gomock.Any(), 5).Return(fmt.Errorf("repo: %w", service.ErrUserNotFound))
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "IPv6 address",
+			path: "/api/v2/admin/ips/2001:DB8::1/lock",
+			mockBehaviour: func(s *mock_service.MockUsers) {
+				s.EXPECT().UnlockIP(gomock.Any(), "2001:db8::1").Return(nil)
+			},
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "Invalid IP address",
+			path:           "/api/v2/admin/ips/localhost/lock",
+			mockBehaviour:  func(s *mock_service.MockUsers) {},
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			users := mock_service.NewMockUsers(ctrl)
+			tc.mockBehaviour(users)
+
+			e := newTestServerAs(entity.Actor{UserId: 1, Role: entity.RoleAdmin}, &service.Service{Users: users})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, tc.path, nil)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+		})
+	}
+}
+
+func TestAdminRoutes_getUsers(t *testing.T) {
+	createdAt := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+	disabled := true
+
+	type MockBehaviour func(s *mock_service.MockUsers)
+
+	testCases := []struct {
+		name           string
+		path           string
+		mockBehaviour  MockBehaviour
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name: "OK",
+			path: "/api/v2/admin/users?q=qw&disabled=true&after=10&limit=20",
+			mockBehaviour: func(s *mock_service.MockUsers) {
+				s.EXPECT().ListUsers(gomock.Any(), entity.UserFilter{Query: "qw", Disabled: &disabled, AfterId: 10, Limit: 20}).
+					Return([]entity.User{{Id: 11, Username: "qwe", Role: entity.RoleUser, CreatedAt: createdAt, UpdatedAt: createdAt, DisabledAt: &createdAt}}, nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       `[{"id":11,"username":"qwe","role":"user","two_factor_enabled":false,"created_at":"2022-10-20T12:00:00Z","updated_at":"2022-10-20T12:00:00Z","disabled_at":"2022-10-20T12:00:00Z"}]` + "\n",
+		},
+		{
+			name: "No users",
+			path: "/api/v2/admin/users",
+			mockBehaviour: func(s *mock_service.MockUsers) {
+				s.EXPECT().ListUsers(gomock.Any(), entity.UserFilter{}).Return([]entity.User{}, nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       "[]\n",
+		},
+		{
+			name:           "Invalid filter",
+			path:           "/api/v2/admin/users?disabled=maybe",
+			mockBehaviour:  func(s *mock_service.MockUsers) {},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			users := mock_service.NewMockUsers(ctrl)
+			tc.mockBehaviour(users)
+
+			e := newTestServerAs(entity.Actor{UserId: 1, Role: entity.RoleAdmin}, &service.Service{Users: users})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if len(tc.wantBody) != 0 {
+				assert.Equal(t, tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminRoutes_setDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := mock_service.NewMockUsers(ctrl)
+	users.EXPECT().SetDisabled(gomock.Any(), 5, true).Return(nil)
+	users.EXPECT().SetDisabled(gomock.Any(), 5, false).Return(nil)
+	users.EXPECT().SetDisabled(gomock.Any(), 6, true).Return(fmt.Errorf("repo: %w", service.ErrUserNotFound))
+
+	e := newTestServerAs(entity.Actor{UserId: 1, Role: entity.RoleAdmin}, &service.Service{Users: users})
+
+	for _, tc := range []struct {
+		method         string
+		path           string
+		wantStatusCode int
+	}{
+		{http.MethodPut, "/api/v2/admin/users/5/disabled", http.StatusNoContent},
+		{http.MethodDelete, "/api/v2/admin/users/5/disabled", http.StatusNoContent},
+		{http.MethodPut, "/api/v2/admin/users/6/disabled", http.StatusNotFound},
+	} {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, tc.wantStatusCode, w.Code, tc.method+" "+tc.path)
+	}
+}
+
+func TestAdminRoutes_getAudit(t *testing.T) {
+	createdAt := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+
+	type MockBehaviour func(s *mock_service.MockAudit)
+
+	testCases := []struct {
+		name           string
+		actor          entity.Actor
+		path           string
+		mockBehaviour  MockBehaviour
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:  "OK",
+			actor: entity.Actor{UserId: 1, Role: entity.RoleAdmin},
+			path:  "/api/v2/admin/audit?user_id=2&account_id=7&from=2022-10-20T03:00:00%2B03:00&to=2022-10-21T00:00:00Z&before=100&limit=20",
+			mockBehaviour: func(s *mock_service.MockAudit) {
+				s.EXPECT().ListAudit(gomock.Any(), entity.AuditFilter{
+					UserId:    2,
+					AccountId: 7,
+					From:      time.Date(2022, 10, 20, 0, 0, 0, 0, time.UTC),
+					To:        time.Date(2022, 10, 21, 0, 0, 0, 0, time.UTC),
+					BeforeId:  100,
+					Limit:     20,
+				}).Return([]entity.AuditRecord{{
+					Id:         99,
+					UserId:     2,
+					Role:       entity.RoleUser,
+					IP:         "192.0.2.1",
+					UserAgent:  "billing/1.0",
+					RequestId:  "req-1",
+					Operation:  "POST /api/v2/accounts/:id/withdrawals",
+					AccountIds: []int{7},
+					Params:     []byte(`{"body":{"amount":100},"path":{"id":"7"}}`),
+					Status:     http.StatusConflict,
+					Outcome:    entity.AuditFailed,
+					Error:      "insufficient_funds",
+					CreatedAt:  createdAt,
+				}}, nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody: `[{"id":99,"user_id":2,"role":"user","ip":"192.0.2.1","user_agent":"billing/1.0","request_id":"req-1",` +
+				`"operation":"POST /api/v2/accounts/:id/withdrawals","account_ids":[7],"params":{"body":{"amount":100},"path":{"id":"7"}},` +
+				`"status":409,"outcome":"failed","error":"insufficient_funds","created_at":"2022-10-20T12:00:00Z"}]` + "\n",
+		},
+		{
+			name:           "Invalid time",
+			actor:          entity.Actor{UserId: 1, Role: entity.RoleAdmin},
+			path:           "/api/v2/admin/audit?from=2022-10-20",
+			mockBehaviour:  func(s *mock_service.MockAudit) {},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Not an admin",
+			actor:          entity.Actor{UserId: 1, Role: entity.RoleOperator},
+			path:           "/api/v2/admin/audit",
+			mockBehaviour:  func(s *mock_service.MockAudit) {},
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			audit := mock_service.NewMockAudit(ctrl)
+			tc.mockBehaviour(audit)
+
+			e := newTestServerAs(tc.actor, &service.Service{Audit: audit})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if len(tc.wantBody) != 0 {
+				assert.Equal(t, tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
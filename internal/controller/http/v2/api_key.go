@@ -0,0 +1,99 @@
+package v2
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"user-balance-service/internal/controller/http/rbac"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+)
+
+type apiKeyRoutes struct {
+	s service.ApiKeys
+}
+
+func newApiKeyRoutes(g *echo.Group, s service.ApiKeys) {
+	r := &apiKeyRoutes{s}
+
+	g.POST("", r.createApiKey)
+	g.GET("", r.getApiKeys)
+	g.POST("/:id/rotate", r.rotateApiKey)
+	g.DELETE("/:id", r.revokeApiKey)
+}
+
+type apiKeyRequest struct {
+	Name   string              `json:"name" validate:"required,max=64"`
+	Scopes []entity.Permission `json:"scopes"`
+}
+
+// apiKeyResponse carries the key itself, it can't be shown again later
+type apiKeyResponse struct {
+	entity.ApiKey
+	Key string `json:"key"`
+}
+
+func (r *apiKeyRoutes) createApiKey(c echo.Context) error {
+	var input apiKeyRequest
+	err := c.Bind(&input)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	key, secret, err := r.s.CreateApiKey(c.Request().Context(), rbac.Actor(c), input.Name, input.Scopes)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, apiKeyResponse{ApiKey: key, Key: secret})
+}
+
+func (r *apiKeyRoutes) getApiKeys(c echo.Context) error {
+	keys, err := r.s.ListApiKeys(c.Request().Context(), rbac.Actor(c).UserId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, keys)
+}
+
+func (r *apiKeyRoutes) rotateApiKey(c echo.Context) error {
+	id, err := apiKeyIdParam(c)
+	if err != nil {
+		return err
+	}
+
+	key, secret, err := r.s.RotateApiKey(c.Request().Context(), rbac.Actor(c).UserId, id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, apiKeyResponse{ApiKey: key, Key: secret})
+}
+
+func (r *apiKeyRoutes) revokeApiKey(c echo.Context) error {
+	id, err := apiKeyIdParam(c)
+	if err != nil {
+		return err
+	}
+
+	err = r.s.RevokeApiKey(c.Request().Context(), rbac.Actor(c).UserId, id)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func apiKeyIdParam(c echo.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid API key id")
+	}
+
+	return id, nil
+}
@@ -0,0 +1,151 @@
+package v2
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+func TestApiKeyRoutes_createApiKey(t *testing.T) {
+	createdAt := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+	user := entity.Actor{UserId: 1, Role: entity.RoleOperator}
+
+	type MockBehaviour func(s *mock_service.MockApiKeys)
+
+	testCases := []struct {
+		name           string
+		actor          entity.Actor
+		inputBody      string
+		mockBehaviour  MockBehaviour
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:      "OK",
+			actor:     user,
+			inputBody: `{"name":"billing","scopes":["accounts:refund"]}`,
+			mockBehaviour: func(s *mock_service.MockApiKeys) {
+				s.EXPECT().CreateApiKey(gomock.Any(), user, "billing", []entity.Permission{entity.PermRefund}).
+					Return(entity.ApiKey{
+						Id:        7,
+						UserId:    1,
+						Name:      "billing",
+						Prefix:    "ubs_0123abcd",
+						Scopes:    []entity.Permission{entity.PermRefund},
+						CreatedAt: createdAt,
+					}, "ubs_0123abcdef", nil)
+			},
+			wantStatusCode: http.StatusCreated,
+			wantBody: `{"id":7,"name":"billing","prefix":"ubs_0123abcd","scopes":["accounts:refund"],` +
+				`"created_at":"2022-10-20T12:00:00Z","last_used_at":null,"key":"ubs_0123abcdef"}` + "\n",
+		},
+		{
+			name:           "No name",
+			actor:          user,
+			inputBody:      `{"scopes":[]}`,
+			mockBehaviour:  func(s *mock_service.MockApiKeys) {},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "Scope the owner doesn't have",
+			actor:     user,
+			inputBody: `{"name":"billing","scopes":["roles:manage"]}`,
+			mockBehaviour: func(s *mock_service.MockApiKeys) {
+				s.EXPECT().CreateApiKey(gomock.Any(), user, "billing", []entity.Permission{entity.PermManageRoles}).
+					Return(entity.ApiKey{}, "", service.ErrForbidden)
+			},
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "Made with an API key",
+			actor:          entity.Actor{UserId: 1, Role: entity.RoleOperator, ApiKeyId: 3},
+			inputBody:      `{"name":"billing"}`,
+			mockBehaviour:  func(s *mock_service.MockApiKeys) {},
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			apiKeys := mock_service.NewMockApiKeys(ctrl)
+			tc.mockBehaviour(apiKeys)
+
+			e := newTestServerAs(tc.actor, &service.Service{ApiKeys: apiKeys})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/api-keys", bytes.NewBufferString(tc.inputBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if len(tc.wantBody) != 0 {
+				assert.Equal(t, tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestApiKeyRoutes_revokeApiKey(t *testing.T) {
+	type MockBehaviour func(s *mock_service.MockApiKeys)
+
+	testCases := []struct {
+		name           string
+		path           string
+		mockBehaviour  MockBehaviour
+		wantStatusCode int
+	}{
+		{
+			name: "OK",
+			path: "/api/v2/api-keys/7",
+			mockBehaviour: func(s *mock_service.MockApiKeys) {
+				s.EXPECT().RevokeApiKey(gomock.Any(), 1, 7).Return(nil)
+			},
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name: "Not found",
+			path: "/api/v2/api-keys/8",
+			mockBehaviour: func(s *mock_service.MockApiKeys) {
+				s.EXPECT().RevokeApiKey(gomock.Any(), 1, 8).Return(fmt.Errorf("repo: %w", service.ErrApiKeyNotFound))
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid id",
+			path:           "/api/v2/api-keys/abc",
+			mockBehaviour:  func(s *mock_service.MockApiKeys) {},
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			apiKeys := mock_service.NewMockApiKeys(ctrl)
+			tc.mockBehaviour(apiKeys)
+
+			e := newTestServerAs(entity.Actor{UserId: 1, Role: entity.RoleUser}, &service.Service{ApiKeys: apiKeys})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, tc.path, nil)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+		})
+	}
+}
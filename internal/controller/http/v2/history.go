@@ -0,0 +1,69 @@
+package v2
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"user-balance-service/internal/entity"
+)
+
+type historyRequest struct {
+	Sort     string `query:"sort" validate:"omitempty,oneof=date amount"`
+	Limit    int    `query:"limit" validate:"gte=0"`
+	Cursor   string `query:"cursor" validate:"omitempty,datetime=2006-01-02"`
+	Currency string `query:"currency" validate:"omitempty,iso4217"`
+}
+
+func (r *accountRoutes) getHistory(c echo.Context) error {
+	var (
+		input   historyRequest
+		records []entity.History
+	)
+
+	id, err := accountIdParam(c)
+	if err != nil {
+		return err
+	}
+
+	err = c.Bind(&input)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	// history of a missing account is a 404, not an empty list
+	_, err = r.s.GetAccount(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case input.Limit > 0:
+		records, err = r.h.Pagination(c.Request().Context(), input.Limit, input.Cursor, id)
+	case len(input.Sort) != 0:
+		records, err = r.h.ShowSorted(c.Request().Context(), input.Sort, id)
+	default:
+		records, err = r.h.ShowById(c.Request().Context(), id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(input.Currency) == 0 {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"records": records,
+		})
+	}
+
+	// every record is converted with the rate of its date
+	converted, err := r.h.Convert(c.Request().Context(), records, input.Currency)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"records": converted,
+	})
+}
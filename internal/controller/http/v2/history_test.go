@@ -0,0 +1,87 @@
+package v2
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+func TestAccountRoutes_getHistory(t *testing.T) {
+	type MockBehaviour func(a *mock_service.MockAccount, h *mock_service.MockHistory)
+
+	record := entity.History{
+		Id:        1,
+		Type:      entity.RefillType,
+		Amount:    600,
+		AccountId: 1,
+		Date:      entity.CustomTime(time.Date(2022, 10, 20, 15, 4, 0, 0, time.UTC)),
+	}
+
+	testCases := []struct {
+		name           string
+		path           string
+		mockBehaviour  MockBehaviour
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name: "OK",
+			path: "/api/v2/accounts/1/history",
+			mockBehaviour: func(a *mock_service.MockAccount, h *mock_service.MockHistory) {
+				a.EXPECT().GetAccount(gomock.Any(), 1).Return(entity.Account{Id: 1}, nil)
+				h.EXPECT().ShowById(gomock.Any(), 1).Return([]entity.History{record}, nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody: `{"records":[{"id":1,"type":"пополнение счёта","description":"","amount":600,` +
+				`"account_id":1,"date":"2022-10-20"}]}` + "\n",
+		},
+		{
+			name: "OK with currency",
+			path: "/api/v2/accounts/1/history?currency=USD",
+			mockBehaviour: func(a *mock_service.MockAccount, h *mock_service.MockHistory) {
+				a.EXPECT().GetAccount(gomock.Any(), 1).Return(entity.Account{Id: 1}, nil)
+				h.EXPECT().ShowById(gomock.Any(), 1).Return([]entity.History{record}, nil)
+				h.EXPECT().Convert(gomock.Any(), []entity.History{record}, "USD").Return([]entity.ConvertedHistory{
+					{History: record, Currency: "USD", Rate: 60, ConvertedAmount: 10},
+				}, nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody: `{"records":[{"id":1,"type":"пополнение счёта","description":"","amount":600,` +
+				`"account_id":1,"date":"2022-10-20","currency":"USD","rate":60,"converted_amount":10}]}` + "\n",
+		},
+		{
+			name:           "Invalid currency",
+			path:           "/api/v2/accounts/1/history?currency=dollars",
+			mockBehaviour:  func(a *mock_service.MockAccount, h *mock_service.MockHistory) {},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			account := mock_service.NewMockAccount(ctrl)
+			history := mock_service.NewMockHistory(ctrl)
+			tc.mockBehaviour(account, history)
+
+			e := newTestServer(account, history)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if len(tc.wantBody) != 0 {
+				assert.Equal(t, tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package v2
+
+import (
+	"github.com/labstack/echo/v4"
+	"user-balance-service/internal/controller/http/audit"
+	"user-balance-service/internal/controller/http/rbac"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+)
+
+// NewRouter registers resource-oriented /api/v2 routes. Request logging and
+// request ids are configured by v1.NewRouter on the same echo instance, so
+// only the auth middleware has to be passed in.
+func NewRouter(handler *echo.Echo, services *service.Service, authMiddleware echo.MiddlewareFunc) {
+	api := handler.Group("/api/v2", audit.Log(services.Audit), authMiddleware)
+	{
+		accounts := api.Group("/accounts")
+		{
+			newAccountRoutes(accounts, services.Account, services.History)
+		}
+		transfers := api.Group("/transfers")
+		{
+			newTransferRoutes(transfers, services.Account, services.History, services.TwoFactor)
+		}
+		me := api.Group("/me")
+		{
+			newUserRoutes(me, services.Auth, services.Users)
+			newTwoFactorRoutes(me.Group("/2fa", rbac.DenyApiKeys), services.TwoFactor)
+		}
+		apiKeys := api.Group("/api-keys", rbac.DenyApiKeys)
+		{
+			newApiKeyRoutes(apiKeys, services.ApiKeys)
+		}
+		admin := api.Group("/admin", rbac.Require(entity.PermManageRoles))
+		{
+			newAdminRoutes(admin, services.Users, services.TwoFactor, services.Audit)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package v2
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+	"user-balance-service/internal/controller/http/audit"
+	"user-balance-service/internal/controller/http/etag"
+	"user-balance-service/internal/controller/http/rbac"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+)
+
+type transferRoutes struct {
+	s  service.Account
+	h  service.History
+	tf service.TwoFactor
+}
+
+func newTransferRoutes(g *echo.Group, s service.Account, h service.History, tf service.TwoFactor) {
+	r := &transferRoutes{s, h, tf}
+
+	g.POST("", r.createTransfer) // X-2FA-Code above the threshold
+}
+
+type transferRequest struct {
+	IdFrom int `json:"id_from" validate:"required,gt=0"`
+	IdTo   int `json:"id_to" validate:"required,gt=0,nefield=IdFrom"`
+	Amount int `json:"amount" validate:"gt=0"`
+}
+
+// transfer money from one account to another
+func (r *transferRoutes) createTransfer(c echo.Context) error {
+	var input transferRequest
+
+	err := c.Bind(&input)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	// If-Match refers to the source account
+	version, err := etag.IfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	audit.Account(c, input.IdFrom, input.IdTo)
+	err = rbac.CheckAccount(c, r.s, input.IdFrom, entity.PermManageAccounts)
+	if err != nil {
+		return err
+	}
+
+	err = rbac.AuthorizeTransfer(c, r.tf, input.Amount)
+	if err != nil {
+		return err
+	}
+
+	err = r.s.TransferMoney(c.Request().Context(), input.IdFrom, input.IdTo, input.Amount, version)
+	if err != nil {
+		return err
+	}
+
+	now := entity.CustomTime(time.Now())
+	_, err = r.h.SaveHistory(c.Request().Context(), entity.History{
+		Type:      entity.InnerTransferType,
+		Amount:    input.Amount,
+		AccountId: input.IdFrom,
+		Date:      now,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.h.SaveHistory(c.Request().Context(), entity.History{
+		Type:      entity.OuterTransferType,
+		Amount:    input.Amount,
+		AccountId: input.IdTo,
+		Date:      now,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, c.Echo().Reverse(accountRouteName, input.IdFrom))
+
+	return c.JSON(http.StatusCreated, input)
+}
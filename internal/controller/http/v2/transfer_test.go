@@ -0,0 +1,128 @@
+package v2
+
+import (
+	"bytes"
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+func TestTransferRoutes_createTransfer(t *testing.T) {
+	type MockBehaviour func(a *mock_service.MockAccount, h *mock_service.MockHistory, tf *mock_service.MockTwoFactor)
+
+	actor := entity.Actor{UserId: 1, Role: entity.RoleUser}
+
+	testCases := []struct {
+		name           string
+		code           string
+		mockBehaviour  MockBehaviour
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name: "OK",
+			mockBehaviour: func(a *mock_service.MockAccount, h *mock_service.MockHistory, tf *mock_service.MockTwoFactor) {
+				tf.EXPECT().AuthorizeTransfer(gomock.Any(), actor, 300, "").Return(nil)
+				a.EXPECT().TransferMoney(gomock.Any(), 1, 2, 300, 0).Return(nil)
+				h.EXPECT().SaveHistory(gomock.Any(), gomock.Any()).Return(1, nil).Times(2)
+			},
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `{"id_from":1,"id_to":2,"amount":300}` + "\n",
+		},
+		{
+			name: "With a two-factor code",
+			code: "123456",
+			mockBehaviour: func(a *mock_service.MockAccount, h *mock_service.MockHistory, tf *mock_service.MockTwoFactor) {
+				tf.EXPECT().AuthorizeTransfer(gomock.Any(), actor, 300, "123456").Return(nil)
+				a.EXPECT().TransferMoney(gomock.Any(), 1, 2, 300, 0).Return(nil)
+				h.EXPECT().SaveHistory(gomock.Any(), gomock.Any()).Return(1, nil).Times(2)
+			},
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `{"id_from":1,"id_to":2,"amount":300}` + "\n",
+		},
+		{
+			name: "Two-factor code required",
+			mockBehaviour: func(a *mock_service.MockAccount, h *mock_service.MockHistory, tf *mock_service.MockTwoFactor) {
+				tf.EXPECT().AuthorizeTransfer(gomock.Any(), actor, 300, "").Return(service.ErrTwoFactorRequired)
+			},
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name: "Invalid two-factor code",
+			code: "000000",
+			mockBehaviour: func(a *mock_service.MockAccount, h *mock_service.MockHistory, tf *mock_service.MockTwoFactor) {
+				tf.EXPECT().AuthorizeTransfer(gomock.Any(), actor, 300, "000000").Return(service.ErrInvalidTwoFactorCode)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			account := mock_service.NewMockAccount(ctrl)
+			history := mock_service.NewMockHistory(ctrl)
+			twoFactor := mock_service.NewMockTwoFactor(ctrl)
+			account.EXPECT().CheckAccess(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			tc.mockBehaviour(account, history, twoFactor)
+
+			e := newTestServerAs(actor, &service.Service{Account: account, History: history, TwoFactor: twoFactor})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/transfers",
+				bytes.NewBufferString(`{"id_from":1,"id_to":2,"amount":300}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.code != "" {
+				req.Header.Set("X-2FA-Code", tc.code)
+			}
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if len(tc.wantBody) != 0 {
+				assert.Equal(t, "/api/v2/accounts/1", w.Header().Get(echo.HeaderLocation))
+				assert.Equal(t, tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestTransferRoutes_audit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	actor := entity.Actor{UserId: 1, Role: entity.RoleUser}
+	account := mock_service.NewMockAccount(ctrl)
+	twoFactor := mock_service.NewMockTwoFactor(ctrl)
+	audit := mock_service.NewMockAudit(ctrl)
+
+	// a refused transfer is recorded against both accounts
+	account.EXPECT().CheckAccess(gomock.Any(), actor, 1, entity.PermManageAccounts).Return(service.ErrForbidden)
+	audit.EXPECT().Record(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, record entity.AuditRecord) error {
+		assert.Equal(t, "POST /api/v2/transfers", record.Operation)
+		assert.Equal(t, []int{1, 2}, record.AccountIds)
+		assert.Equal(t, entity.AuditFailed, record.Outcome)
+		assert.Equal(t, "forbidden", record.Error)
+		return nil
+	})
+
+	e := newTestServerAs(actor, &service.Service{Account: account, TwoFactor: twoFactor, Audit: audit})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/transfers",
+		bytes.NewBufferString(`{"id_from":1,"id_to":2,"amount":300}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	e.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,99 @@
+package v2
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"user-balance-service/internal/controller/http/rbac"
+	"user-balance-service/internal/service"
+)
+
+type twoFactorRoutes struct {
+	tf service.TwoFactor
+}
+
+// newTwoFactorRoutes registers enrolment and management of the second
+// factor of the signed-in user. Turning it off and regenerating recovery
+// codes take a code in the X-2FA-Code header.
+func newTwoFactorRoutes(g *echo.Group, tf service.TwoFactor) {
+	r := &twoFactorRoutes{tf}
+
+	g.GET("", r.getStatus)
+	g.POST("", r.enroll)
+	g.POST("/confirm", r.confirm)
+	g.DELETE("", r.disable)
+	g.POST("/recovery-codes", r.regenerateRecoveryCodes)
+}
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type twoFactorStatusResponse struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (r *twoFactorRoutes) getStatus(c echo.Context) error {
+	tf, err := r.tf.TwoFactorStatus(c.Request().Context(), rbac.Actor(c).UserId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, twoFactorStatusResponse{
+		Enabled:           tf.Enabled(),
+		RecoveryCodesLeft: tf.RecoveryCodesLeft,
+	})
+}
+
+// new secret to be added to an authenticator app, enabled by confirm
+func (r *twoFactorRoutes) enroll(c echo.Context) error {
+	enrolment, err := r.tf.EnrollTwoFactor(c.Request().Context(), rbac.Actor(c).UserId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, enrolment)
+}
+
+// the recovery codes are shown only once
+func (r *twoFactorRoutes) confirm(c echo.Context) error {
+	var input twoFactorCodeRequest
+	err := c.Bind(&input)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	codes, err := r.tf.ConfirmTwoFactor(c.Request().Context(), rbac.Actor(c).UserId, input.Code)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (r *twoFactorRoutes) disable(c echo.Context) error {
+	err := r.tf.DisableTwoFactor(c.Request().Context(), rbac.Actor(c).UserId, rbac.TwoFactorCode(c))
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// regeneration invalidates all previous recovery codes
+func (r *twoFactorRoutes) regenerateRecoveryCodes(c echo.Context) error {
+	codes, err := r.tf.RegenerateRecoveryCodes(c.Request().Context(), rbac.Actor(c).UserId, rbac.TwoFactorCode(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
@@ -0,0 +1,188 @@
+package v2
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+func TestTwoFactorRoutes(t *testing.T) {
+	enabledAt := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+	user := entity.Actor{UserId: 1, Role: entity.RoleUser}
+
+	type MockBehaviour func(s *mock_service.MockTwoFactor)
+
+	testCases := []struct {
+		name           string
+		actor          entity.Actor
+		method         string
+		path           string
+		code           string
+		inputBody      string
+		mockBehaviour  MockBehaviour
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:   "Status",
+			actor:  user,
+			method: http.MethodGet,
+			path:   "/api/v2/me/2fa",
+			mockBehaviour: func(s *mock_service.MockTwoFactor) {
+				s.EXPECT().TwoFactorStatus(gomock.Any(), 1).
+					Return(entity.TwoFactor{Username: "qwe", EnabledAt: &enabledAt, RecoveryCodesLeft: 8}, nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"enabled":true,"recovery_codes_left":8}` + "\n",
+		},
+		{
+			name:   "Enroll",
+			actor:  user,
+			method: http.MethodPost,
+			path:   "/api/v2/me/2fa",
+			mockBehaviour: func(s *mock_service.MockTwoFactor) {
+				s.EXPECT().EnrollTwoFactor(gomock.Any(), 1).Return(entity.TwoFactorEnrolment{
+					Secret: "JBSWY3DPEHPK3PXP",
+					URI:    "otpauth://totp/user-balance-service:qwe?secret=JBSWY3DPEHPK3PXP",
+				}, nil)
+			},
+			wantStatusCode: http.StatusCreated,
+			wantBody:       `{"secret":"JBSWY3DPEHPK3PXP","uri":"otpauth://totp/user-balance-service:qwe?secret=JBSWY3DPEHPK3PXP"}` + "\n",
+		},
+		{
+			name:   "Enroll when enabled",
+			actor:  user,
+			method: http.MethodPost,
+			path:   "/api/v2/me/2fa",
+			mockBehaviour: func(s *mock_service.MockTwoFactor) {
+				s.EXPECT().EnrollTwoFactor(gomock.Any(), 1).Return(entity.TwoFactorEnrolment{}, service.ErrTwoFactorEnabled)
+			},
+			wantStatusCode: http.StatusConflict,
+		},
+		{
+			name:           "Enroll with an API key",
+			actor:          entity.Actor{UserId: 1, Role: entity.RoleUser, ApiKeyId: 3},
+			method:         http.MethodPost,
+			path:           "/api/v2/me/2fa",
+			mockBehaviour:  func(s *mock_service.MockTwoFactor) {},
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:      "Confirm",
+			actor:     user,
+			method:    http.MethodPost,
+			path:      "/api/v2/me/2fa/confirm",
+			inputBody: `{"code":"123456"}`,
+			mockBehaviour: func(s *mock_service.MockTwoFactor) {
+				s.EXPECT().ConfirmTwoFactor(gomock.Any(), 1, "123456").Return([]string{"1a2b3-c4d5e"}, nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"recovery_codes":["1a2b3-c4d5e"]}` + "\n",
+		},
+		{
+			name:      "Confirm with a wrong code",
+			actor:     user,
+			method:    http.MethodPost,
+			path:      "/api/v2/me/2fa/confirm",
+			inputBody: `{"code":"000000"}`,
+			mockBehaviour: func(s *mock_service.MockTwoFactor) {
+				s.EXPECT().ConfirmTwoFactor(gomock.Any(), 1, "000000").Return(nil, service.ErrInvalidTwoFactorCode)
+			},
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "Confirm without a code",
+			actor:          user,
+			method:         http.MethodPost,
+			path:           "/api/v2/me/2fa/confirm",
+			inputBody:      `{}`,
+			mockBehaviour:  func(s *mock_service.MockTwoFactor) {},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "Disable",
+			actor:  user,
+			method: http.MethodDelete,
+			path:   "/api/v2/me/2fa",
+			code:   "1a2b3-c4d5e",
+			mockBehaviour: func(s *mock_service.MockTwoFactor) {
+				s.EXPECT().DisableTwoFactor(gomock.Any(), 1, "1a2b3-c4d5e").Return(nil)
+			},
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:   "Regenerate recovery codes",
+			actor:  user,
+			method: http.MethodPost,
+			path:   "/api/v2/me/2fa/recovery-codes",
+			code:   "123456",
+			mockBehaviour: func(s *mock_service.MockTwoFactor) {
+				s.EXPECT().RegenerateRecoveryCodes(gomock.Any(), 1, "123456").Return([]string{"f0f0f-0f0f0"}, nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"recovery_codes":["f0f0f-0f0f0"]}` + "\n",
+		},
+		{
+			name:   "Admin reset",
+			actor:  entity.Actor{UserId: 2, Role: entity.RoleAdmin},
+			method: http.MethodDelete,
+			path:   "/api/v2/admin/users/1/2fa",
+			mockBehaviour: func(s *mock_service.MockTwoFactor) {
+				s.EXPECT().ResetTwoFactor(gomock.Any(), 1).Return(nil)
+			},
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:   "Admin reset of an unknown user",
+			actor:  entity.Actor{UserId: 2, Role: entity.RoleAdmin},
+			method: http.MethodDelete,
+			path:   "/api/v2/admin/users/5/2fa",
+			mockBehaviour: func(s *mock_service.MockTwoFactor) {
+				s.EXPECT().ResetTwoFactor(gomock.Any(), 5).Return(fmt.Errorf("repo: %w", service.ErrUserNotFound))
+			},
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "Reset by a user",
+			actor:          user,
+			method:         http.MethodDelete,
+			path:           "/api/v2/admin/users/1/2fa",
+			mockBehaviour:  func(s *mock_service.MockTwoFactor) {},
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			twoFactor := mock_service.NewMockTwoFactor(ctrl)
+			tc.mockBehaviour(twoFactor)
+
+			e := newTestServerAs(tc.actor, &service.Service{TwoFactor: twoFactor})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.inputBody))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.code != "" {
+				req.Header.Set("X-2FA-Code", tc.code)
+			}
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if len(tc.wantBody) != 0 {
+				assert.Equal(t, tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
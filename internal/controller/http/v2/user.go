@@ -0,0 +1,123 @@
+package v2
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+	"user-balance-service/internal/controller/http/rbac"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+)
+
+type userRoutes struct {
+	a service.Auth
+	u service.Users
+}
+
+// newUserRoutes registers the routes of the signed-in user. Changing the
+// credentials or deactivating the user takes a token, not an API key.
+func newUserRoutes(g *echo.Group, a service.Auth, u service.Users) {
+	r := &userRoutes{a, u}
+
+	g.GET("", r.getProfile)
+	g.PUT("/password", r.changePassword, rbac.DenyApiKeys)
+	g.PUT("/username", r.changeUsername, rbac.DenyApiKeys)
+	g.DELETE("", r.deactivate, rbac.DenyApiKeys)
+}
+
+type userResponse struct {
+	Id         int         `json:"id"`
+	Username   string      `json:"username"`
+	Role       entity.Role `json:"role"`
+	TwoFactor  bool        `json:"two_factor_enabled"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	DisabledAt *time.Time  `json:"disabled_at,omitempty"`
+}
+
+func newUserResponse(user entity.User) userResponse {
+	return userResponse{
+		Id:         user.Id,
+		Username:   user.Username,
+		Role:       user.Role,
+		TwoFactor:  user.TwoFactorEnabled(),
+		CreatedAt:  user.CreatedAt,
+		UpdatedAt:  user.UpdatedAt,
+		DisabledAt: user.DisabledAt,
+	}
+}
+
+type passwordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6,max=72"`
+}
+
+type usernameRequest struct {
+	Username string `json:"username" validate:"required,min=3,max=64"`
+}
+
+func (r *userRoutes) getProfile(c echo.Context) error {
+	user, err := r.u.Profile(c.Request().Context(), rbac.Actor(c).UserId)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// changing the password signs the user out everywhere, including the
+// session that changed it
+func (r *userRoutes) changePassword(c echo.Context) error {
+	var input passwordRequest
+	err := c.Bind(&input)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	err = r.a.ChangePassword(c.Request().Context(), rbac.Actor(c).UserId, input.CurrentPassword, input.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (r *userRoutes) changeUsername(c echo.Context) error {
+	var input usernameRequest
+	err := c.Bind(&input)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(&input)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	id := rbac.Actor(c).UserId
+
+	err = r.u.ChangeUsername(ctx, id, input.Username)
+	if err != nil {
+		return err
+	}
+
+	user, err := r.u.Profile(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+func (r *userRoutes) deactivate(c echo.Context) error {
+	err := r.u.Deactivate(c.Request().Context(), rbac.Actor(c).UserId)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
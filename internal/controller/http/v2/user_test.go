@@ -0,0 +1,145 @@
+package v2
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+func TestUserRoutes(t *testing.T) {
+	createdAt := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+	profile := entity.User{Id: 1, Username: "qwe", Role: entity.RoleUser, CreatedAt: createdAt, UpdatedAt: createdAt}
+
+	type MockBehaviour func(a *mock_service.MockAuth, u *mock_service.MockUsers)
+
+	testCases := []struct {
+		name           string
+		actor          entity.Actor
+		method         string
+		path           string
+		inputBody      string
+		mockBehaviour  MockBehaviour
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:   "Profile",
+			actor:  entity.Actor{UserId: 1, Role: entity.RoleUser},
+			method: http.MethodGet,
+			path:   "/api/v2/me",
+			mockBehaviour: func(a *mock_service.MockAuth, u *mock_service.MockUsers) {
+				u.EXPECT().Profile(gomock.Any(), 1).Return(profile, nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"id":1,"username":"qwe","role":"user","two_factor_enabled":false,"created_at":"2022-10-20T12:00:00Z","updated_at":"2022-10-20T12:00:00Z"}` + "\n",
+		},
+		{
+			name:      "Change password",
+			actor:     entity.Actor{UserId: 1, Role: entity.RoleUser},
+			method:    http.MethodPut,
+			path:      "/api/v2/me/password",
+			inputBody: `{"current_password":"qwerty123","new_password":"qwerty456"}`,
+			mockBehaviour: func(a *mock_service.MockAuth, u *mock_service.MockUsers) {
+				a.EXPECT().ChangePassword(gomock.Any(), 1, "qwerty123", "qwerty456").Return(nil)
+			},
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:      "Wrong current password",
+			actor:     entity.Actor{UserId: 1, Role: entity.RoleUser},
+			method:    http.MethodPut,
+			path:      "/api/v2/me/password",
+			inputBody: `{"current_password":"qwerty124","new_password":"qwerty456"}`,
+			mockBehaviour: func(a *mock_service.MockAuth, u *mock_service.MockUsers) {
+				a.EXPECT().ChangePassword(gomock.Any(), 1, "qwerty124", "qwerty456").Return(service.ErrInvalidPassword)
+			},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Too short password",
+			actor:          entity.Actor{UserId: 1, Role: entity.RoleUser},
+			method:         http.MethodPut,
+			path:           "/api/v2/me/password",
+			inputBody:      `{"current_password":"qwerty123","new_password":"qwe"}`,
+			mockBehaviour:  func(a *mock_service.MockAuth, u *mock_service.MockUsers) {},
+			wantStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Password can't be changed with an API key",
+			actor:          entity.Actor{UserId: 1, Role: entity.RoleUser, ApiKeyId: 3},
+			method:         http.MethodPut,
+			path:           "/api/v2/me/password",
+			inputBody:      `{"current_password":"qwerty123","new_password":"qwerty456"}`,
+			mockBehaviour:  func(a *mock_service.MockAuth, u *mock_service.MockUsers) {},
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:      "Change username",
+			actor:     entity.Actor{UserId: 1, Role: entity.RoleUser},
+			method:    http.MethodPut,
+			path:      "/api/v2/me/username",
+			inputBody: `{"username":"asd"}`,
+			mockBehaviour: func(a *mock_service.MockAuth, u *mock_service.MockUsers) {
+				u.EXPECT().ChangeUsername(gomock.Any(), 1, "asd").Return(nil)
+				renamed := profile
+				renamed.Username = "asd"
+				u.EXPECT().Profile(gomock.Any(), 1).Return(renamed, nil)
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"id":1,"username":"asd","role":"user","two_factor_enabled":false,"created_at":"2022-10-20T12:00:00Z","updated_at":"2022-10-20T12:00:00Z"}` + "\n",
+		},
+		{
+			name:      "Username taken",
+			actor:     entity.Actor{UserId: 1, Role: entity.RoleUser},
+			method:    http.MethodPut,
+			path:      "/api/v2/me/username",
+			inputBody: `{"username":"asd"}`,
+			mockBehaviour: func(a *mock_service.MockAuth, u *mock_service.MockUsers) {
+				u.EXPECT().ChangeUsername(gomock.Any(), 1, "asd").Return(fmt.Errorf("repo: %w", service.ErrUserAlreadyExists))
+			},
+			wantStatusCode: http.StatusConflict,
+		},
+		{
+			name:   "Deactivate",
+			actor:  entity.Actor{UserId: 1, Role: entity.RoleUser},
+			method: http.MethodDelete,
+			path:   "/api/v2/me",
+			mockBehaviour: func(a *mock_service.MockAuth, u *mock_service.MockUsers) {
+				u.EXPECT().Deactivate(gomock.Any(), 1).Return(nil)
+			},
+			wantStatusCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auth := mock_service.NewMockAuth(ctrl)
+			users := mock_service.NewMockUsers(ctrl)
+			tc.mockBehaviour(auth, users)
+
+			e := newTestServerAs(tc.actor, &service.Service{Auth: auth, Users: users})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.inputBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if len(tc.wantBody) != 0 {
+				assert.Equal(t, tc.wantBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Package validation implements echo.Validator on top of go-playground/validator.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+)
+
+// FieldError describes a single rule violated by a request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error is returned by Validate when a payload violates its rules
+type Error struct {
+	Fields []FieldError
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, fmt.Sprintf("%s %s", f.Field, f.Message))
+	}
+
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+type Validator struct {
+	validate *validator.Validate
+}
+
+func New() *Validator {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(fieldName)
+
+	return &Validator{validate: validate}
+}
+
+// fieldName reports fields the way clients send them
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "query", "param", "form"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			continue
+		}
+		if len(name) != 0 {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func (v *Validator) Validate(i interface{}) error {
+	err := v.validate.Struct(i)
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	t := reflect.TypeOf(i)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	fields := make([]FieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		fields = append(fields, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: message(fe, t),
+		})
+	}
+
+	return &Error{Fields: fields}
+}
+
+// message explains fe to the client; t is the validated struct type used to
+// resolve field names referenced by cross-field rules
+func message(fe validator.FieldError, t reflect.Type) string {
+	isString := fe.Kind() == reflect.String
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "lte":
+		return "must be less than or equal to " + fe.Param()
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "nefield":
+		other := fe.Param()
+		if field, ok := t.FieldByName(other); ok {
+			other = fieldName(field)
+		}
+		return "must differ from " + other
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "iso4217":
+		return "must be an ISO 4217 currency code"
+	case "datetime":
+		return "must be a date in format " + fe.Param()
+	default:
+		return fmt.Sprintf("failed on the '%s' rule", fe.Tag())
+	}
+}
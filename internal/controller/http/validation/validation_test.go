@@ -0,0 +1,65 @@
+package validation
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type transferRequest struct {
+	IdFrom int    `json:"id_from" validate:"required,gt=0"`
+	IdTo   int    `json:"id_to" validate:"required,gt=0,nefield=IdFrom"`
+	Amount int    `json:"amount" validate:"gt=0"`
+	Sort   string `query:"sort" validate:"omitempty,oneof=date amount"`
+}
+
+func TestValidator_Validate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		input      transferRequest
+		wantFields []FieldError
+	}{
+		{
+			name:  "OK",
+			input: transferRequest{IdFrom: 1, IdTo: 2, Amount: 100, Sort: "date"},
+		},
+		{
+			name:  "Negative amount",
+			input: transferRequest{IdFrom: 1, IdTo: 2, Amount: -100},
+			wantFields: []FieldError{
+				{Field: "amount", Rule: "gt", Message: "must be greater than 0"},
+			},
+		},
+		{
+			name:  "Same accounts and unknown sort",
+			input: transferRequest{IdFrom: 1, IdTo: 1, Amount: 100, Sort: "id"},
+			wantFields: []FieldError{
+				{Field: "id_to", Rule: "nefield", Message: "must differ from id_from"},
+				{Field: "sort", Rule: "oneof", Message: "must be one of: date, amount"},
+			},
+		},
+		{
+			name:  "Missing ids",
+			input: transferRequest{Amount: 100},
+			wantFields: []FieldError{
+				{Field: "id_from", Rule: "required", Message: "is required"},
+				{Field: "id_to", Rule: "required", Message: "is required"},
+			},
+		},
+	}
+
+	v := New()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := v.Validate(tc.input)
+			if tc.wantFields == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var validationErr *Error
+			assert.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tc.wantFields, validationErr.Fields)
+		})
+	}
+}
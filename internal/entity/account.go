@@ -1,6 +1,11 @@
 package entity
 
+// Account is owned by the user UserId; accounts created before owners were
+// recorded have UserId 0. A Frozen account can't be debited.
 type Account struct {
-	Id      int `json:"id" db:"id" binding:"required"`
-	Balance int `json:"balance" db:"balance" binding:"required"`
+	Id      int  `json:"id" db:"id" binding:"required"`
+	Balance int  `json:"balance" db:"balance" binding:"required"`
+	Version int  `json:"version" db:"version"`
+	UserId  int  `json:"user_id,omitempty" db:"user_id"`
+	Frozen  bool `json:"frozen,omitempty" db:"frozen"`
 }
@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// ApiKey lets a service call the API as the user who created it, limited to
+// Scopes. Only the SHA-256 of the key is stored, Prefix tells keys apart in
+// listings. Role is the current role of the owner.
+type ApiKey struct {
+	Id         int          `json:"id"`
+	UserId     int          `json:"-"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	Scopes     []Permission `json:"scopes"`
+	CreatedAt  time.Time    `json:"created_at"`
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	Hash       string       `json:"-"`
+	Role       Role         `json:"-"`
+}
@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditOutcome of a mutating API call
+type AuditOutcome string
+
+const (
+	AuditSucceeded AuditOutcome = "succeeded"
+	AuditFailed    AuditOutcome = "failed"
+)
+
+// AuditRecord is a row of the audit log: a mutating API call, who made it
+// and how it ended. ApiKeyId is 0 for calls made with an access token.
+// Operation is the method and the route, e.g. "POST /api/v2/transfers",
+// Params the path, query and body parameters without secrets. Error is the
+// problem code of a failed call.
+type AuditRecord struct {
+	Id         int64           `json:"id"`
+	UserId     int             `json:"user_id,omitempty"`
+	ApiKeyId   int             `json:"api_key_id,omitempty"`
+	Role       Role            `json:"role,omitempty"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	RequestId  string          `json:"request_id"`
+	Operation  string          `json:"operation"`
+	AccountIds []int           `json:"account_ids"`
+	Params     json.RawMessage `json:"params"`
+	Status     int             `json:"status"`
+	Outcome    AuditOutcome    `json:"outcome"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditFilter selects audit records. Zero fields don't filter; From is
+// inclusive and To exclusive. Records come newest first, BeforeId is the
+// last id of the previous page.
+type AuditFilter struct {
+	UserId    int
+	ApiKeyId  int
+	AccountId int
+	From      time.Time
+	To        time.Time
+	BeforeId  int64
+	Limit     int
+}
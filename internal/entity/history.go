@@ -5,6 +5,14 @@ import (
 	"time"
 )
 
+// History operation types
+const (
+	RefillType        = "пополнение счёта"
+	WriteOffType      = "снятие со счёта"
+	InnerTransferType = "иcходящий перевод"
+	OuterTransferType = "входящий перевод"
+)
+
 type History struct {
 	Id          int        `json:"id" db:"id"`
 	Type        string     `json:"type" db:"type"`
@@ -14,6 +22,15 @@ type History struct {
 	Date        CustomTime `json:"date" db:"date"`
 }
 
+// ConvertedHistory is a history record with its amount in rubles converted to
+// Currency at the Rate of the record's date
+type ConvertedHistory struct {
+	History
+	Currency        string  `json:"currency"`
+	Rate            float64 `json:"rate"`
+	ConvertedAmount float64 `json:"converted_amount"`
+}
+
 type CustomTime time.Time
 
 const customTimeFormat = `"2006-01-02"`
@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// Client is who a request comes from
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// LoginResult of a sign-in attempt
+type LoginResult string
+
+const (
+	LoginSucceeded LoginResult = "succeeded"
+	LoginFailed    LoginResult = "failed"
+	LoginLocked    LoginResult = "locked"
+	// LoginChallenged is a correct password of a user with 2FA enabled
+	LoginChallenged LoginResult = "challenged"
+)
+
+// LoginAttempt is a row of the login audit. UserId is 0 when the username
+// doesn't exist or the attempt was rejected before looking it up.
+type LoginAttempt struct {
+	Id        int64       `json:"id"`
+	Username  string      `json:"username"`
+	UserId    int         `json:"user_id,omitempty"`
+	IP        string      `json:"ip"`
+	UserAgent string      `json:"user_agent"`
+	Result    LoginResult `json:"result"`
+	CreatedAt time.Time   `json:"created_at"`
+}
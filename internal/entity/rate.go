@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// Rate is the price of one unit of Currency in rubles
+type Rate struct {
+	Currency  string    `json:"currency"`
+	Rate      float64   `json:"rate"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Conversion is an amount in rubles converted to Currency with Rate
+type Conversion struct {
+	Amount        float64   `json:"amount"`
+	Currency      string    `json:"currency"`
+	Rate          float64   `json:"rate"`
+	RateFetchedAt time.Time `json:"rate_fetched_at"`
+}
+
+// DailyRate is the rate of Currency that applied on Date
+type DailyRate struct {
+	Currency string     `json:"currency" db:"currency"`
+	Date     CustomTime `json:"date" db:"date"`
+	Rate     float64    `json:"rate" db:"rate"`
+}
@@ -0,0 +1,94 @@
+package entity
+
+// Role of a user, stored in users and in the access token claims
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleOperator Role = "operator"
+	RoleUser     Role = "user"
+)
+
+// Permission lets a role act on accounts it doesn't own. Owners can do
+// everything with their accounts except freezing them, API keys only what
+// their scopes grant.
+type Permission string
+
+const (
+	PermReadAllHistory Permission = "history:read_all"
+	PermReadAccounts   Permission = "accounts:read_any"
+	PermManageAccounts Permission = "accounts:manage"
+	PermRefund         Permission = "accounts:refund"
+	PermFreeze         Permission = "accounts:freeze"
+	PermManageRoles    Permission = "roles:manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermReadAllHistory,
+		PermReadAccounts,
+		PermManageAccounts,
+		PermRefund,
+		PermFreeze,
+		PermManageRoles,
+	},
+	RoleOperator: {
+		PermReadAccounts,
+		PermRefund,
+		PermFreeze,
+	},
+	RoleUser: {},
+}
+
+// Valid reports whether r is one of the known roles
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+func (r Role) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Valid reports whether p is one of the known permissions, all of which
+// are granted to admins
+func (p Permission) Valid() bool {
+	return RoleAdmin.Can(p)
+}
+
+// Permissions returns what r is granted
+func (r Role) Permissions() []Permission {
+	return append([]Permission{}, rolePermissions[r]...)
+}
+
+// Actor is who a request is made by. Requests made with an API key carry
+// its id and are granted only the permissions in its scopes.
+type Actor struct {
+	UserId   int
+	Role     Role
+	ApiKeyId int
+	Scopes   []Permission
+}
+
+func (a Actor) Can(p Permission) bool {
+	if !a.Role.Can(p) {
+		return false
+	}
+	if a.ApiKeyId == 0 {
+		return true
+	}
+
+	for _, scope := range a.Scopes {
+		if scope == p {
+			return true
+		}
+	}
+
+	return false
+}
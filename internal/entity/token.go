@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// Tokens is issued on sign-in and on refresh. ExpiresIn is the lifetime of
+// AccessToken in seconds.
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// RefreshToken is the server-side record of a refresh token. Id is the
+// SHA-256 of the token, the token itself is never stored. Tokens rotated from
+// one sign-in share FamilyId; a used token has RevokedAt set.
+type RefreshToken struct {
+	Id        string
+	UserId    int
+	FamilyId  string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
@@ -0,0 +1,33 @@
+package entity
+
+import "time"
+
+// TwoFactor is the TOTP state of a user. Secret is set on enrolment and
+// EnabledAt once a code made with it has been confirmed. LastStep is the
+// time step of the last code used, older codes are rejected.
+type TwoFactor struct {
+	Username          string
+	Secret            string
+	EnabledAt         *time.Time
+	LastStep          int64
+	RecoveryCodesLeft int
+}
+
+func (t TwoFactor) Enabled() bool {
+	return t.EnabledAt != nil
+}
+
+// TwoFactorEnrolment is shown once: URI is what an authenticator app reads
+// from a QR code, Secret is for typing in by hand
+type TwoFactorEnrolment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// SignInChallenge is a sign-in with a correct password waiting for the
+// second factor. Attempts counts the codes tried against it.
+type SignInChallenge struct {
+	UserId   int
+	Username string
+	Attempts int64
+}
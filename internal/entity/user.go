@@ -1,8 +1,35 @@
 package entity
 
+import "time"
+
 // User - структура для заполнения данных о пользователе
 type User struct {
-	Id       int    `json:"-" db:"id"`
-	Username string `json:"username" db:"username" validate:"required"`
-	Password string `json:"password" db:"password" validate:"required"`
+	Id         int        `json:"-" db:"id"`
+	Username   string     `json:"username" db:"username" validate:"required,min=3,max=64"`
+	Password   string     `json:"password" db:"password" validate:"required,min=6,max=72"`
+	Role       Role       `json:"-" db:"role"`
+	CreatedAt  time.Time  `json:"-" db:"created_at"`
+	UpdatedAt  time.Time  `json:"-" db:"updated_at"`
+	DisabledAt *time.Time `json:"-" db:"disabled_at"`
+	// TwoFactorAt is when the user enabled TOTP, nil if they haven't
+	TwoFactorAt *time.Time `json:"-" db:"totp_enabled_at"`
+}
+
+// Disabled users can't sign in, and their tokens and API keys stop working
+func (u User) Disabled() bool {
+	return u.DisabledAt != nil
+}
+
+func (u User) TwoFactorEnabled() bool {
+	return u.TwoFactorAt != nil
+}
+
+// UserFilter selects users for listing. Query matches a part of the
+// username, Disabled, if set, picks disabled or active users only. Users
+// come ordered by id, AfterId is the last id of the previous page.
+type UserFilter struct {
+	Query    string
+	Disabled *bool
+	AfterId  int
+	Limit    int
 }
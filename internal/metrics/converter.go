@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"net/http"
+	"time"
+	"user-balance-service/internal/service/webapi"
+)
+
+// Doer measures the calls to an exchange rate provider made through next. A
+// call is an error when it fails or gets a 4xx or 5xx status.
+func (m *Metrics) Doer(provider string, next webapi.Doer) webapi.Doer {
+	return &doer{
+		next:     next,
+		duration: m.converterDuration.WithLabelValues(provider),
+		errors:   m.converterErrors.WithLabelValues(provider),
+	}
+}
+
+type doer struct {
+	next     webapi.Doer
+	duration prometheus.Observer
+	errors   prometheus.Counter
+}
+
+func (d *doer) Do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := d.next.Do(req)
+	d.duration.Observe(time.Since(start).Seconds())
+
+	if err != nil || res.StatusCode >= http.StatusBadRequest {
+		d.errors.Inc()
+	}
+
+	return res, err
+}
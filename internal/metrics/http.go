@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"strconv"
+	"time"
+	"user-balance-service/internal/controller/http/problem"
+)
+
+// unmatchedRoute labels requests that matched no route, so unknown paths
+// don't make new series
+const unmatchedRoute = "unmatched"
+
+// Middleware measures requests by route template, e.g. /api/v2/accounts/:id.
+// A handler error is labelled with the status it'll be answered with.
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				status = problem.New(err).Status
+			}
+
+			route := c.Path()
+			if errors.Is(err, echo.ErrNotFound) {
+				// echo gives the request path as is when no route matched
+				route = unmatchedRoute
+			}
+
+			m.httpDuration.
+				WithLabelValues(c.Request().Method, route, strconv.Itoa(status)).
+				Observe(time.Since(start).Seconds())
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Package metrics collects the service metrics for Prometheus: HTTP request
+// latency, the pgx pool, the cache, exchange rate providers and money
+// operations.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+const namespace = "balance"
+
+// Metrics implements cache.Metrics and service.AccountMetrics, so it can be
+// given straight to the cache and the account service.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpDuration      *prometheus.HistogramVec
+	cacheLookups      *prometheus.CounterVec
+	cacheErrors       *prometheus.CounterVec
+	converterDuration *prometheus.HistogramVec
+	converterErrors   *prometheus.CounterVec
+	operations        *prometheus.CounterVec
+	operationAmount   *prometheus.CounterVec
+	insufficientFunds *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "lookups_total",
+			Help:      "Cache lookups by key family and result (hit or miss).",
+		}, []string{"family", "result"}),
+		cacheErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "errors_total",
+			Help:      "Failed or skipped cache calls by operation.",
+		}, []string{"operation"}),
+		converterDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "converter",
+			Name:      "request_duration_seconds",
+			Help:      "Exchange rate provider call latency, retries included.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"provider"}),
+		converterErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "converter",
+			Name:      "errors_total",
+			Help:      "Exchange rate provider calls that failed or got an error status.",
+		}, []string{"provider"}),
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "account",
+			Name:      "operations_total",
+			Help:      "Successful deposits, write-offs and transfers.",
+		}, []string{"operation"}),
+		operationAmount: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "account",
+			Name:      "operation_amount_total",
+			Help:      "Money moved by successful deposits, write-offs and transfers.",
+		}, []string{"operation"}),
+		insufficientFunds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "account",
+			Name:      "insufficient_funds_total",
+			Help:      "Write-offs and transfers rejected for insufficient funds.",
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpDuration,
+		m.cacheLookups,
+		m.cacheErrors,
+		m.converterDuration,
+		m.converterErrors,
+		m.operations,
+		m.operationAmount,
+		m.insufficientFunds,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) Hit(family string) {
+	m.cacheLookups.WithLabelValues(family, "hit").Inc()
+}
+
+func (m *Metrics) Miss(family string) {
+	m.cacheLookups.WithLabelValues(family, "miss").Inc()
+}
+
+func (m *Metrics) Error(op string) {
+	m.cacheErrors.WithLabelValues(op).Inc()
+}
+
+func (m *Metrics) Operation(operation string, amount int) {
+	m.operations.WithLabelValues(operation).Inc()
+	m.operationAmount.WithLabelValues(operation).Add(float64(amount))
+}
+
+func (m *Metrics) InsufficientFunds(operation string) {
+	m.insufficientFunds.WithLabelValues(operation).Inc()
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Pool is implemented by *pgxpool.Pool
+type Pool interface {
+	Stat() *pgxpool.Stat
+}
+
+// ObservePool exports the pool statistics, read on every scrape
+func (m *Metrics) ObservePool(pool Pool) {
+	m.registry.MustRegister(newPoolCollector(pool))
+}
+
+type poolCollector struct {
+	pool Pool
+
+	acquiredConns     *prometheus.Desc
+	idleConns         *prometheus.Desc
+	constructingConns *prometheus.Desc
+	totalConns        *prometheus.Desc
+	maxConns          *prometheus.Desc
+	acquires          *prometheus.Desc
+	emptyAcquires     *prometheus.Desc
+	canceledAcquires  *prometheus.Desc
+	acquireDuration   *prometheus.Desc
+}
+
+func newPoolCollector(pool Pool) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pgx_pool", name), help, nil, nil)
+	}
+
+	return &poolCollector{
+		pool:              pool,
+		acquiredConns:     desc("acquired_conns", "Connections currently in use."),
+		idleConns:         desc("idle_conns", "Idle connections."),
+		constructingConns: desc("constructing_conns", "Connections being opened."),
+		totalConns:        desc("total_conns", "All connections of the pool."),
+		maxConns:          desc("max_conns", "Largest size of the pool."),
+		acquires:          desc("acquires_total", "Connections acquired from the pool."),
+		emptyAcquires:     desc("empty_acquires_total", "Acquires that had to wait for a connection."),
+		canceledAcquires:  desc("canceled_acquires_total", "Acquires cancelled by the context."),
+		acquireDuration:   desc("acquire_duration_seconds_total", "Time spent acquiring connections."),
+	}
+}
+
+func (p *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(p, ch)
+}
+
+func (p *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := p.pool.Stat()
+
+	gauge := func(desc *prometheus.Desc, value int32) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value))
+	}
+	counter := func(desc *prometheus.Desc, value int64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value))
+	}
+
+	gauge(p.acquiredConns, stat.AcquiredConns())
+	gauge(p.idleConns, stat.IdleConns())
+	gauge(p.constructingConns, stat.ConstructingConns())
+	gauge(p.totalConns, stat.TotalConns())
+	gauge(p.maxConns, stat.MaxConns())
+	counter(p.acquires, stat.AcquireCount())
+	counter(p.emptyAcquires, stat.EmptyAcquireCount())
+	counter(p.canceledAcquires, stat.CanceledAcquireCount())
+	ch <- prometheus.MustNewConstMetric(p.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
@@ -2,45 +2,181 @@ package service
 
 import (
 	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"user-balance-service/internal/entity"
+	"user-balance-service/pkg/tracing"
 )
 
+// Operations reported to AccountMetrics
+const (
+	OperationDeposit  = "deposit"
+	OperationWriteOff = "write_off"
+	OperationTransfer = "transfer"
+)
+
+type AccountOption func(s *AccountService)
+
+// ObserveOperations reports deposits, write-offs and transfers to metrics
+func ObserveOperations(metrics AccountMetrics) AccountOption {
+	return func(s *AccountService) {
+		s.metrics = metrics
+	}
+}
+
 type AccountService struct {
-	repo AccountRepo
-	wapi ConverterWEBAPI
+	repo    AccountRepo
+	rates   Rates
+	metrics AccountMetrics
 }
 
-func NewAccountService(repo AccountRepo, wapi ConverterWEBAPI) *AccountService {
-	return &AccountService{
-		repo: repo,
-		wapi: wapi,
+func NewAccountService(repo AccountRepo, rates Rates, opts ...AccountOption) *AccountService {
+	s := &AccountService{
+		repo:    repo,
+		rates:   rates,
+		metrics: nopAccountMetrics{},
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *AccountService) CreateAccount(ctx context.Context, userId int) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.CreateAccount", trace.WithAttributes(attribute.Int("user.id", userId)))
+	defer tracing.End(span, &err)
+
+	return s.repo.CreateAccount(ctx, userId)
+}
+
+// CheckAccess lets owners at their accounts; anyone else needs perm. API keys
+// always need perm in their scopes, owning the account grants them nothing.
+// The account is only looked up for actors without perm.
+func (s *AccountService) CheckAccess(ctx context.Context, actor entity.Actor, id int, perm entity.Permission) (err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.CheckAccess", trace.WithAttributes(attribute.Int("account.id", id)))
+	defer tracing.End(span, &err)
+
+	if actor.Can(perm) {
+		return nil
+	}
+	if actor.ApiKeyId != 0 {
+		return ErrForbidden
+	}
+
+	account, err := s.repo.GetAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	if account.UserId == 0 || account.UserId != actor.UserId {
+		return ErrForbidden
+	}
+
+	return nil
 }
 
-func (s *AccountService) CreateAccount(ctx context.Context) (int, error) {
-	return s.repo.CreateAccount(ctx)
+func (s *AccountService) SetFrozen(ctx context.Context, id int, frozen bool) (err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.SetFrozen", trace.WithAttributes(attribute.Int("account.id", id)))
+	defer tracing.End(span, &err)
+
+	return s.repo.SetFrozen(ctx, id, frozen)
 }
 
-func (s *AccountService) DeleteAccount(ctx context.Context, id int) error {
-	return s.repo.DeleteAccount(ctx, id)
+func (s *AccountService) DeleteAccount(ctx context.Context, id, version int) (err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.DeleteAccount", trace.WithAttributes(attribute.Int("account.id", id)))
+	defer tracing.End(span, &err)
+
+	return s.repo.DeleteAccount(ctx, id, version)
 }
 
-func (s *AccountService) WriteOff(ctx context.Context, id, amount int) error {
-	return s.repo.WriteOff(ctx, id, amount)
+func (s *AccountService) WriteOff(ctx context.Context, id, amount, version int) (err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.WriteOff", trace.WithAttributes(
+		attribute.Int("account.id", id),
+		attribute.Int("amount", amount),
+	))
+	defer tracing.End(span, &err)
+
+	// a negative write-off would act as a deposit
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+
+	err = s.repo.WriteOff(ctx, id, amount, version)
+	s.observe(OperationWriteOff, amount, err)
+
+	return err
 }
 
-func (s *AccountService) GetAccount(ctx context.Context, id int) (entity.Account, error) {
+func (s *AccountService) GetAccount(ctx context.Context, id int) (_ entity.Account, err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.GetAccount", trace.WithAttributes(attribute.Int("account.id", id)))
+	defer tracing.End(span, &err)
+
 	return s.repo.GetAccount(ctx, id)
 }
 
-func (s *AccountService) MakeDeposit(ctx context.Context, id, amount int) error {
-	return s.repo.MakeDeposit(ctx, id, amount)
+func (s *AccountService) MakeDeposit(ctx context.Context, id, amount, version int) (err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.MakeDeposit", trace.WithAttributes(
+		attribute.Int("account.id", id),
+		attribute.Int("amount", amount),
+	))
+	defer tracing.End(span, &err)
+
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+
+	err = s.repo.MakeDeposit(ctx, id, amount, version)
+	s.observe(OperationDeposit, amount, err)
+
+	return err
 }
 
-func (s *AccountService) TransferMoney(ctx context.Context, idFrom, idTo, amount int) error {
-	return s.repo.TransferMoney(ctx, idFrom, idTo, amount)
+func (s *AccountService) TransferMoney(ctx context.Context, idFrom, idTo, amount, version int) (err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.TransferMoney", trace.WithAttributes(
+		attribute.Int("account.id_from", idFrom),
+		attribute.Int("account.id_to", idTo),
+		attribute.Int("amount", amount),
+	))
+	defer tracing.End(span, &err)
+
+	err = s.repo.TransferMoney(ctx, idFrom, idTo, amount, version)
+	s.observe(OperationTransfer, amount, err)
+
+	return err
+}
+
+// observe reports the outcome of a money operation, other failures aren't
+// counted here
+func (s *AccountService) observe(operation string, amount int, err error) {
+	switch {
+	case err == nil:
+		s.metrics.Operation(operation, amount)
+	case errors.Is(err, ErrInsufficientFunds):
+		s.metrics.InsufficientFunds(operation)
+	}
 }
 
-func (s *AccountService) ConvertToCurrency(ctx context.Context, currencyTo string, amount float64) (float64, error) {
-	return s.wapi.ConvertToCurrency(ctx, currencyTo, amount)
+type nopAccountMetrics struct{}
+
+func (nopAccountMetrics) Operation(operation string, amount int) {}
+func (nopAccountMetrics) InsufficientFunds(operation string)     {}
+
+// ConvertToCurrency converts amount in rubles with the stored rate of currencyTo
+func (s *AccountService) ConvertToCurrency(ctx context.Context, currencyTo string, amount float64) (_ entity.Conversion, err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.ConvertToCurrency", trace.WithAttributes(attribute.String("currency", currencyTo)))
+	defer tracing.End(span, &err)
+
+	rate, err := s.rates.Rate(ctx, currencyTo)
+	if err != nil {
+		return entity.Conversion{}, err
+	}
+
+	return entity.Conversion{
+		Amount:        amount / rate.Rate,
+		Currency:      rate.Currency,
+		Rate:          rate.Rate,
+		RateFetchedAt: rate.FetchedAt,
+	}, nil
 }
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+	"user-balance-service/internal/entity"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+func TestAccountService_CheckAccess(t *testing.T) {
+	type MockBehaviour func(r *mock_service.MockAccountRepo)
+
+	testCases := []struct {
+		name          string
+		actor         entity.Actor
+		perm          entity.Permission
+		mockBehaviour MockBehaviour
+		wantErr       error
+	}{
+		{
+			name:  "owner",
+			actor: entity.Actor{UserId: 1, Role: entity.RoleUser},
+			perm:  entity.PermManageAccounts,
+			mockBehaviour: func(r *mock_service.MockAccountRepo) {
+				r.EXPECT().GetAccount(gomock.Any(), 5).Return(entity.Account{Id: 5, UserId: 1}, nil)
+			},
+		},
+		{
+			name:  "other user",
+			actor: entity.Actor{UserId: 2, Role: entity.RoleUser},
+			perm:  entity.PermReadAccounts,
+			mockBehaviour: func(r *mock_service.MockAccountRepo) {
+				r.EXPECT().GetAccount(gomock.Any(), 5).Return(entity.Account{Id: 5, UserId: 1}, nil)
+			},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:  "account without owner",
+			actor: entity.Actor{UserId: 1, Role: entity.RoleUser},
+			perm:  entity.PermReadAccounts,
+			mockBehaviour: func(r *mock_service.MockAccountRepo) {
+				r.EXPECT().GetAccount(gomock.Any(), 5).Return(entity.Account{Id: 5}, nil)
+			},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:          "granted by role",
+			actor:         entity.Actor{UserId: 2, Role: entity.RoleOperator},
+			perm:          entity.PermRefund,
+			mockBehaviour: func(r *mock_service.MockAccountRepo) {},
+		},
+		{
+			name:  "not granted by role",
+			actor: entity.Actor{UserId: 2, Role: entity.RoleOperator},
+			perm:  entity.PermManageAccounts,
+			mockBehaviour: func(r *mock_service.MockAccountRepo) {
+				r.EXPECT().GetAccount(gomock.Any(), 5).Return(entity.Account{Id: 5, UserId: 1}, nil)
+			},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:          "owner's key without scopes",
+			actor:         entity.Actor{UserId: 1, Role: entity.RoleUser, ApiKeyId: 3},
+			perm:          entity.PermManageAccounts,
+			mockBehaviour: func(r *mock_service.MockAccountRepo) {},
+			wantErr:       ErrForbidden,
+		},
+		{
+			name:          "key without the scope",
+			actor:         entity.Actor{UserId: 2, Role: entity.RoleOperator, ApiKeyId: 3, Scopes: []entity.Permission{entity.PermReadAccounts}},
+			perm:          entity.PermRefund,
+			mockBehaviour: func(r *mock_service.MockAccountRepo) {},
+			wantErr:       ErrForbidden,
+		},
+		{
+			name:          "key with the scope",
+			actor:         entity.Actor{UserId: 2, Role: entity.RoleOperator, ApiKeyId: 3, Scopes: []entity.Permission{entity.PermRefund}},
+			perm:          entity.PermRefund,
+			mockBehaviour: func(r *mock_service.MockAccountRepo) {},
+		},
+		{
+			name:  "account not found",
+			actor: entity.Actor{UserId: 1, Role: entity.RoleUser},
+			perm:  entity.PermReadAccounts,
+			mockBehaviour: func(r *mock_service.MockAccountRepo) {
+				r.EXPECT().GetAccount(gomock.Any(), 5).Return(entity.Account{}, fmt.Errorf("repo: %w", ErrAccountNotFound))
+			},
+			wantErr: ErrAccountNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock_service.NewMockAccountRepo(ctrl)
+			tc.mockBehaviour(repo)
+
+			s := NewAccountService(repo, nil)
+
+			err := s.CheckAccess(context.Background(), tc.actor, 5, tc.perm)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAccountService_Metrics(t *testing.T) {
+	type MockBehaviour func(r *mock_service.MockAccountRepo, m *mock_service.MockAccountMetrics)
+
+	testCases := []struct {
+		name          string
+		call          func(s *AccountService) error
+		mockBehaviour MockBehaviour
+	}{
+		{
+			name: "deposit",
+			call: func(s *AccountService) error {
+				return s.MakeDeposit(context.Background(), 1, 100, 0)
+			},
+			mockBehaviour: func(r *mock_service.MockAccountRepo, m *mock_service.MockAccountMetrics) {
+				r.EXPECT().MakeDeposit(gomock.Any(), 1, 100, 0).Return(nil)
+				m.EXPECT().Operation(OperationDeposit, 100)
+			},
+		},
+		{
+			name: "write-off",
+			call: func(s *AccountService) error {
+				return s.WriteOff(context.Background(), 1, 100, 0)
+			},
+			mockBehaviour: func(r *mock_service.MockAccountRepo, m *mock_service.MockAccountMetrics) {
+				r.EXPECT().WriteOff(gomock.Any(), 1, 100, 0).Return(nil)
+				m.EXPECT().Operation(OperationWriteOff, 100)
+			},
+		},
+		{
+			name: "write-off without money",
+			call: func(s *AccountService) error {
+				return s.WriteOff(context.Background(), 1, 100, 0)
+			},
+			mockBehaviour: func(r *mock_service.MockAccountRepo, m *mock_service.MockAccountMetrics) {
+				r.EXPECT().WriteOff(gomock.Any(), 1, 100, 0).Return(fmt.Errorf("repo: %w", ErrInsufficientFunds))
+				m.EXPECT().InsufficientFunds(OperationWriteOff)
+			},
+		},
+		{
+			name: "transfer",
+			call: func(s *AccountService) error {
+				return s.TransferMoney(context.Background(), 1, 2, 100, 0)
+			},
+			mockBehaviour: func(r *mock_service.MockAccountRepo, m *mock_service.MockAccountMetrics) {
+				r.EXPECT().TransferMoney(gomock.Any(), 1, 2, 100, 0).Return(nil)
+				m.EXPECT().Operation(OperationTransfer, 100)
+			},
+		},
+		{
+			name: "transfer without money",
+			call: func(s *AccountService) error {
+				return s.TransferMoney(context.Background(), 1, 2, 100, 0)
+			},
+			mockBehaviour: func(r *mock_service.MockAccountRepo, m *mock_service.MockAccountMetrics) {
+				r.EXPECT().TransferMoney(gomock.Any(), 1, 2, 100, 0).Return(fmt.Errorf("repo: %w", ErrInsufficientFunds))
+				m.EXPECT().InsufficientFunds(OperationTransfer)
+			},
+		},
+		{
+			name: "other failures aren't counted",
+			call: func(s *AccountService) error {
+				return s.MakeDeposit(context.Background(), 1, 100, 0)
+			},
+			mockBehaviour: func(r *mock_service.MockAccountRepo, m *mock_service.MockAccountMetrics) {
+				r.EXPECT().MakeDeposit(gomock.Any(), 1, 100, 0).Return(ErrAccountNotFound)
+			},
+		},
+		{
+			name: "invalid amount isn't counted",
+			call: func(s *AccountService) error {
+				return s.MakeDeposit(context.Background(), 1, -1, 0)
+			},
+			mockBehaviour: func(r *mock_service.MockAccountRepo, m *mock_service.MockAccountMetrics) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock_service.NewMockAccountRepo(ctrl)
+			metrics := mock_service.NewMockAccountMetrics(ctrl)
+			tc.mockBehaviour(repo, metrics)
+
+			_ = tc.call(NewAccountService(repo, nil, ObserveOperations(metrics)))
+		})
+	}
+}
+
+func TestAccountService_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	})
+
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockAccountRepo(ctrl)
+	repo.EXPECT().TransferMoney(gomock.Any(), 1, 2, 100, 0).DoAndReturn(func(ctx context.Context, idFrom, idTo, amount, version int) error {
+		// the repository runs within the span of the service
+		assert.True(t, trace.SpanContextFromContext(ctx).IsValid())
+		return fmt.Errorf("repo: %w", ErrInsufficientFunds)
+	})
+
+	err := NewAccountService(repo, nil).TransferMoney(context.Background(), 1, 2, 100, 0)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "AccountService.TransferMoney", spans[0].Name())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Contains(t, spans[0].Attributes(), attribute.Int("amount", 100))
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+	"user-balance-service/internal/entity"
+)
+
+const (
+	// apiKeyPrefix starts every key so that it's recognizable in logs and
+	// secret scanners
+	apiKeyPrefix = "ubs_"
+	// apiKeyPrefixLen is how much of a key is stored in the clear
+	apiKeyPrefixLen = len(apiKeyPrefix) + 8
+
+	// lastUsedInterval limits how often last_used_at of a busy key is written
+	lastUsedInterval = time.Minute
+)
+
+type ApiKeyService struct {
+	repo ApiKeyRepo
+	now  func() time.Time
+}
+
+func NewApiKeyService(repo ApiKeyRepo) *ApiKeyService {
+	return &ApiKeyService{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// CreateApiKey makes a key for actor. A key can't be granted permissions
+// its owner doesn't have, and keys can't create other keys.
+func (s *ApiKeyService) CreateApiKey(ctx context.Context, actor entity.Actor, name string, scopes []entity.Permission) (entity.ApiKey, string, error) {
+	if actor.ApiKeyId != 0 {
+		return entity.ApiKey{}, "", ErrForbidden
+	}
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			return entity.ApiKey{}, "", ErrInvalidScope
+		}
+		if !actor.Can(scope) {
+			return entity.ApiKey{}, "", ErrForbidden
+		}
+	}
+
+	key, err := newApiKey()
+	if err != nil {
+		return entity.ApiKey{}, "", fmt.Errorf("service - ApiKeyService - CreateApiKey - newApiKey: %w", err)
+	}
+
+	if scopes == nil {
+		scopes = []entity.Permission{}
+	}
+	created, err := s.repo.CreateApiKey(ctx, entity.ApiKey{
+		UserId: actor.UserId,
+		Name:   name,
+		Prefix: key[:apiKeyPrefixLen],
+		Scopes: scopes,
+		Hash:   apiKeyHash(key),
+	})
+	if err != nil {
+		return entity.ApiKey{}, "", fmt.Errorf("service - ApiKeyService - CreateApiKey - s.repo.CreateApiKey: %w", err)
+	}
+
+	return created, key, nil
+}
+
+// ListApiKeys returns the active keys of the user
+func (s *ApiKeyService) ListApiKeys(ctx context.Context, userId int) ([]entity.ApiKey, error) {
+	return s.repo.ListApiKeys(ctx, userId)
+}
+
+// RotateApiKey replaces the key with id by a new one with the same name and
+// scopes. The old key stops working at once.
+func (s *ApiKeyService) RotateApiKey(ctx context.Context, userId, id int) (entity.ApiKey, string, error) {
+	key, err := newApiKey()
+	if err != nil {
+		return entity.ApiKey{}, "", fmt.Errorf("service - ApiKeyService - RotateApiKey - newApiKey: %w", err)
+	}
+
+	rotated, err := s.repo.RotateApiKey(ctx, userId, id, key[:apiKeyPrefixLen], apiKeyHash(key))
+	if err != nil {
+		return entity.ApiKey{}, "", fmt.Errorf("service - ApiKeyService - RotateApiKey - s.repo.RotateApiKey: %w", err)
+	}
+
+	return rotated, key, nil
+}
+
+func (s *ApiKeyService) RevokeApiKey(ctx context.Context, userId, id int) error {
+	return s.repo.RevokeApiKey(ctx, userId, id)
+}
+
+// ParseApiKey returns the owner of key acting with the key's scopes. Failing
+// to record the use doesn't fail the request.
+func (s *ApiKeyService) ParseApiKey(ctx context.Context, key string) (entity.Actor, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return entity.Actor{}, ErrInvalidApiKey
+	}
+
+	stored, err := s.repo.GetApiKey(ctx, apiKeyHash(key))
+	if err != nil {
+		return entity.Actor{}, fmt.Errorf("service - ApiKeyService - ParseApiKey - s.repo.GetApiKey: %w", err)
+	}
+
+	now := s.now()
+	if stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) >= lastUsedInterval {
+		err = s.repo.TouchApiKey(ctx, stored.Id, now)
+		if err != nil {
+			log.Errorf("service - ApiKeyService - ParseApiKey - s.repo.TouchApiKey: %s", err)
+		}
+	}
+
+	return entity.Actor{
+		UserId:   stored.UserId,
+		Role:     stored.Role,
+		ApiKeyId: stored.Id,
+		Scopes:   stored.Scopes,
+	}, nil
+}
+
+func newApiKey() (string, error) {
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", err
+	}
+
+	return apiKeyPrefix + secret, nil
+}
+
+// apiKeyHash is what a key is stored and looked up by. Keys are random, so
+// unlike passwords they don't need a slow hash.
+func apiKeyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
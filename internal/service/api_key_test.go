@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+func TestApiKeyService_CreateApiKey(t *testing.T) {
+	testCases := []struct {
+		name    string
+		actor   entity.Actor
+		scopes  []entity.Permission
+		wantErr error
+	}{
+		{
+			name:   "OK",
+			actor:  entity.Actor{UserId: 1, Role: entity.RoleOperator},
+			scopes: []entity.Permission{entity.PermRefund, entity.PermReadAccounts},
+		},
+		{
+			name:  "OK without scopes",
+			actor: entity.Actor{UserId: 1, Role: entity.RoleUser},
+		},
+		{
+			name:    "scope the owner doesn't have",
+			actor:   entity.Actor{UserId: 1, Role: entity.RoleOperator},
+			scopes:  []entity.Permission{entity.PermManageAccounts},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:    "unknown scope",
+			actor:   entity.Actor{UserId: 1, Role: entity.RoleAdmin},
+			scopes:  []entity.Permission{"accounts:everything"},
+			wantErr: ErrInvalidScope,
+		},
+		{
+			name:    "created with another key",
+			actor:   entity.Actor{UserId: 1, Role: entity.RoleAdmin, ApiKeyId: 2},
+			wantErr: ErrForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock_service.NewMockApiKeyRepo(ctrl)
+			s := NewApiKeyService(repo)
+
+			var stored entity.ApiKey
+			if tc.wantErr == nil {
+				repo.EXPECT().CreateApiKey(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, key entity.ApiKey) (entity.ApiKey, error) {
+						stored = key
+						key.Id = 7
+						return key, nil
+					})
+			}
+
+			key, secret, err := s.CreateApiKey(context.Background(), tc.actor, "billing", tc.scopes)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, 7, key.Id)
+			assert.True(t, strings.HasPrefix(secret, apiKeyPrefix))
+			assert.Equal(t, secret[:apiKeyPrefixLen], stored.Prefix)
+			assert.Equal(t, apiKeyHash(secret), stored.Hash)
+			assert.Equal(t, tc.actor.UserId, stored.UserId)
+			assert.NotNil(t, stored.Scopes)
+		})
+	}
+}
+
+func TestApiKeyService_RotateApiKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockApiKeyRepo(ctrl)
+	s := NewApiKeyService(repo)
+
+	var hash string
+	repo.EXPECT().RotateApiKey(gomock.Any(), 1, 7, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, id int, prefix, h string) (entity.ApiKey, error) {
+			hash = h
+			return entity.ApiKey{Id: id, Prefix: prefix}, nil
+		})
+	key, secret, err := s.RotateApiKey(context.Background(), 1, 7)
+	require.NoError(t, err)
+	assert.Equal(t, apiKeyHash(secret), hash)
+	assert.Equal(t, secret[:apiKeyPrefixLen], key.Prefix)
+
+	repo.EXPECT().RotateApiKey(gomock.Any(), 1, 8, gomock.Any(), gomock.Any()).
+		Return(entity.ApiKey{}, fmt.Errorf("repo: %w", ErrApiKeyNotFound))
+	_, _, err = s.RotateApiKey(context.Background(), 1, 8)
+	assert.ErrorIs(t, err, ErrApiKeyNotFound)
+}
+
+func TestApiKeyService_ParseApiKey(t *testing.T) {
+	now := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+	recently := now.Add(-10 * time.Second)
+	longAgo := now.Add(-time.Hour)
+
+	type MockBehaviour func(r *mock_service.MockApiKeyRepo)
+
+	testCases := []struct {
+		name          string
+		key           string
+		mockBehaviour MockBehaviour
+		want          entity.Actor
+		wantErr       error
+	}{
+		{
+			name: "first use is recorded",
+			key:  "ubs_secret",
+			mockBehaviour: func(r *mock_service.MockApiKeyRepo) {
+				r.EXPECT().GetApiKey(gomock.Any(), apiKeyHash("ubs_secret")).
+					Return(entity.ApiKey{Id: 3, UserId: 1, Role: entity.RoleOperator, Scopes: []entity.Permission{entity.PermRefund}}, nil)
+				r.EXPECT().TouchApiKey(gomock.Any(), 3, now).Return(nil)
+			},
+			want: entity.Actor{UserId: 1, Role: entity.RoleOperator, ApiKeyId: 3, Scopes: []entity.Permission{entity.PermRefund}},
+		},
+		{
+			name: "recent use isn't recorded again",
+			key:  "ubs_secret",
+			mockBehaviour: func(r *mock_service.MockApiKeyRepo) {
+				r.EXPECT().GetApiKey(gomock.Any(), apiKeyHash("ubs_secret")).
+					Return(entity.ApiKey{Id: 3, UserId: 1, Role: entity.RoleUser, Scopes: []entity.Permission{}, LastUsedAt: &recently}, nil)
+			},
+			want: entity.Actor{UserId: 1, Role: entity.RoleUser, ApiKeyId: 3, Scopes: []entity.Permission{}},
+		},
+		{
+			name: "failing to record the use doesn't fail",
+			key:  "ubs_secret",
+			mockBehaviour: func(r *mock_service.MockApiKeyRepo) {
+				r.EXPECT().GetApiKey(gomock.Any(), apiKeyHash("ubs_secret")).
+					Return(entity.ApiKey{Id: 3, UserId: 1, Role: entity.RoleUser, Scopes: []entity.Permission{}, LastUsedAt: &longAgo}, nil)
+				r.EXPECT().TouchApiKey(gomock.Any(), 3, now).Return(errors.New("connection reset"))
+			},
+			want: entity.Actor{UserId: 1, Role: entity.RoleUser, ApiKeyId: 3, Scopes: []entity.Permission{}},
+		},
+		{
+			name:          "not a key",
+			key:           "secret",
+			mockBehaviour: func(r *mock_service.MockApiKeyRepo) {},
+			wantErr:       ErrInvalidApiKey,
+		},
+		{
+			name: "unknown or revoked key",
+			key:  "ubs_secret",
+			mockBehaviour: func(r *mock_service.MockApiKeyRepo) {
+				r.EXPECT().GetApiKey(gomock.Any(), apiKeyHash("ubs_secret")).
+					Return(entity.ApiKey{}, fmt.Errorf("repo: %w", ErrInvalidApiKey))
+			},
+			wantErr: ErrInvalidApiKey,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock_service.NewMockApiKeyRepo(ctrl)
+			tc.mockBehaviour(repo)
+
+			s := NewApiKeyService(repo)
+			s.now = func() time.Time { return now }
+
+			actor, err := s.ParseApiKey(context.Background(), tc.key)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, actor)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"user-balance-service/internal/entity"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+type AuditService struct {
+	repo AuditRepo
+}
+
+func NewAuditService(repo AuditRepo) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+func (s *AuditService) Record(ctx context.Context, record entity.AuditRecord) error {
+	if record.AccountIds == nil {
+		record.AccountIds = []int{}
+	}
+
+	err := s.repo.SaveAuditRecord(ctx, record)
+	if err != nil {
+		return fmt.Errorf("service - AuditService - Record - s.repo.SaveAuditRecord: %w", err)
+	}
+
+	return nil
+}
+
+// ListAudit returns a page of 50 records by default and 500 at most
+func (s *AuditService) ListAudit(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditRecord, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+
+	records, err := s.repo.ListAuditRecords(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("service - AuditService - ListAudit - s.repo.ListAuditRecords: %w", err)
+	}
+
+	return records, nil
+}
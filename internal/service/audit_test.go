@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"user-balance-service/internal/entity"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+func TestAuditService_Record(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockAuditRepo(ctrl)
+	s := NewAuditService(repo)
+
+	// calls on no account are stored with an empty list
+	repo.EXPECT().SaveAuditRecord(gomock.Any(), entity.AuditRecord{
+		UserId:     1,
+		Operation:  "DELETE /api/v2/me",
+		AccountIds: []int{},
+		Status:     204,
+		Outcome:    entity.AuditSucceeded,
+	}).Return(nil)
+
+	err := s.Record(context.Background(), entity.AuditRecord{
+		UserId:    1,
+		Operation: "DELETE /api/v2/me",
+		Status:    204,
+		Outcome:   entity.AuditSucceeded,
+	})
+	assert.NoError(t, err)
+}
+
+func TestAuditService_ListAudit(t *testing.T) {
+	testCases := []struct {
+		name      string
+		limit     int
+		wantLimit int
+	}{
+		{
+			name:      "default limit",
+			wantLimit: defaultAuditLimit,
+		},
+		{
+			name:      "limit",
+			limit:     10,
+			wantLimit: 10,
+		},
+		{
+			name:      "limit over the max",
+			limit:     1000,
+			wantLimit: maxAuditLimit,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock_service.NewMockAuditRepo(ctrl)
+			s := NewAuditService(repo)
+
+			repo.EXPECT().ListAuditRecords(gomock.Any(), entity.AuditFilter{AccountId: 7, Limit: tc.wantLimit}).
+				Return([]entity.AuditRecord{{Id: 1}}, nil)
+
+			records, err := s.ListAudit(context.Background(), entity.AuditFilter{AccountId: 7, Limit: tc.limit})
+			assert.NoError(t, err)
+			assert.Equal(t, []entity.AuditRecord{{Id: 1}}, records)
+		})
+	}
+}
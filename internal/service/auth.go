@@ -2,87 +2,580 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"sync"
 	"time"
 	"user-balance-service/internal/entity"
 )
 
 const (
-	salt    = "poqi23ytoQUFOiwf82quof2qfoYFQUW"
-	signKey = "OR*#Qiofhwfu3qru(*Q#rh2ir12q4QJWFO2"
+	// legacySalt was shared by the SHA-1 hashes made before per-user salts
+	legacySalt = "poqi23ytoQUFOiwf82quof2qfoYFQUW"
 
-	TokenTTL = 24 * time.Hour
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
 )
 
+// TokenClaims of an access token. StandardClaims.Id is the jti checked
+// against the denylist.
 type TokenClaims struct {
 	jwt.StandardClaims
-	UserId int `json:"user_id"`
+	UserId int         `json:"user_id"`
+	Role   entity.Role `json:"role"`
+}
+
+type AuthOption func(s *AuthService)
+
+// AccessTokenTTL sets the lifetime of access tokens, 15 minutes by default
+func AccessTokenTTL(ttl time.Duration) AuthOption {
+	return func(s *AuthService) {
+		s.accessTokenTTL = ttl
+	}
+}
+
+// RefreshTokenTTL sets the lifetime of refresh tokens, 30 days by default.
+// Rotation doesn't extend it past the lifetime of the first token of a family.
+func RefreshTokenTTL(ttl time.Duration) AuthOption {
+	return func(s *AuthService) {
+		s.refreshTokenTTL = ttl
+	}
+}
+
+// GuardLogins locks out usernames and IP addresses after failed sign-ins
+func GuardLogins(guard *LoginGuard) AuthOption {
+	return func(s *AuthService) {
+		s.guard = guard
+	}
+}
+
+// TwoFactorSignIn asks users with 2FA enabled for a second factor after the
+// password, see CompleteSignIn
+func TwoFactorSignIn(twoFactor *TwoFactorService) AuthOption {
+	return func(s *AuthService) {
+		s.twoFactor = twoFactor
+	}
+}
+
+// AuditLogins records every sign-in attempt in audit
+func AuditLogins(audit LoginAuditRepo) AuthOption {
+	return func(s *AuthService) {
+		s.audit = audit
+	}
 }
 
 type AuthService struct {
-	repo AuthRepo
+	repo      AuthRepo
+	tokens    TokenRepo
+	denylist  Denylist
+	hasher    PasswordHasher
+	keys      TokenKeys
+	guard     *LoginGuard
+	audit     LoginAuditRepo
+	twoFactor *TwoFactorService
+
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	now             func() time.Time
+
+	// dummyHash is verified for unknown usernames, so they take as long to
+	// reject as wrong passwords
+	dummyHash     string
+	dummyHashOnce sync.Once
 }
 
-func NewAuthService(repo AuthRepo) *AuthService {
-	return &AuthService{repo: repo}
+func NewAuthService(repo AuthRepo, tokens TokenRepo, denylist Denylist, hasher PasswordHasher, keys TokenKeys, opts ...AuthOption) *AuthService {
+	s := &AuthService{
+		repo:            repo,
+		tokens:          tokens,
+		denylist:        denylist,
+		hasher:          hasher,
+		keys:            keys,
+		accessTokenTTL:  defaultAccessTokenTTL,
+		refreshTokenTTL: defaultRefreshTokenTTL,
+		now:             time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 func (s *AuthService) CreateUser(ctx context.Context, user entity.User) (int, error) {
-	user.Password = generatePasswordHash(user.Password)
+	hash, err := s.hasher.Hash(user.Password)
+	if err != nil {
+		return 0, fmt.Errorf("service - AuthService - CreateUser - s.hasher.Hash: %w", err)
+	}
+
+	user.Password = hash
 	return s.repo.CreateUser(ctx, user)
 }
 
-func (s *AuthService) GenerateToken(ctx context.Context, username, password string) (string, error) {
+// GenerateToken signs username in. Unknown usernames and wrong passwords
+// get the same ErrInvalidCredentials; while username or the client's IP
+// address is locked out the password isn't checked at all. Users with 2FA
+// enabled get a *TwoFactorChallengeError to pass to CompleteSignIn instead
+// of tokens.
+func (s *AuthService) GenerateToken(ctx context.Context, username, password string, client entity.Client) (entity.Tokens, error) {
+	attempt := entity.LoginAttempt{Username: username, IP: client.IP, UserAgent: client.UserAgent}
+
+	reservation, err := s.reserveLogin(ctx, username, client.IP)
+	if err != nil {
+		attempt.Result = entity.LoginLocked
+		s.recordLogin(ctx, attempt)
+		return entity.Tokens{}, err
+	}
+
 	// get user from DB
-	user, err := s.repo.GetUser(ctx, username, generatePasswordHash(password))
+	user, err := s.repo.GetUser(ctx, username)
+	if errors.Is(err, ErrUserNotFound) {
+		s.verifyDummy(password)
+		s.loginFailed(ctx, attempt)
+		return entity.Tokens{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		reservation.Release(ctx)
+		return entity.Tokens{}, err
+	}
+	attempt.UserId = user.Id
+
+	ok, rehash, err := s.verifyPassword(password, user.Password)
+	if err != nil {
+		reservation.Release(ctx)
+		return entity.Tokens{}, fmt.Errorf("service - AuthService - GenerateToken - s.verifyPassword: %w", err)
+	}
+	if !ok {
+		s.loginFailed(ctx, attempt)
+		return entity.Tokens{}, ErrInvalidCredentials
+	}
+	reservation.Release(ctx)
+
+	if user.Disabled() {
+		attempt.Result = entity.LoginFailed
+		s.recordLogin(ctx, attempt)
+		return entity.Tokens{}, ErrUserDisabled
+	}
+	if rehash {
+		s.rehash(ctx, user.Id, password)
+	}
+
+	if s.twoFactor != nil && user.TwoFactorEnabled() {
+		challenge, err := s.twoFactor.newChallenge(ctx, user)
+		if err != nil {
+			return entity.Tokens{}, err
+		}
+
+		attempt.Result = entity.LoginChallenged
+		s.recordLogin(ctx, attempt)
+		return entity.Tokens{}, challenge
+	}
+
+	return s.signIn(ctx, user, attempt)
+}
+
+// CompleteSignIn issues tokens for a sign-in challenge given a TOTP or
+// recovery code. Wrong codes count as failed sign-ins, and a challenge
+// takes only a few of them.
+func (s *AuthService) CompleteSignIn(ctx context.Context, challenge, code string, client entity.Client) (entity.Tokens, error) {
+	if s.twoFactor == nil {
+		return entity.Tokens{}, ErrInvalidChallenge
+	}
+
+	ch, err := s.twoFactor.attemptChallenge(ctx, challenge)
+	if err != nil {
+		return entity.Tokens{}, err
+	}
+	attempt := entity.LoginAttempt{Username: ch.Username, UserId: ch.UserId, IP: client.IP, UserAgent: client.UserAgent}
+
+	reservation, err := s.reserveLogin(ctx, ch.Username, client.IP)
+	if err != nil {
+		attempt.Result = entity.LoginLocked
+		s.recordLogin(ctx, attempt)
+		return entity.Tokens{}, err
+	}
+
+	err = s.twoFactor.checkCode(ctx, ch.UserId, code)
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		s.loginFailed(ctx, attempt)
+		return entity.Tokens{}, err
+	}
+	reservation.Release(ctx)
+	if err != nil {
+		return entity.Tokens{}, err
+	}
+	s.twoFactor.endChallenge(ctx, challenge)
+
+	// the role may have changed or the user been disabled meanwhile
+	user, err := s.repo.GetUserById(ctx, ch.UserId)
+	if err != nil {
+		return entity.Tokens{}, fmt.Errorf("service - AuthService - CompleteSignIn - s.repo.GetUserById: %w", err)
+	}
+	if user.Disabled() {
+		attempt.Result = entity.LoginFailed
+		s.recordLogin(ctx, attempt)
+		return entity.Tokens{}, ErrUserDisabled
+	}
+
+	return s.signIn(ctx, user, attempt)
+}
+
+// RefreshToken exchanges refreshToken for a new pair and revokes it. A
+// revoked token means it has leaked: whoever presents it second, the client
+// or an attacker, every token of its family is revoked.
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (entity.Tokens, error) {
+	stored, err := s.tokens.GetRefreshToken(ctx, refreshTokenId(refreshToken))
+	if err != nil {
+		return entity.Tokens{}, fmt.Errorf("service - AuthService - RefreshToken - s.tokens.GetRefreshToken: %w", err)
+	}
+	if stored.RevokedAt != nil {
+		s.revokeFamily(ctx, stored)
+		return entity.Tokens{}, ErrRefreshTokenReused
+	}
+	if !s.now().Before(stored.ExpiresAt) {
+		return entity.Tokens{}, ErrInvalidToken
+	}
+
+	// the role may have changed since sign-in
+	user, err := s.repo.GetUserById(ctx, stored.UserId)
+	if err != nil {
+		return entity.Tokens{}, fmt.Errorf("service - AuthService - RefreshToken - s.repo.GetUserById: %w", err)
+	}
+	if user.Disabled() {
+		return entity.Tokens{}, fmt.Errorf("%w: user is disabled", ErrInvalidToken)
+	}
+
+	tokens, err := s.issueTokens(ctx, user.Role, stored)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		// lost a race against another refresh with the same token
+		s.revokeFamily(ctx, stored)
+	}
+	if err != nil {
+		return entity.Tokens{}, err
+	}
+
+	return tokens, nil
+}
+
+// Logout revokes refreshToken and puts accessToken, if not empty, on the
+// denylist until it expires. The refresh token is revoked even when the
+// access token is invalid; an expired access token needs no denying.
+func (s *AuthService) Logout(ctx context.Context, refreshToken, accessToken string) error {
+	err := s.tokens.RevokeRefreshToken(ctx, refreshTokenId(refreshToken))
+	if err != nil {
+		return fmt.Errorf("service - AuthService - Logout - s.tokens.RevokeRefreshToken: %w", err)
+	}
+
+	if accessToken == "" {
+		return nil
+	}
+
+	claims, err := s.parseClaims(accessToken)
+	if errors.Is(err, errTokenExpired) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.denylist.Deny(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0).Sub(s.now()))
+	if err != nil {
+		return fmt.Errorf("service - AuthService - Logout - s.denylist.Deny: %w", err)
+	}
+
+	return nil
+}
+
+// ParseToken returns the user accessToken was issued to and their role. Tokens on
+// the denylist are rejected; if the denylist can't be checked the token is
+// accepted, it expires soon anyway.
+func (s *AuthService) ParseToken(ctx context.Context, accessToken string) (entity.Actor, error) {
+	claims, err := s.parseClaims(accessToken)
+	if err != nil {
+		return entity.Actor{}, err
+	}
+
+	denied, err := s.denylist.IsDenied(ctx, claims.Id, claims.UserId, time.Unix(claims.IssuedAt, 0))
+	if err != nil {
+		log.Warnf("service - AuthService - ParseToken - s.denylist.IsDenied: %s", err)
+	}
+	if denied {
+		return entity.Actor{}, fmt.Errorf("%w: token has been revoked", ErrInvalidToken)
+	}
+
+	return entity.Actor{UserId: claims.UserId, Role: claims.Role}, nil
+}
+
+// ChangePassword replaces the password of the user once current is verified
+// and revokes every token of the user, so other sessions have to sign in
+// with the new password. Wrong current passwords count as failed sign-ins
+// of the user, so a stolen access token can't be used to guess it.
+func (s *AuthService) ChangePassword(ctx context.Context, userId int, current, password string) error {
+	user, err := s.repo.GetUserById(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("service - AuthService - ChangePassword - s.repo.GetUserById: %w", err)
+	}
+
+	reservation, err := s.reserveLogin(ctx, user.Username, "")
+	if err != nil {
+		return err
+	}
+
+	ok, _, err := s.verifyPassword(current, user.Password)
+	if err != nil {
+		reservation.Release(ctx)
+		return fmt.Errorf("service - AuthService - ChangePassword - s.verifyPassword: %w", err)
+	}
+	if !ok {
+		return ErrInvalidPassword
+	}
+	reservation.Release(ctx)
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("service - AuthService - ChangePassword - s.hasher.Hash: %w", err)
+	}
+
+	err = s.repo.UpdatePasswordHash(ctx, userId, hash)
+	if err != nil {
+		return fmt.Errorf("service - AuthService - ChangePassword - s.repo.UpdatePasswordHash: %w", err)
+	}
+
+	return s.RevokeUserTokens(ctx, userId)
+}
+
+// RevokeUserTokens revokes the refresh tokens of the user and denies the
+// access tokens issued to them so far. Timestamps in tokens are whole
+// seconds, so tokens issued within the second of revocation stay valid.
+func (s *AuthService) RevokeUserTokens(ctx context.Context, userId int) error {
+	err := s.tokens.RevokeUserTokens(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("service - AuthService - RevokeUserTokens - s.tokens.RevokeUserTokens: %w", err)
+	}
+
+	err = s.denylist.DenyUser(ctx, userId, s.now(), s.accessTokenTTL)
+	if err != nil {
+		return fmt.Errorf("service - AuthService - RevokeUserTokens - s.denylist.DenyUser: %w", err)
+	}
+
+	return nil
+}
+
+// errTokenExpired is ErrInvalidToken for a valid token past its expiry
+var errTokenExpired = fmt.Errorf("%w: token is expired", ErrInvalidToken)
+
+func (s *AuthService) parseClaims(accessToken string) (*TokenClaims, error) {
+	token, err := jwt.ParseWithClaims(accessToken, &TokenClaims{}, s.keys.Keyfunc)
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Errors == jwt.ValidationErrorExpired {
+		return nil, errTokenExpired
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	claims, ok := token.Claims.(*TokenClaims)
+	if !ok {
+		return nil, fmt.Errorf("%w: token claims are not of type TokenClaims", ErrInvalidToken)
+	}
+
+	// tokens issued before revocation have no id and can't be revoked
+	if claims.Id == "" {
+		return nil, fmt.Errorf("%w: token has no id", ErrInvalidToken)
+	}
+
+	// tokens issued before roles are of regular users
+	if claims.Role == "" {
+		claims.Role = entity.RoleUser
+	}
+	if !claims.Role.Valid() {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidToken, claims.Role)
+	}
+
+	return claims, nil
+}
+
+// signIn starts a new token family for user and records the successful
+// attempt
+func (s *AuthService) signIn(ctx context.Context, user entity.User, attempt entity.LoginAttempt) (entity.Tokens, error) {
+	familyId, err := randomHex(16)
+	if err != nil {
+		return entity.Tokens{}, fmt.Errorf("service - AuthService - signIn - randomHex: %w", err)
+	}
+
+	tokens, err := s.issueTokens(ctx, user.Role, entity.RefreshToken{UserId: user.Id, FamilyId: familyId, ExpiresAt: s.now().Add(s.refreshTokenTTL)})
+	if err != nil {
+		return entity.Tokens{}, err
+	}
+
+	if s.guard != nil {
+		s.guard.Succeed(ctx, user.Username)
+	}
+	attempt.Result = entity.LoginSucceeded
+	s.recordLogin(ctx, attempt)
+
+	return tokens, nil
+}
+
+// issueTokens stores a refresh token of the family of parent, replacing
+// parent unless it is new, and signs an access token with role
+func (s *AuthService) issueTokens(ctx context.Context, role entity.Role, parent entity.RefreshToken) (entity.Tokens, error) {
+	refreshToken, err := randomToken()
+	if err != nil {
+		return entity.Tokens{}, fmt.Errorf("service - AuthService - issueTokens - randomToken: %w", err)
+	}
+
+	next := entity.RefreshToken{
+		Id:        refreshTokenId(refreshToken),
+		UserId:    parent.UserId,
+		FamilyId:  parent.FamilyId,
+		ExpiresAt: parent.ExpiresAt,
+	}
+
+	if parent.Id == "" {
+		err = s.tokens.CreateRefreshToken(ctx, next)
+	} else {
+		err = s.tokens.RotateRefreshToken(ctx, parent.Id, next)
+	}
+	if err != nil {
+		return entity.Tokens{}, fmt.Errorf("service - AuthService - issueTokens - s.tokens: %w", err)
+	}
+
+	accessToken, err := s.signAccessToken(parent.UserId, role)
+	if err != nil {
+		return entity.Tokens{}, fmt.Errorf("service - AuthService - issueTokens - s.signAccessToken: %w", err)
+	}
+
+	return entity.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.accessTokenTTL / time.Second),
+	}, nil
+}
+
+func (s *AuthService) signAccessToken(userId int, role entity.Role) (string, error) {
+	jti, err := randomHex(16)
 	if err != nil {
 		return "", err
 	}
 
-	// generate token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &TokenClaims{
+	now := s.now()
+	return s.keys.Sign(&TokenClaims{
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(TokenTTL).Unix(),
-			IssuedAt:  time.Now().Unix(),
+			Id:        jti,
+			ExpiresAt: now.Add(s.accessTokenTTL).Unix(),
+			IssuedAt:  now.Unix(),
 		},
-		UserId: user.Id,
+		UserId: userId,
+		Role:   role,
 	})
+}
+
+func (s *AuthService) revokeFamily(ctx context.Context, token entity.RefreshToken) {
+	log.Warnf("service - AuthService - user %d: refresh token reused, revoking family %s", token.UserId, token.FamilyId)
 
-	// sign token
-	tokenString, err := token.SignedString([]byte(signKey))
+	err := s.tokens.RevokeTokenFamily(ctx, token.FamilyId)
 	if err != nil {
-		return "", err
+		log.Errorf("service - AuthService - revokeFamily - s.tokens.RevokeTokenFamily: %s", err)
+	}
+}
+
+// verifyPassword checks password against the stored hash. Legacy SHA-1
+// hashes always need a rehash.
+func (s *AuthService) verifyPassword(password, hash string) (ok, rehash bool, err error) {
+	if !strings.HasPrefix(hash, "$") {
+		return subtle.ConstantTimeCompare([]byte(legacyPasswordHash(password)), []byte(hash)) == 1, true, nil
 	}
 
-	return tokenString, nil
+	return s.hasher.Verify(password, hash)
 }
 
-func (s *AuthService) ParseToken(accessToken string) (int, error) {
-	token, err := jwt.ParseWithClaims(accessToken, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
+// reserveLogin counts a sign-in of username from ip against the guard
+// before its credentials are checked, see LoginGuard.Reserve
+func (s *AuthService) reserveLogin(ctx context.Context, username, ip string) (*LoginReservation, error) {
+	if s.guard == nil {
+		return nil, nil
+	}
 
-		return []byte(signKey), nil
-	})
+	return s.guard.Reserve(ctx, username, ip)
+}
+
+// loginFailed records a failed sign-in, already counted by reserveLogin
+func (s *AuthService) loginFailed(ctx context.Context, attempt entity.LoginAttempt) {
+	attempt.Result = entity.LoginFailed
+	s.recordLogin(ctx, attempt)
+}
 
+// recordLogin writes attempt to the login audit; a failed write doesn't fail
+// the sign-in
+func (s *AuthService) recordLogin(ctx context.Context, attempt entity.LoginAttempt) {
+	if s.audit == nil {
+		return
+	}
+
+	err := s.audit.SaveLoginAttempt(ctx, attempt)
 	if err != nil {
-		return 0, err
+		log.Errorf("service - AuthService - recordLogin - s.audit.SaveLoginAttempt: %s", err)
 	}
+}
 
-	claims, ok := token.Claims.(*TokenClaims)
-	if !ok {
-		return 0, fmt.Errorf("token claims are not of type TokenClaims")
+// rehash replaces the hash of a user whose password has just been verified.
+// A failure only postpones the upgrade to the next sign-in.
+func (s *AuthService) rehash(ctx context.Context, id int, password string) {
+	hash, err := s.hasher.Hash(password)
+	if err == nil {
+		err = s.repo.UpdatePasswordHash(ctx, id, hash)
+	}
+	if err != nil {
+		log.Warnf("service - AuthService - rehash - user %d: %s", id, err)
+	}
+}
+
+func (s *AuthService) verifyDummy(password string) {
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = s.hasher.Hash("dummy password")
+	})
+
+	_, _, _ = s.hasher.Verify(password, s.dummyHash)
+}
+
+// refreshTokenId is what a refresh token is stored by
+func refreshTokenId(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
 
-	return claims.UserId, nil
+	return hex.EncodeToString(b), nil
 }
 
-func generatePasswordHash(password string) string {
+func legacyPasswordHash(password string) string {
 	hash := sha1.New()
 	hash.Write([]byte(password))
 
-	return fmt.Sprintf("%x", hash.Sum([]byte(salt)))
+	return fmt.Sprintf("%x", hash.Sum([]byte(legacySalt)))
 }
@@ -0,0 +1,552 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	mock_service "user-balance-service/internal/service/mock"
+	"user-balance-service/pkg/jwtkeys"
+	"user-balance-service/pkg/passhash"
+)
+
+func newTestHasher(t *testing.T) *passhash.Hasher {
+	hasher, err := passhash.New(passhash.Argon2(1024, 1, 1))
+	require.NoError(t, err)
+
+	return hasher
+}
+
+func newTestKeys(t *testing.T) *jwtkeys.Set {
+	key, err := jwtkeys.NewHMAC("test", []byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	keys, err := jwtkeys.New(key)
+	require.NoError(t, err)
+
+	return keys
+}
+
+// argon2idHash matches hashes made by the current hasher
+type argon2idHash struct{}
+
+func (argon2idHash) Matches(x interface{}) bool {
+	hash, ok := x.(string)
+	return ok && strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$")
+}
+
+func (argon2idHash) String() string {
+	return "is an argon2id hash"
+}
+
+func TestAuthService_CreateUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockAuthRepo(ctrl)
+	hasher := newTestHasher(t)
+
+	var stored string
+	repo.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user entity.User) (int, error) {
+			stored = user.Password
+			return 1, nil
+		})
+
+	id, err := NewAuthService(repo, nil, nil, hasher, nil).CreateUser(context.Background(), entity.User{Username: "qwe", Password: "qwerty123"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	ok, rehash, err := hasher.Verify("qwerty123", stored)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+}
+
+func TestAuthService_GenerateToken(t *testing.T) {
+	hasher := newTestHasher(t)
+	currentHash, err := hasher.Hash("qwerty123")
+	require.NoError(t, err)
+
+	type MockBehaviour func(r *mock_service.MockAuthRepo)
+
+	testCases := []struct {
+		name          string
+		password      string
+		mockBehaviour MockBehaviour
+		wantErr       error
+	}{
+		{
+			name:     "current hash",
+			password: "qwerty123",
+			mockBehaviour: func(r *mock_service.MockAuthRepo) {
+				r.EXPECT().GetUser(gomock.Any(), "qwe").Return(entity.User{Id: 1, Username: "qwe", Password: currentHash}, nil)
+			},
+		},
+		{
+			name:     "legacy hash is upgraded",
+			password: "qwerty123",
+			mockBehaviour: func(r *mock_service.MockAuthRepo) {
+				r.EXPECT().GetUser(gomock.Any(), "qwe").Return(entity.User{Id: 1, Username: "qwe", Password: legacyPasswordHash("qwerty123")}, nil)
+				r.EXPECT().UpdatePasswordHash(gomock.Any(), 1, argon2idHash{}).Return(nil)
+			},
+		},
+		{
+			name:     "failed upgrade doesn't fail sign-in",
+			password: "qwerty123",
+			mockBehaviour: func(r *mock_service.MockAuthRepo) {
+				r.EXPECT().GetUser(gomock.Any(), "qwe").Return(entity.User{Id: 1, Username: "qwe", Password: legacyPasswordHash("qwerty123")}, nil)
+				r.EXPECT().UpdatePasswordHash(gomock.Any(), 1, argon2idHash{}).Return(fmt.Errorf("connection reset"))
+			},
+		},
+		{
+			name:     "wrong password",
+			password: "qwerty124",
+			mockBehaviour: func(r *mock_service.MockAuthRepo) {
+				r.EXPECT().GetUser(gomock.Any(), "qwe").Return(entity.User{Id: 1, Username: "qwe", Password: currentHash}, nil)
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:     "wrong password with legacy hash",
+			password: "qwerty124",
+			mockBehaviour: func(r *mock_service.MockAuthRepo) {
+				r.EXPECT().GetUser(gomock.Any(), "qwe").Return(entity.User{Id: 1, Username: "qwe", Password: legacyPasswordHash("qwerty123")}, nil)
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:     "unknown user",
+			password: "qwerty123",
+			mockBehaviour: func(r *mock_service.MockAuthRepo) {
+				r.EXPECT().GetUser(gomock.Any(), "qwe").Return(entity.User{}, fmt.Errorf("repo: %w", ErrUserNotFound))
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:     "disabled user",
+			password: "qwerty123",
+			mockBehaviour: func(r *mock_service.MockAuthRepo) {
+				disabledAt := time.Now()
+				r.EXPECT().GetUser(gomock.Any(), "qwe").Return(entity.User{Id: 1, Username: "qwe", Password: currentHash, DisabledAt: &disabledAt}, nil)
+			},
+			wantErr: ErrUserDisabled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock_service.NewMockAuthRepo(ctrl)
+			tokenRepo := mock_service.NewMockTokenRepo(ctrl)
+			denylist := mock_service.NewMockDenylist(ctrl)
+			tc.mockBehaviour(repo)
+
+			s := NewAuthService(repo, tokenRepo, denylist, hasher, newTestKeys(t))
+
+			if tc.wantErr == nil {
+				tokenRepo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, token entity.RefreshToken) error {
+						assert.Equal(t, 1, token.UserId)
+						assert.Len(t, token.Id, 64)
+						assert.Len(t, token.FamilyId, 32)
+						return nil
+					})
+				denylist.EXPECT().IsDenied(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
+			}
+
+			tokens, err := s.GenerateToken(context.Background(), "qwe", tc.password, entity.Client{})
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 900, tokens.ExpiresIn)
+			assert.NotEmpty(t, tokens.RefreshToken)
+
+			actor, err := s.ParseToken(context.Background(), tokens.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, entity.Actor{UserId: 1, Role: entity.RoleUser}, actor)
+		})
+	}
+}
+
+func TestAuthService_GenerateToken_Lockout(t *testing.T) {
+	hasher := newTestHasher(t)
+	hash, err := hasher.Hash("qwerty123")
+	require.NoError(t, err)
+	client := entity.Client{IP: "192.0.2.1", UserAgent: "curl/7.85"}
+
+	type MockBehaviour func(r *mock_service.MockAuthRepo, a *mock_service.MockLoginAttempts, audit *mock_service.MockLoginAuditRepo)
+
+	testCases := []struct {
+		name          string
+		username      string
+		password      string
+		mockBehaviour MockBehaviour
+		wantErr       error
+	}{
+		{
+			name:     "success clears the username failures",
+			username: "qwe",
+			password: "qwerty123",
+			mockBehaviour: func(r *mock_service.MockAuthRepo, a *mock_service.MockLoginAttempts, audit *mock_service.MockLoginAuditRepo) {
+				a.EXPECT().LockedFor(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil).Times(2)
+				a.EXPECT().AddFailure(gomock.Any(), "user_qwe", gomock.Any()).Return(int64(1), nil)
+				a.EXPECT().AddFailure(gomock.Any(), "ip_192.0.2.1", gomock.Any()).Return(int64(1), nil)
+				r.EXPECT().GetUser(gomock.Any(), "qwe").Return(entity.User{Id: 1, Username: "qwe", Password: hash}, nil)
+				a.EXPECT().RemoveFailure(gomock.Any(), "user_qwe").Return(nil)
+				a.EXPECT().RemoveFailure(gomock.Any(), "ip_192.0.2.1").Return(nil)
+				a.EXPECT().Reset(gomock.Any(), "user_qwe").Return(nil)
+				audit.EXPECT().SaveLoginAttempt(gomock.Any(), entity.LoginAttempt{
+					Username: "qwe", UserId: 1, IP: client.IP, UserAgent: client.UserAgent, Result: entity.LoginSucceeded,
+				}).Return(nil)
+			},
+		},
+		{
+			name:     "wrong password is counted",
+			username: "qwe",
+			password: "qwerty124",
+			mockBehaviour: func(r *mock_service.MockAuthRepo, a *mock_service.MockLoginAttempts, audit *mock_service.MockLoginAuditRepo) {
+				a.EXPECT().LockedFor(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil).Times(2)
+				a.EXPECT().AddFailure(gomock.Any(), "user_qwe", gomock.Any()).Return(int64(1), nil)
+				a.EXPECT().AddFailure(gomock.Any(), "ip_192.0.2.1", gomock.Any()).Return(int64(1), nil)
+				r.EXPECT().GetUser(gomock.Any(), "qwe").Return(entity.User{Id: 1, Username: "qwe", Password: hash}, nil)
+				audit.EXPECT().SaveLoginAttempt(gomock.Any(), entity.LoginAttempt{
+					Username: "qwe", UserId: 1, IP: client.IP, UserAgent: client.UserAgent, Result: entity.LoginFailed,
+				}).Return(nil)
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:     "unknown username is counted the same way",
+			username: "nobody",
+			password: "qwerty123",
+			mockBehaviour: func(r *mock_service.MockAuthRepo, a *mock_service.MockLoginAttempts, audit *mock_service.MockLoginAuditRepo) {
+				a.EXPECT().LockedFor(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil).Times(2)
+				a.EXPECT().AddFailure(gomock.Any(), "user_nobody", gomock.Any()).Return(int64(5), nil)
+				a.EXPECT().TryLock(gomock.Any(), "user_nobody", time.Minute).Return(true, nil)
+				a.EXPECT().AddFailure(gomock.Any(), "ip_192.0.2.1", gomock.Any()).Return(int64(5), nil)
+				r.EXPECT().GetUser(gomock.Any(), "nobody").Return(entity.User{}, fmt.Errorf("repo: %w", ErrUserNotFound))
+				audit.EXPECT().SaveLoginAttempt(gomock.Any(), entity.LoginAttempt{
+					Username: "nobody", IP: client.IP, UserAgent: client.UserAgent, Result: entity.LoginFailed,
+				}).Return(nil)
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:     "locked out before checking the password",
+			username: "qwe",
+			password: "qwerty123",
+			mockBehaviour: func(r *mock_service.MockAuthRepo, a *mock_service.MockLoginAttempts, audit *mock_service.MockLoginAuditRepo) {
+				a.EXPECT().LockedFor(gomock.Any(), "user_qwe").Return(time.Minute, nil)
+				a.EXPECT().LockedFor(gomock.Any(), "ip_192.0.2.1").Return(time.Duration(0), nil)
+				audit.EXPECT().SaveLoginAttempt(gomock.Any(), entity.LoginAttempt{
+					Username: "qwe", IP: client.IP, UserAgent: client.UserAgent, Result: entity.LoginLocked,
+				}).Return(errors.New("connection reset"))
+			},
+			wantErr: ErrTooManyAttempts,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock_service.NewMockAuthRepo(ctrl)
+			attempts := mock_service.NewMockLoginAttempts(ctrl)
+			audit := mock_service.NewMockLoginAuditRepo(ctrl)
+			tokenRepo := mock_service.NewMockTokenRepo(ctrl)
+			tc.mockBehaviour(repo, attempts, audit)
+
+			s := NewAuthService(repo, tokenRepo, nil, hasher, newTestKeys(t), GuardLogins(NewLoginGuard(attempts)), AuditLogins(audit))
+			if tc.wantErr == nil {
+				tokenRepo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Return(nil)
+			}
+
+			_, err := s.GenerateToken(context.Background(), tc.username, tc.password, client)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAuthService_RefreshToken(t *testing.T) {
+	now := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+	revokedAt := now.Add(-time.Minute)
+	active := entity.RefreshToken{
+		Id:        refreshTokenId("refresh"),
+		UserId:    1,
+		FamilyId:  "family",
+		ExpiresAt: now.Add(time.Hour),
+	}
+	revoked := active
+	revoked.RevokedAt = &revokedAt
+	expired := active
+	expired.ExpiresAt = now
+
+	type MockBehaviour func(r *mock_service.MockTokenRepo, u *mock_service.MockAuthRepo)
+
+	testCases := []struct {
+		name          string
+		mockBehaviour MockBehaviour
+		wantErr       error
+	}{
+		{
+			name: "OK",
+			mockBehaviour: func(r *mock_service.MockTokenRepo, u *mock_service.MockAuthRepo) {
+				r.EXPECT().GetRefreshToken(gomock.Any(), active.Id).Return(active, nil)
+				u.EXPECT().GetUserById(gomock.Any(), 1).Return(entity.User{Id: 1, Role: entity.RoleOperator}, nil)
+				r.EXPECT().RotateRefreshToken(gomock.Any(), active.Id, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ string, next entity.RefreshToken) error {
+						assert.NotEqual(t, active.Id, next.Id)
+						assert.Equal(t, active.UserId, next.UserId)
+						assert.Equal(t, active.FamilyId, next.FamilyId)
+						assert.Equal(t, active.ExpiresAt, next.ExpiresAt)
+						return nil
+					})
+			},
+		},
+		{
+			name: "unknown token",
+			mockBehaviour: func(r *mock_service.MockTokenRepo, u *mock_service.MockAuthRepo) {
+				r.EXPECT().GetRefreshToken(gomock.Any(), active.Id).
+					Return(entity.RefreshToken{}, fmt.Errorf("repo: %w", ErrInvalidToken))
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "expired token",
+			mockBehaviour: func(r *mock_service.MockTokenRepo, u *mock_service.MockAuthRepo) {
+				r.EXPECT().GetRefreshToken(gomock.Any(), active.Id).Return(expired, nil)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "reused token revokes family",
+			mockBehaviour: func(r *mock_service.MockTokenRepo, u *mock_service.MockAuthRepo) {
+				r.EXPECT().GetRefreshToken(gomock.Any(), active.Id).Return(revoked, nil)
+				r.EXPECT().RevokeTokenFamily(gomock.Any(), "family").Return(nil)
+			},
+			wantErr: ErrRefreshTokenReused,
+		},
+		{
+			name: "disabled user",
+			mockBehaviour: func(r *mock_service.MockTokenRepo, u *mock_service.MockAuthRepo) {
+				r.EXPECT().GetRefreshToken(gomock.Any(), active.Id).Return(active, nil)
+				u.EXPECT().GetUserById(gomock.Any(), 1).Return(entity.User{Id: 1, Role: entity.RoleOperator, DisabledAt: &revokedAt}, nil)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "concurrent reuse revokes family",
+			mockBehaviour: func(r *mock_service.MockTokenRepo, u *mock_service.MockAuthRepo) {
+				r.EXPECT().GetRefreshToken(gomock.Any(), active.Id).Return(active, nil)
+				u.EXPECT().GetUserById(gomock.Any(), 1).Return(entity.User{Id: 1, Role: entity.RoleOperator}, nil)
+				r.EXPECT().RotateRefreshToken(gomock.Any(), active.Id, gomock.Any()).
+					Return(fmt.Errorf("repo: %w", ErrRefreshTokenReused))
+				r.EXPECT().RevokeTokenFamily(gomock.Any(), "family").Return(nil)
+			},
+			wantErr: ErrRefreshTokenReused,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			tokenRepo := mock_service.NewMockTokenRepo(ctrl)
+			repo := mock_service.NewMockAuthRepo(ctrl)
+			tc.mockBehaviour(tokenRepo, repo)
+
+			s := NewAuthService(repo, tokenRepo, mock_service.NewMockDenylist(ctrl), newTestHasher(t), newTestKeys(t))
+			s.now = func() time.Time { return now }
+
+			tokens, err := s.RefreshToken(context.Background(), "refresh")
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, tokens.AccessToken)
+			assert.NotEqual(t, "refresh", tokens.RefreshToken)
+
+			// the new access token carries the current role
+			var claims TokenClaims
+			_, _, err = new(jwt.Parser).ParseUnverified(tokens.AccessToken, &claims)
+			require.NoError(t, err)
+			assert.Equal(t, entity.RoleOperator, claims.Role)
+		})
+	}
+}
+
+func TestAuthService_Logout(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	tokenRepo := mock_service.NewMockTokenRepo(ctrl)
+	denylist := mock_service.NewMockDenylist(ctrl)
+
+	s := NewAuthService(mock_service.NewMockAuthRepo(ctrl), tokenRepo, denylist, newTestHasher(t), newTestKeys(t))
+	accessToken, err := s.signAccessToken(1, entity.RoleUser)
+	require.NoError(t, err)
+
+	var jti string
+	tokenRepo.EXPECT().RevokeRefreshToken(gomock.Any(), refreshTokenId("refresh")).Return(nil)
+	denylist.EXPECT().Deny(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, id string, ttl time.Duration) error {
+			jti = id
+			assert.True(t, ttl > 14*time.Minute && ttl <= 15*time.Minute, ttl)
+			return nil
+		})
+	require.NoError(t, s.Logout(ctx, "refresh", accessToken))
+
+	// the access token is rejected from now on
+	denylist.EXPECT().IsDenied(gomock.Any(), jti, 1, gomock.Any()).Return(true, nil)
+	_, err = s.ParseToken(ctx, accessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// the refresh token is revoked even when the access token is invalid
+	tokenRepo.EXPECT().RevokeRefreshToken(gomock.Any(), refreshTokenId("refresh")).Return(nil)
+	err = s.Logout(ctx, "refresh", "invalid")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// an expired access token has nothing left to deny
+	s.now = func() time.Time { return time.Now().Add(-time.Hour) }
+	expired, err := s.signAccessToken(1, entity.RoleUser)
+	require.NoError(t, err)
+
+	tokenRepo.EXPECT().RevokeRefreshToken(gomock.Any(), refreshTokenId("refresh")).Return(nil)
+	assert.NoError(t, s.Logout(ctx, "refresh", expired))
+}
+
+func TestAuthService_ParseToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	denylist := mock_service.NewMockDenylist(ctrl)
+	keys := newTestKeys(t)
+	s := NewAuthService(mock_service.NewMockAuthRepo(ctrl), mock_service.NewMockTokenRepo(ctrl), denylist, newTestHasher(t), keys)
+
+	accessToken, err := s.signAccessToken(1, entity.RoleUser)
+	require.NoError(t, err)
+
+	// an unavailable denylist doesn't fail requests
+	denylist.EXPECT().IsDenied(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, errors.New("connection refused"))
+	actor, err := s.ParseToken(context.Background(), accessToken)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Actor{UserId: 1, Role: entity.RoleUser}, actor)
+
+	// tokens without an id can't be revoked, so they aren't accepted
+	legacy, err := keys.Sign(&TokenClaims{
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()},
+		UserId:         1,
+	})
+	require.NoError(t, err)
+
+	_, err = s.ParseToken(context.Background(), legacy)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// tokens signed with unknown keys aren't accepted
+	otherKey, err := jwtkeys.NewHMAC("other", []byte("abcdef0123456789abcdef0123456789"))
+	require.NoError(t, err)
+	otherKeys, err := jwtkeys.New(otherKey)
+	require.NoError(t, err)
+	forged, err := NewAuthService(nil, nil, nil, nil, otherKeys).signAccessToken(1, entity.RoleUser)
+	require.NoError(t, err)
+
+	_, err = s.ParseToken(context.Background(), forged)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	hasher := newTestHasher(t)
+	hash, err := hasher.Hash("qwerty123")
+	require.NoError(t, err)
+	now := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+	errUnavailable := errors.New("connection refused")
+
+	type MockBehaviour func(r *mock_service.MockAuthRepo, tokens *mock_service.MockTokenRepo, denylist *mock_service.MockDenylist)
+
+	testCases := []struct {
+		name          string
+		current       string
+		mockBehaviour MockBehaviour
+		wantErr       error
+	}{
+		{
+			name:    "OK",
+			current: "qwerty123",
+			mockBehaviour: func(r *mock_service.MockAuthRepo, tokens *mock_service.MockTokenRepo, denylist *mock_service.MockDenylist) {
+				r.EXPECT().GetUserById(gomock.Any(), 1).Return(entity.User{Id: 1, Password: hash}, nil)
+				r.EXPECT().UpdatePasswordHash(gomock.Any(), 1, argon2idHash{}).Return(nil)
+				tokens.EXPECT().RevokeUserTokens(gomock.Any(), 1).Return(nil)
+				denylist.EXPECT().DenyUser(gomock.Any(), 1, now, 15*time.Minute).Return(nil)
+			},
+		},
+		{
+			name:    "wrong current password",
+			current: "qwerty124",
+			mockBehaviour: func(r *mock_service.MockAuthRepo, tokens *mock_service.MockTokenRepo, denylist *mock_service.MockDenylist) {
+				r.EXPECT().GetUserById(gomock.Any(), 1).Return(entity.User{Id: 1, Password: hash}, nil)
+			},
+			wantErr: ErrInvalidPassword,
+		},
+		{
+			name:    "access tokens can't be revoked",
+			current: "qwerty123",
+			mockBehaviour: func(r *mock_service.MockAuthRepo, tokens *mock_service.MockTokenRepo, denylist *mock_service.MockDenylist) {
+				r.EXPECT().GetUserById(gomock.Any(), 1).Return(entity.User{Id: 1, Password: hash}, nil)
+				r.EXPECT().UpdatePasswordHash(gomock.Any(), 1, argon2idHash{}).Return(nil)
+				tokens.EXPECT().RevokeUserTokens(gomock.Any(), 1).Return(nil)
+				denylist.EXPECT().DenyUser(gomock.Any(), 1, now, 15*time.Minute).Return(errUnavailable)
+			},
+			wantErr: errUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock_service.NewMockAuthRepo(ctrl)
+			tokens := mock_service.NewMockTokenRepo(ctrl)
+			denylist := mock_service.NewMockDenylist(ctrl)
+			tc.mockBehaviour(repo, tokens, denylist)
+
+			s := NewAuthService(repo, tokens, denylist, hasher, newTestKeys(t))
+			s.now = func() time.Time { return now }
+
+			err := s.ChangePassword(context.Background(), 1, tc.current, "new-password")
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestAuthService_ChangePassword_Throttled(t *testing.T) {
+	hasher := newTestHasher(t)
+	hash, err := hasher.Hash("qwerty123")
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockAuthRepo(ctrl)
+	attempts := &memoryLoginAttempts{failures: make(map[string]int64), locks: make(map[string]time.Duration)}
+	s := NewAuthService(repo, nil, nil, hasher, newTestKeys(t), GuardLogins(NewLoginGuard(attempts)))
+	ctx := context.Background()
+
+	repo.EXPECT().GetUserById(gomock.Any(), 1).Return(entity.User{Id: 1, Username: "qwe", Password: hash}, nil).AnyTimes()
+
+	for i := 0; i < defaultMaxUserFailures; i++ {
+		err = s.ChangePassword(ctx, 1, "qwerty124", "new-password")
+		assert.ErrorIs(t, err, ErrInvalidPassword)
+	}
+
+	// wrong guesses lock the user out like failed sign-ins
+	err = s.ChangePassword(ctx, 1, "qwerty123", "new-password")
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+}
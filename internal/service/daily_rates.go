@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"user-balance-service/internal/entity"
+)
+
+// DailyRates returns the rates that applied on given days. Rates are kept in
+// the repository, so every day is fetched from the provider only once.
+type DailyRates struct {
+	repo     RateRepo
+	provider RateProvider
+}
+
+func NewDailyRates(repo RateRepo, provider RateProvider) *DailyRates {
+	return &DailyRates{
+		repo:     repo,
+		provider: provider,
+	}
+}
+
+// On returns the rates of currency keyed by day (2006-01-02) for every day
+// of dates. Missing days are fetched and stored.
+func (d *DailyRates) On(ctx context.Context, currency string, dates []time.Time) (map[string]float64, error) {
+	currency = strings.ToUpper(currency)
+	rates := make(map[string]float64)
+	if len(dates) == 0 {
+		return rates, nil
+	}
+
+	from, to := day(dates[0]), day(dates[0])
+	for _, date := range dates[1:] {
+		switch date := day(date); {
+		case date.Before(from):
+			from = date
+		case date.After(to):
+			to = date
+		}
+	}
+
+	err := d.stored(ctx, currency, from, to, rates)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, date := range dates {
+		key := dayKey(date)
+		if _, ok := rates[key]; ok {
+			continue
+		}
+
+		rate, err := d.fetch(ctx, currency, day(date))
+		if err != nil {
+			return nil, err
+		}
+		rates[key] = rate
+	}
+
+	return rates, nil
+}
+
+// Backfill fetches every day between from and to missing in the repository
+// and returns the number of fetched days
+func (d *DailyRates) Backfill(ctx context.Context, currency string, from, to time.Time) (int, error) {
+	currency = strings.ToUpper(currency)
+	from, to = day(from), day(to)
+
+	rates := make(map[string]float64)
+	err := d.stored(ctx, currency, from, to, rates)
+	if err != nil {
+		return 0, err
+	}
+
+	fetched := 0
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		if _, ok := rates[dayKey(date)]; ok {
+			continue
+		}
+
+		_, err = d.fetch(ctx, currency, date)
+		if err != nil {
+			return fetched, err
+		}
+		fetched++
+	}
+
+	return fetched, nil
+}
+
+// stored adds the rates already in the repository to rates
+func (d *DailyRates) stored(ctx context.Context, currency string, from, to time.Time, rates map[string]float64) error {
+	stored, err := d.repo.GetDailyRates(ctx, currency, from, to)
+	if err != nil {
+		return fmt.Errorf("service - DailyRates - d.repo.GetDailyRates: %w", err)
+	}
+
+	for _, rate := range stored {
+		rates[dayKey(time.Time(rate.Date))] = rate.Rate
+	}
+
+	return nil
+}
+
+func (d *DailyRates) fetch(ctx context.Context, currency string, date time.Time) (float64, error) {
+	rate, err := d.provider.RateOn(ctx, currency, date)
+	if errors.Is(err, ErrUnknownCurrency) {
+		return 0, fmt.Errorf("service - DailyRates - fetch - d.provider.RateOn: %w", err)
+	}
+	if err != nil {
+		return 0, &ConverterError{Err: err}
+	}
+	if rate <= 0 {
+		return 0, &ConverterError{Err: fmt.Errorf("rate of %s on %s is %v", currency, dayKey(date), rate)}
+	}
+
+	err = d.repo.SaveDailyRate(ctx, entity.DailyRate{
+		Currency: currency,
+		Date:     entity.CustomTime(date),
+		Rate:     rate,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("service - DailyRates - d.repo.SaveDailyRate: %w", err)
+	}
+
+	return rate, nil
+}
+
+// day is the calendar day of t at midnight UTC
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func dayKey(t time.Time) string {
+	return t.Format("2006-01-02")
+}
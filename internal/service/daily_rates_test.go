@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+var errSomethingWentWrong = errors.New("something went wrong")
+
+func date(day int) time.Time {
+	return time.Date(2022, 10, day, 0, 0, 0, 0, time.UTC)
+}
+
+func dailyRate(day int, rate float64) entity.DailyRate {
+	return entity.DailyRate{Currency: "USD", Date: entity.CustomTime(date(day)), Rate: rate}
+}
+
+func TestDailyRates_On(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockRateRepo(ctrl)
+	provider := mock_service.NewMockRateProvider(ctrl)
+	rates := NewDailyRates(repo, provider)
+
+	// operation times don't matter, only their days
+	dates := []time.Time{
+		date(20).Add(15 * time.Hour),
+		date(18).Add(time.Hour),
+		date(20),
+		date(19).Add(23 * time.Hour),
+	}
+
+	repo.EXPECT().GetDailyRates(gomock.Any(), "USD", date(18), date(20)).
+		Return([]entity.DailyRate{dailyRate(18, 61), dailyRate(20, 62)}, nil)
+	provider.EXPECT().RateOn(gomock.Any(), "USD", date(19)).Return(61.5, nil)
+	repo.EXPECT().SaveDailyRate(gomock.Any(), dailyRate(19, 61.5)).Return(nil)
+
+	got, err := rates.On(context.Background(), "usd", dates)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{
+		"2022-10-18": 61,
+		"2022-10-19": 61.5,
+		"2022-10-20": 62,
+	}, got)
+}
+
+func TestDailyRates_On_Errors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		mockBehaviour func(repo *mock_service.MockRateRepo, provider *mock_service.MockRateProvider)
+		wantErr       error
+	}{
+		{
+			name: "provider error",
+			mockBehaviour: func(repo *mock_service.MockRateRepo, provider *mock_service.MockRateProvider) {
+				repo.EXPECT().GetDailyRates(gomock.Any(), "USD", date(20), date(20)).Return(nil, nil)
+				provider.EXPECT().RateOn(gomock.Any(), "USD", date(20)).Return(0.0, errors.New("timeout"))
+			},
+			wantErr: ErrConverterUnavailable,
+		},
+		{
+			name: "zero rate",
+			mockBehaviour: func(repo *mock_service.MockRateRepo, provider *mock_service.MockRateProvider) {
+				repo.EXPECT().GetDailyRates(gomock.Any(), "USD", date(20), date(20)).Return(nil, nil)
+				provider.EXPECT().RateOn(gomock.Any(), "USD", date(20)).Return(0.0, nil)
+			},
+			wantErr: ErrConverterUnavailable,
+		},
+		{
+			name: "repo error",
+			mockBehaviour: func(repo *mock_service.MockRateRepo, provider *mock_service.MockRateProvider) {
+				repo.EXPECT().GetDailyRates(gomock.Any(), "USD", date(20), date(20)).Return(nil, errSomethingWentWrong)
+			},
+			wantErr: errSomethingWentWrong,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock_service.NewMockRateRepo(ctrl)
+			provider := mock_service.NewMockRateProvider(ctrl)
+			tc.mockBehaviour(repo, provider)
+
+			_, err := NewDailyRates(repo, provider).On(context.Background(), "USD", []time.Time{date(20)})
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestDailyRates_Backfill(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockRateRepo(ctrl)
+	provider := mock_service.NewMockRateProvider(ctrl)
+
+	repo.EXPECT().GetDailyRates(gomock.Any(), "USD", date(17), date(20)).
+		Return([]entity.DailyRate{dailyRate(18, 61)}, nil)
+	for _, day := range []int{17, 19, 20} {
+		provider.EXPECT().RateOn(gomock.Any(), "USD", date(day)).Return(60.0, nil)
+		repo.EXPECT().SaveDailyRate(gomock.Any(), dailyRate(day, 60)).Return(nil)
+	}
+
+	fetched, err := NewDailyRates(repo, provider).Backfill(context.Background(), "USD", date(17), date(20).Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 3, fetched)
+}
+
+func TestHistoryService_Convert(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockRateRepo(ctrl)
+	provider := mock_service.NewMockRateProvider(ctrl)
+	history := NewHistoryService(mock_service.NewMockHistoryRepo(ctrl), NewDailyRates(repo, provider))
+
+	records := []entity.History{
+		{Id: 1, Amount: 600, Date: entity.CustomTime(date(18).Add(10 * time.Hour))},
+		{Id: 2, Amount: 620, Date: entity.CustomTime(date(20).Add(12 * time.Hour))},
+	}
+
+	repo.EXPECT().GetDailyRates(gomock.Any(), "USD", date(18), date(20)).
+		Return([]entity.DailyRate{dailyRate(18, 60), dailyRate(20, 62)}, nil)
+
+	got, err := history.Convert(context.Background(), records, "usd")
+	require.NoError(t, err)
+	assert.Equal(t, []entity.ConvertedHistory{
+		{History: records[0], Currency: "USD", Rate: 60, ConvertedAmount: 10},
+		{History: records[1], Currency: "USD", Rate: 62, ConvertedAmount: 10},
+	}, got)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"fmt"
+	"time"
+	"user-balance-service/internal/entity"
+)
+
+// Error is a domain error with a stable machine-readable code. Repositories
+// and services wrap these values with %w, so callers should match them with
+// errors.Is or extract the code with errors.As.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+var (
+	ErrAccountNotFound      = newError("account_not_found", "account not found")
+	ErrInsufficientFunds    = newError("insufficient_funds", "insufficient funds")
+	ErrInvalidAmount        = newError("invalid_amount", "amount must be greater than 0")
+	ErrSameAccount          = newError("same_account", "can't transfer money to the same account")
+	ErrUserNotFound         = newError("user_not_found", "user not found")
+	ErrUserAlreadyExists    = newError("user_already_exists", "user with this username already exists")
+	ErrInvalidCredentials   = newError("invalid_credentials", "invalid username or password")
+	ErrInvalidToken         = newError("invalid_token", "invalid or expired token")
+	ErrRefreshTokenReused   = newError("refresh_token_reused", "refresh token has already been used, sign in again")
+	ErrConverterUnavailable = newError("converter_unavailable", "currency converter is unavailable")
+	ErrUnknownCurrency      = newError("unknown_currency", "unknown currency")
+	ErrVersionMismatch      = newError("version_mismatch", "account was modified by another request")
+	ErrForbidden            = newError("forbidden", "access denied")
+	ErrAccountFrozen        = newError("account_frozen", "account is frozen")
+	ErrInvalidRole          = newError("invalid_role", "unknown role")
+	ErrInvalidApiKey        = newError("invalid_api_key", "invalid or revoked API key")
+	ErrApiKeyNotFound       = newError("api_key_not_found", "API key not found")
+	ErrInvalidScope         = newError("invalid_scope", "unknown permission in scopes")
+	ErrTooManyAttempts      = newError("too_many_attempts", "too many failed sign-in attempts, try again later")
+	ErrUserDisabled         = newError("user_disabled", "user is disabled")
+	ErrInvalidPassword      = newError("invalid_password", "current password is incorrect")
+	ErrTwoFactorRequired    = newError("two_factor_required", "a two-factor code is required")
+	ErrInvalidTwoFactorCode = newError("invalid_two_factor_code", "invalid two-factor code")
+	ErrInvalidChallenge     = newError("invalid_challenge", "invalid or expired sign-in challenge")
+	ErrTwoFactorEnabled     = newError("two_factor_enabled", "two-factor authentication is already enabled")
+	ErrTwoFactorDisabled    = newError("two_factor_disabled", "two-factor authentication is not enabled")
+)
+
+// VersionMismatchError is returned by a compare-and-swap update whose expected
+// version is stale. It carries the current account state.
+type VersionMismatchError struct {
+	Current entity.Account
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("%s: current version is %d", ErrVersionMismatch.Message, e.Current.Version)
+}
+
+func (e *VersionMismatchError) Unwrap() error {
+	return ErrVersionMismatch
+}
+
+// LockedOutError rejects a sign-in while the username or the IP address is
+// locked out after failed attempts. It matches ErrTooManyAttempts.
+type LockedOutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedOutError) Error() string {
+	return fmt.Sprintf("%s: retry after %s", ErrTooManyAttempts.Message, e.RetryAfter)
+}
+
+func (e *LockedOutError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// TwoFactorChallengeError stops a sign-in with a correct password until the
+// second factor is given with Challenge. It matches ErrTwoFactorRequired.
+type TwoFactorChallengeError struct {
+	Challenge string
+	ExpiresIn time.Duration
+}
+
+func (e *TwoFactorChallengeError) Error() string {
+	return ErrTwoFactorRequired.Message
+}
+
+func (e *TwoFactorChallengeError) Is(target error) bool {
+	return target == ErrTwoFactorRequired
+}
+
+// ConverterError is returned when no exchange rate could be got. It matches
+// ErrConverterUnavailable and unwraps to the provider error, e.g. a
+// webapi.StatusError, also from behind webapi.FallbackError.
+type ConverterError struct {
+	Err error
+}
+
+func (e *ConverterError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrConverterUnavailable.Message, e.Err)
+}
+
+func (e *ConverterError) Is(target error) bool {
+	return target == ErrConverterUnavailable
+}
+
+func (e *ConverterError) Unwrap() error {
+	return e.Err
+}
@@ -2,33 +2,97 @@ package service
 
 import (
 	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+	"time"
 	"user-balance-service/internal/entity"
+	"user-balance-service/pkg/tracing"
 )
 
 type HistoryService struct {
-	repo HistoryRepo
+	repo  HistoryRepo
+	rates *DailyRates
 }
 
-func NewHistoryService(repo HistoryRepo) *HistoryService {
-	return &HistoryService{repo: repo}
+func NewHistoryService(repo HistoryRepo, rates *DailyRates) *HistoryService {
+	return &HistoryService{
+		repo:  repo,
+		rates: rates,
+	}
 }
 
-func (h *HistoryService) ShowAll(ctx context.Context) ([]entity.History, error) {
+func (h *HistoryService) ShowAll(ctx context.Context) (_ []entity.History, err error) {
+	ctx, span := tracer.Start(ctx, "HistoryService.ShowAll")
+	defer tracing.End(span, &err)
+
 	return h.repo.ShowAll(ctx)
 }
 
-func (h *HistoryService) ShowById(ctx context.Context, id int) ([]entity.History, error) {
+func (h *HistoryService) ShowById(ctx context.Context, id int) (_ []entity.History, err error) {
+	ctx, span := tracer.Start(ctx, "HistoryService.ShowById", trace.WithAttributes(attribute.Int("account.id", id)))
+	defer tracing.End(span, &err)
+
 	return h.repo.ShowById(ctx, id)
 }
 
-func (h *HistoryService) ShowSorted(ctx context.Context, sortType string, accountId int) ([]entity.History, error) {
+func (h *HistoryService) ShowSorted(ctx context.Context, sortType string, accountId int) (_ []entity.History, err error) {
+	ctx, span := tracer.Start(ctx, "HistoryService.ShowSorted", trace.WithAttributes(
+		attribute.String("history.sort", sortType),
+		attribute.Int("account.id", accountId),
+	))
+	defer tracing.End(span, &err)
+
 	return h.repo.ShowSorted(ctx, sortType, accountId)
 }
 
-func (h *HistoryService) SaveHistory(ctx context.Context, input entity.History) (int, error) {
+func (h *HistoryService) SaveHistory(ctx context.Context, input entity.History) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "HistoryService.SaveHistory", trace.WithAttributes(attribute.Int("account.id", input.AccountId)))
+	defer tracing.End(span, &err)
+
 	return h.repo.SaveHistory(ctx, input)
 }
 
-func (h *HistoryService) Pagination(ctx context.Context, limit int, param string, accountId int) ([]entity.History, error) {
+func (h *HistoryService) Pagination(ctx context.Context, limit int, param string, accountId int) (_ []entity.History, err error) {
+	ctx, span := tracer.Start(ctx, "HistoryService.Pagination", trace.WithAttributes(
+		attribute.Int("history.limit", limit),
+		attribute.Int("account.id", accountId),
+	))
+	defer tracing.End(span, &err)
+
 	return h.repo.Pagination(ctx, limit, param, accountId)
 }
+
+// Convert converts the amount of every record with the rate of its date
+func (h *HistoryService) Convert(ctx context.Context, records []entity.History, currency string) (_ []entity.ConvertedHistory, err error) {
+	ctx, span := tracer.Start(ctx, "HistoryService.Convert", trace.WithAttributes(
+		attribute.String("currency", currency),
+		attribute.Int("history.records", len(records)),
+	))
+	defer tracing.End(span, &err)
+
+	currency = strings.ToUpper(currency)
+
+	days := make([]time.Time, 0, len(records))
+	for _, record := range records {
+		days = append(days, time.Time(record.Date))
+	}
+
+	rates, err := h.rates.On(ctx, currency, days)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]entity.ConvertedHistory, 0, len(records))
+	for _, record := range records {
+		rate := rates[dayKey(time.Time(record.Date))]
+		converted = append(converted, entity.ConvertedHistory{
+			History:         record,
+			Currency:        currency,
+			Rate:            rate,
+			ConvertedAmount: float64(record.Amount) / rate,
+		})
+	}
+
+	return converted, nil
+}
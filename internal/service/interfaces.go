@@ -2,26 +2,89 @@ package service
 
 import (
 	"context"
+	"github.com/golang-jwt/jwt"
+	"time"
 	"user-balance-service/internal/entity"
 )
 
 //go:generate mockgen -source=interfaces.go -destination=mock/mock.go
 
 type (
+	// Auth issues an access and a refresh token on sign-in, client is
+	// checked against lockouts and recorded in the login audit. RefreshToken
+	// exchanges a refresh token for a new pair, Logout revokes a refresh
+	// token and, if given, an access token. ChangePassword revokes every
+	// token of the user.
 	Auth interface {
 		CreateUser(context.Context, entity.User) (int, error)
-		GenerateToken(context.Context, string, string) (string, error)
-		ParseToken(token string) (int, error)
+		GenerateToken(ctx context.Context, username, password string, client entity.Client) (entity.Tokens, error)
+		RefreshToken(ctx context.Context, refreshToken string) (entity.Tokens, error)
+		Logout(ctx context.Context, refreshToken, accessToken string) error
+		ParseToken(ctx context.Context, token string) (entity.Actor, error)
+		ChangePassword(ctx context.Context, userId int, current, password string) error
+		CompleteSignIn(ctx context.Context, challenge, code string, client entity.Client) (entity.Tokens, error)
 	}
 
+	// TwoFactor manages the TOTP second factor of a user. Enrolment is
+	// confirmed with a code, which returns the recovery codes; a code is a
+	// TOTP code or an unused recovery code. AuthorizeTransfer asks for a
+	// code for transfers above the configured threshold.
+	TwoFactor interface {
+		TwoFactorStatus(ctx context.Context, userId int) (entity.TwoFactor, error)
+		EnrollTwoFactor(ctx context.Context, userId int) (entity.TwoFactorEnrolment, error)
+		ConfirmTwoFactor(ctx context.Context, userId int, code string) ([]string, error)
+		DisableTwoFactor(ctx context.Context, userId int, code string) error
+		RegenerateRecoveryCodes(ctx context.Context, userId int, code string) ([]string, error)
+		ResetTwoFactor(ctx context.Context, userId int) error
+		AuthorizeTransfer(ctx context.Context, actor entity.Actor, amount int, code string) error
+	}
+
+	// Users manages user accounts. UnlockUser and UnlockIP lift sign-in
+	// lockouts. Users are returned without their password hash.
+	// Deactivate and SetDisabled revoke every token of a disabled user.
+	Users interface {
+		Profile(ctx context.Context, id int) (entity.User, error)
+		ChangeUsername(ctx context.Context, id int, username string) error
+		Deactivate(ctx context.Context, id int) error
+		ListUsers(ctx context.Context, filter entity.UserFilter) ([]entity.User, error)
+		SetDisabled(ctx context.Context, id int, disabled bool) error
+		SetRole(ctx context.Context, id int, role entity.Role) error
+		UnlockUser(ctx context.Context, id int) error
+		UnlockIP(ctx context.Context, ip string) error
+	}
+
+	// ApiKeys manages the API keys of a user. The key itself is returned
+	// only on creation and rotation. ParseApiKey returns the actor a key
+	// acts as.
+	ApiKeys interface {
+		CreateApiKey(ctx context.Context, actor entity.Actor, name string, scopes []entity.Permission) (entity.ApiKey, string, error)
+		ListApiKeys(ctx context.Context, userId int) ([]entity.ApiKey, error)
+		RotateApiKey(ctx context.Context, userId, id int) (entity.ApiKey, string, error)
+		RevokeApiKey(ctx context.Context, userId, id int) error
+		ParseApiKey(ctx context.Context, key string) (entity.Actor, error)
+	}
+
+	// Account mutations take the expected account version for a compare-and-swap
+	// update; version 0 skips the check. For transfers it applies to idFrom.
+	// CheckAccess fails with ErrForbidden unless actor owns the account or is
+	// granted perm; API keys must be granted perm.
 	Account interface {
-		CreateAccount(ctx context.Context) (int, error)
-		WriteOff(ctx context.Context, id, amount int) error
+		CreateAccount(ctx context.Context, userId int) (int, error)
+		CheckAccess(ctx context.Context, actor entity.Actor, id int, perm entity.Permission) error
+		SetFrozen(ctx context.Context, id int, frozen bool) error
+		WriteOff(ctx context.Context, id, amount, version int) error
 		GetAccount(ctx context.Context, id int) (entity.Account, error)
-		MakeDeposit(ctx context.Context, id, amount int) error
-		TransferMoney(ctx context.Context, idFrom, idTo, amount int) error
-		ConvertToCurrency(ctx context.Context, currencyTo string, amount float64) (float64, error)
-		DeleteAccount(ctx context.Context, id int) error
+		MakeDeposit(ctx context.Context, id, amount, version int) error
+		TransferMoney(ctx context.Context, idFrom, idTo, amount, version int) error
+		ConvertToCurrency(ctx context.Context, currencyTo string, amount float64) (entity.Conversion, error)
+		DeleteAccount(ctx context.Context, id, version int) error
+	}
+
+	// Audit keeps the append-only log of mutating API calls. ListAudit
+	// returns records newest first.
+	Audit interface {
+		Record(ctx context.Context, record entity.AuditRecord) error
+		ListAudit(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditRecord, error)
 	}
 
 	History interface {
@@ -30,20 +93,84 @@ type (
 		ShowSorted(ctx context.Context, sortType string, accountId int) ([]entity.History, error)
 		Pagination(ctx context.Context, limit int, param string, accountId int) ([]entity.History, error)
 		SaveHistory(ctx context.Context, input entity.History) (int, error)
+		Convert(ctx context.Context, records []entity.History, currency string) ([]entity.ConvertedHistory, error)
 	}
 
+	// AuthRepo looks users up by username, the password hash is checked by
+	// the service
 	AuthRepo interface {
 		CreateUser(context.Context, entity.User) (int, error)
-		GetUser(context.Context, string, string) (entity.User, error)
+		GetUser(ctx context.Context, username string) (entity.User, error)
+		GetUserById(ctx context.Context, id int) (entity.User, error)
+		UpdatePasswordHash(ctx context.Context, id int, passwordHash string) error
+		UpdateRole(ctx context.Context, id int, role entity.Role) error
+		UpdateUsername(ctx context.Context, id int, username string) error
+		SetDisabled(ctx context.Context, id int, disabled bool) error
+		ListUsers(ctx context.Context, filter entity.UserFilter) ([]entity.User, error)
+	}
+
+	// TokenRepo stores refresh tokens by the SHA-256 of their value.
+	// RotateRefreshToken fails with ErrRefreshTokenReused if the token has
+	// already been revoked.
+	TokenRepo interface {
+		CreateRefreshToken(ctx context.Context, token entity.RefreshToken) error
+		GetRefreshToken(ctx context.Context, id string) (entity.RefreshToken, error)
+		RotateRefreshToken(ctx context.Context, id string, next entity.RefreshToken) error
+		RevokeRefreshToken(ctx context.Context, id string) error
+		RevokeTokenFamily(ctx context.Context, familyId string) error
+		RevokeUserTokens(ctx context.Context, userId int) error
+	}
+
+	// TokenRevoker revokes every refresh and access token of a user.
+	// Implemented by AuthService.
+	TokenRevoker interface {
+		RevokeUserTokens(ctx context.Context, userId int) error
+	}
+
+	// TwoFactorRepo stores TOTP secrets in users and recovery codes by their
+	// SHA-256. UseTotpStep and UseRecoveryCode report false for a step not
+	// later than the last one used and for a used or unknown code.
+	TwoFactorRepo interface {
+		GetTwoFactor(ctx context.Context, userId int) (entity.TwoFactor, error)
+		SetTotpSecret(ctx context.Context, userId int, secret string) error
+		EnableTwoFactor(ctx context.Context, userId int, step int64, codeHashes []string) error
+		RemoveTwoFactor(ctx context.Context, userId int) error
+		UseTotpStep(ctx context.Context, userId int, step int64) (bool, error)
+		UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error)
+		ReplaceRecoveryCodes(ctx context.Context, userId int, codeHashes []string) error
+	}
+
+	// LoginAuditRepo records sign-in attempts
+	LoginAuditRepo interface {
+		SaveLoginAttempt(ctx context.Context, attempt entity.LoginAttempt) error
+	}
+
+	// AuditRepo appends to the audit log; the table doesn't allow changing
+	// or deleting records
+	AuditRepo interface {
+		SaveAuditRecord(ctx context.Context, record entity.AuditRecord) error
+		ListAuditRecords(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditRecord, error)
+	}
+
+	// ApiKeyRepo stores API keys by the SHA-256 of their value. GetApiKey
+	// returns only active keys, along with the role of their owner.
+	ApiKeyRepo interface {
+		CreateApiKey(ctx context.Context, key entity.ApiKey) (entity.ApiKey, error)
+		GetApiKey(ctx context.Context, hash string) (entity.ApiKey, error)
+		ListApiKeys(ctx context.Context, userId int) ([]entity.ApiKey, error)
+		RotateApiKey(ctx context.Context, userId, id int, prefix, hash string) (entity.ApiKey, error)
+		RevokeApiKey(ctx context.Context, userId, id int) error
+		TouchApiKey(ctx context.Context, id int, usedAt time.Time) error
 	}
 
 	AccountRepo interface {
-		CreateAccount(ctx context.Context) (int, error)
-		WriteOff(ctx context.Context, id, amount int) error
+		CreateAccount(ctx context.Context, userId int) (int, error)
+		SetFrozen(ctx context.Context, id int, frozen bool) error
+		WriteOff(ctx context.Context, id, amount, version int) error
 		GetAccount(ctx context.Context, id int) (entity.Account, error)
-		MakeDeposit(ctx context.Context, id, amount int) error
-		TransferMoney(ctx context.Context, idFrom, idTo, amount int) error
-		DeleteAccount(ctx context.Context, id int) error
+		MakeDeposit(ctx context.Context, id, amount, version int) error
+		TransferMoney(ctx context.Context, idFrom, idTo, amount, version int) error
+		DeleteAccount(ctx context.Context, id, version int) error
 	}
 
 	HistoryRepo interface {
@@ -54,12 +181,87 @@ type (
 		SaveHistory(ctx context.Context, input entity.History) (int, error)
 	}
 
+	// RateRepo stores the daily rates fetched for history conversion
+	RateRepo interface {
+		GetDailyRates(ctx context.Context, currency string, from, to time.Time) ([]entity.DailyRate, error)
+		SaveDailyRate(ctx context.Context, rate entity.DailyRate) error
+	}
+
+	// RedisCache is implemented by rediscache.Redis, memcache.Memory,
+	// memcache.Tiered and memcache.Nop. Get decodes into dst and returns
+	// cache.ErrMiss on a miss; use the typed cache.Get and cache.Set helpers.
 	RedisCache interface {
 		Set(ctx context.Context, key string, value any) error
-		Get(ctx context.Context, key string) (any, error)
+		Get(ctx context.Context, key string, dst any) error
+		Delete(ctx context.Context, keys ...string) error
+		Incr(ctx context.Context, key string) (int64, error)
+		Counter(ctx context.Context, key string) (int64, error)
+	}
+
+	// Denylist holds the ids of revoked access tokens until they expire.
+	// DenyUser revokes the tokens of a user issued before a time. Implemented
+	// by repo.DenylistRepo.
+	Denylist interface {
+		Deny(ctx context.Context, jti string, ttl time.Duration) error
+		DenyUser(ctx context.Context, userId int, before time.Time, ttl time.Duration) error
+		IsDenied(ctx context.Context, jti string, userId int, issuedAt time.Time) (bool, error)
+	}
+
+	// SignInChallenges holds sign-ins waiting for the second factor until
+	// they expire. AttemptChallenge counts a code tried against a challenge
+	// and fails with ErrInvalidChallenge for an unknown one. Implemented by
+	// repo.ChallengeRepo.
+	SignInChallenges interface {
+		CreateChallenge(ctx context.Context, id string, challenge entity.SignInChallenge, ttl time.Duration) error
+		AttemptChallenge(ctx context.Context, id string) (entity.SignInChallenge, error)
+		DeleteChallenge(ctx context.Context, id string) error
+	}
+
+	// AccountMetrics counts money operations: Operation is called after a
+	// successful one, InsufficientFunds when one is rejected for lack of
+	// money. Implemented by metrics.Metrics.
+	AccountMetrics interface {
+		Operation(operation string, amount int)
+		InsufficientFunds(operation string)
+	}
+
+	// LoginAttempts counts failed sign-ins per key until window passes
+	// without one and holds lockouts. Reset clears both. Implemented by
+	// repo.LoginAttemptsRepo.
+	LoginAttempts interface {
+		AddFailure(ctx context.Context, key string, window time.Duration) (int64, error)
+		RemoveFailure(ctx context.Context, key string) error
+		TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error)
+		Unlock(ctx context.Context, key string) error
+		LockedFor(ctx context.Context, key string) (time.Duration, error)
+		Reset(ctx context.Context, key string) error
+	}
+
+	// TokenKeys signs access tokens and returns the key a token is verified
+	// with. Implemented by jwtkeys.Set.
+	TokenKeys interface {
+		Sign(claims jwt.Claims) (string, error)
+		Keyfunc(token *jwt.Token) (interface{}, error)
+	}
+
+	// Rates returns the price of one unit of currency in rubles. Implemented
+	// by RateStore.
+	Rates interface {
+		Rate(ctx context.Context, currency string) (entity.Rate, error)
+	}
+
+	// PasswordHasher is implemented by passhash.Hasher. Verify reports with
+	// rehash that the hash should be replaced with one made by Hash.
+	PasswordHasher interface {
+		Hash(password string) (string, error)
+		Verify(password, hash string) (ok, rehash bool, err error)
 	}
 
-	ConverterWEBAPI interface {
-		ConvertToCurrency(ctx context.Context, currencyTo string, amount float64) (float64, error)
+	// RateProvider returns the price of one unit of currency in rubles, the
+	// latest one or the one that applied on date. Implemented by the webapi
+	// providers.
+	RateProvider interface {
+		Rate(ctx context.Context, currency string) (float64, error)
+		RateOn(ctx context.Context, currency string, date time.Time) (float64, error)
 	}
 )
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	defaultMaxUserFailures = 5
+	defaultMaxIPFailures   = 20
+	defaultFailureWindow   = 24 * time.Hour
+	defaultLockoutBase     = time.Minute
+	defaultLockoutMax      = time.Hour
+)
+
+type LoginGuardOption func(g *LoginGuard)
+
+// MaxFailures sets how many failed sign-ins lock out a username, 5 by
+// default, and an IP address, 20 by default
+func MaxFailures(user, ip int) LoginGuardOption {
+	return func(g *LoginGuard) {
+		g.maxUserFailures = user
+		g.maxIPFailures = ip
+	}
+}
+
+// FailureWindow sets how long failures are counted after the last one,
+// 24 hours by default
+func FailureWindow(window time.Duration) LoginGuardOption {
+	return func(g *LoginGuard) {
+		g.window = window
+	}
+}
+
+// Lockout sets the first lockout, 1 minute by default, and the longest one,
+// 1 hour by default
+func Lockout(base, max time.Duration) LoginGuardOption {
+	return func(g *LoginGuard) {
+		g.lockoutBase = base
+		g.lockoutMax = max
+	}
+}
+
+// LoginGuard counts failed sign-ins per username and per IP address. Once
+// either is past its limit it's locked out, and every further failure
+// doubles the lockout. Usernames are counted whether they exist or not, so
+// lockouts don't tell which do. If the counters can't be reached sign-ins
+// aren't limited.
+//
+// An attempt is counted as failed before the password is checked and taken
+// back once it turns out right, so parallel guesses can't all slip in before
+// the first of them fails: each gets its own count, and the one reaching the
+// limit takes the lock that keeps the others out.
+type LoginGuard struct {
+	attempts LoginAttempts
+
+	maxUserFailures int
+	maxIPFailures   int
+	window          time.Duration
+	lockoutBase     time.Duration
+	lockoutMax      time.Duration
+}
+
+func NewLoginGuard(attempts LoginAttempts, opts ...LoginGuardOption) *LoginGuard {
+	g := &LoginGuard{
+		attempts:        attempts,
+		maxUserFailures: defaultMaxUserFailures,
+		maxIPFailures:   defaultMaxIPFailures,
+		window:          defaultFailureWindow,
+		lockoutBase:     defaultLockoutBase,
+		lockoutMax:      defaultLockoutMax,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// LoginReservation is an attempt counted as failed in advance. A nil
+// reservation, e.g. of an AuthService without a guard, does nothing.
+type LoginReservation struct {
+	guard *LoginGuard
+	keys  []reservedKey
+}
+
+type reservedKey struct {
+	key    string
+	locked bool
+}
+
+// Reserve counts an attempt of username from ip, ip may be empty, as failed
+// and fails with a *LockedOutError while either is locked out. The caller
+// must Release the reservation if the attempt turns out right.
+func (g *LoginGuard) Reserve(ctx context.Context, username, ip string) (*LoginReservation, error) {
+	limits := g.limits(username, ip)
+
+	var retryAfter time.Duration
+	for _, limit := range limits {
+		ttl, err := g.attempts.LockedFor(ctx, limit.key)
+		if err != nil {
+			log.Errorf("service - LoginGuard - Reserve - g.attempts.LockedFor: %s", err)
+			continue
+		}
+		if ttl > retryAfter {
+			retryAfter = ttl
+		}
+	}
+	if retryAfter > 0 {
+		return nil, &LockedOutError{RetryAfter: retryAfter}
+	}
+
+	r := &LoginReservation{guard: g}
+	for _, limit := range limits {
+		reserved, ttl := g.reserve(ctx, limit)
+		if ttl > 0 {
+			r.Release(ctx)
+			return nil, &LockedOutError{RetryAfter: ttl}
+		}
+		if reserved != nil {
+			r.keys = append(r.keys, *reserved)
+		}
+	}
+
+	return r, nil
+}
+
+// reserve counts a failure of limit.key. The failure reaching the limit
+// locks the key out right away; if another attempt has taken the lock
+// meanwhile the failure is taken back and the lockout returned instead.
+func (g *LoginGuard) reserve(ctx context.Context, limit loginLimit) (*reservedKey, time.Duration) {
+	n, err := g.attempts.AddFailure(ctx, limit.key, g.window)
+	if err != nil {
+		log.Errorf("service - LoginGuard - reserve - g.attempts.AddFailure: %s", err)
+		return nil, 0
+	}
+	if n < int64(limit.max) {
+		return &reservedKey{key: limit.key}, 0
+	}
+
+	lockout := g.lockout(n - int64(limit.max))
+	ok, err := g.attempts.TryLock(ctx, limit.key, lockout)
+	if err != nil {
+		log.Errorf("service - LoginGuard - reserve - g.attempts.TryLock: %s", err)
+		return &reservedKey{key: limit.key}, 0
+	}
+	if ok {
+		return &reservedKey{key: limit.key, locked: true}, 0
+	}
+
+	g.removeFailure(ctx, limit.key)
+
+	ttl, err := g.attempts.LockedFor(ctx, limit.key)
+	if err != nil || ttl <= 0 {
+		ttl = lockout
+	}
+	return nil, ttl
+}
+
+// Release takes back the failure counted by Reserve and the lock it took
+func (r *LoginReservation) Release(ctx context.Context) {
+	if r == nil {
+		return
+	}
+
+	for _, k := range r.keys {
+		r.guard.removeFailure(ctx, k.key)
+		if !k.locked {
+			continue
+		}
+
+		err := r.guard.attempts.Unlock(ctx, k.key)
+		if err != nil {
+			log.Errorf("service - LoginGuard - Release - r.guard.attempts.Unlock: %s", err)
+		}
+	}
+	r.keys = nil
+}
+
+// Succeed clears the failures of username. Those of the IP address are
+// kept: signing in to an account of one's own mustn't reset them.
+func (g *LoginGuard) Succeed(ctx context.Context, username string) {
+	err := g.attempts.Reset(ctx, userLoginKey(username))
+	if err != nil {
+		log.Errorf("service - LoginGuard - Succeed - g.attempts.Reset: %s", err)
+	}
+}
+
+func (g *LoginGuard) UnlockUser(ctx context.Context, username string) error {
+	return g.attempts.Reset(ctx, userLoginKey(username))
+}
+
+func (g *LoginGuard) UnlockIP(ctx context.Context, ip string) error {
+	return g.attempts.Reset(ctx, ipLoginKey(ip))
+}
+
+func (g *LoginGuard) removeFailure(ctx context.Context, key string) {
+	err := g.attempts.RemoveFailure(ctx, key)
+	if err != nil {
+		log.Errorf("service - LoginGuard - removeFailure - g.attempts.RemoveFailure: %s", err)
+	}
+}
+
+// lockout doubles the first lockout for every failure past the limit
+func (g *LoginGuard) lockout(excess int64) time.Duration {
+	d := g.lockoutBase
+	for i := int64(0); i < excess && d < g.lockoutMax; i++ {
+		d *= 2
+	}
+	if d > g.lockoutMax {
+		d = g.lockoutMax
+	}
+
+	return d
+}
+
+func userLoginKey(username string) string {
+	return "user_" + username
+}
+
+func ipLoginKey(ip string) string {
+	return "ip_" + ip
+}
+
+// loginLimit is the number of failures that locks out key
+type loginLimit struct {
+	key string
+	max int
+}
+
+func (g *LoginGuard) limits(username, ip string) []loginLimit {
+	limits := []loginLimit{{key: userLoginKey(username), max: g.maxUserFailures}}
+	if ip != "" {
+		limits = append(limits, loginLimit{key: ipLoginKey(ip), max: g.maxIPFailures})
+	}
+
+	return limits
+}
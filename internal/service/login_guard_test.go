@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+func TestLoginGuard_Reserve(t *testing.T) {
+	testCases := []struct {
+		name        string
+		failures    int64
+		wantLockout time.Duration
+	}{
+		{
+			name:     "below the limit",
+			failures: 4,
+		},
+		{
+			name:        "at the limit",
+			failures:    5,
+			wantLockout: time.Minute,
+		},
+		{
+			name:        "past the limit",
+			failures:    7,
+			wantLockout: 4 * time.Minute,
+		},
+		{
+			name:        "capped",
+			failures:    100,
+			wantLockout: time.Hour,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			attempts := mock_service.NewMockLoginAttempts(ctrl)
+			g := NewLoginGuard(attempts)
+			ctx := context.Background()
+
+			attempts.EXPECT().LockedFor(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil).Times(2)
+			attempts.EXPECT().AddFailure(gomock.Any(), "user_qwe", 24*time.Hour).Return(tc.failures, nil)
+			attempts.EXPECT().AddFailure(gomock.Any(), "ip_192.0.2.1", 24*time.Hour).Return(int64(1), nil)
+			if tc.wantLockout != 0 {
+				// the lock is taken before the password is checked
+				attempts.EXPECT().TryLock(gomock.Any(), "user_qwe", tc.wantLockout).Return(true, nil)
+			}
+
+			r, err := g.Reserve(ctx, "qwe", "192.0.2.1")
+			require.NoError(t, err)
+
+			// a right password takes back the failures and the lock
+			attempts.EXPECT().RemoveFailure(gomock.Any(), "user_qwe").Return(nil)
+			attempts.EXPECT().RemoveFailure(gomock.Any(), "ip_192.0.2.1").Return(nil)
+			if tc.wantLockout != 0 {
+				attempts.EXPECT().Unlock(gomock.Any(), "user_qwe").Return(nil)
+			}
+			r.Release(ctx)
+
+			// releasing twice does nothing
+			r.Release(ctx)
+		})
+	}
+}
+
+func TestLoginGuard_Reserve_LockedOut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	attempts := mock_service.NewMockLoginAttempts(ctrl)
+	g := NewLoginGuard(attempts)
+	ctx := context.Background()
+
+	// the longest lockout is reported and nothing is counted
+	attempts.EXPECT().LockedFor(gomock.Any(), "user_qwe").Return(time.Minute, nil)
+	attempts.EXPECT().LockedFor(gomock.Any(), "ip_192.0.2.1").Return(2*time.Minute, nil)
+	_, err := g.Reserve(ctx, "qwe", "192.0.2.1")
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	var lockedOutErr *LockedOutError
+	if assert.ErrorAs(t, err, &lockedOutErr) {
+		assert.Equal(t, 2*time.Minute, lockedOutErr.RetryAfter)
+	}
+
+	// another attempt took the lock meanwhile: the failures are taken back
+	attempts.EXPECT().LockedFor(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil).Times(2)
+	attempts.EXPECT().AddFailure(gomock.Any(), "user_qwe", gomock.Any()).Return(int64(1), nil)
+	attempts.EXPECT().AddFailure(gomock.Any(), "ip_192.0.2.1", gomock.Any()).Return(int64(21), nil)
+	attempts.EXPECT().TryLock(gomock.Any(), "ip_192.0.2.1", 2*time.Minute).Return(false, nil)
+	attempts.EXPECT().RemoveFailure(gomock.Any(), "ip_192.0.2.1").Return(nil)
+	attempts.EXPECT().LockedFor(gomock.Any(), "ip_192.0.2.1").Return(90*time.Second, nil)
+	attempts.EXPECT().RemoveFailure(gomock.Any(), "user_qwe").Return(nil)
+	_, err = g.Reserve(ctx, "qwe", "192.0.2.1")
+	if assert.ErrorAs(t, err, &lockedOutErr) {
+		assert.Equal(t, 90*time.Second, lockedOutErr.RetryAfter)
+	}
+
+	// unavailable counters don't lock anyone out
+	attempts.EXPECT().LockedFor(gomock.Any(), gomock.Any()).Return(time.Duration(0), errors.New("connection refused")).Times(2)
+	attempts.EXPECT().AddFailure(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), errors.New("connection refused")).Times(2)
+	r, err := g.Reserve(ctx, "qwe", "192.0.2.1")
+	assert.NoError(t, err)
+	r.Release(ctx)
+}
+
+// memoryLoginAttempts is LoginAttempts without expiry
+type memoryLoginAttempts struct {
+	mu       sync.Mutex
+	failures map[string]int64
+	locks    map[string]time.Duration
+}
+
+func (m *memoryLoginAttempts) AddFailure(_ context.Context, key string, _ time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures[key]++
+	return m.failures[key], nil
+}
+
+func (m *memoryLoginAttempts) RemoveFailure(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures[key]--
+	return nil
+}
+
+func (m *memoryLoginAttempts) TryLock(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.locks[key]; ok {
+		return false, nil
+	}
+	m.locks[key] = ttl
+	return true, nil
+}
+
+func (m *memoryLoginAttempts) Unlock(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.locks, key)
+	return nil
+}
+
+func (m *memoryLoginAttempts) LockedFor(_ context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.locks[key], nil
+}
+
+func (m *memoryLoginAttempts) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.failures, key)
+	delete(m.locks, key)
+	return nil
+}
+
+func TestLoginGuard_Reserve_ParallelGuesses(t *testing.T) {
+	attempts := &memoryLoginAttempts{failures: make(map[string]int64), locks: make(map[string]time.Duration)}
+	g := NewLoginGuard(attempts)
+
+	var (
+		wg       sync.WaitGroup
+		reserved int32
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := g.Reserve(context.Background(), "qwe", "")
+			if err == nil {
+				atomic.AddInt32(&reserved, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// only as many guesses as the limit get to check the password
+	assert.Equal(t, int32(defaultMaxUserFailures), reserved)
+	assert.Equal(t, int64(defaultMaxUserFailures), attempts.failures["user_qwe"])
+}
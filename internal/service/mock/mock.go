@@ -7,8 +7,10 @@ package mock_service
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 	entity "user-balance-service/internal/entity"
 
+	jwt "github.com/golang-jwt/jwt"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -35,6 +37,35 @@ func (m *MockAuth) EXPECT() *MockAuthMockRecorder {
 	return m.recorder
 }
 
+// ChangePassword mocks base method.
+func (m *MockAuth) ChangePassword(ctx context.Context, userId int, current, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, userId, current, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockAuthMockRecorder) ChangePassword(ctx, userId, current, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockAuth)(nil).ChangePassword), ctx, userId, current, password)
+}
+
+// CompleteSignIn mocks base method.
+func (m *MockAuth) CompleteSignIn(ctx context.Context, challenge, code string, client entity.Client) (entity.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteSignIn", ctx, challenge, code, client)
+	ret0, _ := ret[0].(entity.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteSignIn indicates an expected call of CompleteSignIn.
+func (mr *MockAuthMockRecorder) CompleteSignIn(ctx, challenge, code, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteSignIn", reflect.TypeOf((*MockAuth)(nil).CompleteSignIn), ctx, challenge, code, client)
+}
+
 // CreateUser mocks base method.
 func (m *MockAuth) CreateUser(arg0 context.Context, arg1 entity.User) (int, error) {
 	m.ctrl.T.Helper()
@@ -51,33 +82,423 @@ func (mr *MockAuthMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call
 }
 
 // GenerateToken mocks base method.
-func (m *MockAuth) GenerateToken(arg0 context.Context, arg1, arg2 string) (string, error) {
+func (m *MockAuth) GenerateToken(ctx context.Context, username, password string, client entity.Client) (entity.Tokens, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateToken", arg0, arg1, arg2)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "GenerateToken", ctx, username, password, client)
+	ret0, _ := ret[0].(entity.Tokens)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GenerateToken indicates an expected call of GenerateToken.
-func (mr *MockAuthMockRecorder) GenerateToken(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockAuthMockRecorder) GenerateToken(ctx, username, password, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateToken", reflect.TypeOf((*MockAuth)(nil).GenerateToken), ctx, username, password, client)
+}
+
+// Logout mocks base method.
+func (m *MockAuth) Logout(ctx context.Context, refreshToken, accessToken string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", ctx, refreshToken, accessToken)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockAuthMockRecorder) Logout(ctx, refreshToken, accessToken interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateToken", reflect.TypeOf((*MockAuth)(nil).GenerateToken), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockAuth)(nil).Logout), ctx, refreshToken, accessToken)
 }
 
 // ParseToken mocks base method.
-func (m *MockAuth) ParseToken(token string) (int, error) {
+func (m *MockAuth) ParseToken(ctx context.Context, token string) (entity.Actor, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ParseToken", token)
-	ret0, _ := ret[0].(int)
+	ret := m.ctrl.Call(m, "ParseToken", ctx, token)
+	ret0, _ := ret[0].(entity.Actor)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ParseToken indicates an expected call of ParseToken.
-func (mr *MockAuthMockRecorder) ParseToken(token interface{}) *gomock.Call {
+func (mr *MockAuthMockRecorder) ParseToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseToken", reflect.TypeOf((*MockAuth)(nil).ParseToken), ctx, token)
+}
+
+// RefreshToken mocks base method.
+func (m *MockAuth) RefreshToken(ctx context.Context, refreshToken string) (entity.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshToken", ctx, refreshToken)
+	ret0, _ := ret[0].(entity.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshToken indicates an expected call of RefreshToken.
+func (mr *MockAuthMockRecorder) RefreshToken(ctx, refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockAuth)(nil).RefreshToken), ctx, refreshToken)
+}
+
+// MockTwoFactor is a mock of TwoFactor interface.
+type MockTwoFactor struct {
+	ctrl     *gomock.Controller
+	recorder *MockTwoFactorMockRecorder
+}
+
+// MockTwoFactorMockRecorder is the mock recorder for MockTwoFactor.
+type MockTwoFactorMockRecorder struct {
+	mock *MockTwoFactor
+}
+
+// NewMockTwoFactor creates a new mock instance.
+func NewMockTwoFactor(ctrl *gomock.Controller) *MockTwoFactor {
+	mock := &MockTwoFactor{ctrl: ctrl}
+	mock.recorder = &MockTwoFactorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTwoFactor) EXPECT() *MockTwoFactorMockRecorder {
+	return m.recorder
+}
+
+// AuthorizeTransfer mocks base method.
+func (m *MockTwoFactor) AuthorizeTransfer(ctx context.Context, actor entity.Actor, amount int, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthorizeTransfer", ctx, actor, amount, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AuthorizeTransfer indicates an expected call of AuthorizeTransfer.
+func (mr *MockTwoFactorMockRecorder) AuthorizeTransfer(ctx, actor, amount, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthorizeTransfer", reflect.TypeOf((*MockTwoFactor)(nil).AuthorizeTransfer), ctx, actor, amount, code)
+}
+
+// ConfirmTwoFactor mocks base method.
+func (m *MockTwoFactor) ConfirmTwoFactor(ctx context.Context, userId int, code string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTwoFactor", ctx, userId, code)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmTwoFactor indicates an expected call of ConfirmTwoFactor.
+func (mr *MockTwoFactorMockRecorder) ConfirmTwoFactor(ctx, userId, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTwoFactor", reflect.TypeOf((*MockTwoFactor)(nil).ConfirmTwoFactor), ctx, userId, code)
+}
+
+// DisableTwoFactor mocks base method.
+func (m *MockTwoFactor) DisableTwoFactor(ctx context.Context, userId int, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTwoFactor", ctx, userId, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTwoFactor indicates an expected call of DisableTwoFactor.
+func (mr *MockTwoFactorMockRecorder) DisableTwoFactor(ctx, userId, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTwoFactor", reflect.TypeOf((*MockTwoFactor)(nil).DisableTwoFactor), ctx, userId, code)
+}
+
+// EnrollTwoFactor mocks base method.
+func (m *MockTwoFactor) EnrollTwoFactor(ctx context.Context, userId int) (entity.TwoFactorEnrolment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollTwoFactor", ctx, userId)
+	ret0, _ := ret[0].(entity.TwoFactorEnrolment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollTwoFactor indicates an expected call of EnrollTwoFactor.
+func (mr *MockTwoFactorMockRecorder) EnrollTwoFactor(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTwoFactor", reflect.TypeOf((*MockTwoFactor)(nil).EnrollTwoFactor), ctx, userId)
+}
+
+// RegenerateRecoveryCodes mocks base method.
+func (m *MockTwoFactor) RegenerateRecoveryCodes(ctx context.Context, userId int, code string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegenerateRecoveryCodes", ctx, userId, code)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegenerateRecoveryCodes indicates an expected call of RegenerateRecoveryCodes.
+func (mr *MockTwoFactorMockRecorder) RegenerateRecoveryCodes(ctx, userId, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegenerateRecoveryCodes", reflect.TypeOf((*MockTwoFactor)(nil).RegenerateRecoveryCodes), ctx, userId, code)
+}
+
+// ResetTwoFactor mocks base method.
+func (m *MockTwoFactor) ResetTwoFactor(ctx context.Context, userId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetTwoFactor", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetTwoFactor indicates an expected call of ResetTwoFactor.
+func (mr *MockTwoFactorMockRecorder) ResetTwoFactor(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetTwoFactor", reflect.TypeOf((*MockTwoFactor)(nil).ResetTwoFactor), ctx, userId)
+}
+
+// TwoFactorStatus mocks base method.
+func (m *MockTwoFactor) TwoFactorStatus(ctx context.Context, userId int) (entity.TwoFactor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TwoFactorStatus", ctx, userId)
+	ret0, _ := ret[0].(entity.TwoFactor)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TwoFactorStatus indicates an expected call of TwoFactorStatus.
+func (mr *MockTwoFactorMockRecorder) TwoFactorStatus(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TwoFactorStatus", reflect.TypeOf((*MockTwoFactor)(nil).TwoFactorStatus), ctx, userId)
+}
+
+// MockUsers is a mock of Users interface.
+type MockUsers struct {
+	ctrl     *gomock.Controller
+	recorder *MockUsersMockRecorder
+}
+
+// MockUsersMockRecorder is the mock recorder for MockUsers.
+type MockUsersMockRecorder struct {
+	mock *MockUsers
+}
+
+// NewMockUsers creates a new mock instance.
+func NewMockUsers(ctrl *gomock.Controller) *MockUsers {
+	mock := &MockUsers{ctrl: ctrl}
+	mock.recorder = &MockUsersMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUsers) EXPECT() *MockUsersMockRecorder {
+	return m.recorder
+}
+
+// ChangeUsername mocks base method.
+func (m *MockUsers) ChangeUsername(ctx context.Context, id int, username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeUsername", ctx, id, username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangeUsername indicates an expected call of ChangeUsername.
+func (mr *MockUsersMockRecorder) ChangeUsername(ctx, id, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeUsername", reflect.TypeOf((*MockUsers)(nil).ChangeUsername), ctx, id, username)
+}
+
+// Deactivate mocks base method.
+func (m *MockUsers) Deactivate(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Deactivate", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Deactivate indicates an expected call of Deactivate.
+func (mr *MockUsersMockRecorder) Deactivate(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deactivate", reflect.TypeOf((*MockUsers)(nil).Deactivate), ctx, id)
+}
+
+// ListUsers mocks base method.
+func (m *MockUsers) ListUsers(ctx context.Context, filter entity.UserFilter) ([]entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", ctx, filter)
+	ret0, _ := ret[0].([]entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockUsersMockRecorder) ListUsers(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockUsers)(nil).ListUsers), ctx, filter)
+}
+
+// Profile mocks base method.
+func (m *MockUsers) Profile(ctx context.Context, id int) (entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Profile", ctx, id)
+	ret0, _ := ret[0].(entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Profile indicates an expected call of Profile.
+func (mr *MockUsersMockRecorder) Profile(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Profile", reflect.TypeOf((*MockUsers)(nil).Profile), ctx, id)
+}
+
+// SetDisabled mocks base method.
+func (m *MockUsers) SetDisabled(ctx context.Context, id int, disabled bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDisabled", ctx, id, disabled)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDisabled indicates an expected call of SetDisabled.
+func (mr *MockUsersMockRecorder) SetDisabled(ctx, id, disabled interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDisabled", reflect.TypeOf((*MockUsers)(nil).SetDisabled), ctx, id, disabled)
+}
+
+// SetRole mocks base method.
+func (m *MockUsers) SetRole(ctx context.Context, id int, role entity.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRole", ctx, id, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRole indicates an expected call of SetRole.
+func (mr *MockUsersMockRecorder) SetRole(ctx, id, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRole", reflect.TypeOf((*MockUsers)(nil).SetRole), ctx, id, role)
+}
+
+// UnlockIP mocks base method.
+func (m *MockUsers) UnlockIP(ctx context.Context, ip string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockIP", ctx, ip)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlockIP indicates an expected call of UnlockIP.
+func (mr *MockUsersMockRecorder) UnlockIP(ctx, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockIP", reflect.TypeOf((*MockUsers)(nil).UnlockIP), ctx, ip)
+}
+
+// UnlockUser mocks base method.
+func (m *MockUsers) UnlockUser(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlockUser indicates an expected call of UnlockUser.
+func (mr *MockUsersMockRecorder) UnlockUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockUser", reflect.TypeOf((*MockUsers)(nil).UnlockUser), ctx, id)
+}
+
+// MockApiKeys is a mock of ApiKeys interface.
+type MockApiKeys struct {
+	ctrl     *gomock.Controller
+	recorder *MockApiKeysMockRecorder
+}
+
+// MockApiKeysMockRecorder is the mock recorder for MockApiKeys.
+type MockApiKeysMockRecorder struct {
+	mock *MockApiKeys
+}
+
+// NewMockApiKeys creates a new mock instance.
+func NewMockApiKeys(ctrl *gomock.Controller) *MockApiKeys {
+	mock := &MockApiKeys{ctrl: ctrl}
+	mock.recorder = &MockApiKeysMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockApiKeys) EXPECT() *MockApiKeysMockRecorder {
+	return m.recorder
+}
+
+// CreateApiKey mocks base method.
+func (m *MockApiKeys) CreateApiKey(ctx context.Context, actor entity.Actor, name string, scopes []entity.Permission) (entity.ApiKey, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateApiKey", ctx, actor, name, scopes)
+	ret0, _ := ret[0].(entity.ApiKey)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateApiKey indicates an expected call of CreateApiKey.
+func (mr *MockApiKeysMockRecorder) CreateApiKey(ctx, actor, name, scopes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateApiKey", reflect.TypeOf((*MockApiKeys)(nil).CreateApiKey), ctx, actor, name, scopes)
+}
+
+// ListApiKeys mocks base method.
+func (m *MockApiKeys) ListApiKeys(ctx context.Context, userId int) ([]entity.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListApiKeys", ctx, userId)
+	ret0, _ := ret[0].([]entity.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListApiKeys indicates an expected call of ListApiKeys.
+func (mr *MockApiKeysMockRecorder) ListApiKeys(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListApiKeys", reflect.TypeOf((*MockApiKeys)(nil).ListApiKeys), ctx, userId)
+}
+
+// ParseApiKey mocks base method.
+func (m *MockApiKeys) ParseApiKey(ctx context.Context, key string) (entity.Actor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseApiKey", ctx, key)
+	ret0, _ := ret[0].(entity.Actor)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseApiKey indicates an expected call of ParseApiKey.
+func (mr *MockApiKeysMockRecorder) ParseApiKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseApiKey", reflect.TypeOf((*MockApiKeys)(nil).ParseApiKey), ctx, key)
+}
+
+// RevokeApiKey mocks base method.
+func (m *MockApiKeys) RevokeApiKey(ctx context.Context, userId, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeApiKey", ctx, userId, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeApiKey indicates an expected call of RevokeApiKey.
+func (mr *MockApiKeysMockRecorder) RevokeApiKey(ctx, userId, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeApiKey", reflect.TypeOf((*MockApiKeys)(nil).RevokeApiKey), ctx, userId, id)
+}
+
+// RotateApiKey mocks base method.
+func (m *MockApiKeys) RotateApiKey(ctx context.Context, userId, id int) (entity.ApiKey, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateApiKey", ctx, userId, id)
+	ret0, _ := ret[0].(entity.ApiKey)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// RotateApiKey indicates an expected call of RotateApiKey.
+func (mr *MockApiKeysMockRecorder) RotateApiKey(ctx, userId, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseToken", reflect.TypeOf((*MockAuth)(nil).ParseToken), token)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateApiKey", reflect.TypeOf((*MockApiKeys)(nil).RotateApiKey), ctx, userId, id)
 }
 
 // MockAccount is a mock of Account interface.
@@ -103,11 +524,25 @@ func (m *MockAccount) EXPECT() *MockAccountMockRecorder {
 	return m.recorder
 }
 
+// CheckAccess mocks base method.
+func (m *MockAccount) CheckAccess(ctx context.Context, actor entity.Actor, id int, perm entity.Permission) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckAccess", ctx, actor, id, perm)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckAccess indicates an expected call of CheckAccess.
+func (mr *MockAccountMockRecorder) CheckAccess(ctx, actor, id, perm interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckAccess", reflect.TypeOf((*MockAccount)(nil).CheckAccess), ctx, actor, id, perm)
+}
+
 // ConvertToCurrency mocks base method.
-func (m *MockAccount) ConvertToCurrency(ctx context.Context, currencyTo string, amount float64) (float64, error) {
+func (m *MockAccount) ConvertToCurrency(ctx context.Context, currencyTo string, amount float64) (entity.Conversion, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ConvertToCurrency", ctx, currencyTo, amount)
-	ret0, _ := ret[0].(float64)
+	ret0, _ := ret[0].(entity.Conversion)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -119,32 +554,32 @@ func (mr *MockAccountMockRecorder) ConvertToCurrency(ctx, currencyTo, amount int
 }
 
 // CreateAccount mocks base method.
-func (m *MockAccount) CreateAccount(ctx context.Context) (int, error) {
+func (m *MockAccount) CreateAccount(ctx context.Context, userId int) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAccount", ctx)
+	ret := m.ctrl.Call(m, "CreateAccount", ctx, userId)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateAccount indicates an expected call of CreateAccount.
-func (mr *MockAccountMockRecorder) CreateAccount(ctx interface{}) *gomock.Call {
+func (mr *MockAccountMockRecorder) CreateAccount(ctx, userId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockAccount)(nil).CreateAccount), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockAccount)(nil).CreateAccount), ctx, userId)
 }
 
 // DeleteAccount mocks base method.
-func (m *MockAccount) DeleteAccount(ctx context.Context, id int) error {
+func (m *MockAccount) DeleteAccount(ctx context.Context, id, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteAccount", ctx, id)
+	ret := m.ctrl.Call(m, "DeleteAccount", ctx, id, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteAccount indicates an expected call of DeleteAccount.
-func (mr *MockAccountMockRecorder) DeleteAccount(ctx, id interface{}) *gomock.Call {
+func (mr *MockAccountMockRecorder) DeleteAccount(ctx, id, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockAccount)(nil).DeleteAccount), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockAccount)(nil).DeleteAccount), ctx, id, version)
 }
 
 // GetAccount mocks base method.
@@ -159,495 +594,1694 @@ func (m *MockAccount) GetAccount(ctx context.Context, id int) (entity.Account, e
 // GetAccount indicates an expected call of GetAccount.
 func (mr *MockAccountMockRecorder) GetAccount(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockAccount)(nil).GetAccount), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockAccount)(nil).GetAccount), ctx, id)
+}
+
+// MakeDeposit mocks base method.
+func (m *MockAccount) MakeDeposit(ctx context.Context, id, amount, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MakeDeposit", ctx, id, amount, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MakeDeposit indicates an expected call of MakeDeposit.
+func (mr *MockAccountMockRecorder) MakeDeposit(ctx, id, amount, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MakeDeposit", reflect.TypeOf((*MockAccount)(nil).MakeDeposit), ctx, id, amount, version)
+}
+
+// SetFrozen mocks base method.
+func (m *MockAccount) SetFrozen(ctx context.Context, id int, frozen bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFrozen", ctx, id, frozen)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetFrozen indicates an expected call of SetFrozen.
+func (mr *MockAccountMockRecorder) SetFrozen(ctx, id, frozen interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFrozen", reflect.TypeOf((*MockAccount)(nil).SetFrozen), ctx, id, frozen)
+}
+
+// TransferMoney mocks base method.
+func (m *MockAccount) TransferMoney(ctx context.Context, idFrom, idTo, amount, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferMoney", ctx, idFrom, idTo, amount, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TransferMoney indicates an expected call of TransferMoney.
+func (mr *MockAccountMockRecorder) TransferMoney(ctx, idFrom, idTo, amount, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferMoney", reflect.TypeOf((*MockAccount)(nil).TransferMoney), ctx, idFrom, idTo, amount, version)
+}
+
+// WriteOff mocks base method.
+func (m *MockAccount) WriteOff(ctx context.Context, id, amount, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteOff", ctx, id, amount, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteOff indicates an expected call of WriteOff.
+func (mr *MockAccountMockRecorder) WriteOff(ctx, id, amount, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteOff", reflect.TypeOf((*MockAccount)(nil).WriteOff), ctx, id, amount, version)
+}
+
+// MockAudit is a mock of Audit interface.
+type MockAudit struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditMockRecorder
+}
+
+// MockAuditMockRecorder is the mock recorder for MockAudit.
+type MockAuditMockRecorder struct {
+	mock *MockAudit
+}
+
+// NewMockAudit creates a new mock instance.
+func NewMockAudit(ctrl *gomock.Controller) *MockAudit {
+	mock := &MockAudit{ctrl: ctrl}
+	mock.recorder = &MockAuditMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAudit) EXPECT() *MockAuditMockRecorder {
+	return m.recorder
+}
+
+// ListAudit mocks base method.
+func (m *MockAudit) ListAudit(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAudit", ctx, filter)
+	ret0, _ := ret[0].([]entity.AuditRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAudit indicates an expected call of ListAudit.
+func (mr *MockAuditMockRecorder) ListAudit(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAudit", reflect.TypeOf((*MockAudit)(nil).ListAudit), ctx, filter)
+}
+
+// Record mocks base method.
+func (m *MockAudit) Record(ctx context.Context, record entity.AuditRecord) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Record", ctx, record)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockAuditMockRecorder) Record(ctx, record interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockAudit)(nil).Record), ctx, record)
+}
+
+// MockHistory is a mock of History interface.
+type MockHistory struct {
+	ctrl     *gomock.Controller
+	recorder *MockHistoryMockRecorder
+}
+
+// MockHistoryMockRecorder is the mock recorder for MockHistory.
+type MockHistoryMockRecorder struct {
+	mock *MockHistory
+}
+
+// NewMockHistory creates a new mock instance.
+func NewMockHistory(ctrl *gomock.Controller) *MockHistory {
+	mock := &MockHistory{ctrl: ctrl}
+	mock.recorder = &MockHistoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHistory) EXPECT() *MockHistoryMockRecorder {
+	return m.recorder
+}
+
+// Convert mocks base method.
+func (m *MockHistory) Convert(ctx context.Context, records []entity.History, currency string) ([]entity.ConvertedHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Convert", ctx, records, currency)
+	ret0, _ := ret[0].([]entity.ConvertedHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Convert indicates an expected call of Convert.
+func (mr *MockHistoryMockRecorder) Convert(ctx, records, currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Convert", reflect.TypeOf((*MockHistory)(nil).Convert), ctx, records, currency)
+}
+
+// Pagination mocks base method.
+func (m *MockHistory) Pagination(ctx context.Context, limit int, param string, accountId int) ([]entity.History, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Pagination", ctx, limit, param, accountId)
+	ret0, _ := ret[0].([]entity.History)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Pagination indicates an expected call of Pagination.
+func (mr *MockHistoryMockRecorder) Pagination(ctx, limit, param, accountId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pagination", reflect.TypeOf((*MockHistory)(nil).Pagination), ctx, limit, param, accountId)
+}
+
+// SaveHistory mocks base method.
+func (m *MockHistory) SaveHistory(ctx context.Context, input entity.History) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveHistory", ctx, input)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveHistory indicates an expected call of SaveHistory.
+func (mr *MockHistoryMockRecorder) SaveHistory(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveHistory", reflect.TypeOf((*MockHistory)(nil).SaveHistory), ctx, input)
+}
+
+// ShowAll mocks base method.
+func (m *MockHistory) ShowAll(ctx context.Context) ([]entity.History, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShowAll", ctx)
+	ret0, _ := ret[0].([]entity.History)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ShowAll indicates an expected call of ShowAll.
+func (mr *MockHistoryMockRecorder) ShowAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShowAll", reflect.TypeOf((*MockHistory)(nil).ShowAll), ctx)
+}
+
+// ShowById mocks base method.
+func (m *MockHistory) ShowById(ctx context.Context, id int) ([]entity.History, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShowById", ctx, id)
+	ret0, _ := ret[0].([]entity.History)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ShowById indicates an expected call of ShowById.
+func (mr *MockHistoryMockRecorder) ShowById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShowById", reflect.TypeOf((*MockHistory)(nil).ShowById), ctx, id)
+}
+
+// ShowSorted mocks base method.
+func (m *MockHistory) ShowSorted(ctx context.Context, sortType string, accountId int) ([]entity.History, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShowSorted", ctx, sortType, accountId)
+	ret0, _ := ret[0].([]entity.History)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ShowSorted indicates an expected call of ShowSorted.
+func (mr *MockHistoryMockRecorder) ShowSorted(ctx, sortType, accountId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShowSorted", reflect.TypeOf((*MockHistory)(nil).ShowSorted), ctx, sortType, accountId)
+}
+
+// MockAuthRepo is a mock of AuthRepo interface.
+type MockAuthRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuthRepoMockRecorder
+}
+
+// MockAuthRepoMockRecorder is the mock recorder for MockAuthRepo.
+type MockAuthRepoMockRecorder struct {
+	mock *MockAuthRepo
+}
+
+// NewMockAuthRepo creates a new mock instance.
+func NewMockAuthRepo(ctrl *gomock.Controller) *MockAuthRepo {
+	mock := &MockAuthRepo{ctrl: ctrl}
+	mock.recorder = &MockAuthRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuthRepo) EXPECT() *MockAuthRepoMockRecorder {
+	return m.recorder
+}
+
+// CreateUser mocks base method.
+func (m *MockAuthRepo) CreateUser(arg0 context.Context, arg1 entity.User) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockAuthRepoMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockAuthRepo)(nil).CreateUser), arg0, arg1)
+}
+
+// GetUser mocks base method.
+func (m *MockAuthRepo) GetUser(ctx context.Context, username string) (entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", ctx, username)
+	ret0, _ := ret[0].(entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockAuthRepoMockRecorder) GetUser(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockAuthRepo)(nil).GetUser), ctx, username)
+}
+
+// GetUserById mocks base method.
+func (m *MockAuthRepo) GetUserById(ctx context.Context, id int) (entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserById", ctx, id)
+	ret0, _ := ret[0].(entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserById indicates an expected call of GetUserById.
+func (mr *MockAuthRepoMockRecorder) GetUserById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserById", reflect.TypeOf((*MockAuthRepo)(nil).GetUserById), ctx, id)
+}
+
+// ListUsers mocks base method.
+func (m *MockAuthRepo) ListUsers(ctx context.Context, filter entity.UserFilter) ([]entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", ctx, filter)
+	ret0, _ := ret[0].([]entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockAuthRepoMockRecorder) ListUsers(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockAuthRepo)(nil).ListUsers), ctx, filter)
+}
+
+// SetDisabled mocks base method.
+func (m *MockAuthRepo) SetDisabled(ctx context.Context, id int, disabled bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDisabled", ctx, id, disabled)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDisabled indicates an expected call of SetDisabled.
+func (mr *MockAuthRepoMockRecorder) SetDisabled(ctx, id, disabled interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDisabled", reflect.TypeOf((*MockAuthRepo)(nil).SetDisabled), ctx, id, disabled)
+}
+
+// UpdatePasswordHash mocks base method.
+func (m *MockAuthRepo) UpdatePasswordHash(ctx context.Context, id int, passwordHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasswordHash", ctx, id, passwordHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasswordHash indicates an expected call of UpdatePasswordHash.
+func (mr *MockAuthRepoMockRecorder) UpdatePasswordHash(ctx, id, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordHash", reflect.TypeOf((*MockAuthRepo)(nil).UpdatePasswordHash), ctx, id, passwordHash)
+}
+
+// UpdateRole mocks base method.
+func (m *MockAuthRepo) UpdateRole(ctx context.Context, id int, role entity.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRole", ctx, id, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRole indicates an expected call of UpdateRole.
+func (mr *MockAuthRepoMockRecorder) UpdateRole(ctx, id, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRole", reflect.TypeOf((*MockAuthRepo)(nil).UpdateRole), ctx, id, role)
+}
+
+// UpdateUsername mocks base method.
+func (m *MockAuthRepo) UpdateUsername(ctx context.Context, id int, username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUsername", ctx, id, username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUsername indicates an expected call of UpdateUsername.
+func (mr *MockAuthRepoMockRecorder) UpdateUsername(ctx, id, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUsername", reflect.TypeOf((*MockAuthRepo)(nil).UpdateUsername), ctx, id, username)
+}
+
+// MockTokenRepo is a mock of TokenRepo interface.
+type MockTokenRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenRepoMockRecorder
+}
+
+// MockTokenRepoMockRecorder is the mock recorder for MockTokenRepo.
+type MockTokenRepoMockRecorder struct {
+	mock *MockTokenRepo
+}
+
+// NewMockTokenRepo creates a new mock instance.
+func NewMockTokenRepo(ctrl *gomock.Controller) *MockTokenRepo {
+	mock := &MockTokenRepo{ctrl: ctrl}
+	mock.recorder = &MockTokenRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenRepo) EXPECT() *MockTokenRepoMockRecorder {
+	return m.recorder
+}
+
+// CreateRefreshToken mocks base method.
+func (m *MockTokenRepo) CreateRefreshToken(ctx context.Context, token entity.RefreshToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRefreshToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRefreshToken indicates an expected call of CreateRefreshToken.
+func (mr *MockTokenRepoMockRecorder) CreateRefreshToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRefreshToken", reflect.TypeOf((*MockTokenRepo)(nil).CreateRefreshToken), ctx, token)
+}
+
+// GetRefreshToken mocks base method.
+func (m *MockTokenRepo) GetRefreshToken(ctx context.Context, id string) (entity.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefreshToken", ctx, id)
+	ret0, _ := ret[0].(entity.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshToken indicates an expected call of GetRefreshToken.
+func (mr *MockTokenRepoMockRecorder) GetRefreshToken(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshToken", reflect.TypeOf((*MockTokenRepo)(nil).GetRefreshToken), ctx, id)
+}
+
+// RevokeRefreshToken mocks base method.
+func (m *MockTokenRepo) RevokeRefreshToken(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRefreshToken", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRefreshToken indicates an expected call of RevokeRefreshToken.
+func (mr *MockTokenRepoMockRecorder) RevokeRefreshToken(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshToken", reflect.TypeOf((*MockTokenRepo)(nil).RevokeRefreshToken), ctx, id)
+}
+
+// RevokeTokenFamily mocks base method.
+func (m *MockTokenRepo) RevokeTokenFamily(ctx context.Context, familyId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeTokenFamily", ctx, familyId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeTokenFamily indicates an expected call of RevokeTokenFamily.
+func (mr *MockTokenRepoMockRecorder) RevokeTokenFamily(ctx, familyId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeTokenFamily", reflect.TypeOf((*MockTokenRepo)(nil).RevokeTokenFamily), ctx, familyId)
+}
+
+// RevokeUserTokens mocks base method.
+func (m *MockTokenRepo) RevokeUserTokens(ctx context.Context, userId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserTokens", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserTokens indicates an expected call of RevokeUserTokens.
+func (mr *MockTokenRepoMockRecorder) RevokeUserTokens(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserTokens", reflect.TypeOf((*MockTokenRepo)(nil).RevokeUserTokens), ctx, userId)
+}
+
+// RotateRefreshToken mocks base method.
+func (m *MockTokenRepo) RotateRefreshToken(ctx context.Context, id string, next entity.RefreshToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateRefreshToken", ctx, id, next)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateRefreshToken indicates an expected call of RotateRefreshToken.
+func (mr *MockTokenRepoMockRecorder) RotateRefreshToken(ctx, id, next interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockTokenRepo)(nil).RotateRefreshToken), ctx, id, next)
+}
+
+// MockTokenRevoker is a mock of TokenRevoker interface.
+type MockTokenRevoker struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenRevokerMockRecorder
+}
+
+// MockTokenRevokerMockRecorder is the mock recorder for MockTokenRevoker.
+type MockTokenRevokerMockRecorder struct {
+	mock *MockTokenRevoker
+}
+
+// NewMockTokenRevoker creates a new mock instance.
+func NewMockTokenRevoker(ctrl *gomock.Controller) *MockTokenRevoker {
+	mock := &MockTokenRevoker{ctrl: ctrl}
+	mock.recorder = &MockTokenRevokerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenRevoker) EXPECT() *MockTokenRevokerMockRecorder {
+	return m.recorder
+}
+
+// RevokeUserTokens mocks base method.
+func (m *MockTokenRevoker) RevokeUserTokens(ctx context.Context, userId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserTokens", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserTokens indicates an expected call of RevokeUserTokens.
+func (mr *MockTokenRevokerMockRecorder) RevokeUserTokens(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserTokens", reflect.TypeOf((*MockTokenRevoker)(nil).RevokeUserTokens), ctx, userId)
+}
+
+// MockTwoFactorRepo is a mock of TwoFactorRepo interface.
+type MockTwoFactorRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockTwoFactorRepoMockRecorder
+}
+
+// MockTwoFactorRepoMockRecorder is the mock recorder for MockTwoFactorRepo.
+type MockTwoFactorRepoMockRecorder struct {
+	mock *MockTwoFactorRepo
+}
+
+// NewMockTwoFactorRepo creates a new mock instance.
+func NewMockTwoFactorRepo(ctrl *gomock.Controller) *MockTwoFactorRepo {
+	mock := &MockTwoFactorRepo{ctrl: ctrl}
+	mock.recorder = &MockTwoFactorRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTwoFactorRepo) EXPECT() *MockTwoFactorRepoMockRecorder {
+	return m.recorder
+}
+
+// EnableTwoFactor mocks base method.
+func (m *MockTwoFactorRepo) EnableTwoFactor(ctx context.Context, userId int, step int64, codeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTwoFactor", ctx, userId, step, codeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTwoFactor indicates an expected call of EnableTwoFactor.
+func (mr *MockTwoFactorRepoMockRecorder) EnableTwoFactor(ctx, userId, step, codeHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTwoFactor", reflect.TypeOf((*MockTwoFactorRepo)(nil).EnableTwoFactor), ctx, userId, step, codeHashes)
+}
+
+// GetTwoFactor mocks base method.
+func (m *MockTwoFactorRepo) GetTwoFactor(ctx context.Context, userId int) (entity.TwoFactor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTwoFactor", ctx, userId)
+	ret0, _ := ret[0].(entity.TwoFactor)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTwoFactor indicates an expected call of GetTwoFactor.
+func (mr *MockTwoFactorRepoMockRecorder) GetTwoFactor(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTwoFactor", reflect.TypeOf((*MockTwoFactorRepo)(nil).GetTwoFactor), ctx, userId)
+}
+
+// RemoveTwoFactor mocks base method.
+func (m *MockTwoFactorRepo) RemoveTwoFactor(ctx context.Context, userId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveTwoFactor", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveTwoFactor indicates an expected call of RemoveTwoFactor.
+func (mr *MockTwoFactorRepoMockRecorder) RemoveTwoFactor(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveTwoFactor", reflect.TypeOf((*MockTwoFactorRepo)(nil).RemoveTwoFactor), ctx, userId)
+}
+
+// ReplaceRecoveryCodes mocks base method.
+func (m *MockTwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userId int, codeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceRecoveryCodes", ctx, userId, codeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceRecoveryCodes indicates an expected call of ReplaceRecoveryCodes.
+func (mr *MockTwoFactorRepoMockRecorder) ReplaceRecoveryCodes(ctx, userId, codeHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceRecoveryCodes", reflect.TypeOf((*MockTwoFactorRepo)(nil).ReplaceRecoveryCodes), ctx, userId, codeHashes)
+}
+
+// SetTotpSecret mocks base method.
+func (m *MockTwoFactorRepo) SetTotpSecret(ctx context.Context, userId int, secret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTotpSecret", ctx, userId, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTotpSecret indicates an expected call of SetTotpSecret.
+func (mr *MockTwoFactorRepoMockRecorder) SetTotpSecret(ctx, userId, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTotpSecret", reflect.TypeOf((*MockTwoFactorRepo)(nil).SetTotpSecret), ctx, userId, secret)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockTwoFactorRepo) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userId, codeHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockTwoFactorRepoMockRecorder) UseRecoveryCode(ctx, userId, codeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockTwoFactorRepo)(nil).UseRecoveryCode), ctx, userId, codeHash)
+}
+
+// UseTotpStep mocks base method.
+func (m *MockTwoFactorRepo) UseTotpStep(ctx context.Context, userId int, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTotpStep", ctx, userId, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseTotpStep indicates an expected call of UseTotpStep.
+func (mr *MockTwoFactorRepoMockRecorder) UseTotpStep(ctx, userId, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTotpStep", reflect.TypeOf((*MockTwoFactorRepo)(nil).UseTotpStep), ctx, userId, step)
+}
+
+// MockLoginAuditRepo is a mock of LoginAuditRepo interface.
+type MockLoginAuditRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginAuditRepoMockRecorder
+}
+
+// MockLoginAuditRepoMockRecorder is the mock recorder for MockLoginAuditRepo.
+type MockLoginAuditRepoMockRecorder struct {
+	mock *MockLoginAuditRepo
+}
+
+// NewMockLoginAuditRepo creates a new mock instance.
+func NewMockLoginAuditRepo(ctrl *gomock.Controller) *MockLoginAuditRepo {
+	mock := &MockLoginAuditRepo{ctrl: ctrl}
+	mock.recorder = &MockLoginAuditRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginAuditRepo) EXPECT() *MockLoginAuditRepoMockRecorder {
+	return m.recorder
+}
+
+// SaveLoginAttempt mocks base method.
+func (m *MockLoginAuditRepo) SaveLoginAttempt(ctx context.Context, attempt entity.LoginAttempt) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveLoginAttempt", ctx, attempt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveLoginAttempt indicates an expected call of SaveLoginAttempt.
+func (mr *MockLoginAuditRepoMockRecorder) SaveLoginAttempt(ctx, attempt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveLoginAttempt", reflect.TypeOf((*MockLoginAuditRepo)(nil).SaveLoginAttempt), ctx, attempt)
+}
+
+// MockAuditRepo is a mock of AuditRepo interface.
+type MockAuditRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditRepoMockRecorder
+}
+
+// MockAuditRepoMockRecorder is the mock recorder for MockAuditRepo.
+type MockAuditRepoMockRecorder struct {
+	mock *MockAuditRepo
+}
+
+// NewMockAuditRepo creates a new mock instance.
+func NewMockAuditRepo(ctrl *gomock.Controller) *MockAuditRepo {
+	mock := &MockAuditRepo{ctrl: ctrl}
+	mock.recorder = &MockAuditRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditRepo) EXPECT() *MockAuditRepoMockRecorder {
+	return m.recorder
+}
+
+// ListAuditRecords mocks base method.
+func (m *MockAuditRepo) ListAuditRecords(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditRecords", ctx, filter)
+	ret0, _ := ret[0].([]entity.AuditRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditRecords indicates an expected call of ListAuditRecords.
+func (mr *MockAuditRepoMockRecorder) ListAuditRecords(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditRecords", reflect.TypeOf((*MockAuditRepo)(nil).ListAuditRecords), ctx, filter)
+}
+
+// SaveAuditRecord mocks base method.
+func (m *MockAuditRepo) SaveAuditRecord(ctx context.Context, record entity.AuditRecord) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAuditRecord", ctx, record)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAuditRecord indicates an expected call of SaveAuditRecord.
+func (mr *MockAuditRepoMockRecorder) SaveAuditRecord(ctx, record interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAuditRecord", reflect.TypeOf((*MockAuditRepo)(nil).SaveAuditRecord), ctx, record)
+}
+
+// MockApiKeyRepo is a mock of ApiKeyRepo interface.
+type MockApiKeyRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockApiKeyRepoMockRecorder
+}
+
+// MockApiKeyRepoMockRecorder is the mock recorder for MockApiKeyRepo.
+type MockApiKeyRepoMockRecorder struct {
+	mock *MockApiKeyRepo
+}
+
+// NewMockApiKeyRepo creates a new mock instance.
+func NewMockApiKeyRepo(ctrl *gomock.Controller) *MockApiKeyRepo {
+	mock := &MockApiKeyRepo{ctrl: ctrl}
+	mock.recorder = &MockApiKeyRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockApiKeyRepo) EXPECT() *MockApiKeyRepoMockRecorder {
+	return m.recorder
+}
+
+// CreateApiKey mocks base method.
+func (m *MockApiKeyRepo) CreateApiKey(ctx context.Context, key entity.ApiKey) (entity.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateApiKey", ctx, key)
+	ret0, _ := ret[0].(entity.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateApiKey indicates an expected call of CreateApiKey.
+func (mr *MockApiKeyRepoMockRecorder) CreateApiKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateApiKey", reflect.TypeOf((*MockApiKeyRepo)(nil).CreateApiKey), ctx, key)
+}
+
+// GetApiKey mocks base method.
+func (m *MockApiKeyRepo) GetApiKey(ctx context.Context, hash string) (entity.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApiKey", ctx, hash)
+	ret0, _ := ret[0].(entity.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApiKey indicates an expected call of GetApiKey.
+func (mr *MockApiKeyRepoMockRecorder) GetApiKey(ctx, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApiKey", reflect.TypeOf((*MockApiKeyRepo)(nil).GetApiKey), ctx, hash)
+}
+
+// ListApiKeys mocks base method.
+func (m *MockApiKeyRepo) ListApiKeys(ctx context.Context, userId int) ([]entity.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListApiKeys", ctx, userId)
+	ret0, _ := ret[0].([]entity.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListApiKeys indicates an expected call of ListApiKeys.
+func (mr *MockApiKeyRepoMockRecorder) ListApiKeys(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListApiKeys", reflect.TypeOf((*MockApiKeyRepo)(nil).ListApiKeys), ctx, userId)
+}
+
+// RevokeApiKey mocks base method.
+func (m *MockApiKeyRepo) RevokeApiKey(ctx context.Context, userId, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeApiKey", ctx, userId, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeApiKey indicates an expected call of RevokeApiKey.
+func (mr *MockApiKeyRepoMockRecorder) RevokeApiKey(ctx, userId, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeApiKey", reflect.TypeOf((*MockApiKeyRepo)(nil).RevokeApiKey), ctx, userId, id)
+}
+
+// RotateApiKey mocks base method.
+func (m *MockApiKeyRepo) RotateApiKey(ctx context.Context, userId, id int, prefix, hash string) (entity.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateApiKey", ctx, userId, id, prefix, hash)
+	ret0, _ := ret[0].(entity.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateApiKey indicates an expected call of RotateApiKey.
+func (mr *MockApiKeyRepoMockRecorder) RotateApiKey(ctx, userId, id, prefix, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateApiKey", reflect.TypeOf((*MockApiKeyRepo)(nil).RotateApiKey), ctx, userId, id, prefix, hash)
+}
+
+// TouchApiKey mocks base method.
+func (m *MockApiKeyRepo) TouchApiKey(ctx context.Context, id int, usedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchApiKey", ctx, id, usedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchApiKey indicates an expected call of TouchApiKey.
+func (mr *MockApiKeyRepoMockRecorder) TouchApiKey(ctx, id, usedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchApiKey", reflect.TypeOf((*MockApiKeyRepo)(nil).TouchApiKey), ctx, id, usedAt)
+}
+
+// MockAccountRepo is a mock of AccountRepo interface.
+type MockAccountRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccountRepoMockRecorder
+}
+
+// MockAccountRepoMockRecorder is the mock recorder for MockAccountRepo.
+type MockAccountRepoMockRecorder struct {
+	mock *MockAccountRepo
+}
+
+// NewMockAccountRepo creates a new mock instance.
+func NewMockAccountRepo(ctrl *gomock.Controller) *MockAccountRepo {
+	mock := &MockAccountRepo{ctrl: ctrl}
+	mock.recorder = &MockAccountRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccountRepo) EXPECT() *MockAccountRepoMockRecorder {
+	return m.recorder
+}
+
+// CreateAccount mocks base method.
+func (m *MockAccountRepo) CreateAccount(ctx context.Context, userId int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccount", ctx, userId)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccount indicates an expected call of CreateAccount.
+func (mr *MockAccountRepoMockRecorder) CreateAccount(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockAccountRepo)(nil).CreateAccount), ctx, userId)
+}
+
+// DeleteAccount mocks base method.
+func (m *MockAccountRepo) DeleteAccount(ctx context.Context, id, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccount", ctx, id, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccount indicates an expected call of DeleteAccount.
+func (mr *MockAccountRepoMockRecorder) DeleteAccount(ctx, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockAccountRepo)(nil).DeleteAccount), ctx, id, version)
+}
+
+// GetAccount mocks base method.
+func (m *MockAccountRepo) GetAccount(ctx context.Context, id int) (entity.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccount", ctx, id)
+	ret0, _ := ret[0].(entity.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccount indicates an expected call of GetAccount.
+func (mr *MockAccountRepoMockRecorder) GetAccount(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockAccountRepo)(nil).GetAccount), ctx, id)
+}
+
+// MakeDeposit mocks base method.
+func (m *MockAccountRepo) MakeDeposit(ctx context.Context, id, amount, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MakeDeposit", ctx, id, amount, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MakeDeposit indicates an expected call of MakeDeposit.
+func (mr *MockAccountRepoMockRecorder) MakeDeposit(ctx, id, amount, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MakeDeposit", reflect.TypeOf((*MockAccountRepo)(nil).MakeDeposit), ctx, id, amount, version)
+}
+
+// SetFrozen mocks base method.
+func (m *MockAccountRepo) SetFrozen(ctx context.Context, id int, frozen bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFrozen", ctx, id, frozen)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetFrozen indicates an expected call of SetFrozen.
+func (mr *MockAccountRepoMockRecorder) SetFrozen(ctx, id, frozen interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFrozen", reflect.TypeOf((*MockAccountRepo)(nil).SetFrozen), ctx, id, frozen)
+}
+
+// TransferMoney mocks base method.
+func (m *MockAccountRepo) TransferMoney(ctx context.Context, idFrom, idTo, amount, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferMoney", ctx, idFrom, idTo, amount, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TransferMoney indicates an expected call of TransferMoney.
+func (mr *MockAccountRepoMockRecorder) TransferMoney(ctx, idFrom, idTo, amount, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferMoney", reflect.TypeOf((*MockAccountRepo)(nil).TransferMoney), ctx, idFrom, idTo, amount, version)
+}
+
+// WriteOff mocks base method.
+func (m *MockAccountRepo) WriteOff(ctx context.Context, id, amount, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteOff", ctx, id, amount, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteOff indicates an expected call of WriteOff.
+func (mr *MockAccountRepoMockRecorder) WriteOff(ctx, id, amount, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteOff", reflect.TypeOf((*MockAccountRepo)(nil).WriteOff), ctx, id, amount, version)
+}
+
+// MockHistoryRepo is a mock of HistoryRepo interface.
+type MockHistoryRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockHistoryRepoMockRecorder
+}
+
+// MockHistoryRepoMockRecorder is the mock recorder for MockHistoryRepo.
+type MockHistoryRepoMockRecorder struct {
+	mock *MockHistoryRepo
+}
+
+// NewMockHistoryRepo creates a new mock instance.
+func NewMockHistoryRepo(ctrl *gomock.Controller) *MockHistoryRepo {
+	mock := &MockHistoryRepo{ctrl: ctrl}
+	mock.recorder = &MockHistoryRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHistoryRepo) EXPECT() *MockHistoryRepoMockRecorder {
+	return m.recorder
+}
+
+// Pagination mocks base method.
+func (m *MockHistoryRepo) Pagination(ctx context.Context, limit int, param string, accountId int) ([]entity.History, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Pagination", ctx, limit, param, accountId)
+	ret0, _ := ret[0].([]entity.History)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Pagination indicates an expected call of Pagination.
+func (mr *MockHistoryRepoMockRecorder) Pagination(ctx, limit, param, accountId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pagination", reflect.TypeOf((*MockHistoryRepo)(nil).Pagination), ctx, limit, param, accountId)
+}
+
+// SaveHistory mocks base method.
+func (m *MockHistoryRepo) SaveHistory(ctx context.Context, input entity.History) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveHistory", ctx, input)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveHistory indicates an expected call of SaveHistory.
+func (mr *MockHistoryRepoMockRecorder) SaveHistory(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveHistory", reflect.TypeOf((*MockHistoryRepo)(nil).SaveHistory), ctx, input)
+}
+
+// ShowAll mocks base method.
+func (m *MockHistoryRepo) ShowAll(ctx context.Context) ([]entity.History, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShowAll", ctx)
+	ret0, _ := ret[0].([]entity.History)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ShowAll indicates an expected call of ShowAll.
+func (mr *MockHistoryRepoMockRecorder) ShowAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShowAll", reflect.TypeOf((*MockHistoryRepo)(nil).ShowAll), ctx)
+}
+
+// ShowById mocks base method.
+func (m *MockHistoryRepo) ShowById(ctx context.Context, id int) ([]entity.History, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShowById", ctx, id)
+	ret0, _ := ret[0].([]entity.History)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ShowById indicates an expected call of ShowById.
+func (mr *MockHistoryRepoMockRecorder) ShowById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShowById", reflect.TypeOf((*MockHistoryRepo)(nil).ShowById), ctx, id)
+}
+
+// ShowSorted mocks base method.
+func (m *MockHistoryRepo) ShowSorted(ctx context.Context, sortType string, accountId int) ([]entity.History, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShowSorted", ctx, sortType, accountId)
+	ret0, _ := ret[0].([]entity.History)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ShowSorted indicates an expected call of ShowSorted.
+func (mr *MockHistoryRepoMockRecorder) ShowSorted(ctx, sortType, accountId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShowSorted", reflect.TypeOf((*MockHistoryRepo)(nil).ShowSorted), ctx, sortType, accountId)
+}
+
+// MockRateRepo is a mock of RateRepo interface.
+type MockRateRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateRepoMockRecorder
+}
+
+// MockRateRepoMockRecorder is the mock recorder for MockRateRepo.
+type MockRateRepoMockRecorder struct {
+	mock *MockRateRepo
+}
+
+// NewMockRateRepo creates a new mock instance.
+func NewMockRateRepo(ctrl *gomock.Controller) *MockRateRepo {
+	mock := &MockRateRepo{ctrl: ctrl}
+	mock.recorder = &MockRateRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateRepo) EXPECT() *MockRateRepoMockRecorder {
+	return m.recorder
+}
+
+// GetDailyRates mocks base method.
+func (m *MockRateRepo) GetDailyRates(ctx context.Context, currency string, from, to time.Time) ([]entity.DailyRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDailyRates", ctx, currency, from, to)
+	ret0, _ := ret[0].([]entity.DailyRate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDailyRates indicates an expected call of GetDailyRates.
+func (mr *MockRateRepoMockRecorder) GetDailyRates(ctx, currency, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDailyRates", reflect.TypeOf((*MockRateRepo)(nil).GetDailyRates), ctx, currency, from, to)
+}
+
+// SaveDailyRate mocks base method.
+func (m *MockRateRepo) SaveDailyRate(ctx context.Context, rate entity.DailyRate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDailyRate", ctx, rate)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDailyRate indicates an expected call of SaveDailyRate.
+func (mr *MockRateRepoMockRecorder) SaveDailyRate(ctx, rate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDailyRate", reflect.TypeOf((*MockRateRepo)(nil).SaveDailyRate), ctx, rate)
+}
+
+// MockRedisCache is a mock of RedisCache interface.
+type MockRedisCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockRedisCacheMockRecorder
+}
+
+// MockRedisCacheMockRecorder is the mock recorder for MockRedisCache.
+type MockRedisCacheMockRecorder struct {
+	mock *MockRedisCache
+}
+
+// NewMockRedisCache creates a new mock instance.
+func NewMockRedisCache(ctrl *gomock.Controller) *MockRedisCache {
+	mock := &MockRedisCache{ctrl: ctrl}
+	mock.recorder = &MockRedisCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRedisCache) EXPECT() *MockRedisCacheMockRecorder {
+	return m.recorder
+}
+
+// Counter mocks base method.
+func (m *MockRedisCache) Counter(ctx context.Context, key string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Counter", ctx, key)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Counter indicates an expected call of Counter.
+func (mr *MockRedisCacheMockRecorder) Counter(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Counter", reflect.TypeOf((*MockRedisCache)(nil).Counter), ctx, key)
+}
+
+// Delete mocks base method.
+func (m *MockRedisCache) Delete(ctx context.Context, keys ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range keys {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Delete", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockRedisCacheMockRecorder) Delete(ctx interface{}, keys ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, keys...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRedisCache)(nil).Delete), varargs...)
 }
 
-// MakeDeposit mocks base method.
-func (m *MockAccount) MakeDeposit(ctx context.Context, id, amount int) error {
+// Get mocks base method.
+func (m *MockRedisCache) Get(ctx context.Context, key string, dst any) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "MakeDeposit", ctx, id, amount)
+	ret := m.ctrl.Call(m, "Get", ctx, key, dst)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// MakeDeposit indicates an expected call of MakeDeposit.
-func (mr *MockAccountMockRecorder) MakeDeposit(ctx, id, amount interface{}) *gomock.Call {
+// Get indicates an expected call of Get.
+func (mr *MockRedisCacheMockRecorder) Get(ctx, key, dst interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MakeDeposit", reflect.TypeOf((*MockAccount)(nil).MakeDeposit), ctx, id, amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRedisCache)(nil).Get), ctx, key, dst)
 }
 
-// TransferMoney mocks base method.
-func (m *MockAccount) TransferMoney(ctx context.Context, idFrom, idTo, amount int) error {
+// Incr mocks base method.
+func (m *MockRedisCache) Incr(ctx context.Context, key string) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "TransferMoney", ctx, idFrom, idTo, amount)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "Incr", ctx, key)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// TransferMoney indicates an expected call of TransferMoney.
-func (mr *MockAccountMockRecorder) TransferMoney(ctx, idFrom, idTo, amount interface{}) *gomock.Call {
+// Incr indicates an expected call of Incr.
+func (mr *MockRedisCacheMockRecorder) Incr(ctx, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferMoney", reflect.TypeOf((*MockAccount)(nil).TransferMoney), ctx, idFrom, idTo, amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Incr", reflect.TypeOf((*MockRedisCache)(nil).Incr), ctx, key)
 }
 
-// WriteOff mocks base method.
-func (m *MockAccount) WriteOff(ctx context.Context, id, amount int) error {
+// Set mocks base method.
+func (m *MockRedisCache) Set(ctx context.Context, key string, value any) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "WriteOff", ctx, id, amount)
+	ret := m.ctrl.Call(m, "Set", ctx, key, value)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// WriteOff indicates an expected call of WriteOff.
-func (mr *MockAccountMockRecorder) WriteOff(ctx, id, amount interface{}) *gomock.Call {
+// Set indicates an expected call of Set.
+func (mr *MockRedisCacheMockRecorder) Set(ctx, key, value interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteOff", reflect.TypeOf((*MockAccount)(nil).WriteOff), ctx, id, amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockRedisCache)(nil).Set), ctx, key, value)
 }
 
-// MockHistory is a mock of History interface.
-type MockHistory struct {
+// MockDenylist is a mock of Denylist interface.
+type MockDenylist struct {
 	ctrl     *gomock.Controller
-	recorder *MockHistoryMockRecorder
+	recorder *MockDenylistMockRecorder
 }
 
-// MockHistoryMockRecorder is the mock recorder for MockHistory.
-type MockHistoryMockRecorder struct {
-	mock *MockHistory
+// MockDenylistMockRecorder is the mock recorder for MockDenylist.
+type MockDenylistMockRecorder struct {
+	mock *MockDenylist
 }
 
-// NewMockHistory creates a new mock instance.
-func NewMockHistory(ctrl *gomock.Controller) *MockHistory {
-	mock := &MockHistory{ctrl: ctrl}
-	mock.recorder = &MockHistoryMockRecorder{mock}
+// NewMockDenylist creates a new mock instance.
+func NewMockDenylist(ctrl *gomock.Controller) *MockDenylist {
+	mock := &MockDenylist{ctrl: ctrl}
+	mock.recorder = &MockDenylistMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockHistory) EXPECT() *MockHistoryMockRecorder {
+func (m *MockDenylist) EXPECT() *MockDenylistMockRecorder {
 	return m.recorder
 }
 
-// Pagination mocks base method.
-func (m *MockHistory) Pagination(ctx context.Context, limit int, param string, accountId int) ([]entity.History, error) {
+// Deny mocks base method.
+func (m *MockDenylist) Deny(ctx context.Context, jti string, ttl time.Duration) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Pagination", ctx, limit, param, accountId)
-	ret0, _ := ret[0].([]entity.History)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "Deny", ctx, jti, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Pagination indicates an expected call of Pagination.
-func (mr *MockHistoryMockRecorder) Pagination(ctx, limit, param, accountId interface{}) *gomock.Call {
+// Deny indicates an expected call of Deny.
+func (mr *MockDenylistMockRecorder) Deny(ctx, jti, ttl interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pagination", reflect.TypeOf((*MockHistory)(nil).Pagination), ctx, limit, param, accountId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deny", reflect.TypeOf((*MockDenylist)(nil).Deny), ctx, jti, ttl)
 }
 
-// SaveHistory mocks base method.
-func (m *MockHistory) SaveHistory(ctx context.Context, input entity.History) (int, error) {
+// DenyUser mocks base method.
+func (m *MockDenylist) DenyUser(ctx context.Context, userId int, before time.Time, ttl time.Duration) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveHistory", ctx, input)
-	ret0, _ := ret[0].(int)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "DenyUser", ctx, userId, before, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// SaveHistory indicates an expected call of SaveHistory.
-func (mr *MockHistoryMockRecorder) SaveHistory(ctx, input interface{}) *gomock.Call {
+// DenyUser indicates an expected call of DenyUser.
+func (mr *MockDenylistMockRecorder) DenyUser(ctx, userId, before, ttl interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveHistory", reflect.TypeOf((*MockHistory)(nil).SaveHistory), ctx, input)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DenyUser", reflect.TypeOf((*MockDenylist)(nil).DenyUser), ctx, userId, before, ttl)
 }
 
-// ShowAll mocks base method.
-func (m *MockHistory) ShowAll(ctx context.Context) ([]entity.History, error) {
+// IsDenied mocks base method.
+func (m *MockDenylist) IsDenied(ctx context.Context, jti string, userId int, issuedAt time.Time) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ShowAll", ctx)
-	ret0, _ := ret[0].([]entity.History)
+	ret := m.ctrl.Call(m, "IsDenied", ctx, jti, userId, issuedAt)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ShowAll indicates an expected call of ShowAll.
-func (mr *MockHistoryMockRecorder) ShowAll(ctx interface{}) *gomock.Call {
+// IsDenied indicates an expected call of IsDenied.
+func (mr *MockDenylistMockRecorder) IsDenied(ctx, jti, userId, issuedAt interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShowAll", reflect.TypeOf((*MockHistory)(nil).ShowAll), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsDenied", reflect.TypeOf((*MockDenylist)(nil).IsDenied), ctx, jti, userId, issuedAt)
 }
 
-// ShowById mocks base method.
-func (m *MockHistory) ShowById(ctx context.Context, id int) ([]entity.History, error) {
+// MockSignInChallenges is a mock of SignInChallenges interface.
+type MockSignInChallenges struct {
+	ctrl     *gomock.Controller
+	recorder *MockSignInChallengesMockRecorder
+}
+
+// MockSignInChallengesMockRecorder is the mock recorder for MockSignInChallenges.
+type MockSignInChallengesMockRecorder struct {
+	mock *MockSignInChallenges
+}
+
+// NewMockSignInChallenges creates a new mock instance.
+func NewMockSignInChallenges(ctrl *gomock.Controller) *MockSignInChallenges {
+	mock := &MockSignInChallenges{ctrl: ctrl}
+	mock.recorder = &MockSignInChallengesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSignInChallenges) EXPECT() *MockSignInChallengesMockRecorder {
+	return m.recorder
+}
+
+// AttemptChallenge mocks base method.
+func (m *MockSignInChallenges) AttemptChallenge(ctx context.Context, id string) (entity.SignInChallenge, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ShowById", ctx, id)
-	ret0, _ := ret[0].([]entity.History)
+	ret := m.ctrl.Call(m, "AttemptChallenge", ctx, id)
+	ret0, _ := ret[0].(entity.SignInChallenge)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ShowById indicates an expected call of ShowById.
-func (mr *MockHistoryMockRecorder) ShowById(ctx, id interface{}) *gomock.Call {
+// AttemptChallenge indicates an expected call of AttemptChallenge.
+func (mr *MockSignInChallengesMockRecorder) AttemptChallenge(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShowById", reflect.TypeOf((*MockHistory)(nil).ShowById), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttemptChallenge", reflect.TypeOf((*MockSignInChallenges)(nil).AttemptChallenge), ctx, id)
 }
 
-// ShowSorted mocks base method.
-func (m *MockHistory) ShowSorted(ctx context.Context, sortType string, accountId int) ([]entity.History, error) {
+// CreateChallenge mocks base method.
+func (m *MockSignInChallenges) CreateChallenge(ctx context.Context, id string, challenge entity.SignInChallenge, ttl time.Duration) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ShowSorted", ctx, sortType, accountId)
-	ret0, _ := ret[0].([]entity.History)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "CreateChallenge", ctx, id, challenge, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// ShowSorted indicates an expected call of ShowSorted.
-func (mr *MockHistoryMockRecorder) ShowSorted(ctx, sortType, accountId interface{}) *gomock.Call {
+// CreateChallenge indicates an expected call of CreateChallenge.
+func (mr *MockSignInChallengesMockRecorder) CreateChallenge(ctx, id, challenge, ttl interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShowSorted", reflect.TypeOf((*MockHistory)(nil).ShowSorted), ctx, sortType, accountId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateChallenge", reflect.TypeOf((*MockSignInChallenges)(nil).CreateChallenge), ctx, id, challenge, ttl)
 }
 
-// MockAuthRepo is a mock of AuthRepo interface.
-type MockAuthRepo struct {
+// DeleteChallenge mocks base method.
+func (m *MockSignInChallenges) DeleteChallenge(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteChallenge", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteChallenge indicates an expected call of DeleteChallenge.
+func (mr *MockSignInChallengesMockRecorder) DeleteChallenge(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteChallenge", reflect.TypeOf((*MockSignInChallenges)(nil).DeleteChallenge), ctx, id)
+}
+
+// MockAccountMetrics is a mock of AccountMetrics interface.
+type MockAccountMetrics struct {
 	ctrl     *gomock.Controller
-	recorder *MockAuthRepoMockRecorder
+	recorder *MockAccountMetricsMockRecorder
 }
 
-// MockAuthRepoMockRecorder is the mock recorder for MockAuthRepo.
-type MockAuthRepoMockRecorder struct {
-	mock *MockAuthRepo
+// MockAccountMetricsMockRecorder is the mock recorder for MockAccountMetrics.
+type MockAccountMetricsMockRecorder struct {
+	mock *MockAccountMetrics
 }
 
-// NewMockAuthRepo creates a new mock instance.
-func NewMockAuthRepo(ctrl *gomock.Controller) *MockAuthRepo {
-	mock := &MockAuthRepo{ctrl: ctrl}
-	mock.recorder = &MockAuthRepoMockRecorder{mock}
+// NewMockAccountMetrics creates a new mock instance.
+func NewMockAccountMetrics(ctrl *gomock.Controller) *MockAccountMetrics {
+	mock := &MockAccountMetrics{ctrl: ctrl}
+	mock.recorder = &MockAccountMetricsMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockAuthRepo) EXPECT() *MockAuthRepoMockRecorder {
+func (m *MockAccountMetrics) EXPECT() *MockAccountMetricsMockRecorder {
 	return m.recorder
 }
 
-// CreateUser mocks base method.
-func (m *MockAuthRepo) CreateUser(arg0 context.Context, arg1 entity.User) (int, error) {
+// InsufficientFunds mocks base method.
+func (m *MockAccountMetrics) InsufficientFunds(operation string) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1)
-	ret0, _ := ret[0].(int)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	m.ctrl.Call(m, "InsufficientFunds", operation)
 }
 
-// CreateUser indicates an expected call of CreateUser.
-func (mr *MockAuthRepoMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call {
+// InsufficientFunds indicates an expected call of InsufficientFunds.
+func (mr *MockAccountMetricsMockRecorder) InsufficientFunds(operation interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockAuthRepo)(nil).CreateUser), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsufficientFunds", reflect.TypeOf((*MockAccountMetrics)(nil).InsufficientFunds), operation)
 }
 
-// GetUser mocks base method.
-func (m *MockAuthRepo) GetUser(arg0 context.Context, arg1, arg2 string) (entity.User, error) {
+// Operation mocks base method.
+func (m *MockAccountMetrics) Operation(operation string, amount int) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUser", arg0, arg1, arg2)
-	ret0, _ := ret[0].(entity.User)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	m.ctrl.Call(m, "Operation", operation, amount)
 }
 
-// GetUser indicates an expected call of GetUser.
-func (mr *MockAuthRepoMockRecorder) GetUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+// Operation indicates an expected call of Operation.
+func (mr *MockAccountMetricsMockRecorder) Operation(operation, amount interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockAuthRepo)(nil).GetUser), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Operation", reflect.TypeOf((*MockAccountMetrics)(nil).Operation), operation, amount)
 }
 
-// MockAccountRepo is a mock of AccountRepo interface.
-type MockAccountRepo struct {
+// MockLoginAttempts is a mock of LoginAttempts interface.
+type MockLoginAttempts struct {
 	ctrl     *gomock.Controller
-	recorder *MockAccountRepoMockRecorder
+	recorder *MockLoginAttemptsMockRecorder
 }
 
-// MockAccountRepoMockRecorder is the mock recorder for MockAccountRepo.
-type MockAccountRepoMockRecorder struct {
-	mock *MockAccountRepo
+// MockLoginAttemptsMockRecorder is the mock recorder for MockLoginAttempts.
+type MockLoginAttemptsMockRecorder struct {
+	mock *MockLoginAttempts
 }
 
-// NewMockAccountRepo creates a new mock instance.
-func NewMockAccountRepo(ctrl *gomock.Controller) *MockAccountRepo {
-	mock := &MockAccountRepo{ctrl: ctrl}
-	mock.recorder = &MockAccountRepoMockRecorder{mock}
+// NewMockLoginAttempts creates a new mock instance.
+func NewMockLoginAttempts(ctrl *gomock.Controller) *MockLoginAttempts {
+	mock := &MockLoginAttempts{ctrl: ctrl}
+	mock.recorder = &MockLoginAttemptsMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockAccountRepo) EXPECT() *MockAccountRepoMockRecorder {
+func (m *MockLoginAttempts) EXPECT() *MockLoginAttemptsMockRecorder {
 	return m.recorder
 }
 
-// CreateAccount mocks base method.
-func (m *MockAccountRepo) CreateAccount(ctx context.Context) (int, error) {
+// AddFailure mocks base method.
+func (m *MockLoginAttempts) AddFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAccount", ctx)
-	ret0, _ := ret[0].(int)
+	ret := m.ctrl.Call(m, "AddFailure", ctx, key, window)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateAccount indicates an expected call of CreateAccount.
-func (mr *MockAccountRepoMockRecorder) CreateAccount(ctx interface{}) *gomock.Call {
+// AddFailure indicates an expected call of AddFailure.
+func (mr *MockLoginAttemptsMockRecorder) AddFailure(ctx, key, window interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockAccountRepo)(nil).CreateAccount), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddFailure", reflect.TypeOf((*MockLoginAttempts)(nil).AddFailure), ctx, key, window)
 }
 
-// DeleteAccount mocks base method.
-func (m *MockAccountRepo) DeleteAccount(ctx context.Context, id int) error {
+// LockedFor mocks base method.
+func (m *MockLoginAttempts) LockedFor(ctx context.Context, key string) (time.Duration, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteAccount", ctx, id)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "LockedFor", ctx, key)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// DeleteAccount indicates an expected call of DeleteAccount.
-func (mr *MockAccountRepoMockRecorder) DeleteAccount(ctx, id interface{}) *gomock.Call {
+// LockedFor indicates an expected call of LockedFor.
+func (mr *MockLoginAttemptsMockRecorder) LockedFor(ctx, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockAccountRepo)(nil).DeleteAccount), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockedFor", reflect.TypeOf((*MockLoginAttempts)(nil).LockedFor), ctx, key)
 }
 
-// GetAccount mocks base method.
-func (m *MockAccountRepo) GetAccount(ctx context.Context, id int) (entity.Account, error) {
+// RemoveFailure mocks base method.
+func (m *MockLoginAttempts) RemoveFailure(ctx context.Context, key string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAccount", ctx, id)
-	ret0, _ := ret[0].(entity.Account)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "RemoveFailure", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetAccount indicates an expected call of GetAccount.
-func (mr *MockAccountRepoMockRecorder) GetAccount(ctx, id interface{}) *gomock.Call {
+// RemoveFailure indicates an expected call of RemoveFailure.
+func (mr *MockLoginAttemptsMockRecorder) RemoveFailure(ctx, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockAccountRepo)(nil).GetAccount), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveFailure", reflect.TypeOf((*MockLoginAttempts)(nil).RemoveFailure), ctx, key)
 }
 
-// MakeDeposit mocks base method.
-func (m *MockAccountRepo) MakeDeposit(ctx context.Context, id, amount int) error {
+// Reset mocks base method.
+func (m *MockLoginAttempts) Reset(ctx context.Context, key string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "MakeDeposit", ctx, id, amount)
+	ret := m.ctrl.Call(m, "Reset", ctx, key)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// MakeDeposit indicates an expected call of MakeDeposit.
-func (mr *MockAccountRepoMockRecorder) MakeDeposit(ctx, id, amount interface{}) *gomock.Call {
+// Reset indicates an expected call of Reset.
+func (mr *MockLoginAttemptsMockRecorder) Reset(ctx, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MakeDeposit", reflect.TypeOf((*MockAccountRepo)(nil).MakeDeposit), ctx, id, amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockLoginAttempts)(nil).Reset), ctx, key)
 }
 
-// TransferMoney mocks base method.
-func (m *MockAccountRepo) TransferMoney(ctx context.Context, idFrom, idTo, amount int) error {
+// TryLock mocks base method.
+func (m *MockLoginAttempts) TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "TransferMoney", ctx, idFrom, idTo, amount)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "TryLock", ctx, key, ttl)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// TransferMoney indicates an expected call of TransferMoney.
-func (mr *MockAccountRepoMockRecorder) TransferMoney(ctx, idFrom, idTo, amount interface{}) *gomock.Call {
+// TryLock indicates an expected call of TryLock.
+func (mr *MockLoginAttemptsMockRecorder) TryLock(ctx, key, ttl interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferMoney", reflect.TypeOf((*MockAccountRepo)(nil).TransferMoney), ctx, idFrom, idTo, amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryLock", reflect.TypeOf((*MockLoginAttempts)(nil).TryLock), ctx, key, ttl)
 }
 
-// WriteOff mocks base method.
-func (m *MockAccountRepo) WriteOff(ctx context.Context, id, amount int) error {
+// Unlock mocks base method.
+func (m *MockLoginAttempts) Unlock(ctx context.Context, key string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "WriteOff", ctx, id, amount)
+	ret := m.ctrl.Call(m, "Unlock", ctx, key)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// WriteOff indicates an expected call of WriteOff.
-func (mr *MockAccountRepoMockRecorder) WriteOff(ctx, id, amount interface{}) *gomock.Call {
+// Unlock indicates an expected call of Unlock.
+func (mr *MockLoginAttemptsMockRecorder) Unlock(ctx, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteOff", reflect.TypeOf((*MockAccountRepo)(nil).WriteOff), ctx, id, amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockLoginAttempts)(nil).Unlock), ctx, key)
 }
 
-// MockHistoryRepo is a mock of HistoryRepo interface.
-type MockHistoryRepo struct {
+// MockTokenKeys is a mock of TokenKeys interface.
+type MockTokenKeys struct {
 	ctrl     *gomock.Controller
-	recorder *MockHistoryRepoMockRecorder
+	recorder *MockTokenKeysMockRecorder
 }
 
-// MockHistoryRepoMockRecorder is the mock recorder for MockHistoryRepo.
-type MockHistoryRepoMockRecorder struct {
-	mock *MockHistoryRepo
+// MockTokenKeysMockRecorder is the mock recorder for MockTokenKeys.
+type MockTokenKeysMockRecorder struct {
+	mock *MockTokenKeys
 }
 
-// NewMockHistoryRepo creates a new mock instance.
-func NewMockHistoryRepo(ctrl *gomock.Controller) *MockHistoryRepo {
-	mock := &MockHistoryRepo{ctrl: ctrl}
-	mock.recorder = &MockHistoryRepoMockRecorder{mock}
+// NewMockTokenKeys creates a new mock instance.
+func NewMockTokenKeys(ctrl *gomock.Controller) *MockTokenKeys {
+	mock := &MockTokenKeys{ctrl: ctrl}
+	mock.recorder = &MockTokenKeysMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockHistoryRepo) EXPECT() *MockHistoryRepoMockRecorder {
+func (m *MockTokenKeys) EXPECT() *MockTokenKeysMockRecorder {
 	return m.recorder
 }
 
-// Pagination mocks base method.
-func (m *MockHistoryRepo) Pagination(ctx context.Context, limit int, param string, accountId int) ([]entity.History, error) {
+// Keyfunc mocks base method.
+func (m *MockTokenKeys) Keyfunc(token *jwt.Token) (interface{}, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Pagination", ctx, limit, param, accountId)
-	ret0, _ := ret[0].([]entity.History)
+	ret := m.ctrl.Call(m, "Keyfunc", token)
+	ret0, _ := ret[0].(interface{})
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Pagination indicates an expected call of Pagination.
-func (mr *MockHistoryRepoMockRecorder) Pagination(ctx, limit, param, accountId interface{}) *gomock.Call {
+// Keyfunc indicates an expected call of Keyfunc.
+func (mr *MockTokenKeysMockRecorder) Keyfunc(token interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pagination", reflect.TypeOf((*MockHistoryRepo)(nil).Pagination), ctx, limit, param, accountId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keyfunc", reflect.TypeOf((*MockTokenKeys)(nil).Keyfunc), token)
 }
 
-// SaveHistory mocks base method.
-func (m *MockHistoryRepo) SaveHistory(ctx context.Context, input entity.History) (int, error) {
+// Sign mocks base method.
+func (m *MockTokenKeys) Sign(claims jwt.Claims) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveHistory", ctx, input)
-	ret0, _ := ret[0].(int)
+	ret := m.ctrl.Call(m, "Sign", claims)
+	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// SaveHistory indicates an expected call of SaveHistory.
-func (mr *MockHistoryRepoMockRecorder) SaveHistory(ctx, input interface{}) *gomock.Call {
+// Sign indicates an expected call of Sign.
+func (mr *MockTokenKeysMockRecorder) Sign(claims interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveHistory", reflect.TypeOf((*MockHistoryRepo)(nil).SaveHistory), ctx, input)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sign", reflect.TypeOf((*MockTokenKeys)(nil).Sign), claims)
 }
 
-// ShowAll mocks base method.
-func (m *MockHistoryRepo) ShowAll(ctx context.Context) ([]entity.History, error) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ShowAll", ctx)
-	ret0, _ := ret[0].([]entity.History)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+// MockRates is a mock of Rates interface.
+type MockRates struct {
+	ctrl     *gomock.Controller
+	recorder *MockRatesMockRecorder
 }
 
-// ShowAll indicates an expected call of ShowAll.
-func (mr *MockHistoryRepoMockRecorder) ShowAll(ctx interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShowAll", reflect.TypeOf((*MockHistoryRepo)(nil).ShowAll), ctx)
+// MockRatesMockRecorder is the mock recorder for MockRates.
+type MockRatesMockRecorder struct {
+	mock *MockRates
 }
 
-// ShowById mocks base method.
-func (m *MockHistoryRepo) ShowById(ctx context.Context, id int) ([]entity.History, error) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ShowById", ctx, id)
-	ret0, _ := ret[0].([]entity.History)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+// NewMockRates creates a new mock instance.
+func NewMockRates(ctrl *gomock.Controller) *MockRates {
+	mock := &MockRates{ctrl: ctrl}
+	mock.recorder = &MockRatesMockRecorder{mock}
+	return mock
 }
 
-// ShowById indicates an expected call of ShowById.
-func (mr *MockHistoryRepoMockRecorder) ShowById(ctx, id interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShowById", reflect.TypeOf((*MockHistoryRepo)(nil).ShowById), ctx, id)
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRates) EXPECT() *MockRatesMockRecorder {
+	return m.recorder
 }
 
-// ShowSorted mocks base method.
-func (m *MockHistoryRepo) ShowSorted(ctx context.Context, sortType string, accountId int) ([]entity.History, error) {
+// Rate mocks base method.
+func (m *MockRates) Rate(ctx context.Context, currency string) (entity.Rate, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ShowSorted", ctx, sortType, accountId)
-	ret0, _ := ret[0].([]entity.History)
+	ret := m.ctrl.Call(m, "Rate", ctx, currency)
+	ret0, _ := ret[0].(entity.Rate)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ShowSorted indicates an expected call of ShowSorted.
-func (mr *MockHistoryRepoMockRecorder) ShowSorted(ctx, sortType, accountId interface{}) *gomock.Call {
+// Rate indicates an expected call of Rate.
+func (mr *MockRatesMockRecorder) Rate(ctx, currency interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShowSorted", reflect.TypeOf((*MockHistoryRepo)(nil).ShowSorted), ctx, sortType, accountId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rate", reflect.TypeOf((*MockRates)(nil).Rate), ctx, currency)
 }
 
-// MockRedisCache is a mock of RedisCache interface.
-type MockRedisCache struct {
+// MockPasswordHasher is a mock of PasswordHasher interface.
+type MockPasswordHasher struct {
 	ctrl     *gomock.Controller
-	recorder *MockRedisCacheMockRecorder
+	recorder *MockPasswordHasherMockRecorder
 }
 
-// MockRedisCacheMockRecorder is the mock recorder for MockRedisCache.
-type MockRedisCacheMockRecorder struct {
-	mock *MockRedisCache
+// MockPasswordHasherMockRecorder is the mock recorder for MockPasswordHasher.
+type MockPasswordHasherMockRecorder struct {
+	mock *MockPasswordHasher
 }
 
-// NewMockRedisCache creates a new mock instance.
-func NewMockRedisCache(ctrl *gomock.Controller) *MockRedisCache {
-	mock := &MockRedisCache{ctrl: ctrl}
-	mock.recorder = &MockRedisCacheMockRecorder{mock}
+// NewMockPasswordHasher creates a new mock instance.
+func NewMockPasswordHasher(ctrl *gomock.Controller) *MockPasswordHasher {
+	mock := &MockPasswordHasher{ctrl: ctrl}
+	mock.recorder = &MockPasswordHasherMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockRedisCache) EXPECT() *MockRedisCacheMockRecorder {
+func (m *MockPasswordHasher) EXPECT() *MockPasswordHasherMockRecorder {
 	return m.recorder
 }
 
-// Get mocks base method.
-func (m *MockRedisCache) Get(ctx context.Context, key string) (any, error) {
+// Hash mocks base method.
+func (m *MockPasswordHasher) Hash(password string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", ctx, key)
-	ret0, _ := ret[0].(any)
+	ret := m.ctrl.Call(m, "Hash", password)
+	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Get indicates an expected call of Get.
-func (mr *MockRedisCacheMockRecorder) Get(ctx, key interface{}) *gomock.Call {
+// Hash indicates an expected call of Hash.
+func (mr *MockPasswordHasherMockRecorder) Hash(password interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRedisCache)(nil).Get), ctx, key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Hash", reflect.TypeOf((*MockPasswordHasher)(nil).Hash), password)
 }
 
-// Set mocks base method.
-func (m *MockRedisCache) Set(ctx context.Context, key string, value any) error {
+// Verify mocks base method.
+func (m *MockPasswordHasher) Verify(password, hash string) (bool, bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Set", ctx, key, value)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "Verify", password, hash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
-// Set indicates an expected call of Set.
-func (mr *MockRedisCacheMockRecorder) Set(ctx, key, value interface{}) *gomock.Call {
+// Verify indicates an expected call of Verify.
+func (mr *MockPasswordHasherMockRecorder) Verify(password, hash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockRedisCache)(nil).Set), ctx, key, value)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockPasswordHasher)(nil).Verify), password, hash)
 }
 
-// MockConverterWEBAPI is a mock of ConverterWEBAPI interface.
-type MockConverterWEBAPI struct {
+// MockRateProvider is a mock of RateProvider interface.
+type MockRateProvider struct {
 	ctrl     *gomock.Controller
-	recorder *MockConverterWEBAPIMockRecorder
+	recorder *MockRateProviderMockRecorder
 }
 
-// MockConverterWEBAPIMockRecorder is the mock recorder for MockConverterWEBAPI.
-type MockConverterWEBAPIMockRecorder struct {
-	mock *MockConverterWEBAPI
+// MockRateProviderMockRecorder is the mock recorder for MockRateProvider.
+type MockRateProviderMockRecorder struct {
+	mock *MockRateProvider
 }
 
-// NewMockConverterWEBAPI creates a new mock instance.
-func NewMockConverterWEBAPI(ctrl *gomock.Controller) *MockConverterWEBAPI {
-	mock := &MockConverterWEBAPI{ctrl: ctrl}
-	mock.recorder = &MockConverterWEBAPIMockRecorder{mock}
+// NewMockRateProvider creates a new mock instance.
+func NewMockRateProvider(ctrl *gomock.Controller) *MockRateProvider {
+	mock := &MockRateProvider{ctrl: ctrl}
+	mock.recorder = &MockRateProviderMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockConverterWEBAPI) EXPECT() *MockConverterWEBAPIMockRecorder {
+func (m *MockRateProvider) EXPECT() *MockRateProviderMockRecorder {
 	return m.recorder
 }
 
-// ConvertToCurrency mocks base method.
-func (m *MockConverterWEBAPI) ConvertToCurrency(ctx context.Context, currencyTo string, amount float64) (float64, error) {
+// Rate mocks base method.
+func (m *MockRateProvider) Rate(ctx context.Context, currency string) (float64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ConvertToCurrency", ctx, currencyTo, amount)
+	ret := m.ctrl.Call(m, "Rate", ctx, currency)
 	ret0, _ := ret[0].(float64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ConvertToCurrency indicates an expected call of ConvertToCurrency.
-func (mr *MockConverterWEBAPIMockRecorder) ConvertToCurrency(ctx, currencyTo, amount interface{}) *gomock.Call {
+// Rate indicates an expected call of Rate.
+func (mr *MockRateProviderMockRecorder) Rate(ctx, currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rate", reflect.TypeOf((*MockRateProvider)(nil).Rate), ctx, currency)
+}
+
+// RateOn mocks base method.
+func (m *MockRateProvider) RateOn(ctx context.Context, currency string, date time.Time) (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RateOn", ctx, currency, date)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RateOn indicates an expected call of RateOn.
+func (mr *MockRateProviderMockRecorder) RateOn(ctx, currency, date interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConvertToCurrency", reflect.TypeOf((*MockConverterWEBAPI)(nil).ConvertToCurrency), ctx, currencyTo, amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RateOn", reflect.TypeOf((*MockRateProvider)(nil).RateOn), ctx, currency, date)
 }
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+	"strings"
+	"sync"
+	"time"
+	"user-balance-service/internal/entity"
+)
+
+const (
+	defaultMaxRateAge = time.Hour
+)
+
+// RateStore keeps the latest exchange rate of every requested currency.
+// Rates are served from memory while they are younger than the staleness
+// limit, older ones are fetched again from the converter. Run refreshes
+// all known rates in the background so requests rarely hit the converter.
+type RateStore struct {
+	provider RateProvider
+	maxAge   time.Duration
+
+	mu    sync.RWMutex
+	rates map[string]entity.Rate
+
+	// fetches collapses concurrent fetches of the same currency
+	fetches singleflight.Group
+	now     func() time.Time
+}
+
+type RateOption func(*RateStore)
+
+// MaxRateAge is the staleness limit, rates older than that are never served
+func MaxRateAge(d time.Duration) RateOption {
+	return func(s *RateStore) {
+		s.maxAge = d
+	}
+}
+
+// Preload registers currencies refreshed by Run before anyone asks for them
+func Preload(currencies ...string) RateOption {
+	return func(s *RateStore) {
+		for _, currency := range currencies {
+			s.rates[strings.ToUpper(currency)] = entity.Rate{}
+		}
+	}
+}
+
+func NewRateStore(provider RateProvider, opts ...RateOption) *RateStore {
+	s := &RateStore{
+		provider: provider,
+		maxAge:   defaultMaxRateAge,
+		rates:    make(map[string]entity.Rate),
+		now:      time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Rate returns the rate of currency, fetching it if the stored one is stale
+func (s *RateStore) Rate(ctx context.Context, currency string) (entity.Rate, error) {
+	currency = strings.ToUpper(currency)
+
+	s.mu.RLock()
+	rate, ok := s.rates[currency]
+	s.mu.RUnlock()
+
+	if ok && s.fresh(rate) {
+		return rate, nil
+	}
+
+	return s.fetch(ctx, currency)
+}
+
+// Refresh fetches every known currency. It returns the first error, but
+// still tries the remaining currencies.
+func (s *RateStore) Refresh(ctx context.Context) error {
+	s.mu.RLock()
+	currencies := make([]string, 0, len(s.rates))
+	for currency := range s.rates {
+		currencies = append(currencies, currency)
+	}
+	s.mu.RUnlock()
+
+	var firstErr error
+	for _, currency := range currencies {
+		_, err := s.fetch(ctx, currency)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Run refreshes the rates every interval until ctx is cancelled
+func (s *RateStore) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.Refresh(ctx)
+		if err != nil {
+			log.Warnf("service - RateStore - Run - s.Refresh: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *RateStore) fresh(rate entity.Rate) bool {
+	return !rate.FetchedAt.IsZero() && s.now().Sub(rate.FetchedAt) <= s.maxAge
+}
+
+func (s *RateStore) fetch(ctx context.Context, currency string) (entity.Rate, error) {
+	v, err, _ := s.fetches.Do(currency, func() (interface{}, error) {
+		value, err := s.provider.Rate(ctx, currency)
+		if errors.Is(err, ErrUnknownCurrency) {
+			return entity.Rate{}, fmt.Errorf("service - RateStore - fetch - s.provider.Rate: %w", err)
+		}
+		if err != nil {
+			return entity.Rate{}, &ConverterError{Err: err}
+		}
+		if value <= 0 {
+			return entity.Rate{}, &ConverterError{Err: fmt.Errorf("rate of %s is %v", currency, value)}
+		}
+
+		rate := entity.Rate{Currency: currency, Rate: value, FetchedAt: s.now()}
+
+		s.mu.Lock()
+		s.rates[currency] = rate
+		s.mu.Unlock()
+
+		return rate, nil
+	})
+
+	return v.(entity.Rate), err
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+func newTestRateStore(t *testing.T, opts ...RateOption) (*RateStore, *mock_service.MockRateProvider, *time.Time) {
+	ctrl := gomock.NewController(t)
+	provider := mock_service.NewMockRateProvider(ctrl)
+
+	now := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+	s := NewRateStore(provider, opts...)
+	s.now = func() time.Time { return now }
+
+	return s, provider, &now
+}
+
+func TestRateStore_Rate(t *testing.T) {
+	s, provider, now := newTestRateStore(t, MaxRateAge(time.Hour))
+	ctx := context.Background()
+	fetchedAt := *now
+
+	provider.EXPECT().Rate(gomock.Any(), "USD").Return(60.5, nil)
+
+	// currency codes are case-insensitive
+	rate, err := s.Rate(ctx, "usd")
+	require.NoError(t, err)
+	assert.Equal(t, entity.Rate{Currency: "USD", Rate: 60.5, FetchedAt: fetchedAt}, rate)
+
+	// served from the store within the staleness limit
+	*now = now.Add(time.Hour)
+	rate, err = s.Rate(ctx, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, fetchedAt, rate.FetchedAt)
+
+	// stale rates are fetched again
+	*now = now.Add(time.Second)
+	provider.EXPECT().Rate(gomock.Any(), "USD").Return(61.0, nil)
+	rate, err = s.Rate(ctx, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, entity.Rate{Currency: "USD", Rate: 61, FetchedAt: *now}, rate)
+}
+
+func TestRateStore_Rate_Errors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rate  float64
+		err   error
+		stale bool
+	}{
+		{
+			name: "converter error",
+			err:  errors.New("connection refused"),
+		},
+		{
+			name: "zero rate",
+			rate: 0,
+		},
+		{
+			name:  "stale rate is not served",
+			err:   errors.New("connection refused"),
+			stale: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, provider, now := newTestRateStore(t, MaxRateAge(time.Minute))
+			ctx := context.Background()
+
+			if tc.stale {
+				provider.EXPECT().Rate(gomock.Any(), "USD").Return(60.0, nil)
+				_, err := s.Rate(ctx, "USD")
+				require.NoError(t, err)
+				*now = now.Add(2 * time.Minute)
+			}
+
+			provider.EXPECT().Rate(gomock.Any(), "USD").Return(tc.rate, tc.err)
+
+			_, err := s.Rate(ctx, "USD")
+			assert.ErrorIs(t, err, ErrConverterUnavailable)
+			if tc.err != nil {
+				// the provider error is kept
+				assert.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestRateStore_Rate_UnknownCurrency(t *testing.T) {
+	s, provider, _ := newTestRateStore(t)
+
+	provider.EXPECT().Rate(gomock.Any(), "XXX").Return(0.0, fmt.Errorf("webapi: %w", ErrUnknownCurrency))
+
+	// no provider quoting the currency isn't an outage
+	_, err := s.Rate(context.Background(), "XXX")
+	assert.ErrorIs(t, err, ErrUnknownCurrency)
+	assert.NotErrorIs(t, err, ErrConverterUnavailable)
+}
+
+func TestRateStore_Refresh(t *testing.T) {
+	s, provider, now := newTestRateStore(t, Preload("usd", "EUR"))
+	ctx := context.Background()
+
+	provider.EXPECT().Rate(gomock.Any(), "USD").Return(60.0, nil)
+	provider.EXPECT().Rate(gomock.Any(), "EUR").Return(0.0, errors.New("timeout"))
+
+	// a failing currency doesn't stop the others
+	err := s.Refresh(ctx)
+	assert.ErrorIs(t, err, ErrConverterUnavailable)
+
+	rate, err := s.Rate(ctx, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, entity.Rate{Currency: "USD", Rate: 60, FetchedAt: *now}, rate)
+}
+
+func TestRateStore_Rate_Concurrent(t *testing.T) {
+	s, provider, _ := newTestRateStore(t)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	provider.EXPECT().Rate(gomock.Any(), "USD").
+		DoAndReturn(func(context.Context, string) (float64, error) {
+			<-release
+			return 60, nil
+		}).
+		Times(1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rate, err := s.Rate(ctx, "USD")
+			assert.NoError(t, err)
+			assert.Equal(t, float64(60), rate.Rate)
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+}
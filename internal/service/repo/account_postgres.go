@@ -5,35 +5,90 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Masterminds/squirrel"
-	"github.com/mitchellh/mapstructure"
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	"user-balance-service/pkg/cache"
 	"user-balance-service/pkg/postgres"
-	"user-balance-service/pkg/rediscache"
+	"user-balance-service/pkg/tracing"
 )
 
-const accountRedisKeyPrefix = "account_id"
+// accountRedisKeyPrefix changes with the cached fields of entity.Account
+const accountRedisKeyPrefix = "account_v2"
+
+// accountColumns are scanned by accountFields
+var accountColumns = []string{"id", "balance", "version", "COALESCE(user_id, 0)", "frozen"}
+
+func accountFields(account *entity.Account) []interface{} {
+	return []interface{}{&account.Id, &account.Balance, &account.Version, &account.UserId, &account.Frozen}
+}
 
 type AccountRepo struct {
 	*postgres.Postgres
-	*rediscache.Redis
+	service.RedisCache
+
+	// loads collapses concurrent cache misses of the same key
+	loads singleflight.Group
 }
 
-func NewAccountRepo(pg *postgres.Postgres, redisCache *rediscache.Redis) *AccountRepo {
+func NewAccountRepo(pg *postgres.Postgres, redisCache service.RedisCache) *AccountRepo {
 	return &AccountRepo{
-		Postgres: pg,
-		Redis:    redisCache,
+		Postgres:   pg,
+		RedisCache: redisCache,
 	}
 }
 
-func accountRedisKey(id int) string {
-	return fmt.Sprintf("%s_%d", accountRedisKeyPrefix, id)
+// accountVersionKeyPrefix prefixes the per-account version embedded in the
+// cache key of an account. A write bumps the version instead of deleting the
+// key, so a reader that loaded the account before the write stores it under
+// the old version and can't bring the stale balance back into the cache.
+const accountVersionKeyPrefix = "account_version"
+
+func accountVersionKey(id int) string {
+	return fmt.Sprintf("%s_%d", accountVersionKeyPrefix, id)
+}
+
+func accountRedisKey(id int, version int64) string {
+	return fmt.Sprintf("%s_%d_v%d", accountRedisKeyPrefix, id, version)
+}
+
+// accountKey returns the cache key of the current version of account id
+func (a *AccountRepo) accountKey(ctx context.Context, id int) (string, error) {
+	version, err := a.RedisCache.Counter(ctx, accountVersionKey(id))
+	if err != nil {
+		return "", fmt.Errorf("a.RedisCache.Counter: %w", err)
+	}
+
+	return accountRedisKey(id, version), nil
+}
+
+// invalidateAccounts drops cached accounts by bumping their versions. Old
+// keys become unreachable and simply expire. The changes are saved by now, so
+// a failed bump doesn't fail the write: the cache logs and counts it, and the
+// stale entry lives no longer than the TTL.
+func (a *AccountRepo) invalidateAccounts(ctx context.Context, ids ...int) {
+	for _, id := range ids {
+		_, _ = a.RedisCache.Incr(ctx, accountVersionKey(id))
+	}
 }
 
-func (a *AccountRepo) CreateAccount(ctx context.Context) (int, error) {
+// CreateAccount creates an empty account owned by userId, 0 for no owner
+func (a *AccountRepo) CreateAccount(ctx context.Context, userId int) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "AccountRepo.CreateAccount", trace.WithAttributes(attribute.Int("user.id", userId)))
+	defer tracing.End(span, &err)
+
+	var owner interface{}
+	if userId != 0 {
+		owner = userId
+	}
+
 	sql, args, err := a.Builder.
 		Insert("accounts").
-		Columns("balance").
-		Values(0).
+		Columns("balance", "user_id").
+		Values(0, owner).
 		Suffix("RETURNING id").
 		ToSql()
 
@@ -50,202 +105,342 @@ func (a *AccountRepo) CreateAccount(ctx context.Context) (int, error) {
 	return id, nil
 }
 
-func (a *AccountRepo) DeleteAccount(ctx context.Context, id int) error {
-	sql, args, err := a.Builder.
+func (a *AccountRepo) DeleteAccount(ctx context.Context, id, version int) (err error) {
+	ctx, span := tracer.Start(ctx, "AccountRepo.DeleteAccount", trace.WithAttributes(attribute.Int("account.id", id)))
+	defer tracing.End(span, &err)
+
+	query := a.Builder.
 		Delete("accounts").
-		Where("id = ?", id).
-		ToSql()
+		Where("id = ?", id)
+	if version != 0 {
+		query = query.Where(squirrel.Eq{"version": version})
+	}
 
+	sql, args, err := query.ToSql()
 	if err != nil {
 		return fmt.Errorf("repo - AccountRepo - DeleteAccount - a.Builder: %w", err)
 	}
 
-	_, err = a.Pool.Exec(ctx, sql, args...)
+	tag, err := a.Pool.Exec(ctx, sql, args...)
 	if err != nil {
 		return fmt.Errorf("repo - AccountRepo - DeleteAccount - a.Pool.Exec: %w", err)
 	}
-
-	// save changes in cache
-	err = a.Redis.Set(ctx, accountRedisKey(id), nil)
-	if err != nil {
-		return fmt.Errorf("repo - AccountRepo - WriteOff - a.Redis.Set: %w", err)
+	if tag.RowsAffected() == 0 {
+		_, err = a.checkVersion(ctx, id, version)
+		if err == nil {
+			err = service.ErrAccountNotFound
+		}
+		return fmt.Errorf("repo - AccountRepo - DeleteAccount: %w", err)
 	}
 
+	a.invalidateAccounts(ctx, id)
+
 	return nil
 }
 
-func (a *AccountRepo) WriteOff(ctx context.Context, id, amount int) error {
-	account, err := a.GetAccount(ctx, id)
-	if err != nil {
-		return fmt.Errorf("repo - AccountRepo - WriteOff - a.GetAccount: %w", err)
-	}
-
-	if account.Balance-amount < 0 {
-		return fmt.Errorf("repo - AccountRepo - WriteOff - balance can't be less than 0")
-	}
+// WriteOff checks the balance and debits it in a single guarded UPDATE,
+// so concurrent write-offs can't push the balance below 0 or debit an account
+// that is being frozen.
+func (a *AccountRepo) WriteOff(ctx context.Context, id, amount, version int) (err error) {
+	ctx, span := tracer.Start(ctx, "AccountRepo.WriteOff", trace.WithAttributes(attribute.Int("account.id", id)))
+	defer tracing.End(span, &err)
 
-	sql, args, err := a.Builder.
+	query := a.Builder.
 		Update("accounts").
 		Set("balance", squirrel.Expr("balance - ?", amount)).
-		Where("id = ?", id).
-		ToSql()
+		Set("version", squirrel.Expr("version + 1")).
+		Where(squirrel.Eq{"id": id}).
+		Where(squirrel.GtOrEq{"balance": amount}).
+		Where(squirrel.Eq{"frozen": false})
+	if version != 0 {
+		query = query.Where(squirrel.Eq{"version": version})
+	}
+
+	sql, args, err := query.ToSql()
 	if err != nil {
 		return fmt.Errorf("repo - AccountRepo - WriteOff - a.Builder: %w", err)
 	}
 
-	_, err = a.Pool.Exec(ctx, sql, args...)
+	tag, err := a.Pool.Exec(ctx, sql, args...)
 	if err != nil {
 		return fmt.Errorf("repo - AccountRepo - WriteOff - a.Pool.Exec: %w", err)
 	}
-
-	// save changes in cache
-	account.Balance -= amount
-	err = a.Redis.Set(ctx, accountRedisKey(id), account)
-	if err != nil {
-		return fmt.Errorf("repo - AccountRepo - WriteOff - a.Redis.Set: %w", err)
+	if tag.RowsAffected() == 0 {
+		// the account exists and has the expected version, so the frozen or
+		// the balance guard failed
+		var current entity.Account
+		current, err = a.checkVersion(ctx, id, version)
+		switch {
+		case err != nil:
+		case current.Frozen:
+			err = service.ErrAccountFrozen
+		default:
+			err = service.ErrInsufficientFunds
+		}
+		return fmt.Errorf("repo - AccountRepo - WriteOff: %w", err)
 	}
 
+	a.invalidateAccounts(ctx, id)
+
 	return nil
 }
 
-func (a *AccountRepo) GetAccount(ctx context.Context, id int) (entity.Account, error) {
-	var account entity.Account
-
-	// search in cache
-	value, err := a.Redis.Get(ctx, accountRedisKey(id))
-	if value != nil && err == nil {
-		err := mapstructure.Decode(value.(map[string]interface{}), &account)
-		if err != nil {
-			return entity.Account{}, fmt.Errorf("repo - AccountRepo - GetAccount - mapstructure.Decode: %w", err)
-		}
-		return account, nil
-	}
-
-	// do request
+// checkVersion explains why a guarded statement matched no rows: the account
+// is missing or its version differs from the expected one. It returns the
+// current account and nil when neither is the case, so the statement failed
+// on some other condition.
+func (a *AccountRepo) checkVersion(ctx context.Context, id, version int) (entity.Account, error) {
 	sql, args, err := a.Builder.
-		Select("id, balance").
+		Select(accountColumns...).
 		From("accounts").
-		Where("id = ?", id).
+		Where(squirrel.Eq{"id": id}).
 		ToSql()
-
 	if err != nil {
-		return entity.Account{}, fmt.Errorf("repo - AccountRepo - GetAccount - a.Builder: %w", err)
+		return entity.Account{}, fmt.Errorf("a.Builder: %w", err)
 	}
 
-	err = a.Pool.QueryRow(ctx, sql, args...).Scan(&account.Id, &account.Balance)
+	var current entity.Account
+	err = a.Pool.QueryRow(ctx, sql, args...).Scan(accountFields(&current)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.Account{}, service.ErrAccountNotFound
+	}
 	if err != nil {
-		return entity.Account{}, fmt.Errorf("repo - AccountRepo - GetAccount - a.Pool.QueryRow: %w", err)
+		return entity.Account{}, fmt.Errorf("a.Pool.QueryRow: %w", err)
+	}
+
+	if version != 0 && current.Version != version {
+		return entity.Account{}, &service.VersionMismatchError{Current: current}
+	}
+
+	return current, nil
+}
+
+func (a *AccountRepo) GetAccount(ctx context.Context, id int) (_ entity.Account, err error) {
+	ctx, span := tracer.Start(ctx, "AccountRepo.GetAccount", trace.WithAttributes(attribute.Int("account.id", id)))
+	defer tracing.End(span, &err)
+
+	load := func(ctx context.Context) (entity.Account, error) {
+		sql, args, err := a.Builder.
+			Select(accountColumns...).
+			From("accounts").
+			Where("id = ?", id).
+			ToSql()
+
+		if err != nil {
+			return entity.Account{}, fmt.Errorf("repo - AccountRepo - GetAccount - a.Builder: %w", err)
+		}
+
+		var account entity.Account
+		err = a.Pool.QueryRow(ctx, sql, args...).Scan(accountFields(&account)...)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.Account{}, fmt.Errorf("repo - AccountRepo - GetAccount - a.Pool.QueryRow: %w", service.ErrAccountNotFound)
+		}
+		if err != nil {
+			return entity.Account{}, fmt.Errorf("repo - AccountRepo - GetAccount - a.Pool.QueryRow: %w", err)
+		}
+
+		return account, nil
 	}
 
-	// save in cache
-	err = a.Redis.Set(ctx, accountRedisKey(id), account)
+	// a value found under a wrong version might be stale, so the cache is
+	// bypassed when the version can't be read
+	key, err := a.accountKey(ctx, id)
 	if err != nil {
-		return entity.Account{}, fmt.Errorf("repo - AccountRepo - GetAccount - a.Redis.Set: %w", err)
+		return load(ctx)
 	}
 
-	return account, nil
+	return cache.GetOrLoad(ctx, a.RedisCache, &a.loads, key, load)
 }
 
-func (a *AccountRepo) MakeDeposit(ctx context.Context, id, amount int) error {
-	sql, args, err := a.Builder.
+func (a *AccountRepo) MakeDeposit(ctx context.Context, id, amount, version int) (err error) {
+	ctx, span := tracer.Start(ctx, "AccountRepo.MakeDeposit", trace.WithAttributes(attribute.Int("account.id", id)))
+	defer tracing.End(span, &err)
+
+	query := a.Builder.
 		Update("accounts").
 		Set("balance", squirrel.Expr("balance + ?", amount)).
-		Where(squirrel.Eq{"id": id}).
-		ToSql()
+		Set("version", squirrel.Expr("version + 1")).
+		Where(squirrel.Eq{"id": id})
+	if version != 0 {
+		query = query.Where(squirrel.Eq{"version": version})
+	}
 
+	sql, args, err := query.ToSql()
 	if err != nil {
 		return fmt.Errorf("repo - AccountRepo - MakeDeposit - r.Builder: %w", err)
 	}
 
-	_, err = a.Pool.Exec(ctx, sql, args...)
+	tag, err := a.Pool.Exec(ctx, sql, args...)
 	if err != nil {
 		return fmt.Errorf("repo - AccountRepo - MakeDeposit - r.Pool.Exec: %w", err)
 	}
+	if tag.RowsAffected() == 0 {
+		_, err = a.checkVersion(ctx, id, version)
+		if err == nil {
+			err = service.ErrAccountNotFound
+		}
+		return fmt.Errorf("repo - AccountRepo - MakeDeposit: %w", err)
+	}
+
+	a.invalidateAccounts(ctx, id)
+
+	return nil
+}
+
+// SetFrozen freezes or unfreezes the account; the version is bumped so cached
+// ETags go stale
+func (a *AccountRepo) SetFrozen(ctx context.Context, id int, frozen bool) (err error) {
+	ctx, span := tracer.Start(ctx, "AccountRepo.SetFrozen", trace.WithAttributes(attribute.Int("account.id", id)))
+	defer tracing.End(span, &err)
 
-	// save changes in cache
-	account, err := a.GetAccount(ctx, id)
+	sql, args, err := a.Builder.
+		Update("accounts").
+		Set("frozen", frozen).
+		Set("version", squirrel.Expr("version + 1")).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
 	if err != nil {
-		return fmt.Errorf("repo - AccountRepo - MakeDeposit - a.GetAccount: %w", err)
+		return fmt.Errorf("repo - AccountRepo - SetFrozen - a.Builder: %w", err)
 	}
-	account.Balance += amount
-	err = a.Redis.Set(ctx, accountRedisKey(id), account)
+
+	tag, err := a.Pool.Exec(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf("repo - AccountRepo - MakeDeposit - a.Redis.Set: %w", err)
+		return fmt.Errorf("repo - AccountRepo - SetFrozen - a.Pool.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("repo - AccountRepo - SetFrozen: %w", service.ErrAccountNotFound)
 	}
 
+	a.invalidateAccounts(ctx, id)
+
 	return nil
 }
 
-// TransferMoney TODO: check if amount > 0
-func (a *AccountRepo) TransferMoney(ctx context.Context, idFrom, idTo, amount int) error {
+// TransferMoney locks both accounts with SELECT ... FOR UPDATE before checking
+// the balance. Rows are always locked in id order, so two opposite transfers
+// between the same accounts can't deadlock.
+func (a *AccountRepo) TransferMoney(ctx context.Context, idFrom, idTo, amount, version int) (err error) {
+	ctx, span := tracer.Start(ctx, "AccountRepo.TransferMoney", trace.WithAttributes(attribute.Int("account.id_from", idFrom), attribute.Int("account.id_to", idTo)))
+	defer tracing.End(span, &err)
+
 	if amount <= 0 {
-		return errors.New("repo - AccountRepo - TransferMoney - amount can't be 0 or less than 0")
+		return fmt.Errorf("repo - AccountRepo - TransferMoney - amount can't be 0 or less than 0: %w", service.ErrInvalidAmount)
+	}
+	if idFrom == idTo {
+		return fmt.Errorf("repo - AccountRepo - TransferMoney: %w", service.ErrSameAccount)
 	}
 
-	accountFrom, err := a.GetAccount(ctx, idFrom)
+	tx, err := a.Pool.Begin(ctx)
 	if err != nil {
-		return err
+		return fmt.Errorf("repo - AccountRepo - TransferMoney - a.Pool.Begin: %w", err)
 	}
-	accountTo, err := a.GetAccount(ctx, idTo)
+
+	// lock accounts
+	accounts, err := a.lockAccounts(ctx, tx, idFrom, idTo)
 	if err != nil {
-		return err
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - AccountRepo - TransferMoney - a.lockAccounts: %w", err)
 	}
 
-	if accountFrom.Balance-amount < 0 {
-		return errors.New("repo - AccountRepo - TransferMoney - balance can't be less than 0")
+	for _, id := range []int{idFrom, idTo} {
+		if _, ok := accounts[id]; !ok {
+			_ = tx.Rollback(ctx)
+			return fmt.Errorf("repo - AccountRepo - TransferMoney - account %d: %w", id, service.ErrAccountNotFound)
+		}
 	}
 
-	tx, err := a.Pool.Begin(ctx)
-	if err != nil {
-		return fmt.Errorf("repo - AccountRepo - TransferMoney - a.Pool.Begin: %w", err)
+	accountFrom := accounts[idFrom]
+
+	if version != 0 && accountFrom.Version != version {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - AccountRepo - TransferMoney: %w", &service.VersionMismatchError{Current: accountFrom})
+	}
+
+	if accountFrom.Frozen {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - AccountRepo - TransferMoney - account %d: %w", idFrom, service.ErrAccountFrozen)
+	}
+
+	if accountFrom.Balance-amount < 0 {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - AccountRepo - TransferMoney - balance can't be less than 0: %w", service.ErrInsufficientFunds)
 	}
 
 	// first operation
 	sql, args, err := a.Builder.
 		Update("accounts").
 		Set("balance", squirrel.Expr("balance - ?", amount)).
+		Set("version", squirrel.Expr("version + 1")).
 		Where(squirrel.Eq{"id": idFrom}).
 		ToSql()
 	if err != nil {
+		_ = tx.Rollback(ctx)
 		return fmt.Errorf("repo - AccountRepo - TransferMoney - a.Builder: %w", err)
 	}
 
-	_, err = a.Pool.Exec(ctx, sql, args...)
+	_, err = tx.Exec(ctx, sql, args...)
 	if err != nil {
 		_ = tx.Rollback(ctx)
-		return fmt.Errorf("repo - AccountRepo - TransferMoney - a.Pool.Exec: %w", err)
+		return fmt.Errorf("repo - AccountRepo - TransferMoney - tx.Exec: %w", err)
 	}
 
 	// second operation
 	sql, args, err = a.Builder.
 		Update("accounts").
 		Set("balance", squirrel.Expr("balance + ?", amount)).
+		Set("version", squirrel.Expr("version + 1")).
 		Where(squirrel.Eq{"id": idTo}).
 		ToSql()
 	if err != nil {
+		_ = tx.Rollback(ctx)
 		return fmt.Errorf("repo - AccountRepo - TransferMoney - a.Builder: %w", err)
 	}
 
-	_, err = a.Pool.Exec(ctx, sql, args...)
+	_, err = tx.Exec(ctx, sql, args...)
 	if err != nil {
 		_ = tx.Rollback(ctx)
-		return fmt.Errorf("repo - AccountRepo - TransferMoney - a.Pool.Exec: %w", err)
+		return fmt.Errorf("repo - AccountRepo - TransferMoney - tx.Exec: %w", err)
 	}
 
-	_ = tx.Commit(ctx)
+	err = commit(ctx, tx, func(ctx context.Context) {
+		a.invalidateAccounts(ctx, idFrom, idTo)
+	})
+	if err != nil {
+		return fmt.Errorf("repo - AccountRepo - TransferMoney - commit: %w", err)
+	}
 
-	// save changes in cache
-	accountFrom.Balance -= amount
-	accountTo.Balance += amount
+	return nil
+}
 
-	err = a.Redis.Set(ctx, accountRedisKey(idFrom), accountFrom)
+// lockAccounts selects accounts FOR UPDATE in ascending id order. Missing
+// accounts are absent from the result.
+func (a *AccountRepo) lockAccounts(ctx context.Context, tx pgx.Tx, ids ...int) (map[int]entity.Account, error) {
+	sql, args, err := a.Builder.
+		Select(accountColumns...).
+		From("accounts").
+		Where(squirrel.Eq{"id": ids}).
+		OrderBy("id").
+		Suffix("FOR UPDATE").
+		ToSql()
 	if err != nil {
-		return fmt.Errorf("repo - AccountRepo - TransferMoney - a.Redis.Set(idFrom): %w", err)
+		return nil, fmt.Errorf("a.Builder: %w", err)
 	}
-	err = a.Redis.Set(ctx, accountRedisKey(idTo), accountTo)
+
+	rows, err := tx.Query(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf("repo - AccountRepo - TransferMoney - a.Redis.Set(idTo): %w", err)
+		return nil, fmt.Errorf("tx.Query: %w", err)
 	}
+	defer rows.Close()
 
-	return nil
+	accounts := make(map[int]entity.Account, len(ids))
+	for rows.Next() {
+		var account entity.Account
+		err = rows.Scan(accountFields(&account)...)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		accounts[account.Id] = account
+	}
+
+	return accounts, rows.Err()
 }
@@ -6,14 +6,19 @@ import (
 	"github.com/Masterminds/squirrel"
 	"github.com/alicebob/miniredis"
 	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v4"
 	"github.com/pashagolub/pgxmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"testing"
 	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
 	"user-balance-service/pkg/postgres"
 	"user-balance-service/pkg/rediscache"
 )
 
+var errSomethingWentWrong = errors.New("something went wrong")
+
 func TestAccountRepo_CreateAccount(t *testing.T) {
 	// new mini Redis server
 	miniRedis, err := miniredis.Run()
@@ -39,9 +44,9 @@ func TestAccountRepo_CreateAccount(t *testing.T) {
 	accountRepoMock := NewAccountRepo(postgresMock, redisCache)
 
 	rows := pgxmock.NewRows([]string{"id"}).AddRow(1)
-	mockPool.ExpectQuery("INSERT INTO accounts").WillReturnRows(rows)
+	mockPool.ExpectQuery("INSERT INTO accounts").WithArgs(0, 1).WillReturnRows(rows)
 
-	id, err := accountRepoMock.CreateAccount(context.Background())
+	id, err := accountRepoMock.CreateAccount(context.Background(), 1)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, id)
 
@@ -55,6 +60,7 @@ func TestAccountRepo_GetAccount(t *testing.T) {
 	if err != nil {
 		t.Error()
 	}
+	defer miniRedis.Close()
 	redisClientMock := redis.NewClient(&redis.Options{Addr: miniRedis.Addr()})
 	redisCache := rediscache.New(redisClientMock)
 
@@ -94,17 +100,17 @@ func TestAccountRepo_GetAccount(t *testing.T) {
 				id:  1,
 			},
 			mockBehaviour: func(args args, account entity.Account) {
-				rows := mockPool.NewRows([]string{"id", "balance"}).
-					AddRow(1, 500)
+				rows := mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).
+					AddRow(1, 500, 3, 0, false)
 
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
+				mockPool.ExpectQuery("SELECT id, balance, version, COALESCE\\(user_id, 0\\), frozen FROM accounts").
 					WithArgs(args.id).
 					WillReturnRows(rows)
-				miniRedis.Close()
 			},
 			want: entity.Account{
 				Id:      1,
 				Balance: 500,
+				Version: 3,
 			},
 			wantErr: false,
 		},
@@ -112,13 +118,12 @@ func TestAccountRepo_GetAccount(t *testing.T) {
 			name: "fail when no such account",
 			args: args{
 				ctx: context.Background(),
-				id:  1,
+				id:  2,
 			},
 			mockBehaviour: func(args args, account entity.Account) {
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
+				mockPool.ExpectQuery("SELECT id, balance, version, COALESCE\\(user_id, 0\\), frozen FROM accounts").
 					WithArgs(args.id).
 					WillReturnError(errors.New("no such account"))
-				miniRedis.Close()
 			},
 			want:    entity.Account{},
 			wantErr: true,
@@ -149,6 +154,7 @@ func TestAccountRepo_WriteOff(t *testing.T) {
 	if err != nil {
 		t.Error()
 	}
+	defer miniRedis.Close()
 	client := redis.NewClient(&redis.Options{Addr: miniRedis.Addr()})
 	redisCache := rediscache.New(client)
 
@@ -167,9 +173,10 @@ func TestAccountRepo_WriteOff(t *testing.T) {
 	mockAccountRepo := NewAccountRepo(mockPostgres, redisCache)
 
 	type args struct {
-		ctx    context.Context
-		id     int
-		amount int
+		ctx     context.Context
+		id      int
+		amount  int
+		version int
 	}
 
 	type MockBehaviour func(args args)
@@ -178,7 +185,7 @@ func TestAccountRepo_WriteOff(t *testing.T) {
 		name          string
 		args          args
 		mockBehaviour MockBehaviour
-		wantErr       bool
+		wantErr       error
 	}{
 		{
 			name: "success",
@@ -188,22 +195,10 @@ func TestAccountRepo_WriteOff(t *testing.T) {
 				amount: 500,
 			},
 			mockBehaviour: func(args args) {
-				rows := mockPool.NewRows([]string{"id", "balance"}).
-					AddRow(args.id, 1000)
-
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
-					WithArgs(args.id).
-					WillReturnRows(rows)
-
-				result := pgxmock.NewResult("UPDATE", 1)
-
-				mockPool.ExpectExec("UPDATE accounts").
-					WithArgs(args.amount, args.id).
-					WillReturnResult(result)
-
-				miniRedis.Close()
+				mockPool.ExpectExec("UPDATE accounts SET balance = balance - (.+), version = version \\+ 1 WHERE id = (.+) AND balance >= (.+)").
+					WithArgs(args.amount, args.id, args.amount, false).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 			},
-			wantErr: false,
 		},
 		{
 			name: "success with exact same amount",
@@ -213,22 +208,10 @@ func TestAccountRepo_WriteOff(t *testing.T) {
 				amount: 500,
 			},
 			mockBehaviour: func(args args) {
-				rows := mockPool.NewRows([]string{"id", "balance"}).
-					AddRow(args.id, 500)
-
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
-					WithArgs(args.id).
-					WillReturnRows(rows)
-
-				result := pgxmock.NewResult("UPDATE", 1)
-
 				mockPool.ExpectExec("UPDATE accounts").
-					WithArgs(args.amount, args.id).
-					WillReturnResult(result)
-
-				miniRedis.Close()
+					WithArgs(args.amount, args.id, args.amount, false).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 			},
-			wantErr: false,
 		},
 		{
 			name: "fail when not enough money",
@@ -238,16 +221,17 @@ func TestAccountRepo_WriteOff(t *testing.T) {
 				amount: 500,
 			},
 			mockBehaviour: func(args args) {
-				rows := mockPool.NewRows([]string{"id", "balance"}).
-					AddRow(args.id, 400)
+				// the guarded update doesn't match the row
+				mockPool.ExpectExec("UPDATE accounts").
+					WithArgs(args.amount, args.id, args.amount, false).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 0))
 
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
+				rows := mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).AddRow(args.id, 400, 1, 0, false)
+				mockPool.ExpectQuery("SELECT id, balance, version, COALESCE\\(user_id, 0\\), frozen FROM accounts").
 					WithArgs(args.id).
 					WillReturnRows(rows)
-
-				miniRedis.Close()
 			},
-			wantErr: true,
+			wantErr: service.ErrInsufficientFunds,
 		},
 		{
 			name: "fail when wrong id",
@@ -257,15 +241,68 @@ func TestAccountRepo_WriteOff(t *testing.T) {
 				amount: 500,
 			},
 			mockBehaviour: func(args args) {
-				// write a command for a called function (getAccount)
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
+				mockPool.ExpectExec("UPDATE accounts").
+					WithArgs(args.amount, args.id, args.amount, false).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+				mockPool.ExpectQuery("SELECT id, balance, version, COALESCE\\(user_id, 0\\), frozen FROM accounts").
 					WithArgs(args.id).
-					WillReturnError(errors.New("no such account"))
+					WillReturnError(pgx.ErrNoRows)
+			},
+			wantErr: service.ErrAccountNotFound,
+		},
+		{
+			name: "success with matching version",
+			args: args{
+				ctx:     context.Background(),
+				id:      1,
+				amount:  500,
+				version: 4,
+			},
+			mockBehaviour: func(args args) {
+				mockPool.ExpectExec("UPDATE accounts (.+) AND version = (.+)").
+					WithArgs(args.amount, args.id, args.amount, false, args.version).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+			},
+		},
+		{
+			name: "fail when version mismatch",
+			args: args{
+				ctx:     context.Background(),
+				id:      1,
+				amount:  500,
+				version: 4,
+			},
+			mockBehaviour: func(args args) {
+				mockPool.ExpectExec("UPDATE accounts").
+					WithArgs(args.amount, args.id, args.amount, false, args.version).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 0))
 
-				// no sense to update when getAccount returned an error
-				miniRedis.Close()
+				rows := mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).AddRow(args.id, 1000, 5, 0, false)
+				mockPool.ExpectQuery("SELECT id, balance, version, COALESCE\\(user_id, 0\\), frozen FROM accounts").
+					WithArgs(args.id).
+					WillReturnRows(rows)
 			},
-			wantErr: true,
+			wantErr: service.ErrVersionMismatch,
+		},
+		{
+			name: "fail when account is frozen",
+			args: args{
+				ctx:    context.Background(),
+				id:     1,
+				amount: 500,
+			},
+			mockBehaviour: func(args args) {
+				mockPool.ExpectExec("UPDATE accounts").
+					WithArgs(args.amount, args.id, args.amount, false).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+				rows := mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).AddRow(args.id, 1000, 1, 0, true)
+				mockPool.ExpectQuery("SELECT id, balance, version, COALESCE\\(user_id, 0\\), frozen FROM accounts").
+					WithArgs(args.id).
+					WillReturnRows(rows)
+			},
+			wantErr: service.ErrAccountFrozen,
 		},
 	}
 
@@ -273,9 +310,9 @@ func TestAccountRepo_WriteOff(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.mockBehaviour(tc.args)
 
-			err := mockAccountRepo.WriteOff(tc.args.ctx, tc.args.id, tc.args.amount)
-			if tc.wantErr {
-				assert.Error(t, err)
+			err := mockAccountRepo.WriteOff(tc.args.ctx, tc.args.id, tc.args.amount, tc.args.version)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
 			} else {
 				assert.NoError(t, err)
 			}
@@ -291,6 +328,7 @@ func TestAccountRepo_TransferMoney(t *testing.T) {
 	if err != nil {
 		t.Error()
 	}
+	defer miniRedis.Close()
 
 	client := redis.NewClient(&redis.Options{Addr: miniRedis.Addr()})
 	redisCache := rediscache.New(client)
@@ -307,11 +345,14 @@ func TestAccountRepo_TransferMoney(t *testing.T) {
 
 	accountRepo := NewAccountRepo(mockPostgres, redisCache)
 
+	const lockQuery = "SELECT id, balance, version, COALESCE\\(user_id, 0\\), frozen FROM accounts WHERE id IN \\((.+)\\) ORDER BY id FOR UPDATE"
+
 	type args struct {
-		ctx    context.Context
-		idFrom int
-		idTo   int
-		amount int
+		ctx     context.Context
+		idFrom  int
+		idTo    int
+		amount  int
+		version int
 	}
 
 	type MockBehaviour func(args args)
@@ -320,7 +361,7 @@ func TestAccountRepo_TransferMoney(t *testing.T) {
 		name          string
 		args          args
 		mockBehaviour MockBehaviour
-		wantErr       bool
+		wantErr       error
 	}{
 		{
 			name: "OK",
@@ -331,20 +372,45 @@ func TestAccountRepo_TransferMoney(t *testing.T) {
 				amount: 500,
 			},
 			mockBehaviour: func(args args) {
-				rows := mockPool.NewRows([]string{"id", "balance"}).
-					AddRow(args.idFrom, 1000)
+				mockPool.ExpectBegin()
 
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
-					WithArgs(args.idFrom).
+				rows := mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).
+					AddRow(args.idFrom, 1000, 1, 0, false).
+					AddRow(args.idTo, 200, 1, 0, false)
+				mockPool.ExpectQuery(lockQuery).
+					WithArgs(args.idFrom, args.idTo).
 					WillReturnRows(rows)
 
-				rows = mockPool.NewRows([]string{"id", "balance"}).AddRow(args.idTo, 200)
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
-					WithArgs(args.idTo).
-					WillReturnRows(rows)
+				result := pgxmock.NewResult("UPDATE", 1)
+				mockPool.ExpectExec("UPDATE accounts").
+					WithArgs(args.amount, args.idFrom).WillReturnResult(result)
+
+				result = pgxmock.NewResult("UPDATE", 1)
+				mockPool.ExpectExec("UPDATE accounts").
+					WithArgs(args.amount, args.idTo).WillReturnResult(result)
 
+				mockPool.ExpectCommit()
+			},
+		},
+		{
+			name: "OK when locking in reverse order",
+			args: args{
+				ctx:    context.Background(),
+				idFrom: 2,
+				idTo:   1,
+				amount: 500,
+			},
+			mockBehaviour: func(args args) {
 				mockPool.ExpectBegin()
 
+				// rows come back ordered by id regardless of the transfer direction
+				rows := mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).
+					AddRow(args.idTo, 200, 1, 0, false).
+					AddRow(args.idFrom, 1000, 1, 0, false)
+				mockPool.ExpectQuery(lockQuery).
+					WithArgs(args.idFrom, args.idTo).
+					WillReturnRows(rows)
+
 				result := pgxmock.NewResult("UPDATE", 1)
 				mockPool.ExpectExec("UPDATE accounts").
 					WithArgs(args.amount, args.idFrom).WillReturnResult(result)
@@ -354,8 +420,6 @@ func TestAccountRepo_TransferMoney(t *testing.T) {
 					WithArgs(args.amount, args.idTo).WillReturnResult(result)
 
 				mockPool.ExpectCommit()
-
-				miniRedis.Close()
 			},
 		},
 		{
@@ -367,21 +431,18 @@ func TestAccountRepo_TransferMoney(t *testing.T) {
 				amount: 500,
 			},
 			mockBehaviour: func(args args) {
-				rows := mockPool.NewRows([]string{"id", "balance"}).
-					AddRow(args.idFrom, 499)
-
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
-					WithArgs(args.idFrom).
-					WillReturnRows(rows)
+				mockPool.ExpectBegin()
 
-				rows = mockPool.NewRows([]string{"id", "balance"}).AddRow(args.idTo, 200)
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
-					WithArgs(args.idTo).
+				rows := mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).
+					AddRow(args.idFrom, 499, 1, 0, false).
+					AddRow(args.idTo, 200, 1, 0, false)
+				mockPool.ExpectQuery(lockQuery).
+					WithArgs(args.idFrom, args.idTo).
 					WillReturnRows(rows)
 
-				miniRedis.Close()
+				mockPool.ExpectRollback()
 			},
-			wantErr: true,
+			wantErr: service.ErrInsufficientFunds,
 		},
 		{
 			name: "Failure incorrect input",
@@ -392,7 +453,18 @@ func TestAccountRepo_TransferMoney(t *testing.T) {
 				amount: 0,
 			},
 			mockBehaviour: func(args args) {},
-			wantErr:       true,
+			wantErr:       service.ErrInvalidAmount,
+		},
+		{
+			name: "Failure same account",
+			args: args{
+				ctx:    context.Background(),
+				idFrom: 1,
+				idTo:   1,
+				amount: 100,
+			},
+			mockBehaviour: func(args args) {},
+			wantErr:       service.ErrSameAccount,
 		},
 		{
 			name: "Failure incorrect id",
@@ -403,14 +475,43 @@ func TestAccountRepo_TransferMoney(t *testing.T) {
 				amount: 100,
 			},
 			mockBehaviour: func(args args) {
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
-					WithArgs(args.idFrom).
-					WillReturnError(errors.New("no such account"))
+				mockPool.ExpectBegin()
+
+				rows := mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).
+					AddRow(args.idTo, 200, 1, 0, false)
+				mockPool.ExpectQuery(lockQuery).
+					WithArgs(args.idFrom, args.idTo).
+					WillReturnRows(rows)
+
+				mockPool.ExpectRollback()
 			},
-			wantErr: true,
+			wantErr: service.ErrAccountNotFound,
 		},
 		{
-			name: "Failure in 1st insert",
+			name: "Failure version mismatch",
+			args: args{
+				ctx:     context.Background(),
+				idFrom:  1,
+				idTo:    2,
+				amount:  100,
+				version: 1,
+			},
+			mockBehaviour: func(args args) {
+				mockPool.ExpectBegin()
+
+				rows := mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).
+					AddRow(args.idFrom, 1000, 2, 0, false).
+					AddRow(args.idTo, 200, 1, 0, false)
+				mockPool.ExpectQuery(lockQuery).
+					WithArgs(args.idFrom, args.idTo).
+					WillReturnRows(rows)
+
+				mockPool.ExpectRollback()
+			},
+			wantErr: service.ErrVersionMismatch,
+		},
+		{
+			name: "Failure in 1st update",
 			args: args{
 				ctx:    context.Background(),
 				idFrom: 1,
@@ -418,31 +519,24 @@ func TestAccountRepo_TransferMoney(t *testing.T) {
 				amount: 500,
 			},
 			mockBehaviour: func(args args) {
-				rows := mockPool.NewRows([]string{"id", "balance"}).
-					AddRow(args.idFrom, 899)
-
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
-					WithArgs(args.idFrom).
-					WillReturnRows(rows)
+				mockPool.ExpectBegin()
 
-				rows = mockPool.NewRows([]string{"id", "balance"}).AddRow(args.idTo, 200)
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
-					WithArgs(args.idTo).
+				rows := mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).
+					AddRow(args.idFrom, 899, 1, 0, false).
+					AddRow(args.idTo, 200, 1, 0, false)
+				mockPool.ExpectQuery(lockQuery).
+					WithArgs(args.idFrom, args.idTo).
 					WillReturnRows(rows)
 
-				mockPool.ExpectBegin()
-
 				mockPool.ExpectExec("UPDATE accounts").
-					WithArgs(args.amount, args.idFrom).WillReturnError(errors.New("something went wrong"))
+					WithArgs(args.amount, args.idFrom).WillReturnError(errSomethingWentWrong)
 
 				mockPool.ExpectRollback()
-
-				miniRedis.Close()
 			},
-			wantErr: true,
+			wantErr: errSomethingWentWrong,
 		},
 		{
-			name: "Failure in 2nd insert",
+			name: "Failure in 2nd update",
 			args: args{
 				ctx:    context.Background(),
 				idFrom: 1,
@@ -450,20 +544,15 @@ func TestAccountRepo_TransferMoney(t *testing.T) {
 				amount: 500,
 			},
 			mockBehaviour: func(args args) {
-				rows := mockPool.NewRows([]string{"id", "balance"}).
-					AddRow(args.idFrom, 899)
-
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
-					WithArgs(args.idFrom).
-					WillReturnRows(rows)
+				mockPool.ExpectBegin()
 
-				rows = mockPool.NewRows([]string{"id", "balance"}).AddRow(args.idTo, 200)
-				mockPool.ExpectQuery("SELECT id, balance FROM accounts").
-					WithArgs(args.idTo).
+				rows := mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).
+					AddRow(args.idFrom, 899, 1, 0, false).
+					AddRow(args.idTo, 200, 1, 0, false)
+				mockPool.ExpectQuery(lockQuery).
+					WithArgs(args.idFrom, args.idTo).
 					WillReturnRows(rows)
 
-				mockPool.ExpectBegin()
-
 				result := pgxmock.NewResult("UPDATE", 1)
 				mockPool.ExpectExec("UPDATE accounts").
 					WithArgs(args.amount, args.idFrom).
@@ -471,13 +560,11 @@ func TestAccountRepo_TransferMoney(t *testing.T) {
 
 				mockPool.ExpectExec("UPDATE accounts").
 					WithArgs(args.amount, args.idTo).
-					WillReturnError(errors.New("something went wrong"))
+					WillReturnError(errSomethingWentWrong)
 
 				mockPool.ExpectRollback()
-
-				miniRedis.Close()
 			},
-			wantErr: true,
+			wantErr: errSomethingWentWrong,
 		},
 	}
 
@@ -485,9 +572,9 @@ func TestAccountRepo_TransferMoney(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.mockBehaviour(tc.args)
 
-			err := accountRepo.TransferMoney(tc.args.ctx, tc.args.idFrom, tc.args.idTo, tc.args.amount)
-			if tc.wantErr {
-				assert.Error(t, err)
+			err := accountRepo.TransferMoney(tc.args.ctx, tc.args.idFrom, tc.args.idTo, tc.args.amount, tc.args.version)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
 			} else {
 				assert.NoError(t, err)
 			}
@@ -496,5 +583,76 @@ func TestAccountRepo_TransferMoney(t *testing.T) {
 			assert.NoError(t, err)
 		})
 	}
+}
 
+func TestAccountRepo_GetAccount_NotFound(t *testing.T) {
+	miniRedis, err := miniredis.Run()
+	if err != nil {
+		t.Error()
+	}
+	defer miniRedis.Close()
+	redisCache := rediscache.New(redis.NewClient(&redis.Options{Addr: miniRedis.Addr()}))
+
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Error()
+	}
+	defer mockPool.Close()
+
+	mockPostgres := &postgres.Postgres{
+		Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		Pool:    mockPool,
+	}
+
+	accountRepo := NewAccountRepo(mockPostgres, redisCache)
+
+	mockPool.ExpectQuery("SELECT id, balance, version, COALESCE\\(user_id, 0\\), frozen FROM accounts").
+		WithArgs(1).
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err = accountRepo.GetAccount(context.Background(), 1)
+	assert.ErrorIs(t, err, service.ErrAccountNotFound)
+
+	err = mockPool.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestAccountRepo_SetFrozen(t *testing.T) {
+	miniRedis, err := miniredis.Run()
+	if err != nil {
+		t.Error()
+	}
+	defer miniRedis.Close()
+	redisCache := rediscache.New(redis.NewClient(&redis.Options{Addr: miniRedis.Addr()}))
+
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Error()
+	}
+	defer mockPool.Close()
+
+	mockPostgres := &postgres.Postgres{
+		Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		Pool:    mockPool,
+	}
+
+	accountRepo := NewAccountRepo(mockPostgres, redisCache)
+
+	require.NoError(t, miniRedis.Set(accountRedisKey(1, 0), `{"id":1,"balance":500,"version":1}`))
+
+	mockPool.ExpectExec("UPDATE accounts SET frozen = (.+), version = version \\+ 1 WHERE id = (.+)").
+		WithArgs(true, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec("UPDATE accounts").
+		WithArgs(false, 2).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	assert.NoError(t, accountRepo.SetFrozen(context.Background(), 1, true))
+	assert.True(t, miniRedis.Exists(accountVersionKey(1)))
+
+	err = accountRepo.SetFrozen(context.Background(), 2, false)
+	assert.ErrorIs(t, err, service.ErrAccountNotFound)
+
+	err = mockPool.ExpectationsWereMet()
+	assert.NoError(t, err)
 }
@@ -0,0 +1,219 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"strings"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	"user-balance-service/pkg/postgres"
+)
+
+var apiKeyColumns = []string{"id", "user_id", "name", "prefix", "scopes", "created_at", "last_used_at"}
+
+type ApiKeyRepo struct {
+	*postgres.Postgres
+}
+
+func NewApiKeyRepo(pg *postgres.Postgres) *ApiKeyRepo {
+	return &ApiKeyRepo{pg}
+}
+
+func (r *ApiKeyRepo) CreateApiKey(ctx context.Context, key entity.ApiKey) (entity.ApiKey, error) {
+	sql, args, err := r.Builder.
+		Insert("api_keys").
+		Columns("user_id", "name", "prefix", "key_hash", "scopes").
+		Values(key.UserId, key.Name, key.Prefix, key.Hash, permissionsToStrings(key.Scopes)).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+
+	if err != nil {
+		return entity.ApiKey{}, fmt.Errorf("repo - ApiKeyRepo - CreateApiKey - r.Builder: %w", err)
+	}
+
+	err = r.Pool.QueryRow(ctx, sql, args...).Scan(&key.Id, &key.CreatedAt)
+	if err != nil {
+		return entity.ApiKey{}, fmt.Errorf("repo - ApiKeyRepo - CreateApiKey - r.Pool.QueryRow: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetApiKey returns the active key with hash and the role of its owner. An
+// unknown or revoked key, or one of a disabled user, is
+// service.ErrInvalidApiKey.
+func (r *ApiKeyRepo) GetApiKey(ctx context.Context, hash string) (entity.ApiKey, error) {
+	sql, args, err := r.Builder.
+		Select("k.id", "k.user_id", "k.name", "k.prefix", "k.scopes", "k.created_at", "k.last_used_at", "u.role").
+		From("api_keys k").
+		Join("users u ON u.id = k.user_id").
+		Where("k.key_hash = ?", hash).
+		Where("k.revoked_at IS NULL").
+		Where("u.disabled_at IS NULL").
+		ToSql()
+
+	if err != nil {
+		return entity.ApiKey{}, fmt.Errorf("repo - ApiKeyRepo - GetApiKey - r.Builder: %w", err)
+	}
+
+	var (
+		key    entity.ApiKey
+		scopes []string
+	)
+	err = r.Pool.QueryRow(ctx, sql, args...).
+		Scan(&key.Id, &key.UserId, &key.Name, &key.Prefix, &scopes, &key.CreatedAt, &key.LastUsedAt, &key.Role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.ApiKey{}, fmt.Errorf("repo - ApiKeyRepo - GetApiKey - r.Pool.QueryRow: %w", service.ErrInvalidApiKey)
+	}
+	if err != nil {
+		return entity.ApiKey{}, fmt.Errorf("repo - ApiKeyRepo - GetApiKey - r.Pool.QueryRow: %w", err)
+	}
+	key.Scopes = stringsToPermissions(scopes)
+
+	return key, nil
+}
+
+// ListApiKeys returns the active keys of the user, oldest first
+func (r *ApiKeyRepo) ListApiKeys(ctx context.Context, userId int) ([]entity.ApiKey, error) {
+	sql, args, err := r.Builder.
+		Select(apiKeyColumns...).
+		From("api_keys").
+		Where("user_id = ?", userId).
+		Where("revoked_at IS NULL").
+		OrderBy("id").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("repo - ApiKeyRepo - ListApiKeys - r.Builder: %w", err)
+	}
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo - ApiKeyRepo - ListApiKeys - r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []entity.ApiKey{}
+	for rows.Next() {
+		key, err := scanApiKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repo - ApiKeyRepo - ListApiKeys - rows.Scan: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("repo - ApiKeyRepo - ListApiKeys - rows.Err: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RotateApiKey replaces the hash and prefix of the user's active key with id.
+// Anything else is service.ErrApiKeyNotFound.
+func (r *ApiKeyRepo) RotateApiKey(ctx context.Context, userId, id int, prefix, hash string) (entity.ApiKey, error) {
+	sql, args, err := r.Builder.
+		Update("api_keys").
+		Set("prefix", prefix).
+		Set("key_hash", hash).
+		Set("last_used_at", nil).
+		Where("id = ?", id).
+		Where("user_id = ?", userId).
+		Where("revoked_at IS NULL").
+		Suffix("RETURNING " + strings.Join(apiKeyColumns, ", ")).
+		ToSql()
+
+	if err != nil {
+		return entity.ApiKey{}, fmt.Errorf("repo - ApiKeyRepo - RotateApiKey - r.Builder: %w", err)
+	}
+
+	key, err := scanApiKey(r.Pool.QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.ApiKey{}, fmt.Errorf("repo - ApiKeyRepo - RotateApiKey - r.Pool.QueryRow: %w", service.ErrApiKeyNotFound)
+	}
+	if err != nil {
+		return entity.ApiKey{}, fmt.Errorf("repo - ApiKeyRepo - RotateApiKey - r.Pool.QueryRow: %w", err)
+	}
+
+	return key, nil
+}
+
+// RevokeApiKey revokes the user's active key with id. Anything else is
+// service.ErrApiKeyNotFound.
+func (r *ApiKeyRepo) RevokeApiKey(ctx context.Context, userId, id int) error {
+	sql, args, err := r.Builder.
+		Update("api_keys").
+		Set("revoked_at", squirrel.Expr("now()")).
+		Where("id = ?", id).
+		Where("user_id = ?", userId).
+		Where("revoked_at IS NULL").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - ApiKeyRepo - RevokeApiKey - r.Builder: %w", err)
+	}
+
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("repo - ApiKeyRepo - RevokeApiKey - r.Pool.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("repo - ApiKeyRepo - RevokeApiKey: %w", service.ErrApiKeyNotFound)
+	}
+
+	return nil
+}
+
+func (r *ApiKeyRepo) TouchApiKey(ctx context.Context, id int, usedAt time.Time) error {
+	sql, args, err := r.Builder.
+		Update("api_keys").
+		Set("last_used_at", usedAt).
+		Where("id = ?", id).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - ApiKeyRepo - TouchApiKey - r.Builder: %w", err)
+	}
+
+	_, err = r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("repo - ApiKeyRepo - TouchApiKey - r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+func scanApiKey(row pgx.Row) (entity.ApiKey, error) {
+	var (
+		key    entity.ApiKey
+		scopes []string
+	)
+	err := row.Scan(&key.Id, &key.UserId, &key.Name, &key.Prefix, &scopes, &key.CreatedAt, &key.LastUsedAt)
+	if err != nil {
+		return entity.ApiKey{}, err
+	}
+	key.Scopes = stringsToPermissions(scopes)
+
+	return key, nil
+}
+
+func permissionsToStrings(permissions []entity.Permission) []string {
+	s := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		s = append(s, string(p))
+	}
+	return s
+}
+
+func stringsToPermissions(s []string) []entity.Permission {
+	permissions := make([]entity.Permission, 0, len(s))
+	for _, p := range s {
+		permissions = append(permissions, entity.Permission(p))
+	}
+	return permissions
+}
@@ -0,0 +1,144 @@
+package repo
+
+import (
+	"context"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	"user-balance-service/pkg/postgres"
+)
+
+func newApiKeyTestRepo(t *testing.T) (*ApiKeyRepo, pgxmock.PgxPoolIface) {
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(mockPool.Close)
+
+	return NewApiKeyRepo(&postgres.Postgres{
+		Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		Pool:    mockPool,
+	}), mockPool
+}
+
+func TestApiKeyRepo_CreateApiKey(t *testing.T) {
+	repo, mockPool := newApiKeyTestRepo(t)
+	createdAt := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+
+	mockPool.ExpectQuery("INSERT INTO api_keys \\(user_id,name,prefix,key_hash,scopes\\)").
+		WithArgs(1, "billing", "ubs_0123abcd", "hash", []string{"accounts:refund"}).
+		WillReturnRows(mockPool.NewRows([]string{"id", "created_at"}).AddRow(7, createdAt))
+
+	key, err := repo.CreateApiKey(context.Background(), entity.ApiKey{
+		UserId: 1,
+		Name:   "billing",
+		Prefix: "ubs_0123abcd",
+		Scopes: []entity.Permission{entity.PermRefund},
+		Hash:   "hash",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 7, key.Id)
+	assert.Equal(t, createdAt, key.CreatedAt)
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestApiKeyRepo_GetApiKey(t *testing.T) {
+	createdAt := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name    string
+		rows    func(mockPool pgxmock.PgxPoolIface) *pgxmock.Rows
+		err     error
+		want    entity.ApiKey
+		wantErr error
+	}{
+		{
+			name: "OK",
+			rows: func(mockPool pgxmock.PgxPoolIface) *pgxmock.Rows {
+				return mockPool.NewRows([]string{"id", "user_id", "name", "prefix", "scopes", "created_at", "last_used_at", "role"}).
+					AddRow(7, 1, "billing", "ubs_0123abcd", []string{"accounts:refund"}, createdAt, nil, entity.RoleOperator)
+			},
+			want: entity.ApiKey{
+				Id:        7,
+				UserId:    1,
+				Name:      "billing",
+				Prefix:    "ubs_0123abcd",
+				Scopes:    []entity.Permission{entity.PermRefund},
+				CreatedAt: createdAt,
+				Role:      entity.RoleOperator,
+			},
+		},
+		{
+			name:    "Unknown or revoked",
+			err:     pgx.ErrNoRows,
+			wantErr: service.ErrInvalidApiKey,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mockPool := newApiKeyTestRepo(t)
+
+			query := mockPool.ExpectQuery("SELECT (.+) FROM api_keys k JOIN users u ON u.id = k.user_id WHERE k.key_hash = (.+) AND k.revoked_at IS NULL").
+				WithArgs("hash")
+			if tc.err != nil {
+				query.WillReturnError(tc.err)
+			} else {
+				query.WillReturnRows(tc.rows(mockPool))
+			}
+
+			got, err := repo.GetApiKey(context.Background(), "hash")
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, got)
+			}
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestApiKeyRepo_RotateApiKey(t *testing.T) {
+	repo, mockPool := newApiKeyTestRepo(t)
+	createdAt := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+
+	mockPool.ExpectQuery("UPDATE api_keys SET prefix = (.+), key_hash = (.+), last_used_at = (.+) WHERE id = (.+) AND user_id = (.+) AND revoked_at IS NULL RETURNING").
+		WithArgs("ubs_4567cdef", "new", nil, 7, 1).
+		WillReturnRows(mockPool.NewRows([]string{"id", "user_id", "name", "prefix", "scopes", "created_at", "last_used_at"}).
+			AddRow(7, 1, "billing", "ubs_4567cdef", []string{}, createdAt, nil))
+	mockPool.ExpectQuery("UPDATE api_keys").
+		WithArgs("ubs_4567cdef", "new", nil, 8, 1).
+		WillReturnError(pgx.ErrNoRows)
+
+	key, err := repo.RotateApiKey(context.Background(), 1, 7, "ubs_4567cdef", "new")
+	require.NoError(t, err)
+	assert.Equal(t, "ubs_4567cdef", key.Prefix)
+	assert.Equal(t, []entity.Permission{}, key.Scopes)
+
+	_, err = repo.RotateApiKey(context.Background(), 1, 8, "ubs_4567cdef", "new")
+	assert.ErrorIs(t, err, service.ErrApiKeyNotFound)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestApiKeyRepo_RevokeApiKey(t *testing.T) {
+	repo, mockPool := newApiKeyTestRepo(t)
+
+	mockPool.ExpectExec("UPDATE api_keys SET revoked_at = now\\(\\) WHERE id = (.+) AND user_id = (.+) AND revoked_at IS NULL").
+		WithArgs(7, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec("UPDATE api_keys").
+		WithArgs(7, 2).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	assert.NoError(t, repo.RevokeApiKey(context.Background(), 1, 7))
+	// someone else's key
+	assert.ErrorIs(t, repo.RevokeApiKey(context.Background(), 2, 7), service.ErrApiKeyNotFound)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
@@ -0,0 +1,114 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"user-balance-service/internal/entity"
+	"user-balance-service/pkg/postgres"
+)
+
+type AuditRepo struct {
+	*postgres.Postgres
+}
+
+func NewAuditRepo(pg *postgres.Postgres) *AuditRepo {
+	return &AuditRepo{pg}
+}
+
+// SaveAuditRecord appends record; a zero UserId or ApiKeyId is stored as
+// NULL
+func (r *AuditRepo) SaveAuditRecord(ctx context.Context, record entity.AuditRecord) error {
+	var userId, apiKeyId *int
+	if record.UserId != 0 {
+		userId = &record.UserId
+	}
+	if record.ApiKeyId != 0 {
+		apiKeyId = &record.ApiKeyId
+	}
+
+	params := record.Params
+	if len(params) == 0 {
+		params = json.RawMessage("{}")
+	}
+
+	sql, args, err := r.Builder.
+		Insert("audit_log").
+		Columns("user_id", "api_key_id", "role", "ip", "user_agent", "request_id", "operation",
+			"account_ids", "params", "status", "outcome", "error").
+		Values(userId, apiKeyId, record.Role, record.IP, record.UserAgent, record.RequestId, record.Operation,
+			record.AccountIds, params, record.Status, record.Outcome, record.Error).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - AuditRepo - SaveAuditRecord - r.Builder: %w", err)
+	}
+
+	_, err = r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("repo - AuditRepo - SaveAuditRecord - r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// ListAuditRecords returns a page of the records matching filter, newest
+// first
+func (r *AuditRepo) ListAuditRecords(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditRecord, error) {
+	builder := r.Builder.
+		Select("id", "COALESCE(user_id, 0)", "COALESCE(api_key_id, 0)", "role", "ip", "user_agent", "request_id",
+			"operation", "account_ids", "params", "status", "outcome", "error", "created_at").
+		From("audit_log").
+		OrderBy("id DESC").
+		Limit(uint64(filter.Limit))
+
+	if filter.UserId != 0 {
+		builder = builder.Where("user_id = ?", filter.UserId)
+	}
+	if filter.ApiKeyId != 0 {
+		builder = builder.Where("api_key_id = ?", filter.ApiKeyId)
+	}
+	if filter.AccountId != 0 {
+		// containment, unlike ANY, can use the GIN index
+		builder = builder.Where("account_ids @> ARRAY[?]::INT[]", filter.AccountId)
+	}
+	if !filter.From.IsZero() {
+		builder = builder.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		builder = builder.Where("created_at < ?", filter.To)
+	}
+	if filter.BeforeId != 0 {
+		builder = builder.Where("id < ?", filter.BeforeId)
+	}
+
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("repo - AuditRepo - ListAuditRecords - r.Builder: %w", err)
+	}
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo - AuditRepo - ListAuditRecords - r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	records := []entity.AuditRecord{}
+	for rows.Next() {
+		var record entity.AuditRecord
+		err = rows.Scan(&record.Id, &record.UserId, &record.ApiKeyId, &record.Role, &record.IP, &record.UserAgent,
+			&record.RequestId, &record.Operation, &record.AccountIds, &record.Params, &record.Status, &record.Outcome,
+			&record.Error, &record.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("repo - AuditRepo - ListAuditRecords - rows.Scan: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("repo - AuditRepo - ListAuditRecords - rows.Err: %w", err)
+	}
+
+	return records, nil
+}
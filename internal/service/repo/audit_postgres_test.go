@@ -0,0 +1,137 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Masterminds/squirrel"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/pkg/postgres"
+)
+
+func newAuditTestRepo(t *testing.T) (*AuditRepo, pgxmock.PgxPoolIface) {
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(mockPool.Close)
+
+	return NewAuditRepo(&postgres.Postgres{
+		Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		Pool:    mockPool,
+	}), mockPool
+}
+
+func TestAuditRepo_SaveAuditRecord(t *testing.T) {
+	repo, mockPool := newAuditTestRepo(t)
+	userId, apiKeyId := 1, 3
+
+	mockPool.ExpectExec("INSERT INTO audit_log \\(user_id,api_key_id,role,ip,user_agent,request_id,operation,account_ids,params,status,outcome,error\\)").
+		WithArgs(&userId, &apiKeyId, entity.RoleUser, "192.0.2.1", "billing/1.0", "req-1", "POST /api/v2/transfers",
+			[]int{1, 2}, json.RawMessage(`{"body":{"amount":100}}`), 201, entity.AuditSucceeded, "").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	// an unauthenticated call has no actor
+	mockPool.ExpectExec("INSERT INTO audit_log").
+		WithArgs((*int)(nil), (*int)(nil), entity.Role(""), "192.0.2.1", "", "", "DELETE /api/v2/me",
+			[]int{}, json.RawMessage(`{}`), 401, entity.AuditFailed, "unauthorized").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	err := repo.SaveAuditRecord(context.Background(), entity.AuditRecord{
+		UserId:     1,
+		ApiKeyId:   3,
+		Role:       entity.RoleUser,
+		IP:         "192.0.2.1",
+		UserAgent:  "billing/1.0",
+		RequestId:  "req-1",
+		Operation:  "POST /api/v2/transfers",
+		AccountIds: []int{1, 2},
+		Params:     json.RawMessage(`{"body":{"amount":100}}`),
+		Status:     201,
+		Outcome:    entity.AuditSucceeded,
+	})
+	assert.NoError(t, err)
+
+	err = repo.SaveAuditRecord(context.Background(), entity.AuditRecord{
+		IP:         "192.0.2.1",
+		Operation:  "DELETE /api/v2/me",
+		AccountIds: []int{},
+		Status:     401,
+		Outcome:    entity.AuditFailed,
+		Error:      "unauthorized",
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestAuditRepo_ListAuditRecords(t *testing.T) {
+	from := time.Date(2022, 10, 20, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 10, 21, 0, 0, 0, 0, time.UTC)
+	createdAt := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "user_id", "api_key_id", "role", "ip", "user_agent", "request_id", "operation",
+		"account_ids", "params", "status", "outcome", "error", "created_at"}
+	record := entity.AuditRecord{
+		Id:         10,
+		UserId:     1,
+		Role:       entity.RoleUser,
+		IP:         "192.0.2.1",
+		UserAgent:  "billing/1.0",
+		RequestId:  "req-1",
+		Operation:  "POST /api/v2/transfers",
+		AccountIds: []int{1, 2},
+		Params:     json.RawMessage(`{"body":{"amount":100}}`),
+		Status:     201,
+		Outcome:    entity.AuditSucceeded,
+		CreatedAt:  createdAt,
+	}
+	row := []interface{}{record.Id, record.UserId, record.ApiKeyId, record.Role, record.IP, record.UserAgent,
+		record.RequestId, record.Operation, record.AccountIds, record.Params, record.Status, record.Outcome,
+		record.Error, record.CreatedAt}
+
+	testCases := []struct {
+		name     string
+		filter   entity.AuditFilter
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "everything",
+			filter:   entity.AuditFilter{Limit: 50},
+			wantSQL:  "SELECT id, (.+) FROM audit_log ORDER BY id DESC LIMIT 50",
+			wantArgs: []interface{}{},
+		},
+		{
+			name: "by actor, account and time",
+			filter: entity.AuditFilter{
+				UserId:    1,
+				ApiKeyId:  3,
+				AccountId: 2,
+				From:      from,
+				To:        to,
+				BeforeId:  11,
+				Limit:     50,
+			},
+			wantSQL: "SELECT id, (.+) FROM audit_log WHERE user_id = \\$1 AND api_key_id = \\$2 AND " +
+				"account_ids @> ARRAY\\[\\$3\\]::INT\\[\\] AND created_at >= \\$4 AND created_at < \\$5 AND id < \\$6 " +
+				"ORDER BY id DESC LIMIT 50",
+			wantArgs: []interface{}{1, 3, 2, from, to, int64(11)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mockPool := newAuditTestRepo(t)
+
+			mockPool.ExpectQuery(tc.wantSQL).
+				WithArgs(tc.wantArgs...).
+				WillReturnRows(pgxmock.NewRows(columns).AddRow(row...))
+
+			records, err := repo.ListAuditRecords(context.Background(), tc.filter)
+			require.NoError(t, err)
+			assert.Equal(t, []entity.AuditRecord{record}, records)
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+	}
+}
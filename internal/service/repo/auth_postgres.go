@@ -2,11 +2,25 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"strings"
 	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
 	"user-balance-service/pkg/postgres"
 )
 
+// uniqueViolationCode is the Postgres SQLSTATE for unique_violation
+const uniqueViolationCode = "23505"
+
+var userColumns = []string{"id", "username", "password_hash", "role", "created_at", "updated_at", "disabled_at", "totp_enabled_at"}
+
+// likeEscaper escapes the LIKE wildcards of a search query
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 type AuthRepo struct {
 	*postgres.Postgres
 }
@@ -29,6 +43,10 @@ func (r *AuthRepo) CreateUser(ctx context.Context, user entity.User) (int, error
 
 	var id int
 	err = r.Pool.QueryRow(ctx, sql, args...).Scan(&id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return 0, fmt.Errorf("repo - AuthRepo - CreateUser - r.Pool.QueryRow: %w", service.ErrUserAlreadyExists)
+	}
 	if err != nil {
 		return 0, fmt.Errorf("repo - AuthRepo - CreateUser - r.Pool.QueryRow: %w", err)
 	}
@@ -36,22 +54,205 @@ func (r *AuthRepo) CreateUser(ctx context.Context, user entity.User) (int, error
 	return id, nil
 }
 
-func (r *AuthRepo) GetUser(ctx context.Context, username, passwordHash string) (entity.User, error) {
+func (r *AuthRepo) GetUser(ctx context.Context, username string) (entity.User, error) {
 	sql, args, err := r.Builder.
-		Select("id", "username", "password_hash").
+		Select(userColumns...).
 		From("users").
-		Where("username = ? AND password_hash = ?", username, passwordHash).
+		Where("username = ?", username).
 		ToSql()
 
 	if err != nil {
 		return entity.User{}, fmt.Errorf("repo - AuthRepo - GetUser - r.Builder: %w", err)
 	}
 
-	var user entity.User
-	err = r.Pool.QueryRow(ctx, sql, args...).Scan(&user.Id, &user.Username, &user.Password)
+	user, err := scanUser(r.Pool.QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.User{}, fmt.Errorf("repo - AuthRepo - GetUser - r.Pool.QueryRow: %w", service.ErrUserNotFound)
+	}
 	if err != nil {
 		return entity.User{}, fmt.Errorf("repo - AuthRepo - GetUser - r.Pool.QueryRow: %w", err)
 	}
 
 	return user, nil
 }
+
+func (r *AuthRepo) GetUserById(ctx context.Context, id int) (entity.User, error) {
+	sql, args, err := r.Builder.
+		Select(userColumns...).
+		From("users").
+		Where("id = ?", id).
+		ToSql()
+
+	if err != nil {
+		return entity.User{}, fmt.Errorf("repo - AuthRepo - GetUserById - r.Builder: %w", err)
+	}
+
+	user, err := scanUser(r.Pool.QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.User{}, fmt.Errorf("repo - AuthRepo - GetUserById - r.Pool.QueryRow: %w", service.ErrUserNotFound)
+	}
+	if err != nil {
+		return entity.User{}, fmt.Errorf("repo - AuthRepo - GetUserById - r.Pool.QueryRow: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *AuthRepo) UpdatePasswordHash(ctx context.Context, id int, passwordHash string) error {
+	sql, args, err := r.Builder.
+		Update("users").
+		Set("password_hash", passwordHash).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("id = ?", id).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - AuthRepo - UpdatePasswordHash - r.Builder: %w", err)
+	}
+
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("repo - AuthRepo - UpdatePasswordHash - r.Pool.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("repo - AuthRepo - UpdatePasswordHash: %w", service.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+func (r *AuthRepo) UpdateRole(ctx context.Context, id int, role entity.Role) error {
+	sql, args, err := r.Builder.
+		Update("users").
+		Set("role", role).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("id = ?", id).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - AuthRepo - UpdateRole - r.Builder: %w", err)
+	}
+
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("repo - AuthRepo - UpdateRole - r.Pool.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("repo - AuthRepo - UpdateRole: %w", service.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// UpdateUsername renames the user; a taken username is
+// service.ErrUserAlreadyExists
+func (r *AuthRepo) UpdateUsername(ctx context.Context, id int, username string) error {
+	sql, args, err := r.Builder.
+		Update("users").
+		Set("username", username).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("id = ?", id).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - AuthRepo - UpdateUsername - r.Builder: %w", err)
+	}
+
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return fmt.Errorf("repo - AuthRepo - UpdateUsername - r.Pool.Exec: %w", service.ErrUserAlreadyExists)
+	}
+	if err != nil {
+		return fmt.Errorf("repo - AuthRepo - UpdateUsername - r.Pool.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("repo - AuthRepo - UpdateUsername: %w", service.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// SetDisabled disables or re-enables the user. Disabling a disabled user
+// keeps the time it was first disabled.
+func (r *AuthRepo) SetDisabled(ctx context.Context, id int, disabled bool) error {
+	var disabledAt interface{}
+	if disabled {
+		disabledAt = squirrel.Expr("COALESCE(disabled_at, now())")
+	}
+
+	sql, args, err := r.Builder.
+		Update("users").
+		Set("disabled_at", disabledAt).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("id = ?", id).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - AuthRepo - SetDisabled - r.Builder: %w", err)
+	}
+
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("repo - AuthRepo - SetDisabled - r.Pool.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("repo - AuthRepo - SetDisabled: %w", service.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// ListUsers returns a page of the users matching filter, ordered by id
+func (r *AuthRepo) ListUsers(ctx context.Context, filter entity.UserFilter) ([]entity.User, error) {
+	builder := r.Builder.
+		Select(userColumns...).
+		From("users").
+		Where("id > ?", filter.AfterId).
+		OrderBy("id").
+		Limit(uint64(filter.Limit))
+
+	if filter.Query != "" {
+		builder = builder.Where(squirrel.ILike{"username": "%" + likeEscaper.Replace(filter.Query) + "%"})
+	}
+	if filter.Disabled != nil {
+		if *filter.Disabled {
+			builder = builder.Where("disabled_at IS NOT NULL")
+		} else {
+			builder = builder.Where("disabled_at IS NULL")
+		}
+	}
+
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("repo - AuthRepo - ListUsers - r.Builder: %w", err)
+	}
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo - AuthRepo - ListUsers - r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	users := []entity.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repo - AuthRepo - ListUsers - rows.Scan: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("repo - AuthRepo - ListUsers - rows.Err: %w", err)
+	}
+
+	return users, nil
+}
+
+func scanUser(row pgx.Row) (entity.User, error) {
+	var user entity.User
+	err := row.Scan(&user.Id, &user.Username, &user.Password, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.DisabledAt, &user.TwoFactorAt)
+	return user, err
+}
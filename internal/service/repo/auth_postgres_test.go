@@ -4,10 +4,14 @@ import (
 	"context"
 	"errors"
 	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
 	"github.com/pashagolub/pgxmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
 	"user-balance-service/pkg/postgres"
 )
 
@@ -49,9 +53,8 @@ func TestAuthRepo_GetUser(t *testing.T) {
 	mockAuthRepo := NewAuthRepo(mockPostgres)
 
 	type args struct {
-		ctx          context.Context
-		username     string
-		passwordHash string
+		ctx      context.Context
+		username string
 	}
 
 	type MockBehaviour func(args args, user entity.User)
@@ -66,35 +69,36 @@ func TestAuthRepo_GetUser(t *testing.T) {
 		{
 			name: "success",
 			args: args{
-				ctx:          context.Background(),
-				username:     "qwe",
-				passwordHash: "qwe1",
+				ctx:      context.Background(),
+				username: "qwe",
 			},
 			mockBehaviour: func(args args, user entity.User) {
-				rows := pgxmock.NewRows([]string{"id", "username", "password_hash"}).
-					AddRow(user.Id, user.Username, user.Password)
+				rows := pgxmock.NewRows(userColumns).
+					AddRow(user.Id, user.Username, user.Password, user.Role, user.CreatedAt, user.UpdatedAt, user.DisabledAt, user.TwoFactorAt)
 
-				mockPool.ExpectQuery("SELECT id, username, password_hash FROM users").
-					WithArgs(args.username, args.passwordHash).
+				mockPool.ExpectQuery("SELECT id, username, password_hash, role, created_at, updated_at, disabled_at, totp_enabled_at FROM users").
+					WithArgs(args.username).
 					WillReturnRows(rows)
 			},
 			want: entity.User{
-				Id:       1,
-				Username: "qwe",
-				Password: "qwe1",
+				Id:        1,
+				Username:  "qwe",
+				Password:  "qwe1",
+				Role:      entity.RoleOperator,
+				CreatedAt: time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC),
+				UpdatedAt: time.Date(2022, 10, 21, 12, 0, 0, 0, time.UTC),
 			},
 			wantErr: false,
 		},
 		{
 			name: "fail",
 			args: args{
-				ctx:          context.Background(),
-				username:     "qwe",
-				passwordHash: "qwe1",
+				ctx:      context.Background(),
+				username: "qwe",
 			},
 			mockBehaviour: func(args args, user entity.User) {
-				mockPool.ExpectQuery("SELECT id, username, password_hash FROM users").
-					WithArgs(args.username, args.passwordHash).
+				mockPool.ExpectQuery("SELECT (.+) FROM users").
+					WithArgs(args.username).
 					WillReturnError(errors.New("no such user"))
 			},
 			want:    entity.User{},
@@ -106,7 +110,7 @@ func TestAuthRepo_GetUser(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.mockBehaviour(tc.args, tc.want)
 
-			got, err := mockAuthRepo.GetUser(tc.args.ctx, tc.args.username, tc.args.passwordHash)
+			got, err := mockAuthRepo.GetUser(tc.args.ctx, tc.args.username)
 			if tc.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -119,3 +123,234 @@ func TestAuthRepo_GetUser(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthRepo_UpdatePasswordHash(t *testing.T) {
+	testCases := []struct {
+		name         string
+		rowsAffected int64
+		wantErr      error
+	}{
+		{
+			name:         "success",
+			rowsAffected: 1,
+		},
+		{
+			name:         "user not found",
+			rowsAffected: 0,
+			wantErr:      service.ErrUserNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPool, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mockPool.Close()
+
+			repo := NewAuthRepo(&postgres.Postgres{
+				Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+				Pool:    mockPool,
+			})
+
+			mockPool.ExpectExec("UPDATE users SET password_hash = (.+), updated_at = now\\(\\) WHERE id = (.+)").
+				WithArgs("$argon2id$new", 1).
+				WillReturnResult(pgxmock.NewResult("UPDATE", tc.rowsAffected))
+
+			err = repo.UpdatePasswordHash(context.Background(), 1, "$argon2id$new")
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAuthRepo_UpdateRole(t *testing.T) {
+	testCases := []struct {
+		name         string
+		rowsAffected int64
+		wantErr      error
+	}{
+		{
+			name:         "success",
+			rowsAffected: 1,
+		},
+		{
+			name:         "user not found",
+			rowsAffected: 0,
+			wantErr:      service.ErrUserNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPool, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mockPool.Close()
+
+			repo := NewAuthRepo(&postgres.Postgres{
+				Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+				Pool:    mockPool,
+			})
+
+			mockPool.ExpectExec("UPDATE users SET role = (.+), updated_at = now\\(\\) WHERE id = (.+)").
+				WithArgs(entity.RoleAdmin, 1).
+				WillReturnResult(pgxmock.NewResult("UPDATE", tc.rowsAffected))
+
+			err = repo.UpdateRole(context.Background(), 1, entity.RoleAdmin)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAuthRepo_UpdateUsername(t *testing.T) {
+	testCases := []struct {
+		name         string
+		rowsAffected int64
+		execErr      error
+		wantErr      error
+	}{
+		{
+			name:         "success",
+			rowsAffected: 1,
+		},
+		{
+			name:         "user not found",
+			rowsAffected: 0,
+			wantErr:      service.ErrUserNotFound,
+		},
+		{
+			name:    "username taken",
+			execErr: &pgconn.PgError{Code: uniqueViolationCode},
+			wantErr: service.ErrUserAlreadyExists,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPool, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mockPool.Close()
+
+			repo := NewAuthRepo(&postgres.Postgres{
+				Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+				Pool:    mockPool,
+			})
+
+			exec := mockPool.ExpectExec("UPDATE users SET username = (.+), updated_at = now\\(\\) WHERE id = (.+)").
+				WithArgs("asd", 1)
+			if tc.execErr != nil {
+				exec.WillReturnError(tc.execErr)
+			} else {
+				exec.WillReturnResult(pgxmock.NewResult("UPDATE", tc.rowsAffected))
+			}
+
+			err = repo.UpdateUsername(context.Background(), 1, "asd")
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAuthRepo_SetDisabled(t *testing.T) {
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockPool.Close()
+
+	repo := NewAuthRepo(&postgres.Postgres{
+		Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		Pool:    mockPool,
+	})
+
+	mockPool.ExpectExec("UPDATE users SET disabled_at = COALESCE\\(disabled_at, now\\(\\)\\), updated_at = now\\(\\) WHERE id = (.+)").
+		WithArgs(1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	assert.NoError(t, repo.SetDisabled(context.Background(), 1, true))
+
+	mockPool.ExpectExec("UPDATE users SET disabled_at = (.+), updated_at = now\\(\\) WHERE id = (.+)").
+		WithArgs(nil, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	assert.NoError(t, repo.SetDisabled(context.Background(), 1, false))
+
+	mockPool.ExpectExec("UPDATE users").
+		WithArgs(2).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	assert.ErrorIs(t, repo.SetDisabled(context.Background(), 2, true), service.ErrUserNotFound)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestAuthRepo_ListUsers(t *testing.T) {
+	disabled := true
+	disabledAt := time.Date(2022, 10, 21, 12, 0, 0, 0, time.UTC)
+	createdAt := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		filter   entity.UserFilter
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "all",
+			filter:   entity.UserFilter{Limit: 50},
+			wantSQL:  "SELECT (.+) FROM users WHERE id > \\$1 ORDER BY id LIMIT 50",
+			wantArgs: []interface{}{0},
+		},
+		{
+			name:     "search escapes wildcards",
+			filter:   entity.UserFilter{Query: "a_b%", AfterId: 10, Limit: 50},
+			wantSQL:  "SELECT (.+) FROM users WHERE id > \\$1 AND username ILIKE \\$2 ORDER BY id LIMIT 50",
+			wantArgs: []interface{}{10, `%a\_b\%%`},
+		},
+		{
+			name:     "disabled",
+			filter:   entity.UserFilter{Disabled: &disabled, Limit: 50},
+			wantSQL:  "SELECT (.+) FROM users WHERE id > \\$1 AND disabled_at IS NOT NULL ORDER BY id LIMIT 50",
+			wantArgs: []interface{}{0},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPool, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mockPool.Close()
+
+			repo := NewAuthRepo(&postgres.Postgres{
+				Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+				Pool:    mockPool,
+			})
+
+			rows := pgxmock.NewRows(userColumns).
+				AddRow(11, "a_b%c", "hash", entity.RoleUser, createdAt, createdAt, &disabledAt, nil)
+			mockPool.ExpectQuery(tc.wantSQL).
+				WithArgs(tc.wantArgs...).
+				WillReturnRows(rows)
+
+			users, err := repo.ListUsers(context.Background(), tc.filter)
+			require.NoError(t, err)
+			assert.Equal(t, []entity.User{{
+				Id:         11,
+				Username:   "a_b%c",
+				Password:   "hash",
+				Role:       entity.RoleUser,
+				CreatedAt:  createdAt,
+				UpdatedAt:  createdAt,
+				DisabledAt: &disabledAt,
+			}}, users)
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/pkg/cache"
+)
+
+// Cached history is split into namespaces: one for queries over all accounts
+// and one per account. Every cache key embeds the current version of its
+// namespace, so a write bumps the version and all keys of the old version
+// become unreachable at once, without knowing which sort or pagination keys
+// were cached. Old keys simply expire.
+//
+// A reader that loaded rows before a concurrent write stores them under the
+// old version, so it can't bring stale data back into the cache.
+const historyNamespaceKeyPrefix = "history_namespace"
+
+func historyNamespaceKey(accountId int) string {
+	if accountId == 0 {
+		return historyNamespaceKeyPrefix
+	}
+	return fmt.Sprintf("%s_%d", historyNamespaceKeyPrefix, accountId)
+}
+
+// historyKey places key into the namespace of accountId, 0 meaning all accounts
+func (h *HistoryRepo) historyKey(ctx context.Context, accountId int, key string) (string, error) {
+	namespace := historyNamespaceKey(accountId)
+
+	version, err := h.RedisCache.Counter(ctx, namespace)
+	if err != nil {
+		return "", fmt.Errorf("h.RedisCache.Counter: %w", err)
+	}
+
+	return fmt.Sprintf("%s_v%d_%s", namespace, version, key), nil
+}
+
+// cachedTime keeps the full timestamp and its offset with every codec: JSON
+// uses RFC 3339 with nanoseconds instead of the API layout of
+// entity.CustomTime, which drops the time of day, and binary codecs such as
+// msgpack use time.Time.MarshalBinary instead of converting to local time.
+type cachedTime time.Time
+
+func (t cachedTime) MarshalJSON() ([]byte, error) {
+	return time.Time(t).MarshalJSON()
+}
+
+func (t *cachedTime) UnmarshalJSON(b []byte) error {
+	return (*time.Time)(t).UnmarshalJSON(b)
+}
+
+func (t cachedTime) MarshalBinary() ([]byte, error) {
+	return time.Time(t).MarshalBinary()
+}
+
+func (t *cachedTime) UnmarshalBinary(data []byte) error {
+	return (*time.Time)(t).UnmarshalBinary(data)
+}
+
+// cachedHistoryRecord is entity.History as stored in the cache
+type cachedHistoryRecord struct {
+	Id          int        `json:"id" msgpack:"id"`
+	Type        string     `json:"type" msgpack:"type"`
+	Description string     `json:"description" msgpack:"description"`
+	Amount      int        `json:"amount" msgpack:"amount"`
+	AccountId   int        `json:"account_id" msgpack:"account_id"`
+	Date        cachedTime `json:"date" msgpack:"date"`
+}
+
+func toCachedHistory(history []entity.History) []cachedHistoryRecord {
+	if history == nil {
+		return nil
+	}
+
+	records := make([]cachedHistoryRecord, 0, len(history))
+	for _, h := range history {
+		records = append(records, cachedHistoryRecord{
+			Id:          h.Id,
+			Type:        h.Type,
+			Description: h.Description,
+			Amount:      h.Amount,
+			AccountId:   h.AccountId,
+			Date:        cachedTime(h.Date),
+		})
+	}
+	return records
+}
+
+func fromCachedHistory(records []cachedHistoryRecord) []entity.History {
+	if records == nil {
+		return nil
+	}
+
+	history := make([]entity.History, 0, len(records))
+	for _, r := range records {
+		history = append(history, entity.History{
+			Id:          r.Id,
+			Type:        r.Type,
+			Description: r.Description,
+			Amount:      r.Amount,
+			AccountId:   r.AccountId,
+			Date:        entity.CustomTime(r.Date),
+		})
+	}
+	return history
+}
+
+// cachedHistory serves key from the namespace of accountId and runs load on
+// a miss. When the namespace version can't be read the cache is bypassed, as
+// a value found under a wrong version might be stale.
+func (h *HistoryRepo) cachedHistory(ctx context.Context, accountId int, key string, load func(ctx context.Context) ([]entity.History, error)) ([]entity.History, error) {
+	namespacedKey, err := h.historyKey(ctx, accountId, key)
+	if err != nil {
+		return load(ctx)
+	}
+
+	records, err := cache.GetOrLoad(ctx, h.RedisCache, &h.loads, namespacedKey, func(ctx context.Context) ([]cachedHistoryRecord, error) {
+		history, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return toCachedHistory(history), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fromCachedHistory(records), nil
+}
+
+// invalidateHistory drops cached history of accountId and all-accounts
+// queries. Like invalidateAccounts it doesn't fail the saved write.
+func (h *HistoryRepo) invalidateHistory(ctx context.Context, accountId int) {
+	for _, namespace := range []string{historyNamespaceKey(0), historyNamespaceKey(accountId)} {
+		_, _ = h.RedisCache.Incr(ctx, namespace)
+	}
+}
+
+// commit commits tx and then runs afterCommit hooks, so caches are
+// invalidated only once the changes are visible to other connections and a
+// concurrent reader can't cache the pre-commit state again. Hooks don't run
+// when the commit fails. Single statements outside a transaction commit on
+// their own, so they invalidate right after the statement returns.
+func commit(ctx context.Context, tx pgx.Tx, afterCommit ...func(ctx context.Context)) error {
+	err := tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("tx.Commit: %w", err)
+	}
+
+	for _, hook := range afterCommit {
+		hook(ctx)
+	}
+
+	return nil
+}
@@ -0,0 +1,353 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis/v8"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	"user-balance-service/pkg/cache"
+	"user-balance-service/pkg/memcache"
+	"user-balance-service/pkg/postgres"
+	"user-balance-service/pkg/rediscache"
+)
+
+func newCacheTestRepo(t *testing.T) (*Repository, pgxmock.PgxPoolIface, *miniredis.Miniredis) {
+	miniRedis, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(miniRedis.Close)
+
+	repo, mockPool := newCacheTestRepoWith(t, rediscache.New(redis.NewClient(&redis.Options{Addr: miniRedis.Addr()})))
+
+	return repo, mockPool, miniRedis
+}
+
+func newCacheTestRepoWith(t *testing.T, cache service.RedisCache) (*Repository, pgxmock.PgxPoolIface) {
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(mockPool.Close)
+
+	mockPostgres := &postgres.Postgres{
+		Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		Pool:    mockPool,
+	}
+
+	return New(mockPostgres, cache), mockPool
+}
+
+func historyRows(mockPool pgxmock.PgxPoolIface, history ...entity.History) *pgxmock.Rows {
+	rows := mockPool.NewRows([]string{"id", "type", "description", "amount", "account_id", "date"})
+	for _, h := range history {
+		rows.AddRow(h.Id, h.Type, h.Description, h.Amount, h.AccountId, h.Date)
+	}
+	return rows
+}
+
+func TestHistoryRepo_ReadYourWrites(t *testing.T) {
+	miniRedis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer miniRedis.Close()
+
+	backends := map[string]service.RedisCache{
+		"redis":  rediscache.New(redis.NewClient(&redis.Options{Addr: miniRedis.Addr()})),
+		"memory": memcache.New(),
+		"tiered": memcache.NewTiered(memcache.New(), rediscache.New(redis.NewClient(&redis.Options{Addr: miniRedis.Addr(), DB: 1}))),
+	}
+
+	for name, cache := range backends {
+		t.Run(name, func(t *testing.T) {
+			repo, mockPool := newCacheTestRepoWith(t, cache)
+			testHistoryReadYourWrites(t, repo, mockPool)
+		})
+	}
+}
+
+func testHistoryReadYourWrites(t *testing.T, repo *Repository, mockPool pgxmock.PgxPoolIface) {
+	ctx := context.Background()
+
+	date := entity.CustomTime(time.Date(2022, 10, 20, 0, 0, 0, 0, time.UTC))
+	first := entity.History{Id: 1, Type: entity.RefillType, Amount: 100, AccountId: 1, Date: date}
+	second := entity.History{Id: 2, Type: entity.WriteOffType, Amount: 50, AccountId: 1, Date: date}
+	other := entity.History{Id: 3, Type: entity.RefillType, Amount: 70, AccountId: 2, Date: date}
+
+	// warm up the cache
+	mockPool.ExpectQuery("SELECT (.+) FROM history WHERE account_id = (.+)").
+		WithArgs(1).
+		WillReturnRows(historyRows(mockPool, first))
+	mockPool.ExpectQuery("SELECT (.+) FROM history WHERE account_id = (.+)").
+		WithArgs(2).
+		WillReturnRows(historyRows(mockPool, other))
+	mockPool.ExpectQuery("SELECT (.+) FROM history$").
+		WillReturnRows(historyRows(mockPool, first, other))
+
+	got, err := repo.ShowById(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.History{first}, got)
+	_, err = repo.ShowById(ctx, 2)
+	require.NoError(t, err)
+	_, err = repo.ShowAll(ctx)
+	require.NoError(t, err)
+
+	// served from cache, no queries expected
+	got, err = repo.ShowById(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.History{first}, got)
+	require.NoError(t, mockPool.ExpectationsWereMet())
+
+	// write to account 1
+	mockPool.ExpectQuery("INSERT INTO history").
+		WillReturnRows(mockPool.NewRows([]string{"id"}).AddRow(second.Id))
+	_, err = repo.SaveHistory(ctx, second)
+	require.NoError(t, err)
+
+	// account 1 and all-accounts queries see the write
+	mockPool.ExpectQuery("SELECT (.+) FROM history WHERE account_id = (.+)").
+		WithArgs(1).
+		WillReturnRows(historyRows(mockPool, first, second))
+	mockPool.ExpectQuery("SELECT (.+) FROM history$").
+		WillReturnRows(historyRows(mockPool, first, second, other))
+
+	got, err = repo.ShowById(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.History{first, second}, got)
+
+	got, err = repo.ShowAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.History{first, second, other}, got)
+
+	// account 2 is still served from cache
+	got, err = repo.ShowById(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.History{other}, got)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestHistoryRepo_CacheKeepsTimeOfDay(t *testing.T) {
+	miniRedis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer miniRedis.Close()
+
+	dates := []time.Time{
+		time.Date(2022, 10, 20, 15, 4, 5, 123456789, time.UTC),
+		time.Date(2022, 10, 20, 23, 30, 0, 0, time.FixedZone("MSK", 3*60*60)),
+	}
+
+	codecs := map[string]cache.Codec{
+		"json":    cache.JSON,
+		"msgpack": cache.Msgpack,
+	}
+
+	for codecName, codec := range codecs {
+		backends := map[string]service.RedisCache{
+			"redis":  rediscache.New(redis.NewClient(&redis.Options{Addr: miniRedis.Addr()}), rediscache.Codec(codec)),
+			"memory": memcache.New(memcache.Codec(codec)),
+		}
+
+		for backendName, backend := range backends {
+			t.Run(codecName+"/"+backendName, func(t *testing.T) {
+				miniRedis.FlushAll()
+				repo, mockPool := newCacheTestRepoWith(t, backend)
+				ctx := context.Background()
+
+				history := make([]entity.History, 0, len(dates))
+				for i, date := range dates {
+					history = append(history, entity.History{Id: i + 1, Type: entity.RefillType, Amount: 100, AccountId: 1, Date: entity.CustomTime(date)})
+				}
+
+				mockPool.ExpectQuery("SELECT (.+) FROM history WHERE account_id = (.+)").
+					WithArgs(1).
+					WillReturnRows(historyRows(mockPool, history...))
+
+				_, err := repo.ShowById(ctx, 1)
+				require.NoError(t, err)
+
+				// served from cache
+				got, err := repo.ShowById(ctx, 1)
+				require.NoError(t, err)
+				require.Len(t, got, len(dates))
+
+				for i, date := range dates {
+					cached := time.Time(got[i].Date)
+					assert.True(t, date.Equal(cached), "got %s, want %s", cached, date)
+					assert.Equal(t, date.Format(time.RFC3339Nano), cached.Format(time.RFC3339Nano))
+				}
+
+				assert.NoError(t, mockPool.ExpectationsWereMet())
+			})
+		}
+	}
+}
+
+func TestAccountRepo_ReadYourWrites(t *testing.T) {
+	repo, mockPool, miniRedis := newCacheTestRepo(t)
+	ctx := context.Background()
+
+	mockPool.ExpectQuery("SELECT id, balance, version, COALESCE\\(user_id, 0\\), frozen FROM accounts").
+		WithArgs(1).
+		WillReturnRows(mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).AddRow(1, 500, 1, 0, false))
+
+	got, err := repo.GetAccount(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Account{Id: 1, Balance: 500, Version: 1}, got)
+
+	// served from cache
+	got, err = repo.GetAccount(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 500, got.Balance)
+	require.NoError(t, mockPool.ExpectationsWereMet())
+
+	mockPool.ExpectExec("UPDATE accounts").
+		WithArgs(100, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	require.NoError(t, repo.MakeDeposit(ctx, 1, 100, 0))
+
+	mockPool.ExpectQuery("SELECT id, balance, version, COALESCE\\(user_id, 0\\), frozen FROM accounts").
+		WithArgs(1).
+		WillReturnRows(mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).AddRow(1, 600, 2, 0, false))
+
+	got, err = repo.GetAccount(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Account{Id: 1, Balance: 600, Version: 2}, got)
+
+	// deleting leaves no reachable key instead of caching null
+	mockPool.ExpectExec("DELETE FROM accounts").
+		WithArgs(1).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	require.NoError(t, repo.DeleteAccount(ctx, 1, 0))
+	assert.False(t, miniRedis.Exists(accountRedisKey(1, 2)))
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestAccountRepo_StaleFill(t *testing.T) {
+	repo, mockPool, _ := newCacheTestRepo(t)
+	ctx := context.Background()
+
+	// a reader resolves the key and loads the account before a write
+	staleKey, err := repo.AccountRepo.accountKey(ctx, 1)
+	require.NoError(t, err)
+
+	mockPool.ExpectExec("UPDATE accounts").
+		WithArgs(100, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	require.NoError(t, repo.MakeDeposit(ctx, 1, 100, 0))
+
+	// and caches it after the invalidation
+	require.NoError(t, repo.AccountRepo.RedisCache.Set(ctx, staleKey, entity.Account{Id: 1, Balance: 500, Version: 1}))
+
+	mockPool.ExpectQuery("SELECT id, balance, version, COALESCE\\(user_id, 0\\), frozen FROM accounts").
+		WithArgs(1).
+		WillReturnRows(mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).AddRow(1, 600, 2, 0, false))
+
+	got, err := repo.GetAccount(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Account{Id: 1, Balance: 600, Version: 2}, got)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestAccountRepo_TransferMoney_InvalidatesAfterCommit(t *testing.T) {
+	const lockQuery = "SELECT id, balance, version, COALESCE\\(user_id, 0\\), frozen FROM accounts WHERE id IN \\((.+)\\) ORDER BY id FOR UPDATE"
+
+	testCases := []struct {
+		name            string
+		commitErr       error
+		wantErr         bool
+		wantInvalidated bool
+	}{
+		{
+			name:            "committed",
+			wantInvalidated: true,
+		},
+		{
+			name:      "commit failed",
+			commitErr: errors.New("connection reset"),
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mockPool, miniRedis := newCacheTestRepo(t)
+			ctx := context.Background()
+
+			require.NoError(t, miniRedis.Set(accountRedisKey(1, 0), `{"id":1,"balance":500,"version":1}`))
+			require.NoError(t, miniRedis.Set(accountRedisKey(2, 0), `{"id":2,"balance":0,"version":1}`))
+
+			mockPool.ExpectBegin()
+			mockPool.ExpectQuery(lockQuery).
+				WithArgs(1, 2).
+				WillReturnRows(mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).
+					AddRow(1, 500, 1, 0, false).
+					AddRow(2, 0, 1, 0, false))
+			mockPool.ExpectExec("UPDATE accounts").
+				WithArgs(100, 1).
+				WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+			mockPool.ExpectExec("UPDATE accounts").
+				WithArgs(100, 2).
+				WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+			if tc.commitErr != nil {
+				mockPool.ExpectCommit().WillReturnError(tc.commitErr)
+			} else {
+				mockPool.ExpectCommit()
+			}
+
+			err := repo.TransferMoney(ctx, 1, 2, 100, 0)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.wantInvalidated, miniRedis.Exists(accountVersionKey(1)))
+			assert.Equal(t, tc.wantInvalidated, miniRedis.Exists(accountVersionKey(2)))
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepo_RedisDown(t *testing.T) {
+	miniRedis, err := miniredis.Run()
+	require.NoError(t, err)
+	addr := miniRedis.Addr()
+	miniRedis.Close()
+
+	redisCache := rediscache.New(redis.NewClient(&redis.Options{Addr: addr, MaxRetries: -1}))
+	repo, mockPool := newCacheTestRepoWith(t, cache.NewResilient(redisCache))
+	ctx := context.Background()
+
+	// reads go to the database
+	mockPool.ExpectQuery("SELECT id, balance, version, COALESCE\\(user_id, 0\\), frozen FROM accounts").
+		WithArgs(1).
+		WillReturnRows(mockPool.NewRows([]string{"id", "balance", "version", "user_id", "frozen"}).AddRow(1, 500, 1, 0, false))
+	account, err := repo.GetAccount(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 500, account.Balance)
+
+	mockPool.ExpectQuery("SELECT (.+) FROM history WHERE account_id = (.+)").
+		WithArgs(1).
+		WillReturnRows(historyRows(mockPool))
+	_, err = repo.ShowById(ctx, 1)
+	require.NoError(t, err)
+
+	// writes succeed, the cache can't be invalidated but isn't read either
+	mockPool.ExpectExec("UPDATE accounts").
+		WithArgs(100, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	assert.NoError(t, repo.MakeDeposit(ctx, 1, 100, 0))
+
+	mockPool.ExpectQuery("INSERT INTO history").
+		WillReturnRows(mockPool.NewRows([]string{"id"}).AddRow(1))
+	_, err = repo.SaveHistory(ctx, entity.History{Type: entity.RefillType, Amount: 100, AccountId: 1})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
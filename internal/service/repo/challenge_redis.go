@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+)
+
+const signInChallengeRedisKeyPrefix = "signin_challenge"
+
+func signInChallengeRedisKey(id string) string {
+	return fmt.Sprintf("%s_%s", signInChallengeRedisKeyPrefix, id)
+}
+
+// attemptChallengeScript counts an attempt against an existing challenge
+// only, so that an expired one isn't brought back without a TTL
+var attemptChallengeScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return nil
+end
+local attempts = redis.call("HINCRBY", KEYS[1], "attempts", 1)
+return {redis.call("HGET", KEYS[1], "user_id"), redis.call("HGET", KEYS[1], "username"), attempts}
+`)
+
+// ChallengeRepo keeps sign-ins waiting for the second factor in Redis
+// hashes that expire with the challenge
+type ChallengeRepo struct {
+	client *redis.Client
+}
+
+func NewChallengeRepo(client *redis.Client) *ChallengeRepo {
+	return &ChallengeRepo{client: client}
+}
+
+func (r *ChallengeRepo) CreateChallenge(ctx context.Context, id string, challenge entity.SignInChallenge, ttl time.Duration) error {
+	key := signInChallengeRedisKey(id)
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HMSet(ctx, key, "user_id", challenge.UserId, "username", challenge.Username, "attempts", 0)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("repo - ChallengeRepo - CreateChallenge - r.client.TxPipelined: %w", err)
+	}
+
+	return nil
+}
+
+// AttemptChallenge returns the challenge with the attempt counted; an
+// unknown or expired one is service.ErrInvalidChallenge
+func (r *ChallengeRepo) AttemptChallenge(ctx context.Context, id string) (entity.SignInChallenge, error) {
+	values, err := attemptChallengeScript.Run(ctx, r.client, []string{signInChallengeRedisKey(id)}).Slice()
+	if errors.Is(err, redis.Nil) {
+		return entity.SignInChallenge{}, fmt.Errorf("repo - ChallengeRepo - AttemptChallenge: %w", service.ErrInvalidChallenge)
+	}
+	if err != nil {
+		return entity.SignInChallenge{}, fmt.Errorf("repo - ChallengeRepo - AttemptChallenge - attemptChallengeScript.Run: %w", err)
+	}
+
+	userId, _ := values[0].(string)
+	username, _ := values[1].(string)
+	attempts, _ := values[2].(int64)
+
+	uid, err := strconv.Atoi(userId)
+	if err != nil {
+		return entity.SignInChallenge{}, fmt.Errorf("repo - ChallengeRepo - AttemptChallenge - strconv.Atoi: %w", err)
+	}
+
+	return entity.SignInChallenge{UserId: uid, Username: username, Attempts: attempts}, nil
+}
+
+func (r *ChallengeRepo) DeleteChallenge(ctx context.Context, id string) error {
+	err := r.client.Del(ctx, signInChallengeRedisKey(id)).Err()
+	if err != nil {
+		return fmt.Errorf("repo - ChallengeRepo - DeleteChallenge - r.client.Del: %w", err)
+	}
+
+	return nil
+}
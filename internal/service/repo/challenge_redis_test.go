@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"context"
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+)
+
+func TestChallengeRepo(t *testing.T) {
+	miniRedis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer miniRedis.Close()
+
+	challenges := NewChallengeRepo(redis.NewClient(&redis.Options{Addr: miniRedis.Addr()}))
+	ctx := context.Background()
+
+	_, err = challenges.AttemptChallenge(ctx, "unknown")
+	assert.ErrorIs(t, err, service.ErrInvalidChallenge)
+
+	require.NoError(t, challenges.CreateChallenge(ctx, "id", entity.SignInChallenge{UserId: 1, Username: "qwe"}, time.Minute))
+
+	for i := int64(1); i <= 2; i++ {
+		ch, err := challenges.AttemptChallenge(ctx, "id")
+		require.NoError(t, err)
+		assert.Equal(t, entity.SignInChallenge{UserId: 1, Username: "qwe", Attempts: i}, ch)
+	}
+
+	require.NoError(t, challenges.DeleteChallenge(ctx, "id"))
+	_, err = challenges.AttemptChallenge(ctx, "id")
+	assert.ErrorIs(t, err, service.ErrInvalidChallenge)
+
+	// an expired challenge isn't brought back by an attempt
+	require.NoError(t, challenges.CreateChallenge(ctx, "expiring", entity.SignInChallenge{UserId: 1, Username: "qwe"}, time.Minute))
+	miniRedis.FastForward(time.Minute)
+	_, err = challenges.AttemptChallenge(ctx, "expiring")
+	assert.ErrorIs(t, err, service.ErrInvalidChallenge)
+	assert.False(t, miniRedis.Exists(signInChallengeRedisKey("expiring")))
+}
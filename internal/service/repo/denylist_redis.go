@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"time"
+)
+
+const (
+	deniedTokenRedisKeyPrefix = "denied_token"
+	deniedUserRedisKeyPrefix  = "denied_user"
+)
+
+func deniedTokenRedisKey(jti string) string {
+	return fmt.Sprintf("%s_%s", deniedTokenRedisKeyPrefix, jti)
+}
+
+func deniedUserRedisKey(userId int) string {
+	return fmt.Sprintf("%s_%d", deniedUserRedisKeyPrefix, userId)
+}
+
+// DenylistRepo keeps the ids of revoked access tokens in Redis, every id
+// expires with its token. Revoking all tokens of a user stores the time
+// they were revoked until the last of them expires.
+type DenylistRepo struct {
+	client *redis.Client
+}
+
+func NewDenylistRepo(client *redis.Client) *DenylistRepo {
+	return &DenylistRepo{client: client}
+}
+
+// Deny adds jti for ttl; a token that has already expired isn't stored
+func (d *DenylistRepo) Deny(ctx context.Context, jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+
+	err := d.client.Set(ctx, deniedTokenRedisKey(jti), 1, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("repo - DenylistRepo - Deny - d.client.Set: %w", err)
+	}
+
+	return nil
+}
+
+// DenyUser denies the tokens of the user issued before before for ttl
+func (d *DenylistRepo) DenyUser(ctx context.Context, userId int, before time.Time, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+
+	err := d.client.Set(ctx, deniedUserRedisKey(userId), before.Unix(), ttl).Err()
+	if err != nil {
+		return fmt.Errorf("repo - DenylistRepo - DenyUser - d.client.Set: %w", err)
+	}
+
+	return nil
+}
+
+// IsDenied reports whether the token jti issued to the user at issuedAt has
+// been revoked, by itself or along with every token of the user
+func (d *DenylistRepo) IsDenied(ctx context.Context, jti string, userId int, issuedAt time.Time) (bool, error) {
+	values, err := d.client.MGet(ctx, deniedTokenRedisKey(jti), deniedUserRedisKey(userId)).Result()
+	if err != nil {
+		return false, fmt.Errorf("repo - DenylistRepo - IsDenied - d.client.MGet: %w", err)
+	}
+
+	if values[0] != nil {
+		return true, nil
+	}
+
+	before, ok := values[1].(string)
+	if !ok {
+		return false, nil
+	}
+	unix, err := strconv.ParseInt(before, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("repo - DenylistRepo - IsDenied - strconv.ParseInt: %w", err)
+	}
+
+	return issuedAt.Unix() < unix, nil
+}
@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"context"
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDenylistRepo(t *testing.T) {
+	miniRedis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer miniRedis.Close()
+
+	denylist := NewDenylistRepo(redis.NewClient(&redis.Options{Addr: miniRedis.Addr()}))
+	ctx := context.Background()
+	issuedAt := time.Now()
+
+	denied, err := denylist.IsDenied(ctx, "jti", 1, issuedAt)
+	require.NoError(t, err)
+	assert.False(t, denied)
+
+	require.NoError(t, denylist.Deny(ctx, "jti", time.Minute))
+	denied, err = denylist.IsDenied(ctx, "jti", 1, issuedAt)
+	require.NoError(t, err)
+	assert.True(t, denied)
+
+	// the id is forgotten when the token expires
+	miniRedis.FastForward(time.Minute)
+	denied, err = denylist.IsDenied(ctx, "jti", 1, issuedAt)
+	require.NoError(t, err)
+	assert.False(t, denied)
+
+	// expired tokens aren't stored
+	require.NoError(t, denylist.Deny(ctx, "expired", -time.Second))
+	assert.False(t, miniRedis.Exists(deniedTokenRedisKey("expired")))
+}
+
+func TestDenylistRepo_DenyUser(t *testing.T) {
+	miniRedis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer miniRedis.Close()
+
+	denylist := NewDenylistRepo(redis.NewClient(&redis.Options{Addr: miniRedis.Addr()}))
+	ctx := context.Background()
+	revokedAt := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, denylist.DenyUser(ctx, 1, revokedAt, time.Minute))
+
+	// tokens issued before are denied, later ones and those of others aren't
+	denied, err := denylist.IsDenied(ctx, "old", 1, revokedAt.Add(-time.Second))
+	require.NoError(t, err)
+	assert.True(t, denied)
+
+	denied, err = denylist.IsDenied(ctx, "new", 1, revokedAt)
+	require.NoError(t, err)
+	assert.False(t, denied)
+
+	denied, err = denylist.IsDenied(ctx, "other", 2, revokedAt.Add(-time.Second))
+	require.NoError(t, err)
+	assert.False(t, denied)
+
+	miniRedis.FastForward(time.Minute)
+	denied, err = denylist.IsDenied(ctx, "old", 1, revokedAt.Add(-time.Second))
+	require.NoError(t, err)
+	assert.False(t, denied)
+}
@@ -3,12 +3,14 @@ package repo
 import (
 	"context"
 	"fmt"
-	"github.com/mitchellh/mapstructure"
-	"reflect"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 	"time"
 	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
 	"user-balance-service/pkg/postgres"
-	"user-balance-service/pkg/rediscache"
+	"user-balance-service/pkg/tracing"
 )
 
 const (
@@ -32,165 +34,110 @@ func paginationHistoryRedisKey(limit int, cursor string, id int) string {
 
 type HistoryRepo struct {
 	*postgres.Postgres
-	*rediscache.Redis
+	service.RedisCache
+
+	// loads collapses concurrent cache misses of the same key
+	loads singleflight.Group
 }
 
-func NewHistoryRepo(pg *postgres.Postgres, redisCache *rediscache.Redis) *HistoryRepo {
+func NewHistoryRepo(pg *postgres.Postgres, redisCache service.RedisCache) *HistoryRepo {
 	return &HistoryRepo{
-		Postgres: pg,
-		Redis:    redisCache,
+		Postgres:   pg,
+		RedisCache: redisCache,
 	}
 }
 
-func (h *HistoryRepo) ShowAll(ctx context.Context) ([]entity.History, error) {
-	var accounts []entity.History
-	var err error
-
-	// search in cache
-	value, err := h.Redis.Get(ctx, allHistoryRedisKey)
-	if value != nil && err == nil {
-		return extractHistorySliceFromTypeAny(value, accounts)
-	}
-
-	// do request
-	sql, args, err := h.Builder.
-		Select("id", "type", "description", "amount", "account_id", "date").
-		From("history").
-		ToSql()
+func (h *HistoryRepo) ShowAll(ctx context.Context) (_ []entity.History, err error) {
+	ctx, span := tracer.Start(ctx, "HistoryRepo.ShowAll")
+	defer tracing.End(span, &err)
 
-	if err != nil {
-		return nil, fmt.Errorf("repo - HistoryRepo - ShowAll - a.Builder: %w", err)
-	}
-
-	rows, err := h.Pool.Query(ctx, sql, args...)
-	if err != nil {
-		return nil, fmt.Errorf("repo - HistoryRepo - ShowAll - a.Pool.Query: %w", err)
-	}
+	return h.cachedHistory(ctx, 0, allHistoryRedisKey, func(ctx context.Context) ([]entity.History, error) {
+		sql, args, err := h.Builder.
+			Select("id", "type", "description", "amount", "account_id", "date").
+			From("history").
+			ToSql()
 
-	for rows.Next() {
-		var account entity.History
-		err := rows.Scan(&account.Id, &account.Type, &account.Description, &account.Amount, &account.AccountId, &account.Date)
 		if err != nil {
-			return nil, fmt.Errorf("repo - HistoryRepo - ShowAll - rows.Scan: %w", err)
+			return nil, fmt.Errorf("repo - HistoryRepo - ShowAll - a.Builder: %w", err)
 		}
-		accounts = append(accounts, account)
-	}
 
-	// save in cache
-	err = h.Redis.Set(ctx, allHistoryRedisKey, accounts)
-	if err != nil {
-		return nil, fmt.Errorf("repo - HistoryRepo - ShowAll - h.Redis.Set: %w", err)
-	}
+		accounts, err := h.queryHistory(ctx, sql, args)
+		if err != nil {
+			return nil, fmt.Errorf("repo - HistoryRepo - ShowAll - h.queryHistory: %w", err)
+		}
 
-	return accounts, nil
+		return accounts, nil
+	})
 }
 
-func (h *HistoryRepo) ShowById(ctx context.Context, id int) ([]entity.History, error) {
-	var accounts []entity.History
-
-	// search in cache
-	value, err := h.Redis.Get(ctx, historyByIdRedisKey(id))
-	if value != nil && err == nil {
-		return extractHistorySliceFromTypeAny(value, accounts)
-	}
-
-	// do request
-	sql, args, err := h.Builder.
-		Select("id", "type", "description", "amount", "account_id", "date").
-		From("history").
-		Where("account_id = ?", id).
-		ToSql()
-
-	if err != nil {
-		return nil, fmt.Errorf("repo - HistoryRepo - ShowById - a.Builder: %w", err)
-	}
+func (h *HistoryRepo) ShowById(ctx context.Context, id int) (_ []entity.History, err error) {
+	ctx, span := tracer.Start(ctx, "HistoryRepo.ShowById", trace.WithAttributes(attribute.Int("account.id", id)))
+	defer tracing.End(span, &err)
 
-	rows, err := h.Pool.Query(ctx, sql, args...)
-	if err != nil {
-		return nil, fmt.Errorf("repo - HistoryRepo - ShowById - a.Pool.Query: %w", err)
-	}
+	return h.cachedHistory(ctx, id, historyByIdRedisKey(id), func(ctx context.Context) ([]entity.History, error) {
+		sql, args, err := h.Builder.
+			Select("id", "type", "description", "amount", "account_id", "date").
+			From("history").
+			Where("account_id = ?", id).
+			ToSql()
 
-	for rows.Next() {
-		var account entity.History
-		err := rows.Scan(&account.Id, &account.Type, &account.Description, &account.Amount, &account.AccountId, &account.Date)
 		if err != nil {
-			return nil, fmt.Errorf("repo - HistoryRepo - ShowById - rows.Scan: %w", err)
+			return nil, fmt.Errorf("repo - HistoryRepo - ShowById - a.Builder: %w", err)
 		}
-		accounts = append(accounts, account)
-	}
 
-	// save in cache
-	err = h.Redis.Set(ctx, historyByIdRedisKey(id), accounts)
-	if err != nil {
-		return nil, fmt.Errorf("repo - HistoryRepo - ShowAll - h.Redis.Set: %w", err)
-	}
+		accounts, err := h.queryHistory(ctx, sql, args)
+		if err != nil {
+			return nil, fmt.Errorf("repo - HistoryRepo - ShowById - h.queryHistory: %w", err)
+		}
 
-	return accounts, nil
+		return accounts, nil
+	})
 }
 
-func (h *HistoryRepo) ShowSorted(ctx context.Context, sortType string, accountId int) ([]entity.History, error) {
-	var (
-		sql  string
-		args []interface{}
-		err  error
-	)
-
-	// search in cache
-	value, err := h.Redis.Get(ctx, sortedHistoryRedisKey(sortType, accountId))
-	if value != nil {
-		accounts, ok := value.([]entity.History)
-		if ok {
-			return accounts, nil
+func (h *HistoryRepo) ShowSorted(ctx context.Context, sortType string, accountId int) (_ []entity.History, err error) {
+	ctx, span := tracer.Start(ctx, "HistoryRepo.ShowSorted", trace.WithAttributes(attribute.Int("account.id", accountId)))
+	defer tracing.End(span, &err)
+
+	key := sortedHistoryRedisKey(sortType, accountId)
+
+	return h.cachedHistory(ctx, accountId, key, func(ctx context.Context) ([]entity.History, error) {
+		var (
+			sql  string
+			args []interface{}
+			err  error
+		)
+
+		switch {
+		case accountId == 0:
+			sql, args, err = h.Builder.
+				Select("id", "type", "description", "amount", "account_id", "date").
+				From("history").OrderBy(sortType).
+				ToSql()
+		case accountId != 0:
+			sql, args, err = h.Builder.
+				Select("id", "type", "description", "amount", "account_id", "date").
+				From("history").
+				Where("account_id = ?", accountId).
+				OrderBy(sortType).
+				ToSql()
 		}
-	}
-	// if err != nil {
-	// 	return nil, fmt.Errorf("repo - HistoryRepo - ShowSorted - h.Redis.Get: %w", err)
-	// }
-
-	// do request
-	switch {
-	case accountId == 0:
-		sql, args, err = h.Builder.
-			Select("id", "type", "description", "amount", "account_id", "date").
-			From("history").OrderBy(sortType).
-			ToSql()
-	case accountId != 0:
-		sql, args, err = h.Builder.
-			Select("id", "type", "description", "amount", "account_id", "date").
-			From("history").
-			Where("account_id = ?", accountId).
-			OrderBy(sortType).
-			ToSql()
-	}
-	if err != nil {
-		return nil, fmt.Errorf("repo - HistoryRepo - ShowSorted - a.Builder: %w", err)
-	}
-
-	rows, err := h.Pool.Query(ctx, sql, args...)
-	if err != nil {
-		return nil, fmt.Errorf("repo - HistoryRepo - ShowSorted - a.Pool.Query: %w", err)
-	}
-
-	var accounts []entity.History
-	for rows.Next() {
-		var account entity.History
-		err := rows.Scan(&account.Id, &account.Type, &account.Description, &account.Amount, &account.AccountId, &account.Date)
 		if err != nil {
-			return nil, fmt.Errorf("repo - HistoryRepo - ShowSorted - rows.Scan: %w", err)
+			return nil, fmt.Errorf("repo - HistoryRepo - ShowSorted - a.Builder: %w", err)
 		}
-		accounts = append(accounts, account)
-	}
 
-	// save in cache
-	err = h.Redis.Set(ctx, sortedHistoryRedisKey(sortType, accountId), accounts)
-	if err != nil {
-		return nil, fmt.Errorf("repo - HistoryRepo - ShowSorted - h.Redis.Set: %w", err)
-	}
+		accounts, err := h.queryHistory(ctx, sql, args)
+		if err != nil {
+			return nil, fmt.Errorf("repo - HistoryRepo - ShowSorted - h.queryHistory: %w", err)
+		}
 
-	return accounts, nil
+		return accounts, nil
+	})
 }
 
-func (h *HistoryRepo) SaveHistory(ctx context.Context, input entity.History) (int, error) {
+func (h *HistoryRepo) SaveHistory(ctx context.Context, input entity.History) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "HistoryRepo.SaveHistory", trace.WithAttributes(attribute.Int("account.id", input.AccountId)))
+	defer tracing.End(span, &err)
+
 	sql, args, err := h.Builder.
 		Insert("history").
 		Columns("type", "description", "amount", "account_id", "date").
@@ -207,15 +154,16 @@ func (h *HistoryRepo) SaveHistory(ctx context.Context, input entity.History) (in
 		return 0, fmt.Errorf("repo - HistoryRepo - SaveHistory - h.Pool.QueryRow: %w", err)
 	}
 
+	h.invalidateHistory(ctx, input.AccountId)
+
 	return id, nil
 }
 
-func (h *HistoryRepo) Pagination(ctx context.Context, limit int, param string, accountId int) ([]entity.History, error) {
-	var (
-		cursor string
-		sql    string
-		args   []interface{}
-	)
+func (h *HistoryRepo) Pagination(ctx context.Context, limit int, param string, accountId int) (_ []entity.History, err error) {
+	ctx, span := tracer.Start(ctx, "HistoryRepo.Pagination", trace.WithAttributes(attribute.Int("account.id", accountId)))
+	defer tracing.End(span, &err)
+
+	var cursor string
 
 	// correct parameters
 	switch {
@@ -229,94 +177,64 @@ func (h *HistoryRepo) Pagination(ctx context.Context, limit int, param string, a
 		limit = maxPaginationLimit
 	}
 
-	// search in cache
-	value, err := h.Redis.Get(ctx, paginationHistoryRedisKey(limit, cursor, accountId))
-	if value != nil {
-		accounts, ok := value.([]entity.History)
-		if ok {
-			return accounts, nil
+	key := paginationHistoryRedisKey(limit, cursor, accountId)
+
+	return h.cachedHistory(ctx, accountId, key, func(ctx context.Context) ([]entity.History, error) {
+		var (
+			sql  string
+			args []interface{}
+			err  error
+		)
+
+		switch {
+		case accountId == 0:
+			sql, args, err = h.Builder.
+				Select("id", "type", "description", "amount", "account_id", "date").
+				From("history").
+				Where("date > ?", cursor).
+				OrderBy("date DESC").
+				Limit(uint64(limit)).
+				ToSql()
+		case accountId != 0:
+			sql, args, err = h.Builder.
+				Select("id", "type", "description", "amount", "account_id", "date").
+				From("history").
+				Where("account_id = ?", accountId).
+				Where("date > ?", cursor).
+				OrderBy("date DESC").
+				Limit(uint64(limit)).
+				ToSql()
 		}
-	}
-	// if err != nil {
-	// 	return nil, fmt.Errorf("repo - HistoryRepo - Pagination - h.Redis.Get: %w", err)
-	// }
 
-	// do request
-	switch {
-	case accountId == 0:
-		sql, args, err = h.Builder.
-			Select("id", "type", "description", "amount", "account_id", "date").
-			From("history").
-			Where("date > ?", cursor).
-			OrderBy("date DESC").
-			Limit(uint64(limit)).
-			ToSql()
-	case accountId != 0:
-		sql, args, err = h.Builder.
-			Select("id", "type", "description", "amount", "account_id", "date").
-			From("history").
-			Where("account_id = ?", accountId).
-			Where("date > ?", cursor).
-			OrderBy("date DESC").
-			Limit(uint64(limit)).
-			ToSql()
-	}
+		if err != nil {
+			return nil, fmt.Errorf("repo - HistoryRepo - Pagination - a.Builder: %w", err)
+		}
 
-	if err != nil {
-		return nil, fmt.Errorf("repo - HistoryRepo - ShowSorted - a.Builder: %w", err)
-	}
+		accounts, err := h.queryHistory(ctx, sql, args)
+		if err != nil {
+			return nil, fmt.Errorf("repo - HistoryRepo - Pagination - h.queryHistory: %w", err)
+		}
 
+		return accounts, nil
+	})
+}
+
+func (h *HistoryRepo) queryHistory(ctx context.Context, sql string, args []interface{}) ([]entity.History, error) {
 	rows, err := h.Pool.Query(ctx, sql, args...)
 	if err != nil {
-		return nil, fmt.Errorf("repo - HistoryRepo - ShowSorted - a.Pool.Query: %w", err)
+		return nil, fmt.Errorf("h.Pool.Query: %w", err)
 	}
+	defer rows.Close()
 
 	var accounts []entity.History
 	for rows.Next() {
 		var account entity.History
 		err := rows.Scan(&account.Id, &account.Type, &account.Description, &account.Amount, &account.AccountId, &account.Date)
 		if err != nil {
-			return nil, fmt.Errorf("repo - HistoryRepo - ShowSorted - rows.Scan: %w", err)
+			return nil, fmt.Errorf("rows.Scan: %w", err)
 		}
 		accounts = append(accounts, account)
 	}
 
-	// save in cache
-	err = h.Redis.Set(ctx, paginationHistoryRedisKey(limit, cursor, accountId), accounts)
-	if err != nil {
-		return nil, fmt.Errorf("repo - HistoryRepo - Pagination - h.Redis.Set: %w", err)
-	}
-
-	return accounts, nil
-}
-
-func extractHistorySliceFromTypeAny(value any, accounts []entity.History) ([]entity.History, error) {
-	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-		DecodeHook: StringToCustomTimeHookFunc("2006-01-02"),
-		Result:     &accounts,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("repo - AccountRepo - GetAccount - mapstructure.NewDecoder: %w", err)
-	}
-	err = decoder.Decode(value)
-	return accounts, err
-}
-
-func StringToCustomTimeHookFunc(layout string) mapstructure.DecodeHookFunc {
-	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
-		if f.Kind() != reflect.String {
-			return data, nil
-		}
-		if t != reflect.TypeOf(entity.CustomTime{}) {
-			return data, nil
-		}
-
-		// Convert it by parsing
-		rawtime, err := time.Parse(layout, data.(string))
-		if err != nil {
-			return nil, err
-		}
-		ct := entity.CustomTime(rawtime)
-		return ct, nil
-	}
+	return accounts, rows.Err()
 }
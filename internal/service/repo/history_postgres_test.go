@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/pkg/cache"
+	"user-balance-service/pkg/memcache"
+)
+
+func TestHistoryRepo_CacheHits(t *testing.T) {
+	date := entity.CustomTime(time.Date(2022, 10, 20, 0, 0, 0, 0, time.UTC))
+	history := []entity.History{
+		{Id: 1, Type: entity.RefillType, Description: "salary", Amount: 100, AccountId: 1, Date: date},
+		{Id: 2, Type: entity.WriteOffType, Amount: 50, AccountId: 1, Date: date},
+	}
+
+	queries := []struct {
+		name  string
+		query string
+		args  []interface{}
+		show  func(ctx context.Context, h *HistoryRepo) ([]entity.History, error)
+	}{
+		{
+			name:  "ShowAll",
+			query: "SELECT (.+) FROM history$",
+			show: func(ctx context.Context, h *HistoryRepo) ([]entity.History, error) {
+				return h.ShowAll(ctx)
+			},
+		},
+		{
+			name:  "ShowById",
+			query: "SELECT (.+) FROM history WHERE account_id = (.+)",
+			args:  []interface{}{1},
+			show: func(ctx context.Context, h *HistoryRepo) ([]entity.History, error) {
+				return h.ShowById(ctx, 1)
+			},
+		},
+		{
+			name:  "ShowSorted all accounts",
+			query: "SELECT (.+) FROM history ORDER BY amount",
+			show: func(ctx context.Context, h *HistoryRepo) ([]entity.History, error) {
+				return h.ShowSorted(ctx, "amount", 0)
+			},
+		},
+		{
+			name:  "ShowSorted",
+			query: "SELECT (.+) FROM history WHERE account_id = (.+) ORDER BY date",
+			args:  []interface{}{1},
+			show: func(ctx context.Context, h *HistoryRepo) ([]entity.History, error) {
+				return h.ShowSorted(ctx, "date", 1)
+			},
+		},
+		{
+			name:  "Pagination all accounts",
+			query: "SELECT (.+) FROM history WHERE date > (.+) ORDER BY date DESC LIMIT 5",
+			args:  []interface{}{defaultPaginationCursor},
+			show: func(ctx context.Context, h *HistoryRepo) ([]entity.History, error) {
+				return h.Pagination(ctx, 5, "", 0)
+			},
+		},
+		{
+			name:  "Pagination",
+			query: "SELECT (.+) FROM history WHERE account_id = (.+) AND date > (.+) ORDER BY date DESC LIMIT 5",
+			args:  []interface{}{1, "2022-10-01"},
+			show: func(ctx context.Context, h *HistoryRepo) ([]entity.History, error) {
+				return h.Pagination(ctx, 5, "2022-10-01", 1)
+			},
+		},
+	}
+
+	codecs := map[string]cache.Codec{
+		"json":    cache.JSON,
+		"msgpack": cache.Msgpack,
+	}
+
+	for codecName, codec := range codecs {
+		for _, q := range queries {
+			t.Run(codecName+"/"+q.name, func(t *testing.T) {
+				repo, mockPool := newCacheTestRepoWith(t, memcache.New(memcache.Codec(codec)))
+				ctx := context.Background()
+
+				// only the first call reaches the database
+				mockPool.ExpectQuery(q.query).
+					WithArgs(q.args...).
+					WillReturnRows(historyRows(mockPool, history...))
+
+				got, err := q.show(ctx, repo.HistoryRepo)
+				require.NoError(t, err)
+				assert.Equal(t, history, got)
+
+				got, err = q.show(ctx, repo.HistoryRepo)
+				require.NoError(t, err)
+				assert.Equal(t, history, got)
+
+				assert.NoError(t, mockPool.ExpectationsWereMet())
+			})
+		}
+	}
+}
+
+func TestCacheCodecs_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	account := entity.Account{Id: 1, Balance: 500, Version: 3}
+	history := []entity.History{
+		{Id: 1, Type: entity.InnerTransferType, Amount: 100, AccountId: 1, Date: entity.CustomTime(time.Date(2022, 10, 20, 15, 4, 5, 123456789, time.UTC))},
+	}
+
+	for name, codec := range map[string]cache.Codec{"json": cache.JSON, "msgpack": cache.Msgpack} {
+		t.Run(name, func(t *testing.T) {
+			m := memcache.New(memcache.Codec(codec))
+
+			require.NoError(t, cache.Set(ctx, m, "account", account))
+			gotAccount, err := cache.Get[entity.Account](ctx, m, "account")
+			require.NoError(t, err)
+			assert.Equal(t, account, gotAccount)
+
+			require.NoError(t, cache.Set(ctx, m, "history", toCachedHistory(history)))
+			gotHistory, err := cache.Get[[]cachedHistoryRecord](ctx, m, "history")
+			require.NoError(t, err)
+			assert.Equal(t, history, fromCachedHistory(gotHistory))
+		})
+	}
+}
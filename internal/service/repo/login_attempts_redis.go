@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+const (
+	loginFailuresRedisKeyPrefix = "login_failures"
+	loginLockRedisKeyPrefix     = "login_lock"
+)
+
+func loginFailuresRedisKey(key string) string {
+	return fmt.Sprintf("%s_%s", loginFailuresRedisKeyPrefix, key)
+}
+
+func loginLockRedisKey(key string) string {
+	return fmt.Sprintf("%s_%s", loginLockRedisKeyPrefix, key)
+}
+
+// removeFailureScript decrements a positive counter only: DECR would take it
+// below 0 or bring back an expired one without a TTL. DECR of an existing
+// key keeps its TTL.
+var removeFailureScript = redis.NewScript(`
+local failures = tonumber(redis.call("GET", KEYS[1]))
+if failures == nil or failures <= 0 then
+	return 0
+end
+return redis.call("DECR", KEYS[1])
+`)
+
+// LoginAttemptsRepo keeps failed sign-in counters and lockouts in Redis.
+// Both expire on their own.
+type LoginAttemptsRepo struct {
+	client *redis.Client
+}
+
+func NewLoginAttemptsRepo(client *redis.Client) *LoginAttemptsRepo {
+	return &LoginAttemptsRepo{client: client}
+}
+
+// AddFailure increments the failures of key; every failure makes the counter
+// live for another window
+func (l *LoginAttemptsRepo) AddFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := l.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, loginFailuresRedisKey(key))
+		pipe.Expire(ctx, loginFailuresRedisKey(key), window)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("repo - LoginAttemptsRepo - AddFailure - l.client.TxPipelined: %w", err)
+	}
+
+	return incr.Val(), nil
+}
+
+// RemoveFailure takes back a failure counted by AddFailure
+func (l *LoginAttemptsRepo) RemoveFailure(ctx context.Context, key string) error {
+	err := removeFailureScript.Run(ctx, l.client, []string{loginFailuresRedisKey(key)}).Err()
+	if err != nil {
+		return fmt.Errorf("repo - LoginAttemptsRepo - RemoveFailure - removeFailureScript.Run: %w", err)
+	}
+
+	return nil
+}
+
+// TryLock locks key out for ttl unless it is locked already and reports
+// whether it did
+func (l *LoginAttemptsRepo) TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ok, err := l.client.SetNX(ctx, loginLockRedisKey(key), 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("repo - LoginAttemptsRepo - TryLock - l.client.SetNX: %w", err)
+	}
+
+	return ok, nil
+}
+
+func (l *LoginAttemptsRepo) Unlock(ctx context.Context, key string) error {
+	err := l.client.Del(ctx, loginLockRedisKey(key)).Err()
+	if err != nil {
+		return fmt.Errorf("repo - LoginAttemptsRepo - Unlock - l.client.Del: %w", err)
+	}
+
+	return nil
+}
+
+// LockedFor returns how long key stays locked out, 0 if it isn't
+func (l *LoginAttemptsRepo) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := l.client.PTTL(ctx, loginLockRedisKey(key)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("repo - LoginAttemptsRepo - LockedFor - l.client.PTTL: %w", err)
+	}
+
+	// negative for a missing key or one without expiry, which locks don't have
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+func (l *LoginAttemptsRepo) Reset(ctx context.Context, key string) error {
+	err := l.client.Del(ctx, loginFailuresRedisKey(key), loginLockRedisKey(key)).Err()
+	if err != nil {
+		return fmt.Errorf("repo - LoginAttemptsRepo - Reset - l.client.Del: %w", err)
+	}
+
+	return nil
+}
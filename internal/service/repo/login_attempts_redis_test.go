@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"context"
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestLoginAttemptsRepo(t *testing.T) {
+	miniRedis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer miniRedis.Close()
+
+	attempts := NewLoginAttemptsRepo(redis.NewClient(&redis.Options{Addr: miniRedis.Addr()}))
+	ctx := context.Background()
+
+	for want := int64(1); want <= 3; want++ {
+		n, err := attempts.AddFailure(ctx, "user_qwe", time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, want, n)
+	}
+
+	// every failure restarts the window
+	miniRedis.FastForward(59 * time.Minute)
+	n, err := attempts.AddFailure(ctx, "user_qwe", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+
+	// a failure can be taken back
+	require.NoError(t, attempts.RemoveFailure(ctx, "user_qwe"))
+	n, err = attempts.AddFailure(ctx, "user_qwe", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+
+	ttl, err := attempts.LockedFor(ctx, "user_qwe")
+	require.NoError(t, err)
+	assert.Zero(t, ttl)
+
+	ok, err := attempts.TryLock(ctx, "user_qwe", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ttl, err = attempts.LockedFor(ctx, "user_qwe")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	// only one attempt gets the lock
+	ok, err = attempts.TryLock(ctx, "user_qwe", 2*time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ttl, err = attempts.LockedFor(ctx, "user_qwe")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	// the lock lifts on its own
+	miniRedis.FastForward(time.Minute)
+	ttl, err = attempts.LockedFor(ctx, "user_qwe")
+	require.NoError(t, err)
+	assert.Zero(t, ttl)
+
+	// the lock can be lifted early
+	_, err = attempts.TryLock(ctx, "user_qwe", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, attempts.Unlock(ctx, "user_qwe"))
+	assert.False(t, miniRedis.Exists(loginLockRedisKey("user_qwe")))
+
+	// reset clears both the counter and the lock
+	_, err = attempts.TryLock(ctx, "user_qwe", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, attempts.Reset(ctx, "user_qwe"))
+	assert.False(t, miniRedis.Exists(loginFailuresRedisKey("user_qwe")))
+	assert.False(t, miniRedis.Exists(loginLockRedisKey("user_qwe")))
+}
+
+func TestLoginAttemptsRepo_RemoveFailure(t *testing.T) {
+	miniRedis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer miniRedis.Close()
+
+	attempts := NewLoginAttemptsRepo(redis.NewClient(&redis.Options{Addr: miniRedis.Addr()}))
+	ctx := context.Background()
+	key := loginFailuresRedisKey("user_qwe")
+
+	_, err = attempts.AddFailure(ctx, "user_qwe", time.Hour)
+	require.NoError(t, err)
+
+	// the counter keeps its window
+	miniRedis.FastForward(time.Minute)
+	require.NoError(t, attempts.RemoveFailure(ctx, "user_qwe"))
+	value, err := miniRedis.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, "0", value)
+	assert.Equal(t, 59*time.Minute, miniRedis.TTL(key))
+
+	// and doesn't go below 0
+	require.NoError(t, attempts.RemoveFailure(ctx, "user_qwe"))
+	value, err = miniRedis.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, "0", value)
+
+	// an expired counter isn't brought back
+	miniRedis.FastForward(time.Hour)
+	require.NoError(t, attempts.RemoveFailure(ctx, "user_qwe"))
+	assert.False(t, miniRedis.Exists(key))
+}
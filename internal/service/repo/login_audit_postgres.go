@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"user-balance-service/internal/entity"
+	"user-balance-service/pkg/postgres"
+)
+
+type LoginAuditRepo struct {
+	*postgres.Postgres
+}
+
+func NewLoginAuditRepo(pg *postgres.Postgres) *LoginAuditRepo {
+	return &LoginAuditRepo{pg}
+}
+
+// SaveLoginAttempt stores attempt; a zero UserId is stored as NULL
+func (r *LoginAuditRepo) SaveLoginAttempt(ctx context.Context, attempt entity.LoginAttempt) error {
+	var userId *int
+	if attempt.UserId != 0 {
+		userId = &attempt.UserId
+	}
+
+	sql, args, err := r.Builder.
+		Insert("login_attempts").
+		Columns("username", "user_id", "ip", "user_agent", "result").
+		Values(attempt.Username, userId, attempt.IP, attempt.UserAgent, attempt.Result).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - LoginAuditRepo - SaveLoginAttempt - r.Builder: %w", err)
+	}
+
+	_, err = r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("repo - LoginAuditRepo - SaveLoginAttempt - r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
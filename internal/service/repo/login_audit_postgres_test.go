@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"context"
+	"github.com/Masterminds/squirrel"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"user-balance-service/internal/entity"
+	"user-balance-service/pkg/postgres"
+)
+
+func TestLoginAuditRepo_SaveLoginAttempt(t *testing.T) {
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockPool.Close()
+
+	repo := NewLoginAuditRepo(&postgres.Postgres{
+		Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		Pool:    mockPool,
+	})
+	userId := 1
+
+	mockPool.ExpectExec("INSERT INTO login_attempts \\(username,user_id,ip,user_agent,result\\)").
+		WithArgs("qwe", &userId, "192.0.2.1", "curl/7.85", entity.LoginSucceeded).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	// unknown usernames have no user id
+	mockPool.ExpectExec("INSERT INTO login_attempts").
+		WithArgs("nobody", (*int)(nil), "192.0.2.1", "", entity.LoginFailed).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	err = repo.SaveLoginAttempt(context.Background(), entity.LoginAttempt{
+		Username:  "qwe",
+		UserId:    1,
+		IP:        "192.0.2.1",
+		UserAgent: "curl/7.85",
+		Result:    entity.LoginSucceeded,
+	})
+	assert.NoError(t, err)
+
+	err = repo.SaveLoginAttempt(context.Background(), entity.LoginAttempt{
+		Username: "nobody",
+		IP:       "192.0.2.1",
+		Result:   entity.LoginFailed,
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
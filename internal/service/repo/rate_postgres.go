@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/pkg/postgres"
+)
+
+type RateRepo struct {
+	*postgres.Postgres
+}
+
+func NewRateRepo(pg *postgres.Postgres) *RateRepo {
+	return &RateRepo{pg}
+}
+
+// GetDailyRates returns the stored rates of currency between from and to inclusive
+func (r *RateRepo) GetDailyRates(ctx context.Context, currency string, from, to time.Time) ([]entity.DailyRate, error) {
+	sql, args, err := r.Builder.
+		Select("currency", "date", "rate").
+		From("exchange_rates").
+		Where("currency = ?", currency).
+		Where("date BETWEEN ? AND ?", from, to).
+		OrderBy("date").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("repo - RateRepo - GetDailyRates - r.Builder: %w", err)
+	}
+
+	rows, err := r.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo - RateRepo - GetDailyRates - r.Pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []entity.DailyRate
+	for rows.Next() {
+		var rate entity.DailyRate
+		err = rows.Scan(&rate.Currency, &rate.Date, &rate.Rate)
+		if err != nil {
+			return nil, fmt.Errorf("repo - RateRepo - GetDailyRates - rows.Scan: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("repo - RateRepo - GetDailyRates - rows.Err: %w", err)
+	}
+
+	return rates, nil
+}
+
+// SaveDailyRate stores a rate, a day that is already stored is kept as is
+func (r *RateRepo) SaveDailyRate(ctx context.Context, rate entity.DailyRate) error {
+	sql, args, err := r.Builder.
+		Insert("exchange_rates").
+		Columns("currency", "date", "rate").
+		Values(rate.Currency, time.Time(rate.Date), rate.Rate).
+		Suffix("ON CONFLICT (currency, date) DO NOTHING").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - RateRepo - SaveDailyRate - r.Builder: %w", err)
+	}
+
+	_, err = r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("repo - RateRepo - SaveDailyRate - r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"context"
+	"github.com/Masterminds/squirrel"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/pkg/postgres"
+)
+
+func newRateTestRepo(t *testing.T) (*RateRepo, pgxmock.PgxPoolIface) {
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(mockPool.Close)
+
+	return NewRateRepo(&postgres.Postgres{
+		Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		Pool:    mockPool,
+	}), mockPool
+}
+
+func TestRateRepo_GetDailyRates(t *testing.T) {
+	from := time.Date(2022, 10, 18, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name    string
+		rows    func(mockPool pgxmock.PgxPoolIface) *pgxmock.Rows
+		err     error
+		want    []entity.DailyRate
+		wantErr bool
+	}{
+		{
+			name: "OK",
+			rows: func(mockPool pgxmock.PgxPoolIface) *pgxmock.Rows {
+				return mockPool.NewRows([]string{"currency", "date", "rate"}).
+					AddRow("USD", entity.CustomTime(from), 61.0).
+					AddRow("USD", entity.CustomTime(to), 62.0)
+			},
+			want: []entity.DailyRate{
+				{Currency: "USD", Date: entity.CustomTime(from), Rate: 61},
+				{Currency: "USD", Date: entity.CustomTime(to), Rate: 62},
+			},
+		},
+		{
+			name:    "Query error",
+			err:     errSomethingWentWrong,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mockPool := newRateTestRepo(t)
+
+			query := mockPool.ExpectQuery("SELECT currency, date, rate FROM exchange_rates WHERE currency = (.+) AND date BETWEEN (.+) AND (.+) ORDER BY date").
+				WithArgs("USD", from, to)
+			if tc.err != nil {
+				query.WillReturnError(tc.err)
+			} else {
+				query.WillReturnRows(tc.rows(mockPool))
+			}
+
+			got, err := repo.GetDailyRates(context.Background(), "USD", from, to)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, got)
+			}
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRateRepo_SaveDailyRate(t *testing.T) {
+	repo, mockPool := newRateTestRepo(t)
+	date := time.Date(2022, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	mockPool.ExpectExec("INSERT INTO exchange_rates (.+) ON CONFLICT \\(currency, date\\) DO NOTHING").
+		WithArgs("USD", date, 61.5).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	err := repo.SaveDailyRate(context.Background(), entity.DailyRate{Currency: "USD", Date: entity.CustomTime(date), Rate: 61.5})
+	assert.NoError(t, err)
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
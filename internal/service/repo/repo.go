@@ -1,20 +1,35 @@
 package repo
 
 import (
+	"user-balance-service/internal/service"
 	"user-balance-service/pkg/postgres"
-	"user-balance-service/pkg/rediscache"
+	"user-balance-service/pkg/tracing"
 )
 
+var tracer = tracing.Tracer("user-balance-service/internal/service/repo")
+
 type Repository struct {
 	*AuthRepo
 	*AccountRepo
 	*HistoryRepo
+	*RateRepo
+	*TokenRepo
+	*ApiKeyRepo
+	*TwoFactorRepo
+	*LoginAuditRepo
+	*AuditRepo
 }
 
-func New(pg *postgres.Postgres, redisCache *rediscache.Redis) *Repository {
+func New(pg *postgres.Postgres, redisCache service.RedisCache) *Repository {
 	return &Repository{
-		AuthRepo:    NewAuthRepo(pg),
-		AccountRepo: NewAccountRepo(pg, redisCache),
-		HistoryRepo: NewHistoryRepo(pg, redisCache),
+		AuthRepo:       NewAuthRepo(pg),
+		AccountRepo:    NewAccountRepo(pg, redisCache),
+		HistoryRepo:    NewHistoryRepo(pg, redisCache),
+		RateRepo:       NewRateRepo(pg),
+		TokenRepo:      NewTokenRepo(pg),
+		ApiKeyRepo:     NewApiKeyRepo(pg),
+		TwoFactorRepo:  NewTwoFactorRepo(pg),
+		LoginAuditRepo: NewLoginAuditRepo(pg),
+		AuditRepo:      NewAuditRepo(pg),
 	}
 }
@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"user-balance-service/pkg/postgres"
+)
+
+// SchemaRepo reads the state of the migrations applied by golang-migrate
+type SchemaRepo struct {
+	*postgres.Postgres
+}
+
+func NewSchemaRepo(pg *postgres.Postgres) *SchemaRepo {
+	return &SchemaRepo{pg}
+}
+
+// MigrationVersion returns the version of the last applied migration, 0 if
+// there is none, and whether it failed halfway (dirty)
+func (s *SchemaRepo) MigrationVersion(ctx context.Context) (int, bool, error) {
+	sql, args, err := s.Builder.
+		Select("version", "dirty").
+		From("schema_migrations").
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return 0, false, fmt.Errorf("repo - SchemaRepo - MigrationVersion - s.Builder: %w", err)
+	}
+
+	var (
+		version int
+		dirty   bool
+	)
+	err = s.Pool.QueryRow(ctx, sql, args...).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("repo - SchemaRepo - MigrationVersion - s.Pool.QueryRow: %w", err)
+	}
+
+	return version, dirty, nil
+}
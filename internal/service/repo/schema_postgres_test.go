@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"user-balance-service/pkg/postgres"
+)
+
+func TestSchemaRepo_MigrationVersion(t *testing.T) {
+	type MockBehaviour func(m pgxmock.PgxPoolIface)
+
+	testCases := []struct {
+		name          string
+		mockBehaviour MockBehaviour
+		wantVersion   int
+		wantDirty     bool
+		wantErr       bool
+	}{
+		{
+			name: "applied",
+			mockBehaviour: func(m pgxmock.PgxPoolIface) {
+				m.ExpectQuery("SELECT version, dirty FROM schema_migrations LIMIT 1").
+					WillReturnRows(pgxmock.NewRows([]string{"version", "dirty"}).AddRow(11, false))
+			},
+			wantVersion: 11,
+		},
+		{
+			name: "failed halfway",
+			mockBehaviour: func(m pgxmock.PgxPoolIface) {
+				m.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+					WillReturnRows(pgxmock.NewRows([]string{"version", "dirty"}).AddRow(11, true))
+			},
+			wantVersion: 11,
+			wantDirty:   true,
+		},
+		{
+			name: "none applied",
+			mockBehaviour: func(m pgxmock.PgxPoolIface) {
+				m.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+					WillReturnError(pgx.ErrNoRows)
+			},
+		},
+		{
+			name: "database error",
+			mockBehaviour: func(m pgxmock.PgxPoolIface) {
+				m.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+					WillReturnError(errors.New("relation \"schema_migrations\" does not exist"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPool, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mockPool.Close()
+
+			repo := NewSchemaRepo(&postgres.Postgres{
+				Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+				Pool:    mockPool,
+			})
+			tc.mockBehaviour(mockPool)
+
+			version, dirty, err := repo.MigrationVersion(context.Background())
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantVersion, version)
+			assert.Equal(t, tc.wantDirty, dirty)
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	"user-balance-service/pkg/postgres"
+)
+
+type TokenRepo struct {
+	*postgres.Postgres
+}
+
+func NewTokenRepo(pg *postgres.Postgres) *TokenRepo {
+	return &TokenRepo{pg}
+}
+
+func (r *TokenRepo) CreateRefreshToken(ctx context.Context, token entity.RefreshToken) error {
+	sql, args, err := r.insertRefreshToken(token)
+	if err != nil {
+		return fmt.Errorf("repo - TokenRepo - CreateRefreshToken - r.Builder: %w", err)
+	}
+
+	_, err = r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("repo - TokenRepo - CreateRefreshToken - r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// GetRefreshToken returns the token with id, revoked or not. An unknown id
+// is service.ErrInvalidToken.
+func (r *TokenRepo) GetRefreshToken(ctx context.Context, id string) (entity.RefreshToken, error) {
+	sql, args, err := r.Builder.
+		Select("id", "user_id", "family_id", "expires_at", "revoked_at").
+		From("refresh_tokens").
+		Where("id = ?", id).
+		ToSql()
+
+	if err != nil {
+		return entity.RefreshToken{}, fmt.Errorf("repo - TokenRepo - GetRefreshToken - r.Builder: %w", err)
+	}
+
+	var token entity.RefreshToken
+	err = r.Pool.QueryRow(ctx, sql, args...).
+		Scan(&token.Id, &token.UserId, &token.FamilyId, &token.ExpiresAt, &token.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.RefreshToken{}, fmt.Errorf("repo - TokenRepo - GetRefreshToken - r.Pool.QueryRow: %w", service.ErrInvalidToken)
+	}
+	if err != nil {
+		return entity.RefreshToken{}, fmt.Errorf("repo - TokenRepo - GetRefreshToken - r.Pool.QueryRow: %w", err)
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken revokes the token with id and stores next in one
+// transaction. If the token has already been revoked, e.g. by a concurrent
+// refresh, nothing is stored and service.ErrRefreshTokenReused is returned.
+func (r *TokenRepo) RotateRefreshToken(ctx context.Context, id string, next entity.RefreshToken) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("repo - TokenRepo - RotateRefreshToken - r.Pool.Begin: %w", err)
+	}
+
+	sql, args, err := r.Builder.
+		Update("refresh_tokens").
+		Set("revoked_at", squirrel.Expr("now()")).
+		Where("id = ?", id).
+		Where("revoked_at IS NULL").
+		ToSql()
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - TokenRepo - RotateRefreshToken - r.Builder: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - TokenRepo - RotateRefreshToken - tx.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - TokenRepo - RotateRefreshToken: %w", service.ErrRefreshTokenReused)
+	}
+
+	sql, args, err = r.insertRefreshToken(next)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - TokenRepo - RotateRefreshToken - r.Builder: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - TokenRepo - RotateRefreshToken - tx.Exec: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("repo - TokenRepo - RotateRefreshToken - tx.Commit: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeRefreshToken revokes the token with id; revoking it again is not an
+// error. An unknown id is service.ErrInvalidToken.
+func (r *TokenRepo) RevokeRefreshToken(ctx context.Context, id string) error {
+	sql, args, err := r.Builder.
+		Update("refresh_tokens").
+		Set("revoked_at", squirrel.Expr("COALESCE(revoked_at, now())")).
+		Where("id = ?", id).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - TokenRepo - RevokeRefreshToken - r.Builder: %w", err)
+	}
+
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("repo - TokenRepo - RevokeRefreshToken - r.Pool.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("repo - TokenRepo - RevokeRefreshToken: %w", service.ErrInvalidToken)
+	}
+
+	return nil
+}
+
+// RevokeTokenFamily revokes every token rotated from the same sign-in
+func (r *TokenRepo) RevokeTokenFamily(ctx context.Context, familyId string) error {
+	sql, args, err := r.Builder.
+		Update("refresh_tokens").
+		Set("revoked_at", squirrel.Expr("now()")).
+		Where("family_id = ?", familyId).
+		Where("revoked_at IS NULL").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - TokenRepo - RevokeTokenFamily - r.Builder: %w", err)
+	}
+
+	_, err = r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("repo - TokenRepo - RevokeTokenFamily - r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeUserTokens revokes every refresh token of the user
+func (r *TokenRepo) RevokeUserTokens(ctx context.Context, userId int) error {
+	sql, args, err := r.Builder.
+		Update("refresh_tokens").
+		Set("revoked_at", squirrel.Expr("now()")).
+		Where("user_id = ?", userId).
+		Where("revoked_at IS NULL").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - TokenRepo - RevokeUserTokens - r.Builder: %w", err)
+	}
+
+	_, err = r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("repo - TokenRepo - RevokeUserTokens - r.Pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TokenRepo) insertRefreshToken(token entity.RefreshToken) (string, []interface{}, error) {
+	return r.Builder.
+		Insert("refresh_tokens").
+		Columns("id", "user_id", "family_id", "expires_at").
+		Values(token.Id, token.UserId, token.FamilyId, token.ExpiresAt).
+		ToSql()
+}
@@ -0,0 +1,162 @@
+package repo
+
+import (
+	"context"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	"user-balance-service/pkg/postgres"
+)
+
+func newTokenTestRepo(t *testing.T) (*TokenRepo, pgxmock.PgxPoolIface) {
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(mockPool.Close)
+
+	return NewTokenRepo(&postgres.Postgres{
+		Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		Pool:    mockPool,
+	}), mockPool
+}
+
+func TestTokenRepo_GetRefreshToken(t *testing.T) {
+	expiresAt := time.Date(2022, 11, 20, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name    string
+		rows    func(mockPool pgxmock.PgxPoolIface) *pgxmock.Rows
+		err     error
+		want    entity.RefreshToken
+		wantErr error
+	}{
+		{
+			name: "OK",
+			rows: func(mockPool pgxmock.PgxPoolIface) *pgxmock.Rows {
+				return mockPool.NewRows([]string{"id", "user_id", "family_id", "expires_at", "revoked_at"}).
+					AddRow("id", 1, "family", expiresAt, nil)
+			},
+			want: entity.RefreshToken{Id: "id", UserId: 1, FamilyId: "family", ExpiresAt: expiresAt},
+		},
+		{
+			name:    "Not found",
+			err:     pgx.ErrNoRows,
+			wantErr: service.ErrInvalidToken,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mockPool := newTokenTestRepo(t)
+
+			query := mockPool.ExpectQuery("SELECT id, user_id, family_id, expires_at, revoked_at FROM refresh_tokens WHERE id = (.+)").
+				WithArgs("id")
+			if tc.err != nil {
+				query.WillReturnError(tc.err)
+			} else {
+				query.WillReturnRows(tc.rows(mockPool))
+			}
+
+			got, err := repo.GetRefreshToken(context.Background(), "id")
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, got)
+			}
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTokenRepo_RotateRefreshToken(t *testing.T) {
+	next := entity.RefreshToken{Id: "next", UserId: 1, FamilyId: "family", ExpiresAt: time.Date(2022, 11, 20, 0, 0, 0, 0, time.UTC)}
+
+	testCases := []struct {
+		name     string
+		revoked  int64
+		wantErr  error
+		wantNext bool
+	}{
+		{
+			name:     "OK",
+			revoked:  1,
+			wantNext: true,
+		},
+		{
+			name:    "Already revoked",
+			revoked: 0,
+			wantErr: service.ErrRefreshTokenReused,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mockPool := newTokenTestRepo(t)
+
+			mockPool.ExpectBegin()
+			mockPool.ExpectExec("UPDATE refresh_tokens SET revoked_at = now\\(\\) WHERE id = (.+) AND revoked_at IS NULL").
+				WithArgs("id").
+				WillReturnResult(pgxmock.NewResult("UPDATE", tc.revoked))
+			if tc.wantNext {
+				mockPool.ExpectExec("INSERT INTO refresh_tokens").
+					WithArgs(next.Id, next.UserId, next.FamilyId, next.ExpiresAt).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mockPool.ExpectCommit()
+			} else {
+				mockPool.ExpectRollback()
+			}
+
+			err := repo.RotateRefreshToken(context.Background(), "id", next)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTokenRepo_RevokeRefreshToken(t *testing.T) {
+	repo, mockPool := newTokenTestRepo(t)
+
+	mockPool.ExpectExec("UPDATE refresh_tokens SET revoked_at = COALESCE\\(revoked_at, now\\(\\)\\) WHERE id = (.+)").
+		WithArgs("id").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	assert.NoError(t, repo.RevokeRefreshToken(context.Background(), "id"))
+
+	mockPool.ExpectExec("UPDATE refresh_tokens").
+		WithArgs("unknown").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	assert.ErrorIs(t, repo.RevokeRefreshToken(context.Background(), "unknown"), service.ErrInvalidToken)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestTokenRepo_RevokeTokenFamily(t *testing.T) {
+	repo, mockPool := newTokenTestRepo(t)
+
+	mockPool.ExpectExec("UPDATE refresh_tokens SET revoked_at = now\\(\\) WHERE family_id = (.+) AND revoked_at IS NULL").
+		WithArgs("family").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 3))
+
+	assert.NoError(t, repo.RevokeTokenFamily(context.Background(), "family"))
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestTokenRepo_RevokeUserTokens(t *testing.T) {
+	repo, mockPool := newTokenTestRepo(t)
+
+	mockPool.ExpectExec("UPDATE refresh_tokens SET revoked_at = now\\(\\) WHERE user_id = (.+) AND revoked_at IS NULL").
+		WithArgs(1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+	assert.NoError(t, repo.RevokeUserTokens(context.Background(), 1))
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
@@ -0,0 +1,265 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	"user-balance-service/pkg/postgres"
+)
+
+type TwoFactorRepo struct {
+	*postgres.Postgres
+}
+
+func NewTwoFactorRepo(pg *postgres.Postgres) *TwoFactorRepo {
+	return &TwoFactorRepo{pg}
+}
+
+// GetTwoFactor returns the TOTP state of the user and how many recovery codes
+// they have left
+func (r *TwoFactorRepo) GetTwoFactor(ctx context.Context, userId int) (entity.TwoFactor, error) {
+	sql, args, err := r.Builder.
+		Select("u.username", "COALESCE(u.totp_secret, '')", "u.totp_enabled_at", "u.totp_last_step",
+			"(SELECT count(*) FROM recovery_codes c WHERE c.user_id = u.id AND c.used_at IS NULL)").
+		From("users u").
+		Where("u.id = ?", userId).
+		ToSql()
+
+	if err != nil {
+		return entity.TwoFactor{}, fmt.Errorf("repo - TwoFactorRepo - GetTwoFactor - r.Builder: %w", err)
+	}
+
+	var tf entity.TwoFactor
+	err = r.Pool.QueryRow(ctx, sql, args...).Scan(&tf.Username, &tf.Secret, &tf.EnabledAt, &tf.LastStep, &tf.RecoveryCodesLeft)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.TwoFactor{}, fmt.Errorf("repo - TwoFactorRepo - GetTwoFactor - r.Pool.QueryRow: %w", service.ErrUserNotFound)
+	}
+	if err != nil {
+		return entity.TwoFactor{}, fmt.Errorf("repo - TwoFactorRepo - GetTwoFactor - r.Pool.QueryRow: %w", err)
+	}
+
+	return tf, nil
+}
+
+// SetTotpSecret stores a secret waiting for confirmation. Once 2FA is
+// enabled the secret can't be replaced, that's service.ErrTwoFactorEnabled.
+func (r *TwoFactorRepo) SetTotpSecret(ctx context.Context, userId int, secret string) error {
+	sql, args, err := r.Builder.
+		Update("users").
+		Set("totp_secret", secret).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("id = ?", userId).
+		Where("totp_enabled_at IS NULL").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - TwoFactorRepo - SetTotpSecret - r.Builder: %w", err)
+	}
+
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("repo - TwoFactorRepo - SetTotpSecret - r.Pool.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("repo - TwoFactorRepo - SetTotpSecret: %w", service.ErrTwoFactorEnabled)
+	}
+
+	return nil
+}
+
+// EnableTwoFactor enables the stored secret, marks step used and replaces
+// the recovery codes
+func (r *TwoFactorRepo) EnableTwoFactor(ctx context.Context, userId int, step int64, codeHashes []string) error {
+	sql, args, err := r.Builder.
+		Update("users").
+		Set("totp_enabled_at", squirrel.Expr("now()")).
+		Set("totp_last_step", step).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("id = ?", userId).
+		Where("totp_secret IS NOT NULL").
+		Where("totp_enabled_at IS NULL").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - TwoFactorRepo - EnableTwoFactor - r.Builder: %w", err)
+	}
+
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("repo - TwoFactorRepo - EnableTwoFactor - r.Pool.Begin: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - TwoFactorRepo - EnableTwoFactor - tx.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		// enabled by a concurrent confirmation
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - TwoFactorRepo - EnableTwoFactor: %w", service.ErrTwoFactorEnabled)
+	}
+
+	err = r.replaceRecoveryCodes(ctx, tx, userId, codeHashes)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - TwoFactorRepo - EnableTwoFactor - r.replaceRecoveryCodes: %w", err)
+	}
+
+	err = commit(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("repo - TwoFactorRepo - EnableTwoFactor - commit: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveTwoFactor turns 2FA off and drops the secret and recovery codes
+func (r *TwoFactorRepo) RemoveTwoFactor(ctx context.Context, userId int) error {
+	sql, args, err := r.Builder.
+		Update("users").
+		Set("totp_secret", nil).
+		Set("totp_enabled_at", nil).
+		Set("totp_last_step", 0).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where("id = ?", userId).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("repo - TwoFactorRepo - RemoveTwoFactor - r.Builder: %w", err)
+	}
+
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("repo - TwoFactorRepo - RemoveTwoFactor - r.Pool.Begin: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - TwoFactorRepo - RemoveTwoFactor - tx.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - TwoFactorRepo - RemoveTwoFactor: %w", service.ErrUserNotFound)
+	}
+
+	err = r.replaceRecoveryCodes(ctx, tx, userId, nil)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - TwoFactorRepo - RemoveTwoFactor - r.replaceRecoveryCodes: %w", err)
+	}
+
+	err = commit(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("repo - TwoFactorRepo - RemoveTwoFactor - commit: %w", err)
+	}
+
+	return nil
+}
+
+// UseTotpStep records step as the last one used unless a later or the same
+// one already is, so that concurrent requests can't use one code twice
+func (r *TwoFactorRepo) UseTotpStep(ctx context.Context, userId int, step int64) (bool, error) {
+	sql, args, err := r.Builder.
+		Update("users").
+		Set("totp_last_step", step).
+		Where("id = ?", userId).
+		Where("totp_last_step < ?", step).
+		ToSql()
+
+	if err != nil {
+		return false, fmt.Errorf("repo - TwoFactorRepo - UseTotpStep - r.Builder: %w", err)
+	}
+
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return false, fmt.Errorf("repo - TwoFactorRepo - UseTotpStep - r.Pool.Exec: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *TwoFactorRepo) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error) {
+	sql, args, err := r.Builder.
+		Update("recovery_codes").
+		Set("used_at", squirrel.Expr("now()")).
+		Where("user_id = ?", userId).
+		Where("code_hash = ?", codeHash).
+		Where("used_at IS NULL").
+		ToSql()
+
+	if err != nil {
+		return false, fmt.Errorf("repo - TwoFactorRepo - UseRecoveryCode - r.Builder: %w", err)
+	}
+
+	tag, err := r.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return false, fmt.Errorf("repo - TwoFactorRepo - UseRecoveryCode - r.Pool.Exec: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *TwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userId int, codeHashes []string) error {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("repo - TwoFactorRepo - ReplaceRecoveryCodes - r.Pool.Begin: %w", err)
+	}
+
+	err = r.replaceRecoveryCodes(ctx, tx, userId, codeHashes)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("repo - TwoFactorRepo - ReplaceRecoveryCodes - r.replaceRecoveryCodes: %w", err)
+	}
+
+	err = commit(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("repo - TwoFactorRepo - ReplaceRecoveryCodes - commit: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TwoFactorRepo) replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userId int, codeHashes []string) error {
+	sql, args, err := r.Builder.
+		Delete("recovery_codes").
+		Where("user_id = ?", userId).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("r.Builder: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("tx.Exec: %w", err)
+	}
+
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	insert := r.Builder.
+		Insert("recovery_codes").
+		Columns("user_id", "code_hash")
+	for _, hash := range codeHashes {
+		insert = insert.Values(userId, hash)
+	}
+
+	sql, args, err = insert.ToSql()
+	if err != nil {
+		return fmt.Errorf("r.Builder: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("tx.Exec: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package repo
+
+import (
+	"context"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/internal/service"
+	"user-balance-service/pkg/postgres"
+)
+
+func newTwoFactorTestRepo(t *testing.T) (*TwoFactorRepo, pgxmock.PgxPoolIface) {
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(mockPool.Close)
+
+	return NewTwoFactorRepo(&postgres.Postgres{
+		Builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		Pool:    mockPool,
+	}), mockPool
+}
+
+func TestTwoFactorRepo_GetTwoFactor(t *testing.T) {
+	repo, mockPool := newTwoFactorTestRepo(t)
+	enabledAt := time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+
+	rows := pgxmock.NewRows([]string{"username", "totp_secret", "totp_enabled_at", "totp_last_step", "count"}).
+		AddRow("qwe", "JBSWY3DPEHPK3PXP", &enabledAt, int64(55533328), 9)
+	mockPool.ExpectQuery("SELECT u.username, (.+) FROM users u WHERE u.id = \\$1").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	tf, err := repo.GetTwoFactor(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, entity.TwoFactor{
+		Username:          "qwe",
+		Secret:            "JBSWY3DPEHPK3PXP",
+		EnabledAt:         &enabledAt,
+		LastStep:          55533328,
+		RecoveryCodesLeft: 9,
+	}, tf)
+
+	mockPool.ExpectQuery("SELECT u.username").
+		WithArgs(2).
+		WillReturnError(pgx.ErrNoRows)
+	_, err = repo.GetTwoFactor(context.Background(), 2)
+	assert.ErrorIs(t, err, service.ErrUserNotFound)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestTwoFactorRepo_SetTotpSecret(t *testing.T) {
+	repo, mockPool := newTwoFactorTestRepo(t)
+
+	mockPool.ExpectExec("UPDATE users SET totp_secret = \\$1, updated_at = now\\(\\) WHERE id = \\$2 AND totp_enabled_at IS NULL").
+		WithArgs("JBSWY3DPEHPK3PXP", 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	assert.NoError(t, repo.SetTotpSecret(context.Background(), 1, "JBSWY3DPEHPK3PXP"))
+
+	// already enabled
+	mockPool.ExpectExec("UPDATE users SET totp_secret").
+		WithArgs("JBSWY3DPEHPK3PXP", 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	assert.ErrorIs(t, repo.SetTotpSecret(context.Background(), 1, "JBSWY3DPEHPK3PXP"), service.ErrTwoFactorEnabled)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestTwoFactorRepo_EnableTwoFactor(t *testing.T) {
+	testCases := []struct {
+		name         string
+		rowsAffected int64
+		wantErr      error
+	}{
+		{
+			name:         "success",
+			rowsAffected: 1,
+		},
+		{
+			name:         "enabled concurrently",
+			rowsAffected: 0,
+			wantErr:      service.ErrTwoFactorEnabled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mockPool := newTwoFactorTestRepo(t)
+
+			mockPool.ExpectBegin()
+			mockPool.ExpectExec("UPDATE users SET totp_enabled_at = now\\(\\), totp_last_step = \\$1, (.+) WHERE id = \\$2 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL").
+				WithArgs(int64(100), 1).
+				WillReturnResult(pgxmock.NewResult("UPDATE", tc.rowsAffected))
+			if tc.wantErr != nil {
+				mockPool.ExpectRollback()
+			} else {
+				mockPool.ExpectExec("DELETE FROM recovery_codes WHERE user_id = \\$1").
+					WithArgs(1).
+					WillReturnResult(pgxmock.NewResult("DELETE", 0))
+				mockPool.ExpectExec("INSERT INTO recovery_codes \\(user_id,code_hash\\) VALUES \\(\\$1,\\$2\\),\\(\\$3,\\$4\\)").
+					WithArgs(1, "hash1", 1, "hash2").
+					WillReturnResult(pgxmock.NewResult("INSERT", 2))
+				mockPool.ExpectCommit()
+			}
+
+			err := repo.EnableTwoFactor(context.Background(), 1, 100, []string{"hash1", "hash2"})
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTwoFactorRepo_RemoveTwoFactor(t *testing.T) {
+	repo, mockPool := newTwoFactorTestRepo(t)
+
+	mockPool.ExpectBegin()
+	mockPool.ExpectExec("UPDATE users SET totp_secret = \\$1, totp_enabled_at = \\$2, totp_last_step = \\$3, (.+) WHERE id = \\$4").
+		WithArgs(nil, nil, 0, 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec("DELETE FROM recovery_codes WHERE user_id = \\$1").
+		WithArgs(1).
+		WillReturnResult(pgxmock.NewResult("DELETE", 10))
+	mockPool.ExpectCommit()
+
+	assert.NoError(t, repo.RemoveTwoFactor(context.Background(), 1))
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestTwoFactorRepo_UseTotpStep(t *testing.T) {
+	repo, mockPool := newTwoFactorTestRepo(t)
+
+	mockPool.ExpectExec("UPDATE users SET totp_last_step = \\$1 WHERE id = \\$2 AND totp_last_step < \\$3").
+		WithArgs(int64(100), 1, int64(100)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	ok, err := repo.UseTotpStep(context.Background(), 1, 100)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// the step has been used already
+	mockPool.ExpectExec("UPDATE users SET totp_last_step").
+		WithArgs(int64(100), 1, int64(100)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	ok, err = repo.UseTotpStep(context.Background(), 1, 100)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestTwoFactorRepo_UseRecoveryCode(t *testing.T) {
+	repo, mockPool := newTwoFactorTestRepo(t)
+
+	mockPool.ExpectExec("UPDATE recovery_codes SET used_at = now\\(\\) WHERE user_id = \\$1 AND code_hash = \\$2 AND used_at IS NULL").
+		WithArgs(1, "hash").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	ok, err := repo.UseRecoveryCode(context.Background(), 1, "hash")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	mockPool.ExpectExec("UPDATE recovery_codes").
+		WithArgs(1, "hash").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	ok, err = repo.UseRecoveryCode(context.Background(), 1, "hash")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
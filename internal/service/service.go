@@ -1,20 +1,66 @@
 package service
 
-import (
-	"user-balance-service/internal/service/repo"
-	"user-balance-service/internal/service/webapi"
-)
+import "user-balance-service/pkg/tracing"
+
+var tracer = tracing.Tracer("user-balance-service/internal/service")
+
+type Repository interface {
+	AuthRepo
+	TokenRepo
+	ApiKeyRepo
+	TwoFactorRepo
+	LoginAuditRepo
+	AuditRepo
+	AccountRepo
+	HistoryRepo
+	RateRepo
+}
 
 type Service struct {
 	Auth
+	Users
+	TwoFactor
+	ApiKeys
 	Account
 	History
+	Audit
 }
 
-func New(repo *repo.Repository, wapi *webapi.ConverterAPI) *Service {
+// Deps are what the services are built from. Rates serves the latest
+// exchange rates, RateProvider is asked for the daily rates missing in the
+// repository, Hasher hashes passwords, TokenKeys sign and verify access
+// tokens and Denylist holds revoked ones. LoginAttempts counts failed
+// sign-ins and Challenges holds those waiting for the second factor.
+type Deps struct {
+	Repo              Repository
+	Rates             Rates
+	RateProvider      RateProvider
+	Hasher            PasswordHasher
+	TokenKeys         TokenKeys
+	Denylist          Denylist
+	LoginAttempts     LoginAttempts
+	Challenges        SignInChallenges
+	AuthOptions       []AuthOption
+	LoginGuardOptions []LoginGuardOption
+	TwoFactorOptions  []TwoFactorOption
+	AccountOptions    []AccountOption
+}
+
+func New(deps Deps) *Service {
+	guard := NewLoginGuard(deps.LoginAttempts, deps.LoginGuardOptions...)
+	twoFactorOptions := append([]TwoFactorOption{GuardTwoFactor(guard)}, deps.TwoFactorOptions...)
+	twoFactor := NewTwoFactorService(deps.Repo, deps.Challenges, twoFactorOptions...)
+	authOptions := append([]AuthOption{GuardLogins(guard), AuditLogins(deps.Repo), TwoFactorSignIn(twoFactor)}, deps.AuthOptions...)
+
+	auth := NewAuthService(deps.Repo, deps.Repo, deps.Denylist, deps.Hasher, deps.TokenKeys, authOptions...)
+
 	return &Service{
-		Auth:    NewAuthService(repo),
-		Account: NewAccountService(repo, wapi),
-		History: NewHistoryService(repo),
+		Auth:      auth,
+		Users:     NewUserService(deps.Repo, guard, auth),
+		TwoFactor: twoFactor,
+		ApiKeys:   NewApiKeyService(deps.Repo),
+		Account:   NewAccountService(deps.Repo, deps.Rates, deps.AccountOptions...),
+		History:   NewHistoryService(deps.Repo, NewDailyRates(deps.Repo, deps.RateProvider)),
+		Audit:     NewAuditService(deps.Repo),
 	}
 }
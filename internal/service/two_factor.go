@@ -0,0 +1,415 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+	"user-balance-service/internal/entity"
+	"user-balance-service/pkg/totp"
+)
+
+const (
+	defaultTwoFactorIssuer = "user-balance-service"
+	defaultChallengeTTL    = 5 * time.Minute
+
+	// maxChallengeAttempts is how many codes can be tried against one
+	// sign-in challenge
+	maxChallengeAttempts = 5
+
+	recoveryCodeCount = 10
+	// recoveryCodeLength is the number of hex digits, 80 bits, of a recovery
+	// code, shown in groups of recoveryCodeGroup: 1a2b3-c4d5e-6f7a8-b9c0d
+	recoveryCodeLength = 20
+	recoveryCodeGroup  = 5
+)
+
+type TwoFactorOption func(s *TwoFactorService)
+
+// TwoFactorIssuer names the service in authenticator apps,
+// "user-balance-service" by default
+func TwoFactorIssuer(issuer string) TwoFactorOption {
+	return func(s *TwoFactorService) {
+		s.issuer = issuer
+	}
+}
+
+// ChallengeTTL sets how long the second factor can be given after the
+// password, 5 minutes by default
+func ChallengeTTL(ttl time.Duration) TwoFactorOption {
+	return func(s *TwoFactorService) {
+		s.challengeTTL = ttl
+	}
+}
+
+// TransferThreshold makes transfers of more than amount take a two-factor
+// code; 0, the default, doesn't
+func TransferThreshold(amount int) TwoFactorOption {
+	return func(s *TwoFactorService) {
+		s.transferThreshold = amount
+	}
+}
+
+// GuardTwoFactor counts wrong codes given to DisableTwoFactor,
+// RegenerateRecoveryCodes, AuthorizeTransfer and ConfirmTwoFactor as failed
+// sign-ins of the user, so they lock the user out like wrong passwords do
+func GuardTwoFactor(guard *LoginGuard) TwoFactorOption {
+	return func(s *TwoFactorService) {
+		s.guard = guard
+	}
+}
+
+// RecoveryCodeKey keys the HMAC recovery codes are stored by, so a leaked
+// table can't be checked against guessed codes without the key too
+func RecoveryCodeKey(key []byte) TwoFactorOption {
+	return func(s *TwoFactorService) {
+		s.recoveryKey = key
+	}
+}
+
+// TwoFactorService manages TOTP enrolment and checks second factors: at
+// sign-in, see AuthService.CompleteSignIn, and for large transfers.
+type TwoFactorService struct {
+	repo       TwoFactorRepo
+	challenges SignInChallenges
+	guard      *LoginGuard
+
+	recoveryKey       []byte
+	issuer            string
+	challengeTTL      time.Duration
+	transferThreshold int
+	now               func() time.Time
+}
+
+func NewTwoFactorService(repo TwoFactorRepo, challenges SignInChallenges, opts ...TwoFactorOption) *TwoFactorService {
+	s := &TwoFactorService{
+		repo:         repo,
+		challenges:   challenges,
+		issuer:       defaultTwoFactorIssuer,
+		challengeTTL: defaultChallengeTTL,
+		now:          time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// TwoFactorStatus returns the state of the user's second factor without the
+// secret
+func (s *TwoFactorService) TwoFactorStatus(ctx context.Context, userId int) (entity.TwoFactor, error) {
+	tf, err := s.repo.GetTwoFactor(ctx, userId)
+	if err != nil {
+		return entity.TwoFactor{}, fmt.Errorf("service - TwoFactorService - TwoFactorStatus - s.repo.GetTwoFactor: %w", err)
+	}
+	tf.Secret = ""
+
+	return tf, nil
+}
+
+// EnrollTwoFactor generates a new secret for the user. It takes effect once
+// confirmed; enrolling again before that replaces the secret.
+func (s *TwoFactorService) EnrollTwoFactor(ctx context.Context, userId int) (entity.TwoFactorEnrolment, error) {
+	tf, err := s.repo.GetTwoFactor(ctx, userId)
+	if err != nil {
+		return entity.TwoFactorEnrolment{}, fmt.Errorf("service - TwoFactorService - EnrollTwoFactor - s.repo.GetTwoFactor: %w", err)
+	}
+	if tf.Enabled() {
+		return entity.TwoFactorEnrolment{}, ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return entity.TwoFactorEnrolment{}, fmt.Errorf("service - TwoFactorService - EnrollTwoFactor - totp.NewSecret: %w", err)
+	}
+
+	err = s.repo.SetTotpSecret(ctx, userId, secret)
+	if err != nil {
+		return entity.TwoFactorEnrolment{}, fmt.Errorf("service - TwoFactorService - EnrollTwoFactor - s.repo.SetTotpSecret: %w", err)
+	}
+
+	return entity.TwoFactorEnrolment{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, tf.Username, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables the enrolled secret once code proves the user's
+// app has it, and returns the recovery codes. They are shown only here and
+// on regeneration.
+func (s *TwoFactorService) ConfirmTwoFactor(ctx context.Context, userId int, code string) ([]string, error) {
+	tf, err := s.repo.GetTwoFactor(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("service - TwoFactorService - ConfirmTwoFactor - s.repo.GetTwoFactor: %w", err)
+	}
+	if tf.Enabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+	if tf.Secret == "" {
+		return nil, ErrTwoFactorDisabled
+	}
+
+	reservation, err := s.reserve(ctx, tf.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok, err := totp.Validate(tf.Secret, code, s.now())
+	if err != nil {
+		reservation.Release(ctx)
+		return nil, fmt.Errorf("service - TwoFactorService - ConfirmTwoFactor - totp.Validate: %w", err)
+	}
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	reservation.Release(ctx)
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("service - TwoFactorService - ConfirmTwoFactor - s.newRecoveryCodes: %w", err)
+	}
+
+	err = s.repo.EnableTwoFactor(ctx, userId, step, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("service - TwoFactorService - ConfirmTwoFactor - s.repo.EnableTwoFactor: %w", err)
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor turns the second factor off, given a code
+func (s *TwoFactorService) DisableTwoFactor(ctx context.Context, userId int, code string) error {
+	err := s.verify(ctx, userId, code)
+	if err != nil {
+		return err
+	}
+
+	return s.ResetTwoFactor(ctx, userId)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the user, given a
+// code
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userId int, code string) ([]string, error) {
+	err := s.verify(ctx, userId, code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("service - TwoFactorService - RegenerateRecoveryCodes - s.newRecoveryCodes: %w", err)
+	}
+
+	err = s.repo.ReplaceRecoveryCodes(ctx, userId, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("service - TwoFactorService - RegenerateRecoveryCodes - s.repo.ReplaceRecoveryCodes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// ResetTwoFactor turns the second factor off without a code, for users who
+// have lost both their app and their recovery codes
+func (s *TwoFactorService) ResetTwoFactor(ctx context.Context, userId int) error {
+	err := s.repo.RemoveTwoFactor(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("service - TwoFactorService - ResetTwoFactor - s.repo.RemoveTwoFactor: %w", err)
+	}
+
+	return nil
+}
+
+// AuthorizeTransfer lets transfers above the threshold through only with a
+// valid code of the actor. API keys can't give a second factor, so such
+// transfers are refused to them.
+func (s *TwoFactorService) AuthorizeTransfer(ctx context.Context, actor entity.Actor, amount int, code string) error {
+	if s.transferThreshold <= 0 || amount <= s.transferThreshold {
+		return nil
+	}
+	if actor.ApiKeyId != 0 || code == "" {
+		return ErrTwoFactorRequired
+	}
+
+	err := s.verify(ctx, actor.UserId, code)
+	if errors.Is(err, ErrTwoFactorDisabled) {
+		return fmt.Errorf("%w: enable two-factor authentication to make transfers over %d", ErrTwoFactorRequired, s.transferThreshold)
+	}
+
+	return err
+}
+
+// newChallenge starts the second step of the sign-in of user
+func (s *TwoFactorService) newChallenge(ctx context.Context, user entity.User) (*TwoFactorChallengeError, error) {
+	challenge, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("service - TwoFactorService - newChallenge - randomToken: %w", err)
+	}
+
+	err = s.challenges.CreateChallenge(ctx, refreshTokenId(challenge), entity.SignInChallenge{
+		UserId:   user.Id,
+		Username: user.Username,
+	}, s.challengeTTL)
+	if err != nil {
+		return nil, fmt.Errorf("service - TwoFactorService - newChallenge - s.challenges.CreateChallenge: %w", err)
+	}
+
+	return &TwoFactorChallengeError{Challenge: challenge, ExpiresIn: s.challengeTTL}, nil
+}
+
+// attemptChallenge counts a code tried against challenge. After
+// maxChallengeAttempts the challenge is dropped and the password has to be
+// given again.
+func (s *TwoFactorService) attemptChallenge(ctx context.Context, challenge string) (entity.SignInChallenge, error) {
+	id := refreshTokenId(challenge)
+
+	ch, err := s.challenges.AttemptChallenge(ctx, id)
+	if err != nil {
+		return entity.SignInChallenge{}, fmt.Errorf("service - TwoFactorService - attemptChallenge - s.challenges.AttemptChallenge: %w", err)
+	}
+	if ch.Attempts > maxChallengeAttempts {
+		s.endChallenge(ctx, challenge)
+		return entity.SignInChallenge{}, ErrInvalidChallenge
+	}
+
+	return ch, nil
+}
+
+// endChallenge drops challenge; an undeleted one expires soon anyway
+func (s *TwoFactorService) endChallenge(ctx context.Context, challenge string) {
+	err := s.challenges.DeleteChallenge(ctx, refreshTokenId(challenge))
+	if err != nil {
+		log.Errorf("service - TwoFactorService - endChallenge - s.challenges.DeleteChallenge: %s", err)
+	}
+}
+
+// verify checks code like checkCode. A wrong code counts as a failed
+// sign-in of the user, so codes can't be guessed through the operations
+// that take one.
+func (s *TwoFactorService) verify(ctx context.Context, userId int, code string) error {
+	tf, err := s.enabled(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	reservation, err := s.reserve(ctx, tf.Username)
+	if err != nil {
+		return err
+	}
+
+	err = s.useCode(ctx, userId, tf, code)
+	if !errors.Is(err, ErrInvalidTwoFactorCode) {
+		reservation.Release(ctx)
+	}
+
+	return err
+}
+
+// checkCode checks code, a TOTP code or a recovery code, of the user
+// without counting it; AuthService.CompleteSignIn counts it by username and
+// IP itself
+func (s *TwoFactorService) checkCode(ctx context.Context, userId int, code string) error {
+	tf, err := s.enabled(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	return s.useCode(ctx, userId, tf, code)
+}
+
+// enabled returns the second factor of the user, ErrTwoFactorDisabled if
+// it's off
+func (s *TwoFactorService) enabled(ctx context.Context, userId int) (entity.TwoFactor, error) {
+	tf, err := s.repo.GetTwoFactor(ctx, userId)
+	if err != nil {
+		return entity.TwoFactor{}, fmt.Errorf("service - TwoFactorService - enabled - s.repo.GetTwoFactor: %w", err)
+	}
+	if !tf.Enabled() {
+		return entity.TwoFactor{}, ErrTwoFactorDisabled
+	}
+
+	return tf, nil
+}
+
+// useCode checks code against tf of the user. Both kinds of codes can be
+// used only once.
+func (s *TwoFactorService) useCode(ctx context.Context, userId int, tf entity.TwoFactor, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		step, ok, err := totp.Validate(tf.Secret, code, s.now())
+		if err != nil {
+			return fmt.Errorf("service - TwoFactorService - useCode - totp.Validate: %w", err)
+		}
+		if !ok || step <= tf.LastStep {
+			return ErrInvalidTwoFactorCode
+		}
+
+		ok, err = s.repo.UseTotpStep(ctx, userId, step)
+		if err != nil {
+			return fmt.Errorf("service - TwoFactorService - useCode - s.repo.UseTotpStep: %w", err)
+		}
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+
+		return nil
+	}
+
+	ok, err := s.repo.UseRecoveryCode(ctx, userId, s.recoveryCodeHash(code))
+	if err != nil {
+		return fmt.Errorf("service - TwoFactorService - useCode - s.repo.UseRecoveryCode: %w", err)
+	}
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+// reserve counts a code of username against the guard before it's checked,
+// see LoginGuard.Reserve
+func (s *TwoFactorService) reserve(ctx context.Context, username string) (*LoginReservation, error) {
+	if s.guard == nil {
+		return nil, nil
+	}
+
+	return s.guard.Reserve(ctx, username, "")
+}
+
+// newRecoveryCodes returns recovery codes as shown to the user and the
+// hashes they are stored by
+func (s *TwoFactorService) newRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := randomHex(recoveryCodeLength / 2)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		groups := make([]string, 0, recoveryCodeLength/recoveryCodeGroup)
+		for j := 0; j < recoveryCodeLength; j += recoveryCodeGroup {
+			groups = append(groups, code[j:j+recoveryCodeGroup])
+		}
+
+		codes = append(codes, strings.Join(groups, "-"))
+		hashes = append(hashes, s.recoveryCodeHash(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// recoveryCodeHash ignores case and dashes, so a code can be typed either
+// way. Recovery codes are shorter than API keys, so they are keyed with a
+// server secret too: a leaked table alone can't be checked against guesses.
+func (s *TwoFactorService) recoveryCodeHash(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(code, "-", ""))
+
+	mac := hmac.New(sha256.New, s.recoveryKey)
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+	"user-balance-service/internal/entity"
+	mock_service "user-balance-service/internal/service/mock"
+	"user-balance-service/pkg/totp"
+)
+
+const testTotpSecret = "JBSWY3DPEHPK3PXP"
+
+var testTwoFactorNow = time.Date(2022, 10, 20, 12, 0, 0, 0, time.UTC)
+
+func newTestTwoFactor(repo TwoFactorRepo, challenges SignInChallenges, opts ...TwoFactorOption) *TwoFactorService {
+	opts = append([]TwoFactorOption{RecoveryCodeKey([]byte("recovery"))}, opts...)
+	s := NewTwoFactorService(repo, challenges, opts...)
+	s.now = func() time.Time { return testTwoFactorNow }
+	return s
+}
+
+func testTotpCode(t *testing.T, step int64) string {
+	code, err := totp.Code(testTotpSecret, step)
+	require.NoError(t, err)
+	return code
+}
+
+func TestTwoFactorService_EnrollTwoFactor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockTwoFactorRepo(ctrl)
+	s := newTestTwoFactor(repo, nil, TwoFactorIssuer("Balance"))
+
+	repo.EXPECT().GetTwoFactor(gomock.Any(), 1).Return(entity.TwoFactor{Username: "qwe"}, nil)
+	var stored string
+	repo.EXPECT().SetTotpSecret(gomock.Any(), 1, gomock.Any()).DoAndReturn(func(_ context.Context, _ int, secret string) error {
+		stored = secret
+		return nil
+	})
+
+	enrolment, err := s.EnrollTwoFactor(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, stored, enrolment.Secret)
+	assert.True(t, strings.HasPrefix(enrolment.URI, "otpauth://totp/Balance:qwe?"))
+
+	// a second secret can't replace an enabled one
+	repo.EXPECT().GetTwoFactor(gomock.Any(), 1).Return(entity.TwoFactor{Username: "qwe", EnabledAt: &testTwoFactorNow}, nil)
+	_, err = s.EnrollTwoFactor(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrTwoFactorEnabled)
+}
+
+func TestTwoFactorService_ConfirmTwoFactor(t *testing.T) {
+	step := totp.Step(testTwoFactorNow)
+
+	type MockBehaviour func(r *mock_service.MockTwoFactorRepo)
+
+	testCases := []struct {
+		name          string
+		code          string
+		mockBehaviour MockBehaviour
+		wantErr       error
+	}{
+		{
+			name: "success",
+			code: testTotpCode(t, step),
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {
+				r.EXPECT().GetTwoFactor(gomock.Any(), 1).Return(entity.TwoFactor{Username: "qwe", Secret: testTotpSecret}, nil)
+				r.EXPECT().EnableTwoFactor(gomock.Any(), 1, step, gomock.Len(recoveryCodeCount)).Return(nil)
+			},
+		},
+		{
+			name: "code of the previous period is accepted",
+			code: testTotpCode(t, step-1),
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {
+				r.EXPECT().GetTwoFactor(gomock.Any(), 1).Return(entity.TwoFactor{Username: "qwe", Secret: testTotpSecret}, nil)
+				r.EXPECT().EnableTwoFactor(gomock.Any(), 1, step-1, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name: "wrong code",
+			code: testTotpCode(t, step-5),
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {
+				r.EXPECT().GetTwoFactor(gomock.Any(), 1).Return(entity.TwoFactor{Username: "qwe", Secret: testTotpSecret}, nil)
+			},
+			wantErr: ErrInvalidTwoFactorCode,
+		},
+		{
+			name: "not enrolled",
+			code: "123456",
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {
+				r.EXPECT().GetTwoFactor(gomock.Any(), 1).Return(entity.TwoFactor{Username: "qwe"}, nil)
+			},
+			wantErr: ErrTwoFactorDisabled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock_service.NewMockTwoFactorRepo(ctrl)
+			tc.mockBehaviour(repo)
+
+			codes, err := newTestTwoFactor(repo, nil).ConfirmTwoFactor(context.Background(), 1, tc.code)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, codes, recoveryCodeCount)
+			assert.Regexp(t, "^[0-9a-f]{5}(-[0-9a-f]{5}){3}$", codes[0])
+		})
+	}
+}
+
+func TestTwoFactorService_verify(t *testing.T) {
+	step := totp.Step(testTwoFactorNow)
+	enabled := entity.TwoFactor{Username: "qwe", Secret: testTotpSecret, EnabledAt: &testTwoFactorNow, LastStep: step - 10}
+	recoveryHash := newTestTwoFactor(nil, nil).recoveryCodeHash("1a2b3-c4d5e-6f7a8-b9c0d")
+
+	type MockBehaviour func(r *mock_service.MockTwoFactorRepo)
+
+	testCases := []struct {
+		name          string
+		code          string
+		mockBehaviour MockBehaviour
+		wantErr       error
+	}{
+		{
+			name: "TOTP code",
+			code: testTotpCode(t, step),
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {
+				r.EXPECT().GetTwoFactor(gomock.Any(), 1).Return(enabled, nil)
+				r.EXPECT().UseTotpStep(gomock.Any(), 1, step).Return(true, nil)
+			},
+		},
+		{
+			name: "TOTP code used already",
+			code: testTotpCode(t, step),
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {
+				used := enabled
+				used.LastStep = step
+				r.EXPECT().GetTwoFactor(gomock.Any(), 1).Return(used, nil)
+			},
+			wantErr: ErrInvalidTwoFactorCode,
+		},
+		{
+			name: "TOTP code used concurrently",
+			code: testTotpCode(t, step),
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {
+				r.EXPECT().GetTwoFactor(gomock.Any(), 1).Return(enabled, nil)
+				r.EXPECT().UseTotpStep(gomock.Any(), 1, step).Return(false, nil)
+			},
+			wantErr: ErrInvalidTwoFactorCode,
+		},
+		{
+			name: "recovery code in any case and without the dash",
+			code: "1A2B3C4D5E6F7A8B9C0D",
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {
+				r.EXPECT().GetTwoFactor(gomock.Any(), 1).Return(enabled, nil)
+				r.EXPECT().UseRecoveryCode(gomock.Any(), 1, recoveryHash).Return(true, nil)
+			},
+		},
+		{
+			name: "unknown recovery code",
+			code: "1a2b3-c4d5e-6f7a8-b9c0d",
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {
+				r.EXPECT().GetTwoFactor(gomock.Any(), 1).Return(enabled, nil)
+				r.EXPECT().UseRecoveryCode(gomock.Any(), 1, gomock.Any()).Return(false, nil)
+			},
+			wantErr: ErrInvalidTwoFactorCode,
+		},
+		{
+			name: "2FA disabled",
+			code: "123456",
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {
+				r.EXPECT().GetTwoFactor(gomock.Any(), 1).Return(entity.TwoFactor{Username: "qwe"}, nil)
+			},
+			wantErr: ErrTwoFactorDisabled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock_service.NewMockTwoFactorRepo(ctrl)
+			tc.mockBehaviour(repo)
+
+			err := newTestTwoFactor(repo, nil).verify(context.Background(), 1, tc.code)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTwoFactorService_Guarded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockTwoFactorRepo(ctrl)
+	attempts := &memoryLoginAttempts{failures: make(map[string]int64), locks: make(map[string]time.Duration)}
+	s := newTestTwoFactor(repo, nil, GuardTwoFactor(NewLoginGuard(attempts, MaxFailures(2, 20))))
+	ctx := context.Background()
+	enabled := entity.TwoFactor{Username: "qwe", Secret: testTotpSecret, EnabledAt: &testTwoFactorNow}
+	repo.EXPECT().GetTwoFactor(gomock.Any(), 1).Return(enabled, nil).AnyTimes()
+
+	// a right code doesn't count
+	repo.EXPECT().UseRecoveryCode(gomock.Any(), 1, gomock.Any()).Return(true, nil)
+	repo.EXPECT().ReplaceRecoveryCodes(gomock.Any(), 1, gomock.Len(recoveryCodeCount)).Return(nil)
+	_, err := s.RegenerateRecoveryCodes(ctx, 1, "1a2b3-c4d5e-6f7a8-b9c0d")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), attempts.failures["user_qwe"])
+
+	// wrong ones do, and lock the user out of every operation taking a code
+	repo.EXPECT().UseRecoveryCode(gomock.Any(), 1, gomock.Any()).Return(false, nil).Times(2)
+	for i := 0; i < 2; i++ {
+		err = s.DisableTwoFactor(ctx, 1, "1a2b3-c4d5e-6f7a8-b9c0d")
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	}
+
+	err = s.DisableTwoFactor(ctx, 1, testTotpCode(t, totp.Step(testTwoFactorNow)))
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+
+	err = newTestTwoFactor(repo, nil, TransferThreshold(1), GuardTwoFactor(NewLoginGuard(attempts, MaxFailures(2, 20)))).
+		AuthorizeTransfer(ctx, entity.Actor{UserId: 1, Role: entity.RoleUser}, 2, testTotpCode(t, totp.Step(testTwoFactorNow)))
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+}
+
+func TestTwoFactorService_recoveryCodeHash(t *testing.T) {
+	s := newTestTwoFactor(nil, nil)
+
+	codes, hashes, err := s.newRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, recoveryCodeCount)
+	assert.Equal(t, s.recoveryCodeHash(strings.ToUpper(codes[0])), hashes[0])
+
+	// the hash depends on the key
+	other := newTestTwoFactor(nil, nil, RecoveryCodeKey([]byte("other")))
+	assert.NotEqual(t, other.recoveryCodeHash(codes[0]), hashes[0])
+}
+
+func TestTwoFactorService_AuthorizeTransfer(t *testing.T) {
+	step := totp.Step(testTwoFactorNow)
+	user := entity.Actor{UserId: 1, Role: entity.RoleUser}
+
+	type MockBehaviour func(r *mock_service.MockTwoFactorRepo)
+
+	testCases := []struct {
+		name          string
+		actor         entity.Actor
+		amount        int
+		code          string
+		mockBehaviour MockBehaviour
+		wantErr       error
+	}{
+		{
+			name:          "up to the threshold",
+			actor:         user,
+			amount:        1000,
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {},
+		},
+		{
+			name:          "above the threshold without a code",
+			actor:         user,
+			amount:        1001,
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {},
+			wantErr:       ErrTwoFactorRequired,
+		},
+		{
+			name:          "API keys can't give a code",
+			actor:         entity.Actor{UserId: 1, Role: entity.RoleUser, ApiKeyId: 3},
+			amount:        1001,
+			code:          "123456",
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {},
+			wantErr:       ErrTwoFactorRequired,
+		},
+		{
+			name:   "above the threshold with a code",
+			actor:  user,
+			amount: 1001,
+			code:   testTotpCode(t, step),
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {
+				r.EXPECT().GetTwoFactor(gomock.Any(), 1).
+					Return(entity.TwoFactor{Username: "qwe", Secret: testTotpSecret, EnabledAt: &testTwoFactorNow}, nil)
+				r.EXPECT().UseTotpStep(gomock.Any(), 1, step).Return(true, nil)
+			},
+		},
+		{
+			name:   "2FA isn't enabled",
+			actor:  user,
+			amount: 1001,
+			code:   "123456",
+			mockBehaviour: func(r *mock_service.MockTwoFactorRepo) {
+				r.EXPECT().GetTwoFactor(gomock.Any(), 1).Return(entity.TwoFactor{Username: "qwe"}, nil)
+			},
+			wantErr: ErrTwoFactorRequired,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock_service.NewMockTwoFactorRepo(ctrl)
+			tc.mockBehaviour(repo)
+
+			err := newTestTwoFactor(repo, nil, TransferThreshold(1000)).AuthorizeTransfer(context.Background(), tc.actor, tc.amount, tc.code)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	// no threshold, no second factor
+	err := newTestTwoFactor(nil, nil).AuthorizeTransfer(context.Background(), user, 1_000_000, "")
+	assert.NoError(t, err)
+}
+
+func TestAuthService_TwoFactorSignIn(t *testing.T) {
+	hasher := newTestHasher(t)
+	hash, err := hasher.Hash("qwerty123")
+	require.NoError(t, err)
+	client := entity.Client{IP: "192.0.2.1", UserAgent: "curl/7.85"}
+	step := totp.Step(testTwoFactorNow)
+	user := entity.User{Id: 1, Username: "qwe", Password: hash, Role: entity.RoleUser, TwoFactorAt: &testTwoFactorNow}
+
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockAuthRepo(ctrl)
+	tokenRepo := mock_service.NewMockTokenRepo(ctrl)
+	tfRepo := mock_service.NewMockTwoFactorRepo(ctrl)
+	challenges := mock_service.NewMockSignInChallenges(ctrl)
+	audit := mock_service.NewMockLoginAuditRepo(ctrl)
+
+	s := NewAuthService(repo, tokenRepo, nil, hasher, newTestKeys(t),
+		TwoFactorSignIn(newTestTwoFactor(tfRepo, challenges)), AuditLogins(audit))
+
+	// the password alone gives a challenge
+	repo.EXPECT().GetUser(gomock.Any(), "qwe").Return(user, nil)
+	challenges.EXPECT().CreateChallenge(gomock.Any(), gomock.Any(), entity.SignInChallenge{UserId: 1, Username: "qwe"}, defaultChallengeTTL).Return(nil)
+	audit.EXPECT().SaveLoginAttempt(gomock.Any(), entity.LoginAttempt{
+		Username: "qwe", UserId: 1, IP: client.IP, UserAgent: client.UserAgent, Result: entity.LoginChallenged,
+	}).Return(nil)
+
+	_, err = s.GenerateToken(context.Background(), "qwe", "qwerty123", client)
+	var challenge *TwoFactorChallengeError
+	require.True(t, errors.As(err, &challenge))
+	assert.ErrorIs(t, err, ErrTwoFactorRequired)
+	assert.Equal(t, defaultChallengeTTL, challenge.ExpiresIn)
+	id := refreshTokenId(challenge.Challenge)
+
+	// a wrong code is a failed sign-in
+	challenges.EXPECT().AttemptChallenge(gomock.Any(), id).Return(entity.SignInChallenge{UserId: 1, Username: "qwe", Attempts: 1}, nil)
+	tfRepo.EXPECT().GetTwoFactor(gomock.Any(), 1).
+		Return(entity.TwoFactor{Username: "qwe", Secret: testTotpSecret, EnabledAt: &testTwoFactorNow}, nil)
+	audit.EXPECT().SaveLoginAttempt(gomock.Any(), entity.LoginAttempt{
+		Username: "qwe", UserId: 1, IP: client.IP, UserAgent: client.UserAgent, Result: entity.LoginFailed,
+	}).Return(nil)
+
+	_, err = s.CompleteSignIn(context.Background(), challenge.Challenge, testTotpCode(t, step-5), client)
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	// the right one signs in and ends the challenge
+	challenges.EXPECT().AttemptChallenge(gomock.Any(), id).Return(entity.SignInChallenge{UserId: 1, Username: "qwe", Attempts: 2}, nil)
+	tfRepo.EXPECT().GetTwoFactor(gomock.Any(), 1).
+		Return(entity.TwoFactor{Username: "qwe", Secret: testTotpSecret, EnabledAt: &testTwoFactorNow}, nil)
+	tfRepo.EXPECT().UseTotpStep(gomock.Any(), 1, step).Return(true, nil)
+	challenges.EXPECT().DeleteChallenge(gomock.Any(), id).Return(nil)
+	repo.EXPECT().GetUserById(gomock.Any(), 1).Return(user, nil)
+	tokenRepo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Return(nil)
+	audit.EXPECT().SaveLoginAttempt(gomock.Any(), entity.LoginAttempt{
+		Username: "qwe", UserId: 1, IP: client.IP, UserAgent: client.UserAgent, Result: entity.LoginSucceeded,
+	}).Return(nil)
+
+	tokens, err := s.CompleteSignIn(context.Background(), challenge.Challenge, testTotpCode(t, step), client)
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+
+	// too many attempts drop the challenge
+	challenges.EXPECT().AttemptChallenge(gomock.Any(), id).
+		Return(entity.SignInChallenge{UserId: 1, Username: "qwe", Attempts: maxChallengeAttempts + 1}, nil)
+	challenges.EXPECT().DeleteChallenge(gomock.Any(), id).Return(nil)
+
+	_, err = s.CompleteSignIn(context.Background(), challenge.Challenge, testTotpCode(t, step), client)
+	assert.ErrorIs(t, err, ErrInvalidChallenge)
+
+	// an expired one is unknown
+	challenges.EXPECT().AttemptChallenge(gomock.Any(), id).Return(entity.SignInChallenge{}, fmt.Errorf("repo: %w", ErrInvalidChallenge))
+
+	_, err = s.CompleteSignIn(context.Background(), challenge.Challenge, testTotpCode(t, step), client)
+	assert.ErrorIs(t, err, ErrInvalidChallenge)
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"user-balance-service/internal/entity"
+)
+
+const (
+	defaultUsersLimit = 50
+	maxUsersLimit     = 100
+)
+
+type UserService struct {
+	repo    AuthRepo
+	guard   *LoginGuard
+	revoker TokenRevoker
+}
+
+func NewUserService(repo AuthRepo, guard *LoginGuard, revoker TokenRevoker) *UserService {
+	return &UserService{
+		repo:    repo,
+		guard:   guard,
+		revoker: revoker,
+	}
+}
+
+func (s *UserService) Profile(ctx context.Context, id int) (entity.User, error) {
+	user, err := s.repo.GetUserById(ctx, id)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("service - UserService - Profile - s.repo.GetUserById: %w", err)
+	}
+	user.Password = ""
+
+	return user, nil
+}
+
+// ChangeUsername renames the user. Sign-in lockouts stay with the old
+// username.
+func (s *UserService) ChangeUsername(ctx context.Context, id int, username string) error {
+	return s.repo.UpdateUsername(ctx, id, username)
+}
+
+// Deactivate disables the user at their own request; only an admin can
+// enable them again
+func (s *UserService) Deactivate(ctx context.Context, id int) error {
+	return s.SetDisabled(ctx, id, true)
+}
+
+// ListUsers returns a page of 50 users by default and 100 at most
+func (s *UserService) ListUsers(ctx context.Context, filter entity.UserFilter) ([]entity.User, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultUsersLimit
+	}
+	if filter.Limit > maxUsersLimit {
+		filter.Limit = maxUsersLimit
+	}
+
+	users, err := s.repo.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("service - UserService - ListUsers - s.repo.ListUsers: %w", err)
+	}
+	for i := range users {
+		users[i].Password = ""
+	}
+
+	return users, nil
+}
+
+// SetDisabled disables or re-enables the user. A disabled user is signed
+// out everywhere; their API keys stop working until they are enabled again.
+func (s *UserService) SetDisabled(ctx context.Context, id int, disabled bool) error {
+	err := s.repo.SetDisabled(ctx, id, disabled)
+	if err != nil {
+		return fmt.Errorf("service - UserService - SetDisabled - s.repo.SetDisabled: %w", err)
+	}
+	if !disabled {
+		return nil
+	}
+
+	err = s.revoker.RevokeUserTokens(ctx, id)
+	if err != nil {
+		return fmt.Errorf("service - UserService - SetDisabled - s.revoker.RevokeUserTokens: %w", err)
+	}
+
+	return nil
+}
+
+// SetRole assigns role to the user. It applies to access tokens issued from
+// the next sign-in or refresh.
+func (s *UserService) SetRole(ctx context.Context, id int, role entity.Role) error {
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+
+	return s.repo.UpdateRole(ctx, id, role)
+}
+
+// UnlockUser lifts the sign-in lockout of the user and clears their failures
+func (s *UserService) UnlockUser(ctx context.Context, id int) error {
+	user, err := s.repo.GetUserById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("service - UserService - UnlockUser - s.repo.GetUserById: %w", err)
+	}
+
+	err = s.guard.UnlockUser(ctx, user.Username)
+	if err != nil {
+		return fmt.Errorf("service - UserService - UnlockUser - s.guard.UnlockUser: %w", err)
+	}
+
+	return nil
+}
+
+// UnlockIP lifts the sign-in lockout of the IP address
+func (s *UserService) UnlockIP(ctx context.Context, ip string) error {
+	err := s.guard.UnlockIP(ctx, ip)
+	if err != nil {
+		return fmt.Errorf("service - UserService - UnlockIP - s.guard.UnlockIP: %w", err)
+	}
+
+	return nil
+}
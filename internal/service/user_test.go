@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"user-balance-service/internal/entity"
+	mock_service "user-balance-service/internal/service/mock"
+)
+
+func TestUserService_SetRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockAuthRepo(ctrl)
+	s := NewUserService(repo, nil, nil)
+
+	repo.EXPECT().UpdateRole(gomock.Any(), 1, entity.RoleAdmin).Return(nil)
+	assert.NoError(t, s.SetRole(context.Background(), 1, entity.RoleAdmin))
+
+	err := s.SetRole(context.Background(), 1, entity.Role("root"))
+	assert.ErrorIs(t, err, ErrInvalidRole)
+}
+
+func TestUserService_UnlockUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockAuthRepo(ctrl)
+	attempts := mock_service.NewMockLoginAttempts(ctrl)
+	s := NewUserService(repo, NewLoginGuard(attempts), nil)
+
+	repo.EXPECT().GetUserById(gomock.Any(), 1).Return(entity.User{Id: 1, Username: "qwe"}, nil)
+	attempts.EXPECT().Reset(gomock.Any(), "user_qwe").Return(nil)
+	assert.NoError(t, s.UnlockUser(context.Background(), 1))
+
+	repo.EXPECT().GetUserById(gomock.Any(), 2).Return(entity.User{}, fmt.Errorf("repo: %w", ErrUserNotFound))
+	assert.ErrorIs(t, s.UnlockUser(context.Background(), 2), ErrUserNotFound)
+}
+
+func TestUserService_Profile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockAuthRepo(ctrl)
+	s := NewUserService(repo, nil, nil)
+
+	repo.EXPECT().GetUserById(gomock.Any(), 1).Return(entity.User{Id: 1, Username: "qwe", Password: "$argon2id$hash"}, nil)
+	user, err := s.Profile(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.User{Id: 1, Username: "qwe"}, user)
+}
+
+func TestUserService_ListUsers(t *testing.T) {
+	testCases := []struct {
+		name      string
+		limit     int
+		wantLimit int
+	}{
+		{
+			name:      "default limit",
+			wantLimit: defaultUsersLimit,
+		},
+		{
+			name:      "limit",
+			limit:     10,
+			wantLimit: 10,
+		},
+		{
+			name:      "limit over the max",
+			limit:     1000,
+			wantLimit: maxUsersLimit,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock_service.NewMockAuthRepo(ctrl)
+			s := NewUserService(repo, nil, nil)
+
+			repo.EXPECT().ListUsers(gomock.Any(), entity.UserFilter{Query: "qw", Limit: tc.wantLimit}).
+				Return([]entity.User{{Id: 1, Username: "qwe", Password: "$argon2id$hash"}}, nil)
+
+			users, err := s.ListUsers(context.Background(), entity.UserFilter{Query: "qw", Limit: tc.limit})
+			assert.NoError(t, err)
+			assert.Equal(t, []entity.User{{Id: 1, Username: "qwe"}}, users)
+		})
+	}
+}
+
+func TestUserService_SetDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock_service.NewMockAuthRepo(ctrl)
+	revoker := mock_service.NewMockTokenRevoker(ctrl)
+	s := NewUserService(repo, nil, revoker)
+
+	// disabling signs the user out
+	repo.EXPECT().SetDisabled(gomock.Any(), 1, true).Return(nil)
+	revoker.EXPECT().RevokeUserTokens(gomock.Any(), 1).Return(nil)
+	assert.NoError(t, s.Deactivate(context.Background(), 1))
+
+	repo.EXPECT().SetDisabled(gomock.Any(), 1, false).Return(nil)
+	assert.NoError(t, s.SetDisabled(context.Background(), 1, false))
+
+	repo.EXPECT().SetDisabled(gomock.Any(), 2, true).Return(fmt.Errorf("repo: %w", ErrUserNotFound))
+	assert.ErrorIs(t, s.SetDisabled(context.Background(), 2, true), ErrUserNotFound)
+}
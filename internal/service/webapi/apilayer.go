@@ -0,0 +1,139 @@
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	url "net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	rubles = "RUB"
+
+	// maxResponseSize bounds the body read from a provider
+	maxResponseSize = 1 << 20
+)
+
+// Apilayer gets rates from the apilayer exchangerates_data convert endpoint
+type Apilayer struct {
+	client     Doer
+	pingClient Doer
+	url        string
+	apikey     string
+}
+
+func NewApilayer(client Doer, url, apikey string, opts ...Option) *Apilayer {
+	o := newOptions(client, opts)
+	return &Apilayer{client: client, pingClient: o.pingClient, url: url, apikey: apikey}
+}
+
+// apilayerResponse covers both successful answers and error payloads: the
+// API reports errors in "error", the gateway in front of it in "message"
+type apilayerResponse struct {
+	Success *bool    `json:"success"`
+	Result  *float64 `json:"result"`
+	Error   *struct {
+		Code int    `json:"code"`
+		Type string `json:"type"`
+		Info string `json:"info"`
+	} `json:"error"`
+	Message string `json:"message"`
+}
+
+func (a *Apilayer) Name() string {
+	return "apilayer"
+}
+
+func (a *Apilayer) Ping(ctx context.Context) error {
+	return ping(ctx, a.pingClient, a.Name(), a.url)
+}
+
+func (a *Apilayer) Rate(ctx context.Context, currency string) (float64, error) {
+	return a.convert(ctx, currency, "")
+}
+
+func (a *Apilayer) RateOn(ctx context.Context, currency string, date time.Time) (float64, error) {
+	return a.convert(ctx, currency, date.Format("2006-01-02"))
+}
+
+// convert asks for the rate on date, the latest one if date is empty
+func (a *Apilayer) convert(ctx context.Context, currency, date string) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("webapi - Apilayer - Rate - http.NewRequest: %w", err)
+	}
+	req.Header.Set("apikey", a.apikey)
+
+	// add parameters
+	query := url.Values{
+		"from":   {currency},
+		"to":     {rubles},
+		"amount": {"1"},
+	}
+	if len(date) != 0 {
+		query.Set("date", date)
+	}
+	req.URL.RawQuery = query.Encode()
+
+	// do request
+	res, err := a.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webapi - Apilayer - Rate - a.client.Do: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return 0, fmt.Errorf("webapi - Apilayer - Rate - io.ReadAll: %w", err)
+	}
+
+	rate, err := a.parse(res.StatusCode, body)
+	if err != nil {
+		return 0, fmt.Errorf("webapi - Apilayer - Rate - %s: %w", currency, err)
+	}
+
+	return rate, nil
+}
+
+// parse checks the status and the payload and pulls out the result
+func (a *Apilayer) parse(status int, body []byte) (float64, error) {
+	var val apilayerResponse
+	err := json.Unmarshal(body, &val)
+
+	switch {
+	case err != nil && status != http.StatusOK:
+		return 0, &StatusError{Provider: a.Name(), StatusCode: status}
+	case err != nil:
+		return 0, fmt.Errorf("json.Unmarshal: %w", err)
+	case val.Error != nil:
+		code := val.Error.Type
+		if len(code) == 0 {
+			code = strconv.Itoa(val.Error.Code)
+		}
+		return 0, &ProviderError{Provider: a.Name(), StatusCode: status, Code: code, Message: val.Error.Info}
+	case len(val.Message) != 0 && status != http.StatusOK:
+		return 0, &ProviderError{Provider: a.Name(), StatusCode: status, Code: strconv.Itoa(status), Message: val.Message}
+	case status != http.StatusOK:
+		return 0, &StatusError{Provider: a.Name(), StatusCode: status}
+	case val.Success != nil && !*val.Success:
+		return 0, &ProviderError{Provider: a.Name(), StatusCode: status, Code: "unsuccessful", Message: "success is false"}
+	case val.Result == nil:
+		return 0, fmt.Errorf("result is missing: %w", ErrInvalidRate)
+	}
+
+	return validRate(*val.Result)
+}
+
+// validRate rejects rates that can't be divided by
+func validRate(rate float64) (float64, error) {
+	if rate <= 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return 0, fmt.Errorf("rate is %v: %w", rate, ErrInvalidRate)
+	}
+
+	return rate, nil
+}
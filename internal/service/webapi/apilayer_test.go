@@ -0,0 +1,161 @@
+package webapi
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-balance-service/internal/service/webapi/webapitest"
+	"user-balance-service/pkg/httpclient"
+)
+
+func TestApilayer_Rate_Validation(t *testing.T) {
+	testCases := []struct {
+		name         string
+		status       int
+		body         string
+		wantRate     float64
+		wantStatus   *StatusError
+		wantProvider *ProviderError
+		wantErr      error
+		wantAnyErr   bool
+	}{
+		{
+			name:     "OK",
+			status:   http.StatusOK,
+			body:     `{"success":true,"result":61.25}`,
+			wantRate: 61.25,
+		},
+		{
+			name:     "OK without success flag",
+			status:   http.StatusOK,
+			body:     `{"result":61.25}`,
+			wantRate: 61.25,
+		},
+		{
+			name:    "Zero rate",
+			status:  http.StatusOK,
+			body:    `{"success":true,"result":0}`,
+			wantErr: ErrInvalidRate,
+		},
+		{
+			name:    "Negative rate",
+			status:  http.StatusOK,
+			body:    `{"success":true,"result":-1}`,
+			wantErr: ErrInvalidRate,
+		},
+		{
+			name:    "Missing result",
+			status:  http.StatusOK,
+			body:    `{"success":true}`,
+			wantErr: ErrInvalidRate,
+		},
+		{
+			name:   "Error payload",
+			status: http.StatusOK,
+			body:   `{"success":false,"error":{"code":402,"type":"invalid_from_currency","info":"You have entered an invalid \"from\" property."}}`,
+			wantProvider: &ProviderError{
+				Provider:   "apilayer",
+				StatusCode: http.StatusOK,
+				Code:       "invalid_from_currency",
+				Message:    `You have entered an invalid "from" property.`,
+			},
+		},
+		{
+			name:   "Unsuccessful",
+			status: http.StatusOK,
+			body:   `{"success":false,"result":61.25}`,
+			wantProvider: &ProviderError{
+				Provider:   "apilayer",
+				StatusCode: http.StatusOK,
+				Code:       "unsuccessful",
+				Message:    "success is false",
+			},
+		},
+		{
+			name:   "Gateway message",
+			status: http.StatusTooManyRequests,
+			body:   `{"message":"API rate limit exceeded"}`,
+			wantProvider: &ProviderError{
+				Provider:   "apilayer",
+				StatusCode: http.StatusTooManyRequests,
+				Code:       "429",
+				Message:    "API rate limit exceeded",
+			},
+		},
+		{
+			name:       "Status without payload",
+			status:     http.StatusForbidden,
+			body:       `<html>Forbidden</html>`,
+			wantStatus: &StatusError{Provider: "apilayer", StatusCode: http.StatusForbidden},
+		},
+		{
+			name:       "Status with unknown payload",
+			status:     http.StatusNotFound,
+			body:       `{}`,
+			wantStatus: &StatusError{Provider: "apilayer", StatusCode: http.StatusNotFound},
+		},
+		{
+			name:       "Invalid JSON",
+			status:     http.StatusOK,
+			body:       `{"result":`,
+			wantAnyErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			rate, err := NewApilayer(server.Client(), server.URL, "").Rate(context.Background(), "USD")
+
+			var (
+				statusErr   *StatusError
+				providerErr *ProviderError
+			)
+			switch {
+			case tc.wantStatus != nil:
+				require.ErrorAs(t, err, &statusErr)
+				assert.Equal(t, tc.wantStatus, statusErr)
+			case tc.wantProvider != nil:
+				require.ErrorAs(t, err, &providerErr)
+				assert.Equal(t, tc.wantProvider, providerErr)
+			case tc.wantErr != nil:
+				assert.ErrorIs(t, err, tc.wantErr)
+			case tc.wantAnyErr:
+				assert.Error(t, err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tc.wantRate, rate)
+			}
+		})
+	}
+}
+
+func TestApilayer_Rate_Retries(t *testing.T) {
+	server := webapitest.NewServer(map[string]float64{"USD": 61.25})
+	defer server.Close()
+
+	client := httpclient.New(httpclient.Retries(2), httpclient.Backoff(time.Millisecond, time.Millisecond))
+	apilayer := NewApilayer(client, server.ApilayerURL(), "")
+
+	// the server keeps failing: every request is attempted three times
+	server.Fail(http.StatusServiceUnavailable)
+	_, err := apilayer.Rate(context.Background(), "USD")
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+	assert.Equal(t, 3, server.Requests(webapitest.ApilayerPath))
+
+	server.Fail(http.StatusOK)
+	rate, err := apilayer.Rate(context.Background(), "USD")
+	require.NoError(t, err)
+	assert.Equal(t, 61.25, rate)
+}
@@ -0,0 +1,132 @@
+package webapi
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"golang.org/x/text/encoding/charmap"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CBR gets rates from the Central Bank of Russia daily XML,
+// e.g. https://www.cbr.ru/scripts/XML_daily.asp
+type CBR struct {
+	client     Doer
+	pingClient Doer
+	url        string
+}
+
+func NewCBR(client Doer, url string, opts ...Option) *CBR {
+	o := newOptions(client, opts)
+	return &CBR{client: client, pingClient: o.pingClient, url: url}
+}
+
+type cbrValCurs struct {
+	Date    string      `xml:"Date,attr"`
+	Valutes []cbrValute `xml:"Valute"`
+}
+
+type cbrValute struct {
+	CharCode string `xml:"CharCode"`
+	Nominal  int    `xml:"Nominal"`
+	Value    string `xml:"Value"`
+}
+
+func (c *CBR) Name() string {
+	return "cbr"
+}
+
+func (c *CBR) Ping(ctx context.Context) error {
+	return ping(ctx, c.pingClient, c.Name(), c.url)
+}
+
+func (c *CBR) Rate(ctx context.Context, currency string) (float64, error) {
+	return c.daily(ctx, currency, time.Time{})
+}
+
+// RateOn returns the rate set for date, on weekends and holidays that is the
+// rate of the previous working day
+func (c *CBR) RateOn(ctx context.Context, currency string, date time.Time) (float64, error) {
+	return c.daily(ctx, currency, date)
+}
+
+// daily looks currency up in the rates of date, the latest ones if date is zero
+func (c *CBR) daily(ctx context.Context, currency string, date time.Time) (float64, error) {
+	if currency == rubles {
+		return 1, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("webapi - CBR - Rate - http.NewRequest: %w", err)
+	}
+	if !date.IsZero() {
+		req.URL.RawQuery = url.Values{"date_req": {date.Format("02/01/2006")}}.Encode()
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webapi - CBR - Rate - c.client.Do: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("webapi - CBR - Rate: %w", &StatusError{Provider: c.Name(), StatusCode: res.StatusCode})
+	}
+
+	valCurs, err := decodeCBR(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return 0, fmt.Errorf("webapi - CBR - Rate - decodeCBR: %w", err)
+	}
+
+	for _, v := range valCurs.Valutes {
+		if v.CharCode == currency {
+			return v.rate()
+		}
+	}
+
+	return 0, fmt.Errorf("webapi - CBR - Rate - %s: %w", currency, ErrUnknownCurrency)
+}
+
+// decodeCBR parses the windows-1251 encoded ValCurs document
+func decodeCBR(r io.Reader) (cbrValCurs, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if strings.EqualFold(label, "windows-1251") {
+			return charmap.Windows1251.NewDecoder().Reader(input), nil
+		}
+		return nil, fmt.Errorf("unsupported charset %s", label)
+	}
+
+	var valCurs cbrValCurs
+	err := decoder.Decode(&valCurs)
+	if err != nil {
+		return cbrValCurs{}, err
+	}
+
+	return valCurs, nil
+}
+
+// rate is the price of one unit, the CBR quotes some currencies per 10 or 100
+// units and uses a decimal comma
+func (v cbrValute) rate() (float64, error) {
+	value, err := strconv.ParseFloat(strings.Replace(v.Value, ",", ".", 1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("webapi - CBR - Rate - strconv.ParseFloat: %w", err)
+	}
+	if v.Nominal <= 0 {
+		return 0, fmt.Errorf("webapi - CBR - Rate - nominal of %s is %d: %w", v.CharCode, v.Nominal, ErrInvalidRate)
+	}
+
+	rate, err := validRate(value / float64(v.Nominal))
+	if err != nil {
+		return 0, fmt.Errorf("webapi - CBR - Rate - %s: %w", v.CharCode, err)
+	}
+
+	return rate, nil
+}
@@ -0,0 +1,127 @@
+package webapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// Fallback asks providers in priority order and returns the first rate
+type Fallback struct {
+	providers []Provider
+}
+
+func NewFallback(providers ...Provider) *Fallback {
+	return &Fallback{providers: providers}
+}
+
+func (f *Fallback) Name() string {
+	names := make([]string, 0, len(f.providers))
+	for _, p := range f.providers {
+		names = append(names, p.Name())
+	}
+
+	return strings.Join(names, ",")
+}
+
+// Ping succeeds when any provider is reachable, rates can be got then
+func (f *Fallback) Ping(ctx context.Context) error {
+	failures := make([]string, 0, len(f.providers))
+	for _, p := range f.providers {
+		pinger, ok := p.(Pinger)
+		if !ok {
+			return nil
+		}
+
+		err := pinger.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		failures = append(failures, err.Error())
+	}
+
+	return fmt.Errorf("webapi - Fallback - Ping: no provider reachable: %s", strings.Join(failures, "; "))
+}
+
+// Rate returns ErrUnknownCurrency when no provider quotes the currency,
+// otherwise the errors of all providers are reported together
+func (f *Fallback) Rate(ctx context.Context, currency string) (float64, error) {
+	return f.first(currency, func(p Provider) (float64, error) {
+		return p.Rate(ctx, currency)
+	})
+}
+
+func (f *Fallback) RateOn(ctx context.Context, currency string, date time.Time) (float64, error) {
+	return f.first(currency, func(p Provider) (float64, error) {
+		return p.RateOn(ctx, currency, date)
+	})
+}
+
+// first returns the first rate got from a provider with get
+func (f *Fallback) first(currency string, get func(p Provider) (float64, error)) (float64, error) {
+	var (
+		failures []error
+		unknown  = true
+	)
+
+	for _, p := range f.providers {
+		rate, err := get(p)
+		if err == nil {
+			return rate, nil
+		}
+
+		log.Warnf("webapi - Fallback - Rate - %s: %s", p.Name(), err)
+		if errors.Is(err, ErrUnknownCurrency) {
+			// not why the rate couldn't be got, when another provider failed
+			failures = append(failures, fmt.Errorf("%s: %s", p.Name(), err))
+			continue
+		}
+		failures = append(failures, fmt.Errorf("%s: %w", p.Name(), err))
+		unknown = false
+	}
+
+	if unknown {
+		return 0, fmt.Errorf("webapi - Fallback - Rate - %s: %w", currency, ErrUnknownCurrency)
+	}
+
+	return 0, fmt.Errorf("webapi - Fallback - Rate: %w", &FallbackError{Errs: failures})
+}
+
+// FallbackError is returned when every provider failed. errors.Is and
+// errors.As look through the error of every provider, so e.g. a StatusError
+// of any of them can be told apart.
+type FallbackError struct {
+	Errs []error
+}
+
+func (e *FallbackError) Error() string {
+	messages := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		messages = append(messages, err.Error())
+	}
+
+	return "all providers failed: " + strings.Join(messages, "; ")
+}
+
+func (e *FallbackError) Is(target error) bool {
+	for _, err := range e.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *FallbackError) As(target interface{}) bool {
+	for _, err := range e.Errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,134 @@
+package webapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+	"user-balance-service/internal/service"
+	"user-balance-service/internal/service/webapi/webapitest"
+	"user-balance-service/pkg/httpclient"
+)
+
+func TestFallback_Rate(t *testing.T) {
+	server := webapitest.NewServer(map[string]float64{"USD": 61.25})
+	defer server.Close()
+
+	fallback := NewFallback(
+		NewApilayer(server.Client(), server.ApilayerURL(), ""),
+		NewStatic(map[string]float64{"USD": 60, "EUR": 59.8}),
+	)
+	ctx := context.Background()
+
+	assert.Equal(t, "apilayer,static", fallback.Name())
+
+	// the first provider wins
+	rate, err := fallback.Rate(ctx, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, 61.25, rate)
+	assert.Equal(t, 1, server.Requests(webapitest.ApilayerPath))
+
+	// the next one answers what the first doesn't quote
+	rate, err = fallback.Rate(ctx, "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, 59.8, rate)
+
+	// and when the first one is down
+	server.Fail(http.StatusInternalServerError)
+	rate, err = fallback.Rate(ctx, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, float64(60), rate)
+}
+
+func TestFallback_Errors(t *testing.T) {
+	server := webapitest.NewServer(nil)
+	defer server.Close()
+
+	fallback := NewFallback(
+		NewCBR(server.Client(), server.CBRURL()),
+		NewStatic(nil),
+	)
+	ctx := context.Background()
+
+	_, err := fallback.Rate(ctx, "USD")
+	assert.ErrorIs(t, err, ErrUnknownCurrency)
+
+	server.Fail(http.StatusBadGateway)
+	_, err = fallback.Rate(ctx, "USD")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUnknownCurrency)
+	assert.Contains(t, err.Error(), "cbr: ")
+
+	// the errors of the providers stay typed, also as the service reports them
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusBadGateway, statusErr.StatusCode)
+
+	converterErr := fmt.Errorf("service: %w", &service.ConverterError{Err: err})
+	assert.ErrorIs(t, converterErr, service.ErrConverterUnavailable)
+	statusErr = nil
+	require.ErrorAs(t, converterErr, &statusErr)
+	assert.Equal(t, "cbr", statusErr.Provider)
+}
+
+func TestFallbackError(t *testing.T) {
+	err := &FallbackError{Errs: []error{
+		fmt.Errorf("apilayer: %w", ErrInvalidRate),
+		&StatusError{Provider: "cbr", StatusCode: http.StatusServiceUnavailable},
+	}}
+
+	assert.EqualError(t, err, "all providers failed: apilayer: invalid rate; cbr: unexpected status 503")
+	assert.ErrorIs(t, err, ErrInvalidRate)
+	assert.NotErrorIs(t, err, ErrUnknownCurrency)
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+	var providerErr *ProviderError
+	assert.False(t, errors.As(err, &providerErr))
+}
+
+func TestFallback_Ping(t *testing.T) {
+	server := webapitest.NewServer(nil)
+	defer server.Close()
+	ctx := context.Background()
+
+	apilayer := NewApilayer(server.Client(), server.ApilayerURL(), "")
+	cbr := NewCBR(server.Client(), server.CBRURL())
+
+	// a server that answers, even with an error, is reachable
+	server.APIKey = "secret"
+	assert.NoError(t, apilayer.Ping(ctx))
+	assert.NoError(t, NewFallback(apilayer, cbr).Ping(ctx))
+
+	server.Fail(http.StatusBadGateway)
+	var statusErr *StatusError
+	assert.ErrorAs(t, cbr.Ping(ctx), &statusErr)
+	err := NewFallback(apilayer, cbr).Ping(ctx)
+	assert.ErrorContains(t, err, "apilayer: unexpected status 502")
+	assert.ErrorContains(t, err, "cbr: unexpected status 502")
+
+	// static rates need no server
+	assert.NoError(t, NewFallback(apilayer, NewStatic(nil)).Ping(ctx))
+
+	server.Close()
+	assert.Error(t, apilayer.Ping(ctx))
+}
+
+func TestPingClient(t *testing.T) {
+	server := webapitest.NewServer(nil)
+	defer server.Close()
+	ctx := context.Background()
+
+	// pings skip the retries of the rate client
+	client := httpclient.New(httpclient.Retries(2), httpclient.Backoff(time.Millisecond, time.Millisecond))
+	cbr := NewCBR(client, server.CBRURL(), PingClient(server.Client()))
+
+	server.Fail(http.StatusServiceUnavailable)
+	var statusErr *StatusError
+	assert.ErrorAs(t, cbr.Ping(ctx), &statusErr)
+	assert.Equal(t, 1, server.Requests(webapitest.CBRPath))
+}
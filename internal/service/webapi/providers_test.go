@@ -0,0 +1,72 @@
+package webapi
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+	"user-balance-service/internal/service/webapi/webapitest"
+)
+
+func TestHTTPProviders(t *testing.T) {
+	server := webapitest.NewServer(map[string]float64{"USD": 61.25, "JPY": 0.4128})
+	defer server.Close()
+	server.APIKey = "secret"
+	server.SetRateOn("USD", time.Date(2022, 10, 20, 0, 0, 0, 0, time.UTC), 58.5)
+
+	providers := []Provider{
+		NewApilayer(server.Client(), server.ApilayerURL(), "secret"),
+		NewCBR(server.Client(), server.CBRURL()),
+	}
+
+	for _, p := range providers {
+		t.Run(p.Name(), func(t *testing.T) {
+			ctx := context.Background()
+
+			rate, err := p.Rate(ctx, "USD")
+			require.NoError(t, err)
+			assert.InDelta(t, 61.25, rate, 1e-9)
+
+			// the CBR quotes JPY per 100 units
+			rate, err = p.Rate(ctx, "JPY")
+			require.NoError(t, err)
+			assert.InDelta(t, 0.4128, rate, 1e-9)
+
+			_, err = p.Rate(ctx, "XXX")
+			assert.Error(t, err)
+
+			rate, err = p.RateOn(ctx, "USD", time.Date(2022, 10, 20, 0, 0, 0, 0, time.UTC))
+			require.NoError(t, err)
+			assert.InDelta(t, 58.5, rate, 1e-9)
+
+			rate, err = p.RateOn(ctx, "USD", time.Date(2022, 10, 21, 0, 0, 0, 0, time.UTC))
+			require.NoError(t, err)
+			assert.InDelta(t, 61.25, rate, 1e-9)
+
+			server.Fail(http.StatusServiceUnavailable)
+			defer server.Fail(http.StatusOK)
+
+			_, err = p.Rate(ctx, "USD")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApilayer_InvalidKey(t *testing.T) {
+	server := webapitest.NewServer(map[string]float64{"USD": 61.25})
+	defer server.Close()
+	server.APIKey = "secret"
+
+	_, err := NewApilayer(server.Client(), server.ApilayerURL(), "wrong").Rate(context.Background(), "USD")
+	assert.Error(t, err)
+}
+
+func TestCBR_UnknownCurrency(t *testing.T) {
+	server := webapitest.NewServer(map[string]float64{"USD": 61.25})
+	defer server.Close()
+
+	_, err := NewCBR(server.Client(), server.CBRURL()).Rate(context.Background(), "XXX")
+	assert.ErrorIs(t, err, ErrUnknownCurrency)
+}
@@ -0,0 +1,134 @@
+package webapi
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Static serves fixed rates, e.g. loaded from a file for offline use
+type Static struct {
+	rates map[string]float64
+	now   func() time.Time
+}
+
+func NewStatic(rates map[string]float64) *Static {
+	s := &Static{
+		rates: make(map[string]float64, len(rates)),
+		now:   time.Now,
+	}
+	for currency, rate := range rates {
+		s.rates[strings.ToUpper(currency)] = rate
+	}
+
+	return s
+}
+
+// NewStaticFile loads rates from a YAML file
+//
+//	rates:
+//	  USD: 60.5
+//
+// or a CSV file with currency,rate records and an optional header.
+func NewStaticFile(path string) (*Static, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("webapi - NewStaticFile - os.Open: %w", err)
+	}
+	defer f.Close()
+
+	var rates map[string]float64
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		rates, err = readStaticYAML(f)
+	case ".csv":
+		rates, err = readStaticCSV(f)
+	default:
+		return nil, fmt.Errorf("webapi - NewStaticFile: unsupported file extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("webapi - NewStaticFile - %s: %w", path, err)
+	}
+
+	for currency, rate := range rates {
+		_, err = validRate(rate)
+		if err != nil {
+			return nil, fmt.Errorf("webapi - NewStaticFile - %s - %s: %w", path, currency, err)
+		}
+	}
+
+	return NewStatic(rates), nil
+}
+
+func readStaticYAML(r io.Reader) (map[string]float64, error) {
+	var file struct {
+		Rates map[string]float64 `yaml:"rates"`
+	}
+
+	err := yaml.NewDecoder(r).Decode(&file)
+	if err != nil {
+		return nil, fmt.Errorf("yaml.Decode: %w", err)
+	}
+
+	return file.Rates, nil
+}
+
+func readStaticCSV(r io.Reader) (map[string]float64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("csv.ReadAll: %w", err)
+	}
+
+	rates := make(map[string]float64, len(records))
+	for i, record := range records {
+		rate, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			// the header
+			if i == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		rates[record[0]] = rate
+	}
+
+	return rates, nil
+}
+
+func (s *Static) Name() string {
+	return "static"
+}
+
+func (s *Static) Rate(_ context.Context, currency string) (float64, error) {
+	if currency == rubles {
+		return 1, nil
+	}
+
+	rate, ok := s.rates[currency]
+	if !ok {
+		return 0, fmt.Errorf("webapi - Static - Rate - %s: %w", currency, ErrUnknownCurrency)
+	}
+
+	return rate, nil
+}
+
+// RateOn returns the fixed rate for today only. The rates have no history, so
+// earlier days are ErrNoHistory rather than today's rate stored for them.
+func (s *Static) RateOn(ctx context.Context, currency string, date time.Time) (float64, error) {
+	if currency != rubles && date.UTC().Format("2006-01-02") < s.now().UTC().Format("2006-01-02") {
+		return 0, fmt.Errorf("webapi - Static - RateOn - %s: %w", currency, ErrNoHistory)
+	}
+
+	return s.Rate(ctx, currency)
+}
@@ -0,0 +1,130 @@
+package webapi
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewStaticFile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		file     string
+		content  string
+		wantRate float64
+		wantErr  bool
+	}{
+		{
+			name:     "YAML",
+			file:     "rates.yaml",
+			content:  "rates:\n  USD: 61.25\n  eur: 59.8\n",
+			wantRate: 61.25,
+		},
+		{
+			name:     "CSV with header",
+			file:     "rates.csv",
+			content:  "currency,rate\nUSD,61.25\nEUR, 59.8\n",
+			wantRate: 61.25,
+		},
+		{
+			name:     "CSV without header",
+			file:     "rates.csv",
+			content:  "USD,61.25\n",
+			wantRate: 61.25,
+		},
+		{
+			name:    "Invalid CSV rate",
+			file:    "rates.csv",
+			content: "currency,rate\nUSD,abc\n",
+			wantErr: true,
+		},
+		{
+			name:    "Zero rate",
+			file:    "rates.yaml",
+			content: "rates:\n  USD: 0\n",
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported extension",
+			file:    "rates.json",
+			content: `{"USD":61.25}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			static, err := NewStaticFile(path)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			rate, err := static.Rate(context.Background(), "USD")
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRate, rate)
+
+			_, err = static.Rate(context.Background(), "XXX")
+			assert.ErrorIs(t, err, ErrUnknownCurrency)
+		})
+	}
+}
+
+func TestNewStaticFile_NotFound(t *testing.T) {
+	_, err := NewStaticFile(filepath.Join(t.TempDir(), "rates.yaml"))
+	assert.Error(t, err)
+}
+
+func TestStatic_RateOn(t *testing.T) {
+	now := time.Date(2022, 10, 20, 15, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		currency string
+		date     time.Time
+		wantRate float64
+		wantErr  error
+	}{
+		{
+			name:     "today",
+			currency: "USD",
+			date:     time.Date(2022, 10, 20, 0, 0, 0, 0, time.UTC),
+			wantRate: 60.5,
+		},
+		{
+			name:     "past day",
+			currency: "USD",
+			date:     time.Date(2022, 10, 19, 0, 0, 0, 0, time.UTC),
+			wantErr:  ErrNoHistory,
+		},
+		{
+			name:     "rubles on past day",
+			currency: rubles,
+			date:     time.Date(2022, 10, 19, 0, 0, 0, 0, time.UTC),
+			wantRate: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewStatic(map[string]float64{"USD": 60.5})
+			s.now = func() time.Time { return now }
+
+			rate, err := s.RateOn(context.Background(), tc.currency, tc.date)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRate, rate)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Package webapi implements exchange rate providers.
+package webapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"user-balance-service/internal/service"
+)
+
+var (
+	// ErrUnknownCurrency is returned by providers that don't quote the
+	// currency. It's the service error, so it reaches clients as is rather
+	// than as an unavailable converter.
+	ErrUnknownCurrency = service.ErrUnknownCurrency
+	// ErrInvalidRate is returned when a provider answers with a missing,
+	// zero or negative rate
+	ErrInvalidRate = errors.New("invalid rate")
+	// ErrNoHistory is returned by providers that only know today's rates
+	ErrNoHistory = errors.New("no historical rates")
+)
+
+// Provider returns the price of one unit of currency in rubles, either the
+// latest one or the one that applied on date
+type Provider interface {
+	Name() string
+	Rate(ctx context.Context, currency string) (float64, error)
+	RateOn(ctx context.Context, currency string, date time.Time) (float64, error)
+}
+
+// Pinger is implemented by providers that can check their server answers.
+// Providers without it, like Static, are always reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Doer sends HTTP requests, e.g. *http.Client or *httpclient.Client
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Option configures an HTTP provider
+type Option func(o *options)
+
+type options struct {
+	pingClient Doer
+}
+
+// PingClient sends the pings of Ping instead of the client of the provider.
+// A plain client keeps readiness probes out of the retries, the circuit
+// breaker and the call metrics of rate requests.
+func PingClient(client Doer) Option {
+	return func(o *options) {
+		o.pingClient = client
+	}
+}
+
+func newOptions(client Doer, opts []Option) options {
+	o := options{pingClient: client}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// StatusError is an unexpected HTTP status without an error payload
+type StatusError struct {
+	Provider   string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: unexpected status %d", e.Provider, e.StatusCode)
+}
+
+// ProviderError is an error reported by the provider in its response
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s: %s (%s, status %d)", e.Provider, e.Message, e.Code, e.StatusCode)
+}
+
+// ping sends a HEAD request to url. Any answer but a 5xx means the server is
+// up: no rate is asked for, so an answer like 401 or 405 is fine too.
+func ping(ctx context.Context, client Doer, provider, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", provider, err)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", provider, err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode >= http.StatusInternalServerError {
+		return &StatusError{Provider: provider, StatusCode: res.StatusCode}
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+// Package webapitest provides a local stand-in for the HTTP rate providers.
+package webapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"golang.org/x/text/encoding/charmap"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ApilayerPath = "/exchangerates_data/convert"
+	CBRPath      = "/scripts/XML_daily.asp"
+)
+
+// Server answers the apilayer convert endpoint and the CBR daily XML with
+// the rates it was given. Point the provider URLs at ApilayerURL and CBRURL.
+type Server struct {
+	*httptest.Server
+	APIKey string
+
+	mu       sync.Mutex
+	rates    map[string]float64
+	daily    map[string]map[string]float64
+	status   int
+	requests map[string]int
+}
+
+// NewServer starts a server quoting rates, the price of one unit in rubles
+func NewServer(rates map[string]float64) *Server {
+	s := &Server{
+		rates:    make(map[string]float64),
+		daily:    make(map[string]map[string]float64),
+		status:   http.StatusOK,
+		requests: make(map[string]int),
+	}
+	for currency, rate := range rates {
+		s.rates[currency] = rate
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(ApilayerPath, s.apilayer)
+	mux.HandleFunc(CBRPath, s.cbr)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+func (s *Server) ApilayerURL() string {
+	return s.URL + ApilayerPath
+}
+
+func (s *Server) CBRURL() string {
+	return s.URL + CBRPath
+}
+
+// SetRate changes the quoted rate of currency
+func (s *Server) SetRate(currency string, rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rates[currency] = rate
+}
+
+// SetRateOn quotes rate for currency on date only, other dates get the
+// current rates
+func (s *Server) SetRateOn(currency string, date time.Time, rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := date.Format("2006-01-02")
+	if s.daily[day] == nil {
+		s.daily[day] = make(map[string]float64)
+	}
+	s.daily[day][currency] = rate
+}
+
+// Fail makes every endpoint answer with status, http.StatusOK restores it
+func (s *Server) Fail(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = status
+}
+
+// Requests returns the number of requests served on path
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[path]
+}
+
+// begin counts the request and writes the failure status if one is set.
+// It returns the rates quoted on day, an empty day means today.
+func (s *Server) begin(w http.ResponseWriter, r *http.Request, day string) (map[string]float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[r.URL.Path]++
+	if s.status != http.StatusOK {
+		w.WriteHeader(s.status)
+		return nil, false
+	}
+
+	rates := make(map[string]float64, len(s.rates))
+	for currency, rate := range s.rates {
+		rates[currency] = rate
+	}
+	for currency, rate := range s.daily[day] {
+		rates[currency] = rate
+	}
+
+	return rates, true
+}
+
+func (s *Server) apilayer(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	rates, ok := s.begin(w, r, query.Get("date"))
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if len(s.APIKey) != 0 && r.Header.Get("apikey") != s.APIKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": "Invalid authentication credentials"})
+		return
+	}
+
+	rate, ok := rates[query.Get("from")]
+	if !ok || query.Get("to") != "RUB" {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   map[string]interface{}{"code": 402, "info": "You have entered an invalid \"from\" property."},
+		})
+		return
+	}
+
+	amount, err := strconv.ParseFloat(query.Get("amount"), 64)
+	if err != nil {
+		amount = 1
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"query":   map[string]interface{}{"from": query.Get("from"), "to": "RUB", "amount": amount},
+		"info":    map[string]interface{}{"rate": rate},
+		"result":  rate * amount,
+	})
+}
+
+func (s *Server) cbr(w http.ResponseWriter, r *http.Request) {
+	date, day := time.Now(), ""
+	if dateReq := r.URL.Query().Get("date_req"); len(dateReq) != 0 {
+		parsed, err := time.Parse("02/01/2006", dateReq)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		date, day = parsed, parsed.Format("2006-01-02")
+	}
+
+	rates, ok := s.begin(w, r, day)
+	if !ok {
+		return
+	}
+
+	currencies := make([]string, 0, len(rates))
+	for currency := range rates {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="windows-1251"?>` + "\n")
+	fmt.Fprintf(&b, `<ValCurs Date="%s" name="Foreign Currency Market">`+"\n", date.Format("02.01.2006"))
+	for _, currency := range currencies {
+		// quoted per 100 units with a decimal comma, as the CBR does for some currencies
+		value := strings.Replace(strconv.FormatFloat(rates[currency]*100, 'f', 4, 64), ".", ",", 1)
+		fmt.Fprintf(&b, `<Valute ID="%s"><CharCode>%s</CharCode><Nominal>100</Nominal><Name>Валюта %s</Name><Value>%s</Value></Valute>`+"\n",
+			currency, currency, currency, value)
+	}
+	b.WriteString(`</ValCurs>`)
+
+	body, err := charmap.Windows1251.NewEncoder().String(b.String())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=windows-1251")
+	_, _ = w.Write([]byte(body))
+}
@@ -0,0 +1,2 @@
+ALTER TABLE accounts
+    DROP CONSTRAINT IF EXISTS accounts_balance_non_negative;
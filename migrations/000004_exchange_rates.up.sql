@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency CHAR(3) NOT NULL,
+    date DATE NOT NULL,
+    rate NUMERIC(18, 8) NOT NULL CHECK (rate > 0),
+    fetched_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (currency, date)
+);
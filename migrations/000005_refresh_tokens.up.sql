@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id CHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    family_id CHAR(32) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens (family_id);
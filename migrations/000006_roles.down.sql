@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS accounts_user_id_idx;
+
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS frozen,
+    DROP COLUMN IF EXISTS user_id;
+
+ALTER TABLE users DROP COLUMN IF EXISTS role;
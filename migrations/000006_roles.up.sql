@@ -0,0 +1,9 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user'
+        CHECK (role IN ('admin', 'operator', 'user'));
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS user_id INT REFERENCES users (id) ON DELETE RESTRICT,
+    ADD COLUMN IF NOT EXISTS frozen BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS accounts_user_id_idx ON accounts (user_id);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    username TEXT NOT NULL,
+    user_id INT REFERENCES users (id) ON DELETE SET NULL,
+    ip VARCHAR(45) NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    result VARCHAR(16) NOT NULL CHECK (result IN ('succeeded', 'failed', 'locked')),
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_username_idx ON login_attempts (username, created_at);
+CREATE INDEX IF NOT EXISTS login_attempts_ip_idx ON login_attempts (ip, created_at);
@@ -0,0 +1,11 @@
+DELETE FROM login_attempts WHERE result = 'challenged';
+ALTER TABLE login_attempts DROP CONSTRAINT IF EXISTS login_attempts_result_check;
+ALTER TABLE login_attempts
+    ADD CONSTRAINT login_attempts_result_check CHECK (result IN ('succeeded', 'failed', 'locked'));
+
+DROP TABLE IF EXISTS recovery_codes;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS totp_last_step,
+    DROP COLUMN IF EXISTS totp_enabled_at,
+    DROP COLUMN IF EXISTS totp_secret;
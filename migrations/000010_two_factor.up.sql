@@ -0,0 +1,17 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    used_at TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
+
+ALTER TABLE login_attempts DROP CONSTRAINT IF EXISTS login_attempts_result_check;
+ALTER TABLE login_attempts
+    ADD CONSTRAINT login_attempts_result_check CHECK (result IN ('succeeded', 'failed', 'locked', 'challenged'));
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT,
+    api_key_id INT,
+    role VARCHAR(16) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    operation VARCHAR(128) NOT NULL,
+    account_ids INT[] NOT NULL DEFAULT '{}',
+    params JSONB NOT NULL DEFAULT '{}',
+    status SMALLINT NOT NULL,
+    outcome VARCHAR(16) NOT NULL CHECK (outcome IN ('succeeded', 'failed')),
+    error VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_user_idx ON audit_log (user_id, id);
+CREATE INDEX IF NOT EXISTS audit_log_api_key_idx ON audit_log (api_key_id, id);
+CREATE INDEX IF NOT EXISTS audit_log_account_ids_idx ON audit_log USING GIN (account_ids);
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log (created_at);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,159 @@
+// Package breaker implements a circuit breaker that stops calling a failing
+// dependency for a while instead of waiting on every request.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultThreshold = 5
+	defaultTimeout   = 10 * time.Second
+)
+
+// ErrOpen is returned by Allow while the breaker is open
+var ErrOpen = errors.New("breaker: circuit is open")
+
+type State int
+
+const (
+	// Closed lets every call through
+	Closed State = iota
+	// Open rejects calls until the timeout passes
+	Open
+	// HalfOpen lets a single probe through; its outcome closes or reopens the breaker
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type Breaker struct {
+	threshold     int
+	timeout       time.Duration
+	onStateChange func(from, to State)
+	now           func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func New(opts ...Option) *Breaker {
+	b := &Breaker{
+		threshold: defaultThreshold,
+		timeout:   defaultTimeout,
+		now:       time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Allow reports whether a call may proceed. Every allowed call must be
+// followed by Success or Failure.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		if b.now().Sub(b.openedAt) < b.timeout {
+			return ErrOpen
+		}
+		b.setState(HalfOpen)
+		b.probing = true
+		return nil
+	case HalfOpen:
+		if b.probing {
+			return ErrOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	if b.state != Closed {
+		b.setState(Closed)
+	}
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	b.failures++
+	if b.state == HalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		if b.state != Open {
+			b.setState(Open)
+		}
+	}
+}
+
+// Release ends an allowed call whose outcome says nothing about the
+// dependency, e.g. one cancelled by the caller
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// Do runs fn unless the breaker is open and records its outcome
+func (b *Breaker) Do(fn func() error) error {
+	err := b.Allow()
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	if err != nil {
+		b.Failure()
+		return err
+	}
+
+	b.Success()
+	return nil
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *Breaker) setState(state State) {
+	from := b.state
+	b.state = state
+	if b.onStateChange != nil {
+		b.onStateChange(from, state)
+	}
+}
@@ -0,0 +1,73 @@
+package breaker
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var errFailed = errors.New("failed")
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	var transitions []string
+
+	b := New(Threshold(2), Timeout(time.Second), OnStateChange(func(from, to State) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+	}))
+	b.now = func() time.Time { return now }
+
+	fail := func() error { return errFailed }
+	ok := func() error { return nil }
+
+	// a success resets the failure count
+	assert.ErrorIs(t, b.Do(fail), errFailed)
+	assert.NoError(t, b.Do(ok))
+	assert.ErrorIs(t, b.Do(fail), errFailed)
+	assert.Equal(t, Closed, b.State())
+
+	// consecutive failures open it
+	assert.ErrorIs(t, b.Do(fail), errFailed)
+	assert.Equal(t, Open, b.State())
+	assert.ErrorIs(t, b.Do(ok), ErrOpen)
+
+	// after the timeout a single probe goes through
+	now = now.Add(time.Second)
+	assert.NoError(t, b.Allow())
+	assert.Equal(t, HalfOpen, b.State())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+
+	// a failed probe reopens it
+	b.Failure()
+	assert.Equal(t, Open, b.State())
+	assert.ErrorIs(t, b.Do(ok), ErrOpen)
+
+	// a successful probe closes it
+	now = now.Add(time.Second)
+	assert.NoError(t, b.Do(ok))
+	assert.Equal(t, Closed, b.State())
+
+	assert.Equal(t, []string{
+		"closed->open",
+		"open->half-open",
+		"half-open->open",
+		"open->half-open",
+		"half-open->closed",
+	}, transitions)
+}
+
+func TestBreaker_Release(t *testing.T) {
+	now := time.Now()
+	b := New(Threshold(1), Timeout(time.Second))
+	b.now = func() time.Time { return now }
+
+	b.Failure()
+	now = now.Add(time.Second)
+
+	// a released probe lets the next one through without changing the state
+	assert.NoError(t, b.Allow())
+	b.Release()
+	assert.Equal(t, HalfOpen, b.State())
+	assert.NoError(t, b.Allow())
+}
@@ -0,0 +1,26 @@
+package breaker
+
+import "time"
+
+type Option func(b *Breaker)
+
+// Threshold is the number of consecutive failures that opens the breaker
+func Threshold(failures int) Option {
+	return func(b *Breaker) {
+		b.threshold = failures
+	}
+}
+
+// Timeout is how long the breaker stays open before letting a probe through
+func Timeout(timeout time.Duration) Option {
+	return func(b *Breaker) {
+		b.timeout = timeout
+	}
+}
+
+// OnStateChange is called with the new state after every transition
+func OnStateChange(fn func(from, to State)) Option {
+	return func(b *Breaker) {
+		b.onStateChange = fn
+	}
+}
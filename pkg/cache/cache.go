@@ -0,0 +1,114 @@
+// Package cache provides a typed API over cache backends such as rediscache
+// and memcache. Backends encode values with a pluggable Codec.
+package cache
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/sync/singleflight"
+	"time"
+)
+
+// loadTimeout bounds a load shared by GetOrLoad callers
+const loadTimeout = 10 * time.Second
+
+// ErrMiss is returned by backends when a key is absent or expired
+var ErrMiss = errors.New("cache: miss")
+
+// Getter decodes the value stored at key into dst
+type Getter interface {
+	Get(ctx context.Context, key string, dst any) error
+}
+
+type Setter interface {
+	Set(ctx context.Context, key string, value any) error
+}
+
+// Backend is a complete cache implementation such as rediscache.Redis
+type Backend interface {
+	Getter
+	Setter
+	Delete(ctx context.Context, keys ...string) error
+	Incr(ctx context.Context, key string) (int64, error)
+	Counter(ctx context.Context, key string) (int64, error)
+}
+
+// Get returns the value of type T stored at key. A miss is reported as ErrMiss.
+func Get[T any](ctx context.Context, c Getter, key string) (T, error) {
+	var value T
+
+	err := c.Get(ctx, key, &value)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return value, nil
+}
+
+// Set stores value at key
+func Set[T any](ctx context.Context, c Setter, key string, value T) error {
+	return c.Set(ctx, key, value)
+}
+
+// GetOrLoad returns the value of type T cached at key. On a miss load runs
+// once for all concurrent callers of the same key and its result is cached.
+// The load is detached from the cancellation of the caller that started it,
+// so a client going away doesn't fail the others, and gets its own timeout.
+// The cache never fails the call: read and write errors are treated as a
+// miss and a skipped write. Wrap backends in Resilient to log and count them.
+func GetOrLoad[T any](ctx context.Context, c interface {
+	Getter
+	Setter
+}, group *singleflight.Group, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	value, err := Get[T](ctx, c, key)
+	if err == nil {
+		return value, nil
+	}
+
+	shared, err, _ := group.Do(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(detach(ctx), loadTimeout)
+		defer cancel()
+
+		value, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		_ = Set(ctx, c, key, value)
+
+		return value, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return shared.(T), nil
+}
+
+// detachedContext keeps the values of its parent, such as the trace span,
+// but not its deadline and cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key any) any {
+	return d.parent.Value(key)
+}
@@ -0,0 +1,183 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/singleflight"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"user-balance-service/pkg/breaker"
+	"user-balance-service/pkg/cache"
+	"user-balance-service/pkg/memcache"
+	"user-balance-service/pkg/rediscache"
+)
+
+func TestGetOrLoad_CollapsesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	m := memcache.New()
+
+	var (
+		group   singleflight.Group
+		loads   int32
+		release = make(chan struct{})
+		wg      sync.WaitGroup
+	)
+
+	load := func(ctx context.Context) (int, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return 42, nil
+	}
+
+	const callers = 10
+	results := make([]int, callers)
+	for i := 0; i < callers; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := cache.GetOrLoad(ctx, m, &group, "key", load)
+			assert.NoError(t, err)
+			results[i] = value
+		}()
+	}
+
+	// let every caller reach the cache before the load finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+	for _, value := range results {
+		assert.Equal(t, 42, value)
+	}
+
+	// the result is cached
+	value, err := cache.Get[int](ctx, m, "key")
+	require.NoError(t, err)
+	assert.Equal(t, 42, value)
+}
+
+func TestGetOrLoad_LoadError(t *testing.T) {
+	errLoad := errors.New("load failed")
+
+	var group singleflight.Group
+	_, err := cache.GetOrLoad(context.Background(), memcache.New(), &group, "key", func(ctx context.Context) (int, error) {
+		return 0, errLoad
+	})
+
+	assert.ErrorIs(t, err, errLoad)
+}
+
+func TestGetOrLoad_DetachesLoad(t *testing.T) {
+	type ctxKey struct{}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+	cancel()
+
+	var group singleflight.Group
+	value, err := cache.GetOrLoad(ctx, memcache.New(), &group, "key", func(ctx context.Context) (int, error) {
+		// the caller's cancellation doesn't reach the load, its values do
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		assert.Equal(t, "value", ctx.Value(ctxKey{}))
+
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(10*time.Second), deadline, time.Second)
+
+		return 42, nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 42, value)
+}
+
+func TestResilient_FailsOpen(t *testing.T) {
+	ctx := context.Background()
+
+	miniRedis, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: miniRedis.Addr(), MaxRetries: -1})
+
+	metrics := &countingMetrics{counts: map[string]int{}}
+	r := cache.NewResilient(rediscache.New(client),
+		cache.Observe(metrics),
+		cache.CircuitBreaker(breaker.New(breaker.Threshold(2), breaker.Timeout(time.Hour))))
+
+	require.NoError(t, cache.Set(ctx, r, "key", 1))
+	value, err := cache.Get[int](ctx, r, "key")
+	require.NoError(t, err)
+	assert.Equal(t, 1, value)
+	_, err = cache.Get[int](ctx, r, "account_7")
+	assert.ErrorIs(t, err, cache.ErrMiss)
+	assert.Equal(t, 1, metrics.count("hit key"))
+	assert.Equal(t, 1, metrics.count("miss account"))
+
+	// Redis goes down: reads miss, writes are skipped, nothing fails
+	miniRedis.Close()
+
+	_, err = cache.Get[int](ctx, r, "key")
+	assert.ErrorIs(t, err, cache.ErrMiss)
+	assert.NoError(t, cache.Set(ctx, r, "key", 2))
+	assert.Equal(t, 1, metrics.count("error get"))
+	assert.Equal(t, 1, metrics.count("error set"))
+
+	// the breaker is open now and Redis isn't called at all
+	assert.NoError(t, r.Delete(ctx, "key"))
+	assert.Equal(t, 1, metrics.count("error delete"))
+
+	// namespace versions are the only errors surfaced to callers
+	_, err = r.Incr(ctx, "counter")
+	assert.ErrorIs(t, err, breaker.ErrOpen)
+	_, err = r.Counter(ctx, "counter")
+	assert.ErrorIs(t, err, breaker.ErrOpen)
+}
+
+// countingMetrics counts outcomes by kind and family or operation, e.g.
+// "hit account" or "error get"
+type countingMetrics struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (m *countingMetrics) Hit(family string)  { m.add("hit " + family) }
+func (m *countingMetrics) Miss(family string) { m.add("miss " + family) }
+func (m *countingMetrics) Error(op string)    { m.add("error " + op) }
+
+func (m *countingMetrics) add(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[name]++
+}
+
+func (m *countingMetrics) count(name string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[name]
+}
+
+func TestFamily(t *testing.T) {
+	tests := []struct {
+		key    string
+		family string
+	}{
+		{key: "account_42", family: "account"},
+		{key: "history_namespace_42_v3_sort=date", family: "history"},
+		{key: "counter", family: "counter"},
+		{key: "", family: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.family, cache.Family(tt.key))
+		})
+	}
+}
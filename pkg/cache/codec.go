@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec turns cached values into bytes and back
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	JSON    Codec = jsonCodec{}
+	Msgpack Codec = msgpackCodec{}
+)
+
+// CodecByName returns the codec called "json" or "msgpack"
+func CodecByName(name string) (Codec, error) {
+	switch name {
+	case "json":
+		return JSON, nil
+	case "msgpack":
+		return Msgpack, nil
+	default:
+		return nil, fmt.Errorf("unknown cache codec %q", name)
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"user-balance-service/pkg/breaker"
+)
+
+// Metrics observes cache outcomes. Hits and misses are reported per key
+// family, see Family.
+type Metrics interface {
+	Hit(family string)
+	Miss(family string)
+	Error(op string)
+}
+
+// Family is the part of key before the first underscore, e.g. "account" for
+// "account_42". Keys without one are their own family.
+func Family(key string) string {
+	family, _, _ := strings.Cut(key, "_")
+	return family
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) Hit(family string)  {}
+func (nopMetrics) Miss(family string) {}
+func (nopMetrics) Error(op string)    {}
+
+type ResilientOption func(r *Resilient)
+
+// Observe reports hits, misses and errors to metrics
+func Observe(metrics Metrics) ResilientOption {
+	return func(r *Resilient) {
+		r.metrics = metrics
+	}
+}
+
+// CircuitBreaker replaces the default breaker
+func CircuitBreaker(b *breaker.Breaker) ResilientOption {
+	return func(r *Resilient) {
+		r.breaker = b
+	}
+}
+
+// Resilient makes a backend fail open: errors are logged, counted and turned
+// into misses or skipped writes, so an outage only costs latency. While the
+// backend keeps failing a circuit breaker skips it entirely.
+//
+// Counter and Incr are the exceptions and still return the error: a wrong
+// namespace version could serve stale data, so readers should bypass the cache
+// instead. A failed Incr is logged and counted like the rest; writers whose
+// changes are already saved can go on, the stale entries expire with the TTL.
+type Resilient struct {
+	backend Backend
+	breaker *breaker.Breaker
+	metrics Metrics
+}
+
+func NewResilient(backend Backend, opts ...ResilientOption) *Resilient {
+	r := &Resilient{
+		backend: backend,
+		metrics: nopMetrics{},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.breaker == nil {
+		r.breaker = breaker.New(breaker.OnStateChange(func(from, to breaker.State) {
+			log.Warnf("cache - Resilient - circuit breaker: %s -> %s", from, to)
+		}))
+	}
+
+	return r
+}
+
+func (r *Resilient) Get(ctx context.Context, key string, dst any) error {
+	var miss bool
+	err := r.call(ctx, "get", func() error {
+		err := r.backend.Get(ctx, key, dst)
+		if errors.Is(err, ErrMiss) {
+			// a miss means the backend is healthy
+			miss = true
+			return nil
+		}
+		return err
+	})
+	if err != nil || miss {
+		r.metrics.Miss(Family(key))
+		return ErrMiss
+	}
+
+	r.metrics.Hit(Family(key))
+	return nil
+}
+
+func (r *Resilient) Set(ctx context.Context, key string, value any) error {
+	_ = r.call(ctx, "set", func() error {
+		return r.backend.Set(ctx, key, value)
+	})
+	return nil
+}
+
+func (r *Resilient) Delete(ctx context.Context, keys ...string) error {
+	_ = r.call(ctx, "delete", func() error {
+		return r.backend.Delete(ctx, keys...)
+	})
+	return nil
+}
+
+func (r *Resilient) Incr(ctx context.Context, key string) (int64, error) {
+	var value int64
+	err := r.call(ctx, "incr", func() (err error) {
+		value, err = r.backend.Incr(ctx, key)
+		return err
+	})
+	return value, err
+}
+
+func (r *Resilient) Counter(ctx context.Context, key string) (int64, error) {
+	var value int64
+	err := r.call(ctx, "counter", func() (err error) {
+		value, err = r.backend.Counter(ctx, key)
+		return err
+	})
+	return value, err
+}
+
+// call runs fn through the breaker, logging and counting its failures
+func (r *Resilient) call(ctx context.Context, op string, fn func() error) error {
+	err := r.breaker.Allow()
+	if err != nil {
+		r.metrics.Error(op)
+		return err
+	}
+
+	err = fn()
+	if err != nil {
+		r.breaker.Failure()
+		r.metrics.Error(op)
+		log.Warnf("cache - Resilient - %s: %s", op, err)
+		return err
+	}
+
+	r.breaker.Success()
+	return nil
+}
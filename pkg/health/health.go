@@ -0,0 +1,211 @@
+// Package health runs the readiness checks of the dependencies of a service.
+// A failed critical check makes the service not ready, other failures are
+// reported but leave it ready in a degraded state.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"golang.org/x/sync/singleflight"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultTimeout  = 2 * time.Second
+	defaultCacheFor = time.Second
+)
+
+// Status of a check or of the whole service
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDown     Status = "down"
+	StatusDegraded Status = "degraded"
+	StatusDraining Status = "draining"
+)
+
+// CheckFunc returns an error when the dependency can't be used
+type CheckFunc func(ctx context.Context) error
+
+// Check is the result of one check
+type Check struct {
+	Status   Status `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+	// Duration is in milliseconds
+	Duration int64 `json:"duration_ms"`
+}
+
+// Report is the readiness of the service with the checks it was made from
+type Report struct {
+	Status Status           `json:"status"`
+	Checks map[string]Check `json:"checks,omitempty"`
+}
+
+// Ready tells whether the service should get traffic
+func (r Report) Ready() bool {
+	return r.Status == StatusUp || r.Status == StatusDegraded
+}
+
+// Public returns the report without the errors of the checks, which can
+// tell addresses and versions of the dependencies
+func (r Report) Public() Report {
+	if r.Checks == nil {
+		return r
+	}
+
+	checks := make(map[string]Check, len(r.Checks))
+	for name, c := range r.Checks {
+		c.Error = ""
+		checks[name] = c
+	}
+	r.Checks = checks
+
+	return r
+}
+
+type check struct {
+	name     string
+	critical bool
+	fn       CheckFunc
+}
+
+type Health struct {
+	timeout  time.Duration
+	cacheFor time.Duration
+	checks   []check
+	draining int32
+	now      func() time.Time
+
+	group    singleflight.Group
+	mu       sync.Mutex
+	last     Report
+	lastTime time.Time
+}
+
+func New(opts ...Option) *Health {
+	h := &Health{
+		timeout:  defaultTimeout,
+		cacheFor: defaultCacheFor,
+		now:      time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// Add registers a check. Checks are run concurrently, each within the
+// timeout.
+func (h *Health) Add(name string, critical bool, fn CheckFunc) {
+	h.checks = append(h.checks, check{name: name, critical: critical, fn: fn})
+}
+
+// Drain makes the service not ready for good, so load balancers stop sending
+// requests before it shuts down
+func (h *Health) Drain() {
+	atomic.StoreInt32(&h.draining, 1)
+}
+
+// Check runs every check unless the service is draining. A report is
+// reused for the cache period and concurrent callers share one run, so
+// frequent probes don't load the dependencies. The run doesn't depend on
+// the caller that started it; every check has the timeout anyway.
+func (h *Health) Check(ctx context.Context) Report {
+	if atomic.LoadInt32(&h.draining) == 1 {
+		return Report{Status: StatusDraining}
+	}
+
+	h.mu.Lock()
+	if !h.lastTime.IsZero() && h.now().Sub(h.lastTime) < h.cacheFor {
+		report := h.last
+		h.mu.Unlock()
+		return report
+	}
+	h.mu.Unlock()
+
+	report, _, _ := h.group.Do("check", func() (interface{}, error) {
+		report := h.checkAll(context.Background())
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.last, h.lastTime = report, h.now()
+
+		return report, nil
+	})
+
+	return report.(Report)
+}
+
+// ServeHTTP answers with the full report, errors included, and 503 when the
+// service isn't ready. Serve it only where the public can't reach it, e.g.
+// next to the metrics.
+func (h *Health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := h.Check(r.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+func (h *Health) checkAll(ctx context.Context) Report {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		report = Report{Status: StatusUp, Checks: make(map[string]Check, len(h.checks))}
+	)
+
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+			result := h.run(ctx, c)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			report.Checks[c.name] = result
+			switch {
+			case result.Status == StatusUp:
+			case c.critical:
+				report.Status = StatusDown
+			case report.Status == StatusUp:
+				report.Status = StatusDegraded
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (h *Health) run(ctx context.Context, c check) Check {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+	result := Check{
+		Status:   StatusUp,
+		Critical: c.critical,
+		Duration: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,165 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"user-balance-service/pkg/health"
+)
+
+func up(context.Context) error {
+	return nil
+}
+
+func down(context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestHealth_Check(t *testing.T) {
+	testCases := []struct {
+		name       string
+		postgres   health.CheckFunc
+		converter  health.CheckFunc
+		wantStatus health.Status
+		wantReady  bool
+	}{
+		{
+			name:       "all up",
+			postgres:   up,
+			converter:  up,
+			wantStatus: health.StatusUp,
+			wantReady:  true,
+		},
+		{
+			name:       "non-critical down",
+			postgres:   up,
+			converter:  down,
+			wantStatus: health.StatusDegraded,
+			wantReady:  true,
+		},
+		{
+			name:       "critical down",
+			postgres:   down,
+			converter:  up,
+			wantStatus: health.StatusDown,
+		},
+		{
+			name:       "both down",
+			postgres:   down,
+			converter:  down,
+			wantStatus: health.StatusDown,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := health.New()
+			h.Add("postgres", true, tc.postgres)
+			h.Add("converter", false, tc.converter)
+
+			report := h.Check(context.Background())
+			assert.Equal(t, tc.wantStatus, report.Status)
+			assert.Equal(t, tc.wantReady, report.Ready())
+			assert.Len(t, report.Checks, 2)
+			assert.True(t, report.Checks["postgres"].Critical)
+			assert.False(t, report.Checks["converter"].Critical)
+		})
+	}
+}
+
+func TestHealth_CheckResult(t *testing.T) {
+	h := health.New(health.Timeout(10 * time.Millisecond))
+	h.Add("postgres", true, down)
+	h.Add("redis", true, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := h.Check(context.Background())
+	assert.Equal(t, health.Check{Status: health.StatusDown, Critical: true, Error: "connection refused"}, report.Checks["postgres"])
+	// a hanging check is cut off by the timeout
+	assert.Equal(t, health.StatusDown, report.Checks["redis"].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["redis"].Error)
+	assert.GreaterOrEqual(t, report.Checks["redis"].Duration, int64(10))
+}
+
+func TestHealth_Drain(t *testing.T) {
+	h := health.New()
+	h.Add("postgres", true, up)
+	assert.True(t, h.Check(context.Background()).Ready())
+
+	h.Drain()
+	report := h.Check(context.Background())
+	assert.Equal(t, health.StatusDraining, report.Status)
+	assert.False(t, report.Ready())
+	assert.Empty(t, report.Checks)
+}
+
+func TestHealth_CacheFor(t *testing.T) {
+	var runs int32
+	release := make(chan struct{})
+	counted := func(context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		<-release
+		return nil
+	}
+
+	// concurrent probes share a run and later ones reuse its report
+	h := health.New(health.CacheFor(time.Hour))
+	h.Add("postgres", true, counted)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.True(t, h.Check(context.Background()).Ready())
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.True(t, h.Check(context.Background()).Ready())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+	// draining isn't delayed by the cache
+	h.Drain()
+	assert.Equal(t, health.StatusDraining, h.Check(context.Background()).Status)
+
+	// without the cache every probe runs the checks
+	atomic.StoreInt32(&runs, 0)
+	h = health.New(health.CacheFor(0))
+	h.Add("postgres", true, counted)
+	h.Check(context.Background())
+	h.Check(context.Background())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+}
+
+func TestReport_Public(t *testing.T) {
+	h := health.New()
+	h.Add("postgres", true, down)
+
+	report := h.Check(context.Background())
+	public := report.Public()
+	assert.Equal(t, health.StatusDown, public.Checks["postgres"].Status)
+	assert.Empty(t, public.Checks["postgres"].Error)
+	// the report itself keeps the error
+	assert.Equal(t, "connection refused", report.Checks["postgres"].Error)
+}
+
+func TestHealth_ServeHTTP(t *testing.T) {
+	h := health.New()
+	h.Add("postgres", true, down)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status":"down","checks":{"postgres":{"status":"down","critical":true,"error":"connection refused","duration_ms":0}}}`, w.Body.String())
+}
@@ -0,0 +1,20 @@
+package health
+
+import "time"
+
+type Option func(*Health)
+
+// Timeout limits every check, 2 seconds by default
+func Timeout(timeout time.Duration) Option {
+	return func(h *Health) {
+		h.timeout = timeout
+	}
+}
+
+// CacheFor reuses a report for ttl, 1 second by default; 0 runs the checks
+// on every call
+func CacheFor(ttl time.Duration) Option {
+	return func(h *Health) {
+		h.cacheFor = ttl
+	}
+}
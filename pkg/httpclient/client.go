@@ -0,0 +1,170 @@
+// Package httpclient implements an HTTP client for flaky dependencies: every
+// attempt has a timeout, network errors and 5xx responses are retried with
+// jittered exponential backoff, and a circuit breaker stops calling a
+// dependency that keeps failing.
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+	"user-balance-service/pkg/breaker"
+)
+
+const (
+	defaultTimeout    = 5 * time.Second
+	defaultRetries    = 2
+	defaultBackoff    = 200 * time.Millisecond
+	defaultMaxBackoff = 2 * time.Second
+
+	// drainLimit bounds the body read from a response that is retried
+	drainLimit = 64 << 10
+)
+
+// ErrCircuitOpen is returned without a request while the breaker is open
+var ErrCircuitOpen = breaker.ErrOpen
+
+type Client struct {
+	client     *http.Client
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	breaker    *breaker.Breaker
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func New(opts ...Option) *Client {
+	c := &Client{
+		client:     &http.Client{Timeout: defaultTimeout},
+		retries:    defaultRetries,
+		backoff:    defaultBackoff,
+		maxBackoff: defaultMaxBackoff,
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.breaker == nil {
+		c.breaker = breaker.New()
+	}
+
+	return c
+}
+
+// Do sends req, retrying network errors and 5xx responses. Any other
+// response is returned as is for the caller to check. A request that still
+// fails after all retries counts as one failure of the breaker.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	err := c.breaker.Allow()
+	if err != nil {
+		return nil, fmt.Errorf("httpclient - Do - %s: %w", req.URL.Host, err)
+	}
+
+	res, err := c.do(req)
+
+	switch {
+	case req.Context().Err() != nil:
+		// cancelled by the caller, that's not the dependency's fault
+		c.breaker.Release()
+	case err != nil || res.StatusCode >= http.StatusInternalServerError:
+		c.breaker.Failure()
+	default:
+		c.breaker.Success()
+	}
+
+	return res, err
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		attemptReq, err := rewind(req, attempt)
+		if err != nil {
+			return nil, fmt.Errorf("httpclient - Do - rewind: %w", err)
+		}
+
+		res, err := c.client.Do(attemptReq)
+		if !retryable(res, err) || attempt == c.retries {
+			if err != nil {
+				return nil, fmt.Errorf("httpclient - Do - attempt %d: %w", attempt+1, err)
+			}
+			return res, nil
+		}
+
+		if res != nil {
+			_, _ = io.CopyN(io.Discard, res.Body, drainLimit)
+			res.Body.Close()
+		}
+
+		err = sleep(ctx, c.delay(attempt))
+		if err != nil {
+			return nil, fmt.Errorf("httpclient - Do - attempt %d: %w", attempt+1, err)
+		}
+	}
+}
+
+// delay is the backoff before retry number attempt+1: half of it is fixed,
+// the other half random
+func (c *Client) delay(attempt int) time.Duration {
+	d := c.backoff
+	for i := 0; i < attempt && d < c.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > c.maxBackoff {
+		d = c.maxBackoff
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return d/2 + time.Duration(c.rand.Int63n(int64(d/2)+1))
+}
+
+func retryable(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return res.StatusCode >= http.StatusInternalServerError
+}
+
+// rewind returns req with a fresh body for a retry
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("request body can't be sent again")
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	retry.Body = body
+
+	return retry, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
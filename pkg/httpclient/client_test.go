@@ -0,0 +1,170 @@
+package httpclient
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"user-balance-service/pkg/breaker"
+)
+
+// newTestServer answers with the statuses in order, repeating the last one
+func newTestServer(t *testing.T, statuses ...int) (*httptest.Server, *int64) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt64(&requests, 1))
+		if n > len(statuses) {
+			n = len(statuses)
+		}
+		w.WriteHeader(statuses[n-1])
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func get(t *testing.T, c *Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	res, err := c.Do(req)
+	if res != nil {
+		res.Body.Close()
+	}
+
+	return res, err
+}
+
+func TestClient_Do(t *testing.T) {
+	testCases := []struct {
+		name         string
+		statuses     []int
+		wantStatus   int
+		wantRequests int64
+	}{
+		{
+			name:         "OK",
+			statuses:     []int{http.StatusOK},
+			wantStatus:   http.StatusOK,
+			wantRequests: 1,
+		},
+		{
+			name:         "5xx is retried",
+			statuses:     []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK},
+			wantStatus:   http.StatusOK,
+			wantRequests: 3,
+		},
+		{
+			name:         "retries are bounded",
+			statuses:     []int{http.StatusInternalServerError},
+			wantStatus:   http.StatusInternalServerError,
+			wantRequests: 3,
+		},
+		{
+			name:         "4xx is not retried",
+			statuses:     []int{http.StatusUnauthorized},
+			wantStatus:   http.StatusUnauthorized,
+			wantRequests: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, requests := newTestServer(t, tc.statuses...)
+			c := New(Retries(2), Backoff(time.Millisecond, 2*time.Millisecond))
+
+			res, err := get(t, c, server.URL)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantStatus, res.StatusCode)
+			assert.Equal(t, tc.wantRequests, atomic.LoadInt64(requests))
+		})
+	}
+}
+
+func TestClient_Do_Timeout(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	c := New(Timeout(20*time.Millisecond), Retries(1), Backoff(time.Millisecond, time.Millisecond))
+
+	_, err := get(t, c, server.URL)
+	assert.Error(t, err)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&requests))
+}
+
+func TestClient_Do_CircuitBreaker(t *testing.T) {
+	server, requests := newTestServer(t, http.StatusServiceUnavailable)
+	c := New(Retries(0), CircuitBreaker(breaker.New(breaker.Threshold(2), breaker.Timeout(time.Minute))))
+
+	for i := 0; i < 2; i++ {
+		res, err := get(t, c, server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	}
+
+	// the open breaker fails fast without a request
+	_, err := get(t, c, server.URL)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int64(2), atomic.LoadInt64(requests))
+}
+
+func TestClient_Do_CancelledByCaller(t *testing.T) {
+	server, _ := newTestServer(t, http.StatusServiceUnavailable)
+	b := breaker.New(breaker.Threshold(1))
+	c := New(Retries(5), Backoff(time.Hour, time.Hour), CircuitBreaker(b))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = c.Do(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, breaker.Closed, b.State())
+}
+
+func TestClient_Do_RewindsBody(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	c := New(Backoff(time.Millisecond, time.Millisecond))
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+	require.NoError(t, err)
+
+	res, err := c.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{"payload", "payload"}, bodies)
+}
+
+func TestClient_delay(t *testing.T) {
+	c := New(Backoff(100*time.Millisecond, 300*time.Millisecond))
+
+	for attempt, max := range []time.Duration{100, 200, 300, 300} {
+		max *= time.Millisecond
+		d := c.delay(attempt)
+		assert.GreaterOrEqual(t, d, max/2)
+		assert.LessOrEqual(t, d, max)
+	}
+}
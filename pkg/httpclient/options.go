@@ -0,0 +1,37 @@
+package httpclient
+
+import (
+	"time"
+	"user-balance-service/pkg/breaker"
+)
+
+type Option func(*Client)
+
+// Timeout limits every attempt, including reading the response body
+func Timeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.client.Timeout = timeout
+	}
+}
+
+// Retries is the number of attempts after the first one
+func Retries(retries int) Option {
+	return func(c *Client) {
+		c.retries = retries
+	}
+}
+
+// Backoff is the delay before the first retry, it doubles on every next one
+// up to max. Every delay is randomised by up to a half to spread out retries.
+func Backoff(base, max time.Duration) Option {
+	return func(c *Client) {
+		c.backoff = base
+		c.maxBackoff = max
+	}
+}
+
+func CircuitBreaker(b *breaker.Breaker) Option {
+	return func(c *Client) {
+		c.breaker = b
+	}
+}
@@ -0,0 +1,204 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func rsaPEM(t *testing.T) (private, public []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+}
+
+func ed25519PEM(t *testing.T) (private, public []byte) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+}
+
+func claims() jwt.Claims {
+	return &jwt.StandardClaims{Subject: "1", ExpiresAt: time.Now().Add(time.Minute).Unix()}
+}
+
+func parse(set *Set, token string) error {
+	_, err := jwt.ParseWithClaims(token, &jwt.StandardClaims{}, set.Keyfunc)
+	return err
+}
+
+func TestSet_SignAndVerify(t *testing.T) {
+	rsaPrivate, rsaPublic := rsaPEM(t)
+	edPrivate, edPublic := ed25519PEM(t)
+
+	testCases := []struct {
+		name      string
+		algorithm string
+		private   []byte
+		public    []byte
+	}{
+		{name: "RS256", algorithm: RS256, private: rsaPrivate, public: rsaPublic},
+		{name: "EdDSA", algorithm: EdDSA, private: edPrivate, public: edPublic},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			signing, err := ParsePEM("k1", tc.algorithm, tc.private)
+			require.NoError(t, err)
+			signer, err := New(signing)
+			require.NoError(t, err)
+
+			token, err := signer.Sign(claims())
+			require.NoError(t, err)
+
+			parsed, err := jwt.Parse(token, signer.Keyfunc)
+			require.NoError(t, err)
+			assert.Equal(t, "k1", parsed.Header["kid"])
+			assert.Equal(t, tc.algorithm, parsed.Header["alg"])
+
+			// the public key alone verifies
+			public, err := ParsePEM("k1", tc.algorithm, tc.public)
+			require.NoError(t, err)
+			assert.False(t, public.CanSign())
+			other, err := NewHMAC("other", []byte("0123456789abcdef0123456789abcdef"))
+			require.NoError(t, err)
+			verifier, err := New(other, public)
+			require.NoError(t, err)
+
+			assert.NoError(t, parse(verifier, token))
+		})
+	}
+}
+
+func TestSet_Rotation(t *testing.T) {
+	oldKey, err := NewHMAC("old", []byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	edPrivate, _ := ed25519PEM(t)
+	newKey, err := ParsePEM("new", EdDSA, edPrivate)
+	require.NoError(t, err)
+
+	before, err := New(oldKey)
+	require.NoError(t, err)
+	oldToken, err := before.Sign(claims())
+	require.NoError(t, err)
+
+	// the new key signs, the old one still verifies
+	during, err := New(newKey, oldKey)
+	require.NoError(t, err)
+	newToken, err := during.Sign(claims())
+	require.NoError(t, err)
+	assert.NoError(t, parse(during, oldToken))
+	assert.NoError(t, parse(during, newToken))
+
+	// once the old key is dropped its tokens are rejected
+	after, err := New(newKey)
+	require.NoError(t, err)
+	assert.NoError(t, parse(after, newToken))
+	var validationErr *jwt.ValidationError
+	require.ErrorAs(t, parse(after, oldToken), &validationErr)
+	assert.ErrorIs(t, validationErr.Inner, ErrUnknownKey)
+}
+
+func TestSet_Keyfunc_AlgorithmConfusion(t *testing.T) {
+	rsaPrivate, rsaPublic := rsaPEM(t)
+	key, err := ParsePEM("k1", RS256, rsaPrivate)
+	require.NoError(t, err)
+	set, err := New(key)
+	require.NoError(t, err)
+
+	// an HS256 token signed with the public key as the secret
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+	token.Header["kid"] = "k1"
+	forged, err := token.SignedString(rsaPublic)
+	require.NoError(t, err)
+
+	assert.Error(t, parse(set, forged))
+}
+
+func TestNew(t *testing.T) {
+	_, rsaPublic := rsaPEM(t)
+	public, err := ParsePEM("k1", RS256, rsaPublic)
+	require.NoError(t, err)
+
+	_, err = New(public)
+	assert.ErrorIs(t, err, ErrNoPrivateKey)
+
+	secret, err := NewHMAC("k1", []byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	_, err = New(secret, public)
+	assert.Error(t, err)
+
+	_, err = NewHMAC("k2", []byte("short"))
+	assert.Error(t, err)
+
+	_, err = ParsePEM("k3", "HS512", rsaPublic)
+	assert.ErrorIs(t, err, ErrUnknownAlgorithm)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("0123456789abcdef0123456789abcdef\n"), 0600))
+	rsaPrivate, _ := rsaPEM(t)
+	rsaFile := filepath.Join(dir, "rsa.pem")
+	require.NoError(t, os.WriteFile(rsaFile, rsaPrivate, 0600))
+
+	secret, err := Load("k1", HS256, secretFile)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("0123456789abcdef0123456789abcdef"), secret.public)
+
+	rsaKey, err := Load("k2", RS256, rsaFile)
+	require.NoError(t, err)
+	assert.True(t, rsaKey.CanSign())
+
+	_, err = Load("k3", RS256, filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+}
+
+func TestSet_JWKS(t *testing.T) {
+	secret, err := NewHMAC("a-secret", []byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	rsaPrivate, _ := rsaPEM(t)
+	rsaKey, err := ParsePEM("b-rsa", RS256, rsaPrivate)
+	require.NoError(t, err)
+	_, edPublic := ed25519PEM(t)
+	edKey, err := ParsePEM("c-ed", EdDSA, edPublic)
+	require.NoError(t, err)
+
+	set, err := New(rsaKey, edKey, secret)
+	require.NoError(t, err)
+
+	jwks := set.JWKS()
+	require.Len(t, jwks.Keys, 2)
+
+	assert.Equal(t, "b-rsa", jwks.Keys[0].Id)
+	assert.Equal(t, "RSA", jwks.Keys[0].KeyType)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+	assert.NotEmpty(t, jwks.Keys[0].N)
+
+	assert.Equal(t, "c-ed", jwks.Keys[1].Id)
+	assert.Equal(t, "OKP", jwks.Keys[1].KeyType)
+	assert.Equal(t, "Ed25519", jwks.Keys[1].Curve)
+	assert.Len(t, jwks.Keys[1].X, 43)
+}
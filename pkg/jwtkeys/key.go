@@ -0,0 +1,113 @@
+// Package jwtkeys holds the keys JWTs are signed and verified with. Every key
+// has an id written to the kid header of the tokens it signs, so tokens
+// signed with a retired key keep verifying while the key is still listed.
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"os"
+	"strings"
+)
+
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+
+	// minSecretLength is the HS256 hash size, shorter secrets are brute-forceable
+	minSecretLength = 32
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("jwtkeys: unknown algorithm")
+	ErrNoPrivateKey     = errors.New("jwtkeys: key can't sign, it has no private part")
+)
+
+// Key is an HS256 secret, or an RS256 or EdDSA key pair of which the private
+// part is only needed for signing
+type Key struct {
+	Id        string
+	Algorithm string
+
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// NewHMAC returns an HS256 key, secret signs and verifies
+func NewHMAC(id string, secret []byte) (*Key, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("jwtkeys - NewHMAC - key %q: secret must be at least %d bytes", id, minSecretLength)
+	}
+
+	return &Key{Id: id, Algorithm: HS256, method: jwt.SigningMethodHS256, private: secret, public: secret}, nil
+}
+
+// ParsePEM returns an RS256 or EdDSA key from a PEM encoded private key
+// (PKCS#1 or PKCS#8) or public key (PKIX). A public key only verifies.
+func ParsePEM(id, algorithm string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("jwtkeys - ParsePEM - key %q: no PEM data", id)
+	}
+	private := strings.Contains(block.Type, "PRIVATE KEY")
+
+	key := &Key{Id: id, Algorithm: algorithm}
+	var err error
+
+	switch algorithm {
+	case RS256:
+		key.method = jwt.SigningMethodRS256
+		if private {
+			var rsaKey *rsa.PrivateKey
+			rsaKey, err = jwt.ParseRSAPrivateKeyFromPEM(data)
+			if err == nil {
+				key.private, key.public = rsaKey, &rsaKey.PublicKey
+			}
+		} else {
+			key.public, err = jwt.ParseRSAPublicKeyFromPEM(data)
+		}
+	case EdDSA:
+		key.method = jwt.SigningMethodEdDSA
+		if private {
+			key.private, err = jwt.ParseEdPrivateKeyFromPEM(data)
+			if err == nil {
+				key.public = key.private.(ed25519.PrivateKey).Public()
+			}
+		} else {
+			key.public, err = jwt.ParseEdPublicKeyFromPEM(data)
+		}
+	default:
+		return nil, fmt.Errorf("jwtkeys - ParsePEM - key %q: %w %q", id, ErrUnknownAlgorithm, algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jwtkeys - ParsePEM - key %q: %w", id, err)
+	}
+
+	return key, nil
+}
+
+// Load returns the key of algorithm stored in file: the secret itself for
+// HS256, surrounding whitespace is trimmed, or a PEM key otherwise
+func Load(id, algorithm, file string) (*Key, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("jwtkeys - Load - os.ReadFile: %w", err)
+	}
+
+	if algorithm == HS256 {
+		return NewHMAC(id, []byte(strings.TrimSpace(string(data))))
+	}
+
+	return ParsePEM(id, algorithm, data)
+}
+
+// CanSign reports whether the key has a private part
+func (k *Key) CanSign() bool {
+	return k.private != nil
+}
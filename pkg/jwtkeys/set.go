@@ -0,0 +1,68 @@
+package jwtkeys
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+)
+
+var ErrUnknownKey = errors.New("jwtkeys: unknown key id")
+
+// Set signs tokens with one key and verifies them with any key it holds
+type Set struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// New returns a set signing with signing. verification are keys being
+// rotated in or out; the signing key verifies too.
+func New(signing *Key, verification ...*Key) (*Set, error) {
+	if !signing.CanSign() {
+		return nil, fmt.Errorf("jwtkeys - New - key %q: %w", signing.Id, ErrNoPrivateKey)
+	}
+
+	s := &Set{
+		signing: signing,
+		keys:    map[string]*Key{signing.Id: signing},
+	}
+
+	for _, key := range verification {
+		if _, ok := s.keys[key.Id]; ok {
+			return nil, fmt.Errorf("jwtkeys - New: duplicate key id %q", key.Id)
+		}
+		s.keys[key.Id] = key
+	}
+
+	return s, nil
+}
+
+// Sign returns a token with claims signed with the signing key and its id in
+// the kid header
+func (s *Set) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.signing.method, claims)
+	token.Header["kid"] = s.signing.Id
+
+	signed, err := token.SignedString(s.signing.private)
+	if err != nil {
+		return "", fmt.Errorf("jwtkeys - Sign - token.SignedString: %w", err)
+	}
+
+	return signed, nil
+}
+
+// Keyfunc is a jwt.Keyfunc returning the key named by the kid header. The
+// token must use the algorithm of that key, so a public key can never be
+// used as an HMAC secret.
+func (s *Set) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("jwtkeys: unexpected signing method %q for key %q", token.Method.Alg(), kid)
+	}
+
+	return key.public, nil
+}
@@ -0,0 +1,156 @@
+// Package memcache is an in-process cache with the same API as rediscache.
+package memcache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+	"user-balance-service/pkg/cache"
+)
+
+const (
+	defaultSize   = 10000
+	defaultExpire = 300 * time.Second
+)
+
+type entry struct {
+	key       string
+	data      []byte
+	expiresAt time.Time
+}
+
+// Memory is an LRU cache with a TTL. Values are stored encoded with the
+// codec, so they round-trip exactly like values cached in Redis.
+//
+// Counters live outside the LRU and never expire: evicting a namespace
+// version would make keys of an older version reachable again.
+type Memory struct {
+	size   int
+	expire time.Duration
+	codec  cache.Codec
+	now    func() time.Time
+
+	mu       sync.Mutex
+	items    map[string]*list.Element
+	order    *list.List // front is the most recently used
+	counters map[string]int64
+}
+
+func New(opts ...Option) *Memory {
+	m := &Memory{
+		size:     defaultSize,
+		expire:   defaultExpire,
+		codec:    cache.JSON,
+		now:      time.Now,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		counters: make(map[string]int64),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+func (m *Memory) Set(ctx context.Context, key string, value any) error {
+	data, err := m.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiresAt := m.now().Add(m.expire)
+
+	if element, ok := m.items[key]; ok {
+		e := element.Value.(*entry)
+		e.data, e.expiresAt = data, expiresAt
+		m.order.MoveToFront(element)
+		return nil
+	}
+
+	m.items[key] = m.order.PushFront(&entry{key: key, data: data, expiresAt: expiresAt})
+
+	for m.order.Len() > m.size {
+		m.remove(m.order.Back())
+	}
+
+	return nil
+}
+
+// Get decodes the value stored at key into dst; a missing key is cache.ErrMiss
+func (m *Memory) Get(ctx context.Context, key string, dst any) error {
+	data, ok := m.lookup(key)
+	if !ok {
+		return cache.ErrMiss
+	}
+
+	return m.codec.Unmarshal(data, dst)
+}
+
+func (m *Memory) lookup(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := element.Value.(*entry)
+	if !m.now().Before(e.expiresAt) {
+		m.remove(element)
+		return nil, false
+	}
+
+	m.order.MoveToFront(element)
+	return e.data, true
+}
+
+// Delete removes keys; missing keys are ignored
+func (m *Memory) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := m.items[key]; ok {
+			m.remove(element)
+		}
+	}
+
+	return nil
+}
+
+// Incr increments the counter stored at key
+func (m *Memory) Incr(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.counters[key]++
+	return m.counters[key], nil
+}
+
+// Counter returns the counter stored at key or 0 when it doesn't exist
+func (m *Memory) Counter(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.counters[key], nil
+}
+
+// Len returns the number of cached values, including expired ones not yet evicted
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.order.Len()
+}
+
+func (m *Memory) remove(element *list.Element) {
+	m.order.Remove(element)
+	delete(m.items, element.Value.(*entry).key)
+}
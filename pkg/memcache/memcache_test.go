@@ -0,0 +1,196 @@
+package memcache
+
+import (
+	"context"
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"user-balance-service/pkg/cache"
+	"user-balance-service/pkg/rediscache"
+)
+
+func TestMemory_GetSet(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+
+	_, err := cache.Get[int](ctx, m, "missing")
+	assert.ErrorIs(t, err, cache.ErrMiss)
+
+	require.NoError(t, cache.Set(ctx, m, "account_id_1", map[string]int{"id": 1, "balance": 500}))
+
+	value, err := cache.Get[map[string]int](ctx, m, "account_id_1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"id": 1, "balance": 500}, value)
+}
+
+func TestMemory_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	m := New(Size(2))
+
+	require.NoError(t, m.Set(ctx, "a", 1))
+	require.NoError(t, m.Set(ctx, "b", 2))
+
+	// touch "a", so "b" becomes the least recently used
+	_, err := cache.Get[int](ctx, m, "a")
+	require.NoError(t, err)
+
+	require.NoError(t, m.Set(ctx, "c", 3))
+
+	assert.Equal(t, 2, m.Len())
+	_, err = cache.Get[int](ctx, m, "b")
+	assert.ErrorIs(t, err, cache.ErrMiss)
+	_, err = cache.Get[int](ctx, m, "a")
+	assert.NoError(t, err)
+	_, err = cache.Get[int](ctx, m, "c")
+	assert.NoError(t, err)
+}
+
+func TestMemory_Expire(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	m := New(Expire(time.Minute))
+	m.now = func() time.Time { return now }
+
+	require.NoError(t, m.Set(ctx, "a", 1))
+
+	now = now.Add(59 * time.Second)
+	_, err := cache.Get[int](ctx, m, "a")
+	assert.NoError(t, err)
+
+	now = now.Add(time.Second)
+	_, err = cache.Get[int](ctx, m, "a")
+	assert.ErrorIs(t, err, cache.ErrMiss)
+	assert.Equal(t, 0, m.Len())
+}
+
+func TestMemory_Delete(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+
+	require.NoError(t, m.Set(ctx, "a", 1))
+	require.NoError(t, m.Set(ctx, "b", 2))
+	require.NoError(t, m.Delete(ctx, "a", "b", "missing"))
+
+	assert.Equal(t, 0, m.Len())
+}
+
+func TestMemory_CountersAreNotEvicted(t *testing.T) {
+	ctx := context.Background()
+	m := New(Size(1))
+
+	version, err := m.Counter(ctx, "history_namespace")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), version)
+
+	version, err = m.Incr(ctx, "history_namespace")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), version)
+
+	require.NoError(t, m.Set(ctx, "a", 1))
+	require.NoError(t, m.Set(ctx, "b", 2))
+
+	version, err = m.Counter(ctx, "history_namespace")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), version)
+}
+
+func TestTiered(t *testing.T) {
+	ctx := context.Background()
+
+	miniRedis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer miniRedis.Close()
+
+	back := rediscache.New(redis.NewClient(&redis.Options{Addr: miniRedis.Addr()}))
+	front := New()
+	tiered := NewTiered(front, back)
+
+	// written to both tiers
+	require.NoError(t, tiered.Set(ctx, "a", 1))
+	assert.True(t, miniRedis.Exists("a"))
+	assert.Equal(t, 1, front.Len())
+
+	// a backend hit refills the front tier
+	require.NoError(t, miniRedis.Set("b", "2"))
+	value, err := cache.Get[int](ctx, tiered, "b")
+	require.NoError(t, err)
+	assert.Equal(t, 2, value)
+	assert.Equal(t, 2, front.Len())
+
+	// hot reads don't reach the backend
+	miniRedis.Del("b")
+	_, err = cache.Get[int](ctx, tiered, "b")
+	assert.NoError(t, err)
+
+	require.NoError(t, tiered.Delete(ctx, "a", "b"))
+	assert.False(t, miniRedis.Exists("a"))
+	assert.Equal(t, 0, front.Len())
+
+	// counters are shared through the backend
+	_, err = tiered.Incr(ctx, "history_namespace")
+	require.NoError(t, err)
+	version, err := back.Counter(ctx, "history_namespace")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), version)
+	version, err = front.Counter(ctx, "history_namespace")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), version)
+
+	// and read locally until the local copy expires
+	_, err = back.Incr(ctx, "history_namespace")
+	require.NoError(t, err)
+	version, err = tiered.Counter(ctx, "history_namespace")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), version)
+}
+
+func TestTiered_Counter(t *testing.T) {
+	ctx := context.Background()
+
+	miniRedis, err := miniredis.Run()
+	require.NoError(t, err)
+	defer miniRedis.Close()
+
+	now := time.Now()
+	back := rediscache.New(redis.NewClient(&redis.Options{Addr: miniRedis.Addr(), MaxRetries: -1}))
+	front := New(Expire(time.Second))
+	front.now = func() time.Time { return now }
+	tiered := NewTiered(front, back)
+
+	// a miss is read from the backend and kept locally
+	require.NoError(t, miniRedis.Set("account_version_1", "3"))
+	version, err := tiered.Counter(ctx, "account_version_1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), version)
+
+	// another process bumps the version
+	_, err = back.Incr(ctx, "account_version_1")
+	require.NoError(t, err)
+	version, err = tiered.Counter(ctx, "account_version_1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), version)
+
+	// and it is seen once the local copy expires
+	now = now.Add(time.Second)
+	version, err = tiered.Counter(ctx, "account_version_1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), version)
+
+	// own increments are seen at once
+	version, err = tiered.Incr(ctx, "account_version_1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), version)
+	miniRedis.Close()
+	version, err = tiered.Counter(ctx, "account_version_1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), version)
+
+	// a failed increment drops the local copy
+	_, err = tiered.Incr(ctx, "account_version_1")
+	assert.Error(t, err)
+	_, err = tiered.Counter(ctx, "account_version_1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,29 @@
+package memcache
+
+import (
+	"context"
+	"user-balance-service/pkg/cache"
+)
+
+// Nop caches nothing: every Get is a miss and writes are dropped
+type Nop struct{}
+
+func (Nop) Set(ctx context.Context, key string, value any) error {
+	return nil
+}
+
+func (Nop) Get(ctx context.Context, key string, dst any) error {
+	return cache.ErrMiss
+}
+
+func (Nop) Delete(ctx context.Context, keys ...string) error {
+	return nil
+}
+
+func (Nop) Incr(ctx context.Context, key string) (int64, error) {
+	return 0, nil
+}
+
+func (Nop) Counter(ctx context.Context, key string) (int64, error) {
+	return 0, nil
+}
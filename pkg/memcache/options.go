@@ -0,0 +1,30 @@
+package memcache
+
+import (
+	"time"
+	"user-balance-service/pkg/cache"
+)
+
+type Option func(m *Memory)
+
+// Size bounds the number of cached values; the least recently used ones are
+// evicted first
+func Size(size int) Option {
+	return func(m *Memory) {
+		m.size = size
+	}
+}
+
+func Expire(expire time.Duration) Option {
+	return func(m *Memory) {
+		m.expire = expire
+	}
+}
+
+// Codec sets how values are encoded, cache.JSON by default. Values are kept
+// encoded, so callers never share memory with the cache.
+func Codec(codec cache.Codec) Option {
+	return func(m *Memory) {
+		m.codec = codec
+	}
+}
@@ -0,0 +1,93 @@
+package memcache
+
+import (
+	"context"
+	"user-balance-service/pkg/cache"
+)
+
+// Tiered serves hot reads from process memory and falls back to a shared
+// backend. Writes and deletes go to both tiers, but other processes keep
+// their local copy until it expires, so the front TTL should be short.
+//
+// Counters are cached in the front tier like values, so a hot versioned
+// key costs no backend round trip. Incr updates the local copy at once;
+// other processes see the new version when their copy expires.
+type Tiered struct {
+	front *Memory
+	back  cache.Backend
+}
+
+// NewTiered puts front in front of a shared backend, e.g. rediscache.Redis
+func NewTiered(front *Memory, back cache.Backend) *Tiered {
+	return &Tiered{
+		front: front,
+		back:  back,
+	}
+}
+
+func (t *Tiered) Set(ctx context.Context, key string, value any) error {
+	err := t.back.Set(ctx, key, value)
+	if err != nil {
+		return err
+	}
+
+	return t.front.Set(ctx, key, value)
+}
+
+func (t *Tiered) Get(ctx context.Context, key string, dst any) error {
+	err := t.front.Get(ctx, key, dst)
+	if err == nil {
+		return nil
+	}
+
+	err = t.back.Get(ctx, key, dst)
+	if err != nil {
+		return err
+	}
+
+	// a failed refill only costs another backend read
+	_ = t.front.Set(ctx, key, dst)
+
+	return nil
+}
+
+func (t *Tiered) Delete(ctx context.Context, keys ...string) error {
+	_ = t.front.Delete(ctx, keys...)
+
+	return t.back.Delete(ctx, keys...)
+}
+
+func (t *Tiered) Incr(ctx context.Context, key string) (int64, error) {
+	n, err := t.back.Incr(ctx, key)
+	if err != nil {
+		// the increment may still have reached the backend
+		_ = t.front.Delete(ctx, counterKey(key))
+		return 0, err
+	}
+
+	_ = t.front.Set(ctx, counterKey(key), n)
+
+	return n, nil
+}
+
+func (t *Tiered) Counter(ctx context.Context, key string) (int64, error) {
+	var n int64
+	if t.front.Get(ctx, counterKey(key), &n) == nil {
+		return n, nil
+	}
+
+	n, err := t.back.Counter(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	_ = t.front.Set(ctx, counterKey(key), n)
+
+	return n, nil
+}
+
+// counterKey keeps local copies of counters apart from values, which
+// Memory stores separately from its own counters
+func counterKey(key string) string {
+	return "counter:" + key
+}
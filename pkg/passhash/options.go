@@ -0,0 +1,25 @@
+package passhash
+
+type Option func(*Hasher)
+
+// Algorithm picks the algorithm of new hashes, Argon2id or Bcrypt
+func Algorithm(algorithm string) Option {
+	return func(h *Hasher) {
+		h.algorithm = algorithm
+	}
+}
+
+// Argon2 sets the argon2id memory in KiB, the number of passes and the parallelism
+func Argon2(memory, iterations uint32, parallelism uint8) Option {
+	return func(h *Hasher) {
+		h.memory = memory
+		h.iterations = iterations
+		h.parallelism = parallelism
+	}
+}
+
+func BcryptCost(cost int) Option {
+	return func(h *Hasher) {
+		h.bcryptCost = cost
+	}
+}
@@ -0,0 +1,167 @@
+// Package passhash hashes passwords with argon2id or bcrypt. Every hash has
+// its own random salt and records its algorithm and parameters, so hashes
+// made with older settings still verify and can be detected for rehashing.
+package passhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+
+	// OWASP recommended argon2id settings
+	defaultMemory      = 64 * 1024
+	defaultIterations  = 3
+	defaultParallelism = 2
+	defaultBcryptCost  = 12
+
+	saltLength = 16
+	keyLength  = 32
+)
+
+// ErrUnknownFormat is returned by Verify for hashes it didn't make
+var ErrUnknownFormat = errors.New("passhash: unknown hash format")
+
+type Hasher struct {
+	algorithm   string
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	bcryptCost  int
+}
+
+func New(opts ...Option) (*Hasher, error) {
+	h := &Hasher{
+		algorithm:   Argon2id,
+		memory:      defaultMemory,
+		iterations:  defaultIterations,
+		parallelism: defaultParallelism,
+		bcryptCost:  defaultBcryptCost,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	switch {
+	case h.algorithm != Argon2id && h.algorithm != Bcrypt:
+		return nil, fmt.Errorf("passhash - New: unknown algorithm %q", h.algorithm)
+	case h.memory == 0 || h.iterations == 0 || h.parallelism == 0:
+		return nil, fmt.Errorf("passhash - New: argon2 parameters must be positive")
+	case h.bcryptCost < bcrypt.MinCost || h.bcryptCost > bcrypt.MaxCost:
+		return nil, fmt.Errorf("passhash - New: bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	return h, nil
+}
+
+// Hash returns an encoded hash of password with a new random salt
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("passhash - Hash - bcrypt.GenerateFromPassword: %w", err)
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, saltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", fmt.Errorf("passhash - Hash - rand.Read: %w", err)
+	}
+
+	p := argon2Params{memory: h.memory, iterations: h.iterations, parallelism: h.parallelism}
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, keyLength)
+
+	return p.encode(salt, key), nil
+}
+
+// Verify checks password against hash. rehash reports that the hash was made
+// with another algorithm or other parameters than the current ones.
+func (h *Hasher) Verify(password, hash string) (ok, rehash bool, err error) {
+	switch {
+	case strings.HasPrefix(hash, "$"+Argon2id+"$"):
+		p, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			return false, false, err
+		}
+
+		other := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, uint32(len(key)))
+		ok = subtle.ConstantTimeCompare(key, other) == 1
+		rehash = h.algorithm != Argon2id || p.memory != h.memory || p.iterations != h.iterations || p.parallelism != h.parallelism
+
+		return ok, rehash, nil
+	case strings.HasPrefix(hash, "$2"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, fmt.Errorf("passhash - Verify - bcrypt.CompareHashAndPassword: %w", err)
+		}
+
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return false, false, fmt.Errorf("passhash - Verify - bcrypt.Cost: %w", err)
+		}
+
+		return true, h.algorithm != Bcrypt || cost != h.bcryptCost, nil
+	default:
+		return false, false, ErrUnknownFormat
+	}
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// encode uses the PHC string format, e.g. $argon2id$v=19$m=65536,t=3,p=2$salt$key
+func (p argon2Params) encode(salt, key []byte) string {
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", Argon2id, argon2.Version,
+		p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key))
+}
+
+func decodeArgon2(hash string) (argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return argon2Params{}, nil, nil, ErrUnknownFormat
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return argon2Params{}, nil, nil, fmt.Errorf("passhash - Verify: unsupported argon2 version %q", parts[2])
+	}
+
+	var p argon2Params
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism)
+	if err != nil {
+		return argon2Params{}, nil, nil, fmt.Errorf("passhash - Verify - parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2Params{}, nil, nil, fmt.Errorf("passhash - Verify - salt: %w", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return argon2Params{}, nil, nil, fmt.Errorf("passhash - Verify - key: %w", ErrUnknownFormat)
+	}
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,117 @@
+package passhash
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// cheap parameters keep the tests fast
+func newTestHasher(t *testing.T, opts ...Option) *Hasher {
+	h, err := New(append([]Option{Argon2(1024, 1, 1), BcryptCost(4)}, opts...)...)
+	require.NoError(t, err)
+
+	return h
+}
+
+func TestHasher_HashVerify(t *testing.T) {
+	for _, algorithm := range []string{Argon2id, Bcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			h := newTestHasher(t, Algorithm(algorithm))
+
+			hash, err := h.Hash("qwerty123")
+			require.NoError(t, err)
+
+			ok, rehash, err := h.Verify("qwerty123", hash)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.False(t, rehash)
+
+			ok, _, err = h.Verify("qwerty124", hash)
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			// every hash has its own salt
+			other, err := h.Hash("qwerty123")
+			require.NoError(t, err)
+			assert.NotEqual(t, hash, other)
+		})
+	}
+}
+
+func TestHasher_Hash_Format(t *testing.T) {
+	hash, err := newTestHasher(t).Hash("qwerty123")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), hash)
+}
+
+func TestHasher_Verify_Rehash(t *testing.T) {
+	testCases := []struct {
+		name string
+		from []Option
+		to   []Option
+	}{
+		{
+			name: "argon2 parameters changed",
+			from: []Option{Argon2(1024, 1, 1)},
+			to:   []Option{Argon2(2048, 1, 1)},
+		},
+		{
+			name: "bcrypt cost changed",
+			from: []Option{Algorithm(Bcrypt), BcryptCost(4)},
+			to:   []Option{Algorithm(Bcrypt), BcryptCost(5)},
+		},
+		{
+			name: "bcrypt to argon2id",
+			from: []Option{Algorithm(Bcrypt)},
+			to:   []Option{Algorithm(Argon2id)},
+		},
+		{
+			name: "argon2id to bcrypt",
+			from: []Option{Algorithm(Argon2id)},
+			to:   []Option{Algorithm(Bcrypt)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hash, err := newTestHasher(t, tc.from...).Hash("qwerty123")
+			require.NoError(t, err)
+
+			ok, rehash, err := newTestHasher(t, tc.to...).Verify("qwerty123", hash)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.True(t, rehash)
+		})
+	}
+}
+
+func TestHasher_Verify_Invalid(t *testing.T) {
+	h := newTestHasher(t)
+
+	for _, hash := range []string{
+		"",
+		"7c4a8d09ca3762af61e59520943dc26494f8941b",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5",
+		"$2a$04$invalid",
+	} {
+		ok, _, err := h.Verify("qwerty123", hash)
+		assert.Error(t, err, hash)
+		assert.False(t, ok)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	for _, opts := range [][]Option{
+		{Algorithm("md5")},
+		{Argon2(0, 1, 1)},
+		{BcryptCost(100)},
+	} {
+		_, err := New(opts...)
+		assert.Error(t, err)
+	}
+}
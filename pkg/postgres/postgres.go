@@ -63,8 +63,10 @@ func New(url string, opts ...Option) (*Postgres, error) {
 	poolConfig.MaxConns = int32(pg.maxPoolSize)
 
 	for pg.connAttempts > 0 {
-		pg.Pool, err = pgxpool.ConnectConfig(context.Background(), poolConfig)
+		var pool *pgxpool.Pool
+		pool, err = pgxpool.ConnectConfig(context.Background(), poolConfig)
 		if err == nil {
+			pg.Pool = tracedPgxPool{tracedPool: tracedPool{pool}, pool: pool}
 			break
 		}
 
@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+	"user-balance-service/pkg/tracing"
+)
+
+var tracer = tracing.Tracer("user-balance-service/pkg/postgres")
+
+// tracedPool starts a client span for every statement, including the ones
+// of its transactions. The statement is recorded without its arguments.
+// Spans of queries end once their rows are read: the query runs, and can
+// fail, until then.
+type tracedPool struct {
+	PgxPool
+}
+
+// tracedPgxPool is tracedPool of a *pgxpool.Pool, keeping the statistics of
+// the pool available for metrics
+type tracedPgxPool struct {
+	tracedPool
+	pool *pgxpool.Pool
+}
+
+func (p tracedPgxPool) Stat() *pgxpool.Stat {
+	return p.pool.Stat()
+}
+
+func startSpan(ctx context.Context, sql string) (context.Context, trace.Span) {
+	operation := sql
+	if i := strings.IndexAny(sql, " \n\t"); i > 0 {
+		operation = sql[:i]
+	}
+	operation = strings.ToUpper(operation)
+
+	return tracer.Start(ctx, "postgres "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationKey.String(operation),
+			semconv.DBStatementKey.String(sql),
+		),
+	)
+}
+
+func (p tracedPool) Exec(ctx context.Context, sql string, args ...interface{}) (_ pgconn.CommandTag, err error) {
+	ctx, span := startSpan(ctx, sql)
+	defer tracing.End(span, &err)
+
+	return p.PgxPool.Exec(ctx, sql, args...)
+}
+
+func (p tracedPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := startSpan(ctx, sql)
+
+	rows, err := p.PgxPool.Query(ctx, sql, args...)
+	return traceRows(span, rows, err)
+}
+
+func (p tracedPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, span := startSpan(ctx, sql)
+
+	return tracedRow{Row: p.PgxPool.QueryRow(ctx, sql, args...), span: span}
+}
+
+func (p tracedPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	return p.BeginTx(ctx, pgx.TxOptions{})
+}
+
+func (p tracedPool) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (_ pgx.Tx, err error) {
+	ctx, span := startSpan(ctx, "BEGIN")
+	defer tracing.End(span, &err)
+
+	tx, err := p.PgxPool.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return tracedTx{tx}, nil
+}
+
+type tracedTx struct {
+	pgx.Tx
+}
+
+func (t tracedTx) Exec(ctx context.Context, sql string, args ...interface{}) (_ pgconn.CommandTag, err error) {
+	ctx, span := startSpan(ctx, sql)
+	defer tracing.End(span, &err)
+
+	return t.Tx.Exec(ctx, sql, args...)
+}
+
+func (t tracedTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := startSpan(ctx, sql)
+
+	rows, err := t.Tx.Query(ctx, sql, args...)
+	return traceRows(span, rows, err)
+}
+
+func (t tracedTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, span := startSpan(ctx, sql)
+
+	return tracedRow{Row: t.Tx.QueryRow(ctx, sql, args...), span: span}
+}
+
+func (t tracedTx) Commit(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "COMMIT")
+	defer tracing.End(span, &err)
+
+	return t.Tx.Commit(ctx)
+}
+
+func (t tracedTx) Rollback(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "ROLLBACK")
+	defer tracing.End(span, &err)
+
+	return t.Tx.Rollback(ctx)
+}
+
+// tracedRow ends the span of QueryRow when the row is scanned. No rows
+// isn't a failure of the span.
+type tracedRow struct {
+	pgx.Row
+	span trace.Span
+}
+
+func (r tracedRow) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		tracing.Fail(r.span, err)
+	}
+	r.span.End()
+
+	return err
+}
+
+// traceRows ends span right away if the query failed, otherwise when its
+// rows are read to the end or closed
+func traceRows(span trace.Span, rows pgx.Rows, err error) (pgx.Rows, error) {
+	if err != nil {
+		tracing.Fail(span, err)
+		span.End()
+		return nil, err
+	}
+
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+// tracedRows records the errors of reading rows on the span of their query
+type tracedRows struct {
+	pgx.Rows
+	span  trace.Span
+	ended bool
+}
+
+func (r *tracedRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+
+	r.end()
+	return false
+}
+
+func (r *tracedRows) Scan(dest ...interface{}) error {
+	err := r.Rows.Scan(dest...)
+	if err != nil {
+		tracing.Fail(r.span, err)
+	}
+
+	return err
+}
+
+func (r *tracedRows) Close() {
+	r.Rows.Close()
+	r.end()
+}
+
+func (r *tracedRows) end() {
+	if r.ended {
+		return
+	}
+	r.ended = true
+
+	err := r.Rows.Err()
+	tracing.End(r.span, &err)
+}
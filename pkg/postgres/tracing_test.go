@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+// record sends the spans of the test to the returned recorder
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	})
+
+	return recorder
+}
+
+func newTracedPool(t *testing.T) (tracedPool, pgxmock.PgxPoolIface) {
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	return tracedPool{mockPool}, mockPool
+}
+
+func TestTracedPool_Exec(t *testing.T) {
+	recorder := record(t)
+	pool, mockPool := newTracedPool(t)
+
+	mockPool.ExpectExec("UPDATE accounts").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	_, err := pool.Exec(context.Background(), "UPDATE accounts SET balance = $1", 100)
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "postgres UPDATE", spans[0].Name())
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
+
+func TestTracedPool_Query(t *testing.T) {
+	recorder := record(t)
+	pool, mockPool := newTracedPool(t)
+	ctx := context.Background()
+
+	// the span lasts until the rows are read and gets the error of reading
+	readErr := errors.New("connection reset")
+	mockPool.ExpectQuery("SELECT id FROM accounts").
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).RowError(2, readErr))
+
+	rows, err := pool.Query(ctx, "SELECT id FROM accounts")
+	require.NoError(t, err)
+	assert.Empty(t, recorder.Ended())
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	rows.Close()
+	assert.ErrorIs(t, rows.Err(), readErr)
+	assert.Equal(t, []int{1, 2}, ids)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "postgres SELECT", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+
+	// a failed query ends its span at once
+	mockPool.ExpectQuery("SELECT id FROM accounts").WillReturnError(readErr)
+
+	_, err = pool.Query(ctx, "SELECT id FROM accounts")
+	assert.ErrorIs(t, err, readErr)
+	spans = recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
+
+func TestTracedPool_QueryRow(t *testing.T) {
+	testCases := []struct {
+		name       string
+		rows       *pgxmock.Rows
+		err        error
+		wantErr    error
+		wantStatus codes.Code
+	}{
+		{
+			name:       "row",
+			rows:       pgxmock.NewRows([]string{"balance"}).AddRow(100),
+			wantStatus: codes.Unset,
+		},
+		{
+			name:       "no rows isn't a failure",
+			err:        pgx.ErrNoRows,
+			wantErr:    pgx.ErrNoRows,
+			wantStatus: codes.Unset,
+		},
+		{
+			name:       "scan error",
+			rows:       pgxmock.NewRows([]string{"balance"}).AddRow("not a number"),
+			wantStatus: codes.Error,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := record(t)
+			pool, mockPool := newTracedPool(t)
+
+			expect := mockPool.ExpectQuery("SELECT balance FROM accounts")
+			if tc.err != nil {
+				expect.WillReturnError(tc.err)
+			} else {
+				expect.WillReturnRows(tc.rows)
+			}
+
+			row := pool.QueryRow(context.Background(), "SELECT balance FROM accounts WHERE id = $1", 1)
+			assert.Empty(t, recorder.Ended())
+
+			var balance int
+			err := row.Scan(&balance)
+			switch {
+			case tc.wantErr != nil:
+				assert.ErrorIs(t, err, tc.wantErr)
+			case tc.wantStatus == codes.Error:
+				assert.Error(t, err)
+			default:
+				assert.NoError(t, err)
+			}
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			assert.Equal(t, tc.wantStatus, spans[0].Status().Code)
+		})
+	}
+}
+
+func TestTracedTx(t *testing.T) {
+	recorder := record(t)
+	pool, mockPool := newTracedPool(t)
+	ctx := context.Background()
+
+	mockPool.ExpectBegin()
+	mockPool.ExpectExec("UPDATE accounts").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectQuery("SELECT balance FROM accounts").WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(100))
+	mockPool.ExpectCommit()
+
+	tx, err := pool.Begin(ctx)
+	require.NoError(t, err)
+	_, err = tx.Exec(ctx, "UPDATE accounts SET balance = balance - $1", 100)
+	require.NoError(t, err)
+	var balance int
+	require.NoError(t, tx.QueryRow(ctx, "SELECT balance FROM accounts").Scan(&balance))
+	require.NoError(t, tx.Commit(ctx))
+
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	assert.Equal(t, []string{"postgres BEGIN", "postgres UPDATE", "postgres SELECT", "postgres COMMIT"}, names)
+}
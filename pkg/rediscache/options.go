@@ -1,6 +1,9 @@
 package rediscache
 
-import "time"
+import (
+	"time"
+	"user-balance-service/pkg/cache"
+)
 
 type Option func(r *Redis)
 
@@ -9,3 +12,10 @@ func Expire(expire time.Duration) Option {
 		r.expire = expire
 	}
 }
+
+// Codec sets how values are encoded, cache.JSON by default
+func Codec(codec cache.Codec) Option {
+	return func(r *Redis) {
+		r.codec = codec
+	}
+}
@@ -2,22 +2,31 @@ package rediscache
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
 	"time"
+	"user-balance-service/pkg/cache"
+	"user-balance-service/pkg/tracing"
 )
 
 const defaultExpire = 300 * time.Second
 
+var tracer = tracing.Tracer("user-balance-service/pkg/rediscache")
+
 type Redis struct {
 	client *redis.Client
 	expire time.Duration
+	codec  cache.Codec
 }
 
 func New(client *redis.Client, opts ...Option) *Redis {
 	r := &Redis{
 		client: client,
 		expire: defaultExpire,
+		codec:  cache.JSON,
 	}
 
 	for _, opt := range opts {
@@ -27,28 +36,80 @@ func New(client *redis.Client, opts ...Option) *Redis {
 	return r
 }
 
-func (r *Redis) Set(ctx context.Context, key string, value any) error {
-	data, err := json.Marshal(value)
+// startSpan starts a client span of a Redis command on keys
+func startSpan(ctx context.Context, command string, keys ...string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "redis "+command,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemRedis,
+			semconv.DBOperationKey.String(command),
+			attribute.StringSlice("cache.keys", keys),
+		),
+	)
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value any) (err error) {
+	ctx, span := startSpan(ctx, "SET", key)
+	defer tracing.End(span, &err)
+
+	data, err := r.codec.Marshal(value)
 	if err != nil {
 		return err
 	}
 
-	r.client.Set(ctx, key, data, r.expire)
-	return nil
+	return r.client.Set(ctx, key, data, r.expire).Err()
 }
 
-func (r *Redis) Get(ctx context.Context, key string) (any, error) {
-	var value any
+// Get decodes the value stored at key into dst; a missing key is cache.ErrMiss
+func (r *Redis) Get(ctx context.Context, key string, dst any) error {
+	ctx, span := startSpan(ctx, "GET", key)
+	defer span.End()
 
-	result, err := r.client.Get(ctx, key).Result()
+	data, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// a miss isn't a failure of the span
+		span.SetAttributes(attribute.Bool("cache.hit", false))
+		return cache.ErrMiss
+	}
 	if err != nil {
-		return nil, err
+		tracing.Fail(span, err)
+		return err
 	}
+	span.SetAttributes(attribute.Bool("cache.hit", true))
 
-	err = json.Unmarshal([]byte(result), &value)
+	err = r.codec.Unmarshal(data, dst)
 	if err != nil {
-		return nil, err
+		tracing.Fail(span, err)
+	}
+
+	return err
+}
+
+// Delete removes keys; missing keys are ignored
+func (r *Redis) Delete(ctx context.Context, keys ...string) (err error) {
+	ctx, span := startSpan(ctx, "DEL", keys...)
+	defer tracing.End(span, &err)
+
+	return r.client.Del(ctx, keys...).Err()
+}
+
+// Incr increments the counter stored at key. Counters never expire.
+func (r *Redis) Incr(ctx context.Context, key string) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "INCR", key)
+	defer tracing.End(span, &err)
+
+	return r.client.Incr(ctx, key).Result()
+}
+
+// Counter returns the counter stored at key or 0 when it doesn't exist
+func (r *Redis) Counter(ctx context.Context, key string) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "GET", key)
+	defer tracing.End(span, &err)
+
+	value, err := r.client.Get(ctx, key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
 	}
 
-	return value, nil
+	return value, err
 }
@@ -0,0 +1,81 @@
+package rediscache_test
+
+import (
+	"context"
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+	"user-balance-service/pkg/cache"
+	"user-balance-service/pkg/rediscache"
+)
+
+// record sends the spans of the test to the returned recorder
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	})
+
+	return recorder
+}
+
+func attributeValue(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+func TestRedis_Spans(t *testing.T) {
+	recorder := record(t)
+	ctx := context.Background()
+
+	miniRedis, err := miniredis.Run()
+	require.NoError(t, err)
+	r := rediscache.New(redis.NewClient(&redis.Options{Addr: miniRedis.Addr(), MaxRetries: -1}))
+
+	require.NoError(t, r.Set(ctx, "account_1", 100))
+	var value int
+	require.NoError(t, r.Get(ctx, "account_1", &value))
+	assert.ErrorIs(t, r.Get(ctx, "account_2", &value), cache.ErrMiss)
+	_, err = r.Incr(ctx, "account_version_1")
+	require.NoError(t, err)
+
+	miniRedis.Close()
+	assert.Error(t, r.Delete(ctx, "account_1"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 5)
+
+	set, hit, miss, incr, del := spans[0], spans[1], spans[2], spans[3], spans[4]
+	assert.Equal(t, "redis SET", set.Name())
+	assert.Equal(t, trace.SpanKindClient, set.SpanKind())
+	assert.Equal(t, []string{"account_1"}, attributeValue(set, "cache.keys").AsStringSlice())
+
+	assert.Equal(t, "redis GET", hit.Name())
+	assert.True(t, attributeValue(hit, "cache.hit").AsBool())
+	assert.Equal(t, codes.Unset, hit.Status().Code)
+
+	// a miss isn't a failure
+	assert.Equal(t, "redis GET", miss.Name())
+	assert.False(t, attributeValue(miss, "cache.hit").AsBool())
+	assert.Equal(t, codes.Unset, miss.Status().Code)
+
+	assert.Equal(t, "redis INCR", incr.Name())
+
+	assert.Equal(t, "redis DEL", del.Name())
+	assert.Equal(t, codes.Error, del.Status().Code)
+	assert.NotEmpty(t, del.Events(), "the error is recorded")
+}
@@ -0,0 +1,102 @@
+// Package totp generates and checks RFC 6238 time-based one-time passwords
+// with the parameters authenticator apps expect by default: HMAC-SHA1,
+// 6 digits and 30 second steps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Digits = 6
+	Period = 30 * time.Second
+
+	// secretLength is the size of an HMAC-SHA1 key, as RFC 4226 recommends
+	secretLength = 20
+	// skew is how many steps a code may be off by to allow for clock drift
+	skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret returns a random base32-encoded secret
+func NewSecret() (string, error) {
+	b := make([]byte, secretLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("totp - NewSecret - rand.Read: %w", err)
+	}
+
+	return encoding.EncodeToString(b), nil
+}
+
+// URI is the otpauth:// URI that authenticator apps read from a QR code
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(int(Period/time.Second)))
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Step is the number of the time step t falls into
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code returns the code of secret for the time step
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("totp - Code - encoding.DecodeString: %w", err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", Digits, value%1000000), nil
+}
+
+// Validate checks code against the steps around t and returns the step it
+// matched. Callers should reject steps not later than the last one used,
+// so that a code can't be replayed.
+func Validate(secret, code string, t time.Time) (step int64, ok bool, err error) {
+	if len(code) != Digits {
+		return 0, false, nil
+	}
+
+	current := Step(t)
+	for s := current - skew; s <= current+skew; s++ {
+		want, err := Code(secret, s)
+		if err != nil {
+			return 0, false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return s, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
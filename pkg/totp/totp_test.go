@@ -0,0 +1,73 @@
+package totp
+
+import (
+	"encoding/base32"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors
+var rfc6238Secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// the 8 digit codes of RFC 6238 appendix B, truncated to 6
+	testCases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tc := range testCases {
+		code, err := Code(rfc6238Secret, Step(time.Unix(tc.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, code, tc.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	step, ok, err := Validate(rfc6238Secret, "081804", now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	// a code of the previous step is still accepted
+	step, ok, err = Validate(rfc6238Secret, "081804", now.Add(Period))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	_, ok, err = Validate(rfc6238Secret, "081804", now.Add(2*Period))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, ok, err = Validate(rfc6238Secret, "81804", now)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, err = Validate("not base32!", "081804", now)
+	assert.Error(t, err)
+}
+
+func TestNewSecret(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	_, err = Code(secret, 1)
+	assert.NoError(t, err)
+}
+
+func TestURI(t *testing.T) {
+	assert.Equal(t,
+		"otpauth://totp/User%20Balance:qwe?algorithm=SHA1&digits=6&issuer=User+Balance&period=30&secret=JBSWY3DPEHPK3PXP",
+		URI("User Balance", "qwe", "JBSWY3DPEHPK3PXP"))
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// Middleware starts a server span for every request, continuing the trace
+// of the caller if the request carries one (e.g. a W3C traceparent header).
+// Spans are named after the route template, e.g. "POST /api/v2/transfers".
+func Middleware(serverName string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			ctx, span := tracer.Start(ctx, req.Method+" "+c.Path(),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(semconv.NetAttributesFromHTTPRequest("tcp", req)...),
+				trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(serverName, c.Path(), req)...),
+			)
+			defer span.End()
+
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+			if err != nil {
+				span.RecordError(err)
+				// answer now, so the span gets the status the client does
+				c.Error(err)
+			}
+			if errors.Is(err, echo.ErrNotFound) {
+				// echo gives the request path as is when no route matched
+				span.SetName(req.Method)
+			}
+
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(status)...)
+			span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(status, trace.SpanKindServer))
+
+			return err
+		}
+	}
+}
+
+// Doer sends HTTP requests, e.g. *http.Client or *httpclient.Client
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// NewDoer makes next send the trace context with every request and starts
+// a client span for it. Service names the called dependency.
+func NewDoer(service string, next Doer) Doer {
+	return &doer{
+		service: service,
+		next:    next,
+	}
+}
+
+type doer struct {
+	service string
+	next    Doer
+}
+
+func (d *doer) Do(req *http.Request) (*http.Response, error) {
+	ctx, span := tracer.Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.PeerServiceKey.String(d.service)),
+		trace.WithAttributes(semconv.HTTPClientAttributesFromHTTPRequest(req)...),
+	)
+	defer span.End()
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := d.next.Do(req)
+	if err != nil {
+		Fail(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(res.StatusCode)...)
+	span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(res.StatusCode, trace.SpanKindClient))
+
+	return res, nil
+}
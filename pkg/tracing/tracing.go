@@ -0,0 +1,43 @@
+// Package tracing helps to trace requests with OpenTelemetry: a middleware
+// starts a span for every request to echo, Doer continues the trace in
+// outgoing requests, and End finishes the spans of the layers in between.
+//
+// Spans are made with the global tracer provider and the trace context is
+// read and written with the global propagator, see otel.SetTracerProvider
+// and otel.SetTextMapPropagator. Until they're set nothing is recorded.
+package tracing
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = Tracer("user-balance-service/pkg/tracing")
+
+// Tracer is the name of an instrumented package. The tracer is looked up on
+// every span, so spans go to the tracer provider set at the time.
+type Tracer string
+
+func (t Tracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(string(t)).Start(ctx, name, opts...)
+}
+
+// End records *err on span, if there is one, and ends the span. Defer it
+// with the named error result of the traced function:
+//
+//	ctx, span := tracer.Start(ctx, "AccountRepo.WriteOff")
+//	defer tracing.End(span, &err)
+func End(span trace.Span, err *error) {
+	if *err != nil {
+		Fail(span, *err)
+	}
+	span.End()
+}
+
+// Fail records err on span and marks the span failed
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,184 @@
+package tracing_test
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-balance-service/pkg/tracing"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// record sends the spans of the test to the returned recorder
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	})
+
+	return recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		parent     string
+		wantName   string
+		wantStatus codes.Code
+		wantParent bool
+	}{
+		{
+			name:     "new trace",
+			method:   http.MethodGet,
+			path:     "/accounts/1",
+			wantName: "GET /accounts/:id",
+		},
+		{
+			name:       "continues the caller's trace",
+			method:     http.MethodGet,
+			path:       "/accounts/1",
+			parent:     traceparent,
+			wantName:   "GET /accounts/:id",
+			wantParent: true,
+		},
+		{
+			name:       "handler error",
+			method:     http.MethodGet,
+			path:       "/accounts/2",
+			wantName:   "GET /accounts/:id",
+			wantStatus: codes.Error,
+		},
+		{
+			name:     "client error",
+			method:   http.MethodGet,
+			path:     "/accounts/3",
+			wantName: "GET /accounts/:id",
+		},
+		{
+			name:     "no route",
+			method:   http.MethodGet,
+			path:     "/unknown/42",
+			wantName: "GET",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := record(t)
+
+			var handlerSpan trace.SpanContext
+			e := echo.New()
+			e.Use(tracing.Middleware("test"))
+			e.GET("/accounts/:id", func(c echo.Context) error {
+				handlerSpan = trace.SpanContextFromContext(c.Request().Context())
+				switch c.Param("id") {
+				case "2":
+					return errors.New("database is down")
+				case "3":
+					return echo.ErrForbidden
+				}
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.parent != "" {
+				req.Header.Set("traceparent", tc.parent)
+			}
+			e.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			span := spans[0]
+			assert.Equal(t, tc.wantName, span.Name())
+			assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+			assert.Equal(t, tc.wantStatus, span.Status().Code)
+			assert.Equal(t, tc.wantParent, span.Parent().IsValid())
+			if tc.wantParent {
+				assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+			}
+			if handlerSpan.IsValid() {
+				assert.Equal(t, span.SpanContext().SpanID(), handlerSpan.SpanID())
+			}
+		})
+	}
+}
+
+func TestDoer(t *testing.T) {
+	recorder := record(t)
+
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("traceparent")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	ctx, parent := otel.Tracer("test").Start(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "parent")
+	client := tracing.NewDoer("cbr", server.Client())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/ok", nil)
+	require.NoError(t, err)
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Empty(t, req.Header.Get("traceparent"), "the caller's request is left as is")
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/fail", nil)
+	require.NoError(t, err)
+	res, err = client.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	ok, failed := spans[0], spans[1]
+	assert.Equal(t, "HTTP GET", ok.Name())
+	assert.Equal(t, trace.SpanKindClient, ok.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), ok.Parent().SpanID())
+	assert.Equal(t, codes.Unset, ok.Status().Code)
+	assert.Equal(t, codes.Error, failed.Status().Code)
+
+	// the server got the trace context of the client span
+	assert.Contains(t, got, failed.SpanContext().TraceID().String())
+	assert.Contains(t, got, failed.SpanContext().SpanID().String())
+}
+
+func TestEnd(t *testing.T) {
+	recorder := record(t)
+
+	func() (err error) {
+		_, span := otel.Tracer("test").Start(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "ok")
+		defer tracing.End(span, &err)
+		return nil
+	}()
+	func() (err error) {
+		_, span := otel.Tracer("test").Start(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "failed")
+		defer tracing.End(span, &err)
+		return errors.New("failed")
+	}()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "failed", spans[1].Status().Description)
+	require.Len(t, spans[1].Events(), 1)
+	assert.Equal(t, "exception", spans[1].Events()[0].Name)
+}